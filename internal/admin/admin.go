@@ -0,0 +1,149 @@
+// Package admin 管理后台：为注册的 ViewSet 自动生成增删改查页面，类似 Django admin
+//
+// 页面是一个单页应用，通过各 ViewSet 自己的接口读写数据（沿用浏览器的会话 Cookie），
+// 因此 ViewSet 上配置的权限、限流、审计等全部照常生效；访问管理后台本身需要通过 Site.Permission 的检查
+package admin
+
+import (
+	"embed"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"go-viewset/internal/viewset"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/admin.html
+var templates embed.FS
+
+var pageTemplate = template.Must(template.ParseFS(templates, "templates/admin.html"))
+
+// Site 管理后台
+type Site struct {
+	Title string
+
+	// Permission 访问管理后台需要的权限，默认只允许管理员（viewset.IsAdmin），为 nil 时同样只允许管理员；
+	// 允许任何请求访问需要显式设置为 viewset.AllowAny
+	Permission viewset.Permission
+
+	resources []Resource
+}
+
+// Resource 管理后台中的一个资源，对应一个 ViewSet
+type Resource struct {
+	Name     string        `json:"name"`
+	Prefix   string        `json:"prefix"` // 集合接口的路径，例如 "/api/users"
+	Key      string        `json:"key"`    // 主键的 JSON 字段名
+	Fields   []*meta.Field `json:"fields"`
+	Filters  []string      `json:"filters"`
+	Ordering []string      `json:"ordering"`
+	Search   bool          `json:"search"`
+
+	// Actions 可以批量执行的对象 action，对选中的每个对象调用一次
+	Actions []Action `json:"actions"`
+}
+
+// Action 对象 action
+type Action struct {
+	Method string `json:"method"`
+	Name   string `json:"name"`
+}
+
+// New 创建管理后台
+func New(title string) *Site {
+	return &Site{Title: title, Permission: viewset.IsAdmin}
+}
+
+// Add 添加资源，prefix 为 ViewSet 注册的完整路径；没有模型元数据的 ViewSet 不添加，返回 false
+func (s *Site) Add(prefix string, vs viewset.BaseViewSet) bool {
+	m, ok := vs.(interface{ Meta() *meta.Model })
+	if !ok || m.Meta() == nil {
+		return false
+	}
+	model := m.Meta()
+
+	r := Resource{Name: model.Name, Prefix: strings.TrimSuffix(prefix, "/")}
+	for _, f := range model.Fields {
+		if f.JSONName == "" || f.JSONName == "-" {
+			continue
+		}
+		if f.PrimaryKey && r.Key == "" {
+			r.Key = f.JSONName
+		}
+		r.Fields = append(r.Fields, f)
+	}
+	if q, ok := vs.(interface {
+		QueryFields() (filters, ordering []string, search bool)
+	}); ok {
+		r.Filters, r.Ordering, r.Search = q.QueryFields()
+	}
+	for _, a := range viewset.DiscoverActions(vs) {
+		if a.Detail && a.Method != http.MethodGet {
+			r.Actions = append(r.Actions, Action{Method: a.Method, Name: a.Name})
+		}
+	}
+	s.resources = append(s.resources, r)
+	return true
+}
+
+// Resources 返回已添加的资源，按添加顺序排列
+func (s *Site) Resources() []Resource {
+	return append([]Resource(nil), s.resources...)
+}
+
+// Register 注册管理后台的页面 GET <base>/ 和资源描述 GET <base>/schema.json
+func (s *Site) Register(r *gin.Engine, base string, handlers ...gin.HandlerFunc) {
+	base = strings.TrimSuffix(base, "/")
+	group := r.Group(base, append(handlers[:len(handlers):len(handlers)], s.authorize)...)
+	page := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		if err := pageTemplate.Execute(c.Writer, gin.H{"Title": s.Title, "Schema": base + "/schema.json"}); err != nil {
+			c.Error(err)
+		}
+	}
+	group.GET("", page)
+	group.GET("/", page)
+	group.GET("/schema.json", s.schema)
+}
+
+// schema 返回资源描述和解析响应所需的外层结构配置
+func (s *Site) schema(c *gin.Context) {
+	rc := utils.CurrentResponseConfig()
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"title":     s.Title,
+		"resources": s.resources,
+		"response": gin.H{
+			"envelope":    !rc.DisableEnvelope,
+			"code":        rc.CodeKey,
+			"successCode": rc.SuccessCode,
+			"data":        rc.DataKey,
+			"msg":         rc.MsgKey,
+			"pagination":  rc.PaginationKey,
+			"errors":      rc.ErrorsKey,
+		},
+	})
+}
+
+// authorize 检查访问管理后台的权限，未登录返回 401，无权返回 403
+func (s *Site) authorize(c *gin.Context) {
+	perm := s.Permission
+	if perm == nil {
+		perm = viewset.IsAdmin
+	}
+	if perm.HasPermission(c, "admin") {
+		c.Next()
+		return
+	}
+	if _, ok := c.Get(viewset.ContextUserID); !ok {
+		utils.Unauthorized(c, "请先登录")
+	} else {
+		utils.Forbidden(c, "没有权限访问管理后台")
+	}
+	c.Abort()
+}
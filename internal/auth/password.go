@@ -0,0 +1,92 @@
+// Package auth 密码哈希和重置密码令牌
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码长度限制（字节），bcrypt 只能处理 72 字节以内的密码
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// Cost bcrypt 的计算强度，启动时按配置设置（auth.bcryptCost）
+var Cost = bcrypt.DefaultCost
+
+// 密码校验的错误
+var (
+	ErrPasswordTooShort = errors.New("密码不能少于 8 个字符")
+	ErrPasswordTooLong  = errors.New("密码不能超过 72 个字节")
+)
+
+// ValidatePassword 检查密码长度
+func ValidatePassword(password string) error {
+	switch {
+	case len(password) < MinPasswordLength:
+		return ErrPasswordTooShort
+	case len(password) > MaxPasswordLength:
+		return ErrPasswordTooLong
+	}
+	return nil
+}
+
+// HashPassword 计算密码的 bcrypt 哈希
+func HashPassword(password string) (string, error) {
+	if err := ValidatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword 校验密码与哈希是否匹配，哈希为空（用户不存在或未设置密码）时总是返回 false，
+// 但仍与一个随机密码的哈希比较一次，耗时与哈希存在时相同，不暴露用户是否存在
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+var (
+	dummyOnce sync.Once
+	dummy     []byte
+)
+
+// dummyHash 随机密码的哈希，第一次使用时按 Cost 计算
+func dummyHash() []byte {
+	dummyOnce.Do(func() {
+		random := make([]byte, 32)
+		rand.Read(random)
+		dummy, _ = bcrypt.GenerateFromPassword([]byte(base64.RawURLEncoding.EncodeToString(random)), Cost)
+	})
+	return dummy
+}
+
+// NewToken 生成随机令牌，返回令牌和保存到数据库的哈希（见 HashToken）
+func NewToken() (string, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	return token, HashToken(token), nil
+}
+
+// HashToken 令牌的 SHA-256 十六进制，数据库中只保存哈希，泄露后也无法还原令牌
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
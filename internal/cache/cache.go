@@ -0,0 +1,18 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache 查询结果缓存接口
+// 值为序列化后的字节，便于在进程内缓存和 Redis 等外部缓存之间切换；
+// 每个缓存项可以带若干标签，写操作时按标签批量失效。
+type Cache interface {
+	// Get 读取缓存，不存在或已过期时 ok 为 false
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set 写入缓存
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// Invalidate 清除带有任一标签的缓存项
+	Invalidate(ctx context.Context, tags ...string) error
+}
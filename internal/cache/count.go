@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// CountCache 列表总数缓存
+// 按资源和过滤条件签名缓存 COUNT(*) 结果，写操作时按资源整体失效
+type CountCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]map[string]countEntry
+}
+
+type countEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// NewCountCache 创建总数缓存
+// ttl 用于兜底其他实例或直接修改数据库造成的数据变化
+func NewCountCache(ttl time.Duration) *CountCache {
+	return &CountCache{
+		ttl:     ttl,
+		entries: make(map[string]map[string]countEntry),
+	}
+}
+
+// Get 获取缓存的总数
+func (c *CountCache) Get(resource, signature string) (int64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[resource][signature]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return 0, false
+	}
+	return entry.count, true
+}
+
+// Set 缓存总数
+func (c *CountCache) Set(resource, signature string, count int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries[resource] == nil {
+		c.entries[resource] = make(map[string]countEntry)
+	}
+	c.entries[resource][signature] = countEntry{
+		count:     count,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+// Invalidate 清除某个资源的所有缓存
+func (c *CountCache) Invalidate(resource string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, resource)
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRUCache 进程内 LRU 缓存
+// 超过容量时淘汰最久未使用的缓存项
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	tags     map[string]map[string]struct{}
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	tags      []string
+	expiresAt time.Time
+}
+
+// NewLRUCache 创建 LRU 缓存，capacity 为最多缓存的条目数
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &LRUCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		tags:     make(map[string]map[string]struct{}),
+	}
+}
+
+// Get 读取缓存
+func (c *LRUCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false, nil
+	}
+
+	c.ll.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+// Set 写入缓存
+func (c *LRUCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+
+	entry := &lruEntry{
+		key:       key,
+		value:     value,
+		tags:      tags,
+		expiresAt: time.Now().Add(ttl),
+	}
+	c.items[key] = c.ll.PushFront(entry)
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+
+	for c.ll.Len() > c.capacity {
+		c.remove(c.ll.Back())
+	}
+	return nil
+}
+
+// Invalidate 按标签清除缓存
+func (c *LRUCache) Invalidate(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if elem, ok := c.items[key]; ok {
+				c.remove(elem)
+			}
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
+// remove 删除缓存项及其标签索引，调用方需持有锁
+func (c *LRUCache) remove(elem *list.Element) {
+	entry := elem.Value.(*lruEntry)
+	c.ll.Remove(elem)
+	delete(c.items, entry.key)
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], entry.key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"go-viewset/internal/redis"
+	"strconv"
+	"time"
+)
+
+// redisTagScript 将缓存 key 加入标签集合，集合的过期时间延长到不短于该缓存项，缓存项全部过期后集合也随之过期
+const redisTagScript = `
+redis.call("SADD", KEYS[1], ARGV[1])
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`
+
+// RedisCache 基于 Redis 的缓存
+// 每个标签对应一个集合，记录带有该标签的缓存 key，失效时一并删除
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCache 创建 Redis 缓存，prefix 用于隔离不同应用的 key
+func NewRedisCache(client *redis.Client, prefix string) *RedisCache {
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Get 读取缓存
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.String(ctx, "GET", c.prefix+key)
+	if errors.Is(err, redis.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(value), true, nil
+}
+
+// Set 写入缓存
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	px := strconv.FormatInt(ttl.Milliseconds(), 10)
+	if _, err := c.client.Do(ctx, "SET", c.prefix+key, string(value), "PX", px); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := c.client.Do(ctx, "EVAL", redisTagScript, "1", c.tagKey(tag), c.prefix+key, px); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Invalidate 按标签清除缓存
+func (c *RedisCache) Invalidate(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := c.client.Strings(ctx, "SMEMBERS", c.tagKey(tag))
+		if err != nil && !errors.Is(err, redis.ErrNil) {
+			return err
+		}
+		args := append([]string{"DEL", c.tagKey(tag)}, keys...)
+		if _, err := c.client.Do(ctx, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagKey 标签集合的 key
+func (c *RedisCache) tagKey(tag string) string {
+	return c.prefix + "tag:" + tag
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// defaultPaths 未指定配置文件时依次查找的文件
+var defaultPaths = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
+// toJSON 按扩展名把 YAML/TOML 配置转换为 JSON
+// 转换后统一按 json tag 解析，三种格式的字段名完全一致
+func toJSON(path string, data []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.YAMLToJSON(data)
+	case ".toml":
+		var m map[string]interface{}
+		if err := toml.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		return json.Marshal(m)
+	case ".json", "":
+		return data, nil
+	}
+	return nil, fmt.Errorf("不支持的配置文件格式: %s", path)
+}
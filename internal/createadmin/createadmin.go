@@ -0,0 +1,151 @@
+// Package createadmin 实现 go-viewset createadmin 命令：创建第一个管理员
+//
+// 角色管理接口只允许管理员调用，新部署的数据库中没有管理员时通过该命令授予 admin 角色。
+// 用法：
+//
+//	ADMIN_PASSWORD=... go-viewset createadmin -email admin@example.com -name 管理员
+package createadmin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-viewset/internal/auth"
+	"go-viewset/internal/config"
+	"go-viewset/internal/database"
+	"go-viewset/internal/models"
+	"go-viewset/internal/redis"
+	"go-viewset/internal/session"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// adminRole 管理员角色名（见 middleware.LoadRoles）
+const adminRole = "admin"
+
+// Main 命令入口，返回进程的退出码
+func Main(args []string) int {
+	fs := flag.NewFlagSet("createadmin", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: go-viewset createadmin -email <邮箱> [-name <名称>]")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "为该邮箱的用户设置密码并授予 admin 角色，用户不存在时创建；")
+		fmt.Fprintln(fs.Output(), "密码取自环境变量 ADMIN_PASSWORD，未设置时从标准输入读取一行。")
+		fmt.Fprintln(fs.Output(), "已有用户的密码被替换，原有会话全部失效（防止他人抢先用该邮箱注册）。")
+		fmt.Fprintln(fs.Output(), "")
+		fs.PrintDefaults()
+	}
+	email := fs.String("email", "", "管理员的邮箱")
+	name := fs.String("name", "", "创建用户时的名称，默认为邮箱 @ 之前的部分")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *email == "" {
+		fs.Usage()
+		return 2
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Fprint(os.Stderr, "密码: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(os.Stderr, "读取密码失败:", err)
+			return 1
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if err := run(strings.TrimSpace(*email), *name, password); err != nil {
+		fmt.Fprintln(os.Stderr, "创建管理员失败:", err)
+		return 1
+	}
+	fmt.Printf("✅ %s 已设为管理员\n", *email)
+	return 0
+}
+
+// run 连接配置中的数据库，设置密码、授予 admin 角色并删除用户已有的会话
+func run(email, name, password string) error {
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if cfg.Auth.BcryptCost > 0 {
+		auth.Cost = cfg.Auth.BcryptCost
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	dialector, err := database.Open(cfg.Database.Type, cfg.Database.GetDSN())
+	if err != nil {
+		return err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	// 服务还没有启动过时表可能不存在
+	if err := db.AutoMigrate(&models.User{}, &models.Role{}, &models.Session{}); err != nil {
+		return fmt.Errorf("数据库迁移失败: %w", err)
+	}
+
+	var user models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Where("email = ?", email).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user = models.User{Name: name, Email: email, Status: "active", PasswordHash: hash, EmailVerifiedAt: &now}
+			if user.Name == "" {
+				user.Name, _, _ = strings.Cut(email, "@")
+			}
+			err = tx.Create(&user).Error
+		} else if err == nil {
+			err = tx.Model(&user).Updates(map[string]interface{}{
+				"password_hash":     hash,
+				"status":            "active",
+				"email_verified_at": now,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		role := models.Role{Name: adminRole}
+		if err := tx.Where("name = ?", adminRole).
+			Attrs(models.Role{Description: "管理员"}).FirstOrCreate(&role).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&role).Association("Users").Append(&user); err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// 会话保存在 Redis 时单独删除
+	if cfg.Session.Enabled && cfg.Session.Store == "redis" {
+		client := redis.NewClient(cfg.Cache.Redis.Addr, cfg.Cache.Redis.Password, cfg.Cache.Redis.DB, cfg.Cache.Redis.PoolSize)
+		store := session.NewRedisStore(client, cfg.Cache.Redis.Prefix)
+		if err := store.DeleteUser(context.Background(), user.ID); err != nil {
+			return fmt.Errorf("删除会话失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"go-viewset/internal/cache"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// RegisterCacheInvalidation 注册 GORM 回调，写操作影响了记录时清除以表名为标签的缓存
+// ViewSet 的写操作通过事件总线清除缓存；中间件、后台任务等直接通过 GORM 写入的数据（例如配额的已用次数）由这里清除
+func RegisterCacheInvalidation(db *gorm.DB, c cache.Cache) error {
+	invalidate := func(db *gorm.DB) {
+		if db.Error != nil || db.RowsAffected == 0 || db.Statement.Table == "" {
+			return
+		}
+		if err := c.Invalidate(db.Statement.Context, db.Statement.Table); err != nil {
+			log.Printf("清除 %s 缓存失败: %v", db.Statement.Table, err)
+		}
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("cache:after_create", invalidate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("cache:after_update", invalidate); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("cache:after_delete", invalidate)
+}
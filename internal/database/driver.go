@@ -0,0 +1,42 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// Opener 根据 DSN 创建 GORM 的数据库驱动
+type Opener func(dsn string) gorm.Dialector
+
+// drivers 已注册的数据库驱动，key 为 DatabaseConfig.Type
+var drivers = map[string]Opener{
+	"mysql": mysql.Open,
+}
+
+// RegisterDriver 注册数据库驱动
+// postgres 和 sqlite 驱动分别在 -tags postgres、-tags sqlite 构建时注册（见 driver_postgres.go、driver_sqlite.go），
+// 默认构建只包含 MySQL，避免引入用不到的依赖（sqlite 驱动需要 cgo）
+func RegisterDriver(name string, open Opener) {
+	drivers[name] = open
+}
+
+// Open 按数据库类型创建驱动，类型为空时使用 mysql
+func Open(driver, dsn string) (gorm.Dialector, error) {
+	if driver == "" {
+		driver = "mysql"
+	}
+	open, ok := drivers[driver]
+	if !ok {
+		names := make([]string, 0, len(drivers))
+		for name := range drivers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("不支持的数据库类型 %q（当前构建支持：%s）", driver, strings.Join(names, ", "))
+	}
+	return open(dsn), nil
+}
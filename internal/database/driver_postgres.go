@@ -0,0 +1,9 @@
+//go:build postgres
+
+package database
+
+import "gorm.io/driver/postgres"
+
+func init() {
+	RegisterDriver("postgres", postgres.Open)
+}
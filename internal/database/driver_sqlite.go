@@ -0,0 +1,9 @@
+//go:build sqlite
+
+package database
+
+import "gorm.io/driver/sqlite"
+
+func init() {
+	RegisterDriver("sqlite", sqlite.Open)
+}
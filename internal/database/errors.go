@@ -0,0 +1,135 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// ErrorKind 可识别的数据库错误类型
+type ErrorKind string
+
+const (
+	KindDuplicate       ErrorKind = "duplicate"         // 违反唯一约束
+	KindForeignKey      ErrorKind = "foreign_key"       // 引用的记录不存在
+	KindReferenced      ErrorKind = "referenced"        // 记录仍被其他记录引用，无法删除
+	KindTooLong         ErrorKind = "too_long"          // 数据超出字段长度
+	KindNotNull         ErrorKind = "not_null"          // 必填字段为空
+	KindOutOfRange      ErrorKind = "out_of_range"      // 数值超出范围
+	KindDeadlock        ErrorKind = "deadlock"          // 死锁
+	KindLockWaitTimeout ErrorKind = "lock_wait_timeout" // 等待行锁超时
+)
+
+// Error 翻译后的数据库错误
+type Error struct {
+	Kind   ErrorKind
+	Column string // 相关的列名，无法确定时为空
+	Err    error  // 原始错误
+}
+
+// Error 实现 error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 是否可以重试（死锁、锁等待超时）
+func (e *Error) Retryable() bool {
+	return e.Kind == KindDeadlock || e.Kind == KindLockWaitTimeout
+}
+
+// MySQL 错误码
+const (
+	errDupEntry          = 1062
+	errRowIsReferenced   = 1451
+	errNoReferencedRow   = 1452
+	errRowIsReferenced2  = 1217
+	errNoReferencedRow2  = 1216
+	errDataTooLong       = 1406
+	errBadNull           = 1048
+	errNoDefaultForField = 1364
+	errDataOutOfRange    = 1264
+	errLockDeadlock      = 1213
+	errLockWaitTimeout   = 1205
+	errValueOutOfRange   = 1690
+)
+
+var (
+	columnPattern     = regexp.MustCompile("(?i)column '([^']+)'")
+	fieldPattern      = regexp.MustCompile("(?i)field '([^']+)'")
+	keyPattern        = regexp.MustCompile("(?i)for key '([^']+)'")
+	foreignKeyPattern = regexp.MustCompile("FOREIGN KEY \\(`([^`]+)`\\)")
+)
+
+// TranslateError 将常见的数据库错误翻译为 *Error，无法识别时返回 nil
+// table 用于从唯一索引名（如 idx_users_email）中推断列名
+func TranslateError(err error, table string) *Error {
+	if err == nil {
+		return nil
+	}
+
+	// 开启 gorm.Config.TranslateError 时驱动已经做过翻译，只能得到类型
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &Error{Kind: KindDuplicate, Err: err}
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return &Error{Kind: KindForeignKey, Err: err}
+	}
+
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return nil
+	}
+
+	msg := myErr.Message
+	switch myErr.Number {
+	case errDupEntry:
+		return &Error{Kind: KindDuplicate, Column: columnFromKey(submatch(keyPattern, msg), table), Err: err}
+	case errNoReferencedRow, errNoReferencedRow2:
+		return &Error{Kind: KindForeignKey, Column: submatch(foreignKeyPattern, msg), Err: err}
+	case errRowIsReferenced, errRowIsReferenced2:
+		return &Error{Kind: KindReferenced, Err: err}
+	case errDataTooLong:
+		return &Error{Kind: KindTooLong, Column: submatch(columnPattern, msg), Err: err}
+	case errBadNull:
+		return &Error{Kind: KindNotNull, Column: submatch(columnPattern, msg), Err: err}
+	case errNoDefaultForField:
+		return &Error{Kind: KindNotNull, Column: submatch(fieldPattern, msg), Err: err}
+	case errDataOutOfRange, errValueOutOfRange:
+		return &Error{Kind: KindOutOfRange, Column: submatch(columnPattern, msg), Err: err}
+	case errLockDeadlock:
+		return &Error{Kind: KindDeadlock, Err: err}
+	case errLockWaitTimeout:
+		return &Error{Kind: KindLockWaitTimeout, Err: err}
+	}
+	return nil
+}
+
+// submatch 返回第一个分组的匹配结果
+func submatch(re *regexp.Regexp, s string) string {
+	if m := re.FindStringSubmatch(s); len(m) > 1 {
+		return m[1]
+	}
+	return ""
+}
+
+// columnFromKey 从唯一索引名推断列名
+// MySQL 8 的索引名带表名前缀（users.idx_users_email），GORM 默认的索引名为 idx_<表名>_<列名>
+func columnFromKey(key, table string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	for _, prefix := range []string{"idx_" + table + "_", "uni_" + table + "_"} {
+		if strings.HasPrefix(key, prefix) {
+			return key[len(prefix):]
+		}
+	}
+	return key
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"log"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// IndexAdvisor 缺失索引检测
+// 开发模式下检查运行时用于过滤和排序的字段是否有索引（作为索引的第一列），
+// 没有索引时输出告警，每个字段只告警一次。
+// 索引信息读取自 information_schema，每张表只查询一次。
+type IndexAdvisor struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	indexed map[string]map[string]bool
+	warned  map[string]bool
+}
+
+// NewIndexAdvisor 创建缺失索引检测器
+func NewIndexAdvisor(db *gorm.DB) *IndexAdvisor {
+	return &IndexAdvisor{
+		db:      db,
+		indexed: make(map[string]map[string]bool),
+		warned:  make(map[string]bool),
+	}
+}
+
+// Check 检查字段是否有索引
+// usage 描述字段的用途，例如 "过滤"、"排序"
+func (a *IndexAdvisor) Check(table, usage string, columns ...string) {
+	if len(columns) == 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	indexed, err := a.load(table)
+	if err != nil {
+		return
+	}
+
+	for _, column := range columns {
+		key := table + "." + column
+		if indexed[column] || a.warned[key] {
+			continue
+		}
+		a.warned[key] = true
+		log.Printf("[索引检查] %s字段 %s 没有索引，数据量大时可能导致慢查询", usage, key)
+	}
+}
+
+// load 读取表上所有索引的第一列，调用方需持有锁
+func (a *IndexAdvisor) load(table string) (map[string]bool, error) {
+	if indexed, ok := a.indexed[table]; ok {
+		return indexed, nil
+	}
+
+	var columns []string
+	err := a.db.Raw(
+		"SELECT COLUMN_NAME FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND SEQ_IN_INDEX = 1",
+		table,
+	).Scan(&columns).Error
+	if err != nil {
+		log.Printf("[索引检查] 读取 %s 的索引信息失败: %v", table, err)
+		return nil, err
+	}
+
+	indexed := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		indexed[column] = true
+	}
+	a.indexed[table] = indexed
+	return indexed, nil
+}
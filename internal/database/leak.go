@@ -0,0 +1,259 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"go-viewset/internal/utils"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LeakDetector 连接占用检测
+// 包装连接池的驱动，记录每个连接从取出到归还连接池的时间，事务和未关闭的 Rows 占用连接的时间都包含在内。
+// 连接归还时占用超过阈值输出带路由和请求 ID 的日志；定期检查时输出超过阈值仍未归还的连接（每个连接只输出一次），
+// 连接池耗尽时列出当前仍占用连接的请求，便于定位问题来源。
+type LeakDetector struct {
+	threshold time.Duration
+
+	mu    sync.Mutex
+	inUse map[*leakConn]*checkout
+}
+
+// checkout 一次连接占用
+type checkout struct {
+	start    time.Time
+	info     utils.RequestInfo
+	query    string // 最近执行的语句
+	reported bool   // 已经作为未归还的连接输出过
+}
+
+// NewLeakDetector 创建连接占用检测器
+func NewLeakDetector(threshold time.Duration) *LeakDetector {
+	return &LeakDetector{
+		threshold: threshold,
+		inUse:     make(map[*leakConn]*checkout),
+	}
+}
+
+// Register 将 db 的连接池替换为同一驱动、同一 DSN 的新连接池，新连接池的连接取出和归还都会被记录；
+// 需要在设置连接池参数和执行其他查询之前调用，原连接池被关闭
+func (d *LeakDetector) Register(db *gorm.DB, dsn string) error {
+	old, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: old.Driver()}
+	if dc, ok := old.Driver().(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return err
+		}
+	}
+
+	pool := sql.OpenDB(&leakConnector{Connector: connector, detector: d})
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return old.Close()
+}
+
+// acquire 连接被取出后第一次使用时记录开始时间和请求信息
+func (d *LeakDetector) acquire(ctx context.Context, conn *leakConn, query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	co, ok := d.inUse[conn]
+	if !ok {
+		info, _ := utils.RequestInfoFrom(ctx)
+		co = &checkout{start: time.Now(), info: info}
+		d.inUse[conn] = co
+	}
+	if query != "" {
+		co.query = query
+	}
+}
+
+// release 连接归还连接池（或被关闭）时检查占用时间
+func (d *LeakDetector) release(conn *leakConn) {
+	d.mu.Lock()
+	co, ok := d.inUse[conn]
+	delete(d.inUse, conn)
+	d.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	if elapsed := time.Since(co.start); elapsed >= d.threshold {
+		log.Printf("[连接占用] 连接占用 %s，超过阈值 %s: route=%s %s request_id=%s sql=%s",
+			elapsed, d.threshold, co.info.Method, co.info.Route, co.info.ID, co.query)
+	}
+}
+
+// Monitor 定期检查连接占用，阻塞运行直到 stop 被关闭
+// 输出超过阈值仍未归还的连接；连接池耗尽时输出全部占用连接的请求
+func (d *LeakDetector) Monitor(sqlDB *sql.DB, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		stats := sqlDB.Stats()
+		exhausted := stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections
+		if exhausted {
+			log.Printf("[连接占用] 连接池已耗尽: in_use=%d max_open=%d wait_count=%d wait_duration=%s",
+				stats.InUse, stats.MaxOpenConnections, stats.WaitCount, stats.WaitDuration)
+		}
+
+		d.mu.Lock()
+		for _, co := range d.inUse {
+			elapsed := now.Sub(co.start)
+			if elapsed < d.threshold || (co.reported && !exhausted) {
+				continue
+			}
+			co.reported = true
+			log.Printf("[连接占用]   未归还，已占用 %s: route=%s %s request_id=%s sql=%s",
+				elapsed, co.info.Method, co.info.Route, co.info.ID, co.query)
+		}
+		d.mu.Unlock()
+	}
+}
+
+// dsnConnector 不支持 driver.DriverContext 的驱动按 DSN 打开连接
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect 实现 driver.Connector
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver 实现 driver.Connector
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// leakConnector 打开的连接都经过 leakConn 包装
+type leakConnector struct {
+	driver.Connector
+	detector *LeakDetector
+}
+
+// Connect 实现 driver.Connector
+func (c *leakConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &leakConn{Conn: conn, detector: c.detector}, nil
+}
+
+// leakConn 记录取出和归还的连接
+// database/sql 取出复用的连接时调用 ResetSession，归还时调用 IsValid；
+// 新建的连接在第一次执行语句时开始计时。其他可选接口转发给驱动的连接，驱动不支持时返回 driver.ErrSkip
+type leakConn struct {
+	driver.Conn
+	detector *LeakDetector
+}
+
+// ResetSession 实现 driver.SessionResetter，连接被取出
+func (c *leakConn) ResetSession(ctx context.Context) error {
+	c.detector.acquire(ctx, c, "")
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid 实现 driver.Validator，连接归还连接池
+func (c *leakConn) IsValid() bool {
+	c.detector.release(c)
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// Close 实现 driver.Conn
+func (c *leakConn) Close() error {
+	c.detector.release(c)
+	return c.Conn.Close()
+}
+
+// Prepare 实现 driver.Conn
+func (c *leakConn) Prepare(query string) (driver.Stmt, error) {
+	c.detector.acquire(context.Background(), c, query)
+	return c.Conn.Prepare(query)
+}
+
+// PrepareContext 实现 driver.ConnPrepareContext
+func (c *leakConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	c.detector.acquire(ctx, c, query)
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+// Begin 实现 driver.Conn
+func (c *leakConn) Begin() (driver.Tx, error) {
+	c.detector.acquire(context.Background(), c, "BEGIN")
+	return c.Conn.Begin()
+}
+
+// BeginTx 实现 driver.ConnBeginTx
+func (c *leakConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.detector.acquire(ctx, c, "BEGIN")
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.ReadOnly || opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("驱动不支持只读事务或指定隔离级别")
+	}
+	return c.Conn.Begin()
+}
+
+// ExecContext 实现 driver.ExecerContext
+func (c *leakConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.detector.acquire(ctx, c, query)
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+// QueryContext 实现 driver.QueryerContext
+func (c *leakConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.detector.acquire(ctx, c, query)
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+// Ping 实现 driver.Pinger
+func (c *leakConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// CheckNamedValue 实现 driver.NamedValueChecker
+func (c *leakConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
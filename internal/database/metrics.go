@@ -0,0 +1,68 @@
+package database
+
+import (
+	"go-viewset/internal/metrics"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// metricsStartKey 在语句上记录开始时间的 key
+const metricsStartKey = "metrics:start"
+
+// queryDuration 数据库语句耗时
+var queryDuration = metrics.Default.NewHistogramVec(
+	"db_query_duration_seconds", "数据库语句耗时（秒）", nil,
+	"table", "operation")
+
+// RegisterMetrics 注册 GORM 回调，按表和操作类型统计语句耗时（见 metrics.Handler）
+func RegisterMetrics(db *gorm.DB) error {
+	before := func(db *gorm.DB) {
+		db.InstanceSet(metricsStartKey, time.Now())
+	}
+	after := func(operation string) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			start, ok := db.InstanceGet(metricsStartKey)
+			if !ok {
+				return
+			}
+			queryDuration.Observe(time.Since(start.(time.Time)).Seconds(), db.Statement.Table, operation)
+		}
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("metrics:before_create", before); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("metrics:after_create", after("create")); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("metrics:before_query", before); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("metrics:after_query", after("query")); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("metrics:before_update", before); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("metrics:after_update", after("update")); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("metrics:before_delete", before); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete")); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("metrics:before_row", before); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("metrics:after_row", after("row")); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("metrics:before_raw", before); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw"))
+}
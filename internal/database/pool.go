@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WarmUp 预先建立 n 个数据库连接
+// 连接建立后归还到连接池作为空闲连接，避免服务启动后第一波请求集中建连。
+// n 不应超过 MaxIdleConns，否则多出的连接会在归还时被关闭。
+func WarmUp(sqlDB *sql.DB, n int, timeout time.Duration) error {
+	if n <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// 同时持有 n 个连接，确保连接池真正创建了 n 个不同的连接
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("预热第 %d 个连接失败: %w", i+1, err)
+		}
+		conns = append(conns, conn)
+
+		if err := conn.PingContext(ctx); err != nil {
+			return fmt.Errorf("预热第 %d 个连接失败: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
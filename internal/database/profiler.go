@@ -0,0 +1,66 @@
+package database
+
+import (
+	"go-viewset/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// profileStartKey 在语句上记录开始时间的 key
+const profileStartKey = "profiler:start"
+
+// RegisterProfiler 注册 GORM 回调，将数据库耗时计入请求的性能分析（见 utils.Profile）
+// 只有开启了性能分析的请求才会记录
+func RegisterProfiler(db *gorm.DB) error {
+	before := func(db *gorm.DB) {
+		if utils.ProfileFrom(db.Statement.Context) != nil {
+			db.InstanceSet(profileStartKey, time.Now())
+		}
+	}
+	after := func(db *gorm.DB) {
+		p := utils.ProfileFrom(db.Statement.Context)
+		if p == nil {
+			return
+		}
+		if start, ok := db.InstanceGet(profileStartKey); ok {
+			p.Add("db", time.Since(start.(time.Time)))
+		}
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("profiler:before_create", before); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("profiler:after_create", after); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("profiler:before_query", before); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("profiler:after_query", after); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("profiler:before_update", before); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("profiler:after_update", after); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("profiler:before_delete", before); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("profiler:after_delete", after); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("profiler:before_row", before); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("profiler:after_row", after); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("profiler:before_raw", before); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("profiler:after_raw", after)
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// TenantDSNResolver 返回租户数据库的类型（mysql / postgres / sqlite，为空表示 mysql）和 DSN
+type TenantDSNResolver func(ctx context.Context, tenant string) (driver, dsn string, err error)
+
+// TenantDBs 每个租户使用独立数据库时的连接管理
+// 租户的数据库在第一次使用时打开，之后复用同一个 *gorm.DB（及其连接池）；
+// 同一租户并发的首次请求只打开一次，打开失败时不缓存，下次请求重试
+type TenantDBs struct {
+	resolve TenantDSNResolver
+	config  *gorm.Config
+	setup   func(tenant string, db *gorm.DB) error
+
+	mu  sync.Mutex
+	dbs map[string]*tenantDB
+}
+
+// tenantDB 一个租户的数据库，ready 关闭后 db 和 err 可用
+type tenantDB struct {
+	ready chan struct{}
+	db    *gorm.DB
+	err   error
+}
+
+// NewTenantDBs 创建租户数据库连接管理
+// config 为打开数据库时的 GORM 配置（多个租户之间共享，不能为 nil）；
+// setup 在数据库打开后执行，用于设置连接池、注册回调和迁移表结构，返回错误时关闭该数据库
+func NewTenantDBs(resolve TenantDSNResolver, config *gorm.Config, setup func(tenant string, db *gorm.DB) error) *TenantDBs {
+	return &TenantDBs{
+		resolve: resolve,
+		config:  config,
+		setup:   setup,
+		dbs:     make(map[string]*tenantDB),
+	}
+}
+
+// Get 返回租户的数据库，尚未打开时按 resolve 得到的 DSN 打开
+func (m *TenantDBs) Get(ctx context.Context, tenant string) (*gorm.DB, error) {
+	m.mu.Lock()
+	entry, ok := m.dbs[tenant]
+	if !ok {
+		entry = &tenantDB{ready: make(chan struct{})}
+		m.dbs[tenant] = entry
+	}
+	m.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.ready:
+			return entry.db, entry.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// 在独立的 context 中打开，请求取消不影响其他等待同一租户的请求
+	entry.db, entry.err = m.open(context.WithoutCancel(ctx), tenant)
+	if entry.err != nil {
+		m.mu.Lock()
+		if m.dbs[tenant] == entry {
+			delete(m.dbs, tenant)
+		}
+		m.mu.Unlock()
+	}
+	close(entry.ready)
+	return entry.db, entry.err
+}
+
+// open 打开租户的数据库并执行 setup
+func (m *TenantDBs) open(ctx context.Context, tenant string) (*gorm.DB, error) {
+	driver, dsn, err := m.resolve(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	dialector, err := Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, m.config)
+	if err != nil {
+		return nil, fmt.Errorf("连接租户 %s 的数据库失败: %w", tenant, err)
+	}
+	if m.setup != nil {
+		if err := m.setup(tenant, db); err != nil {
+			closeDB(db)
+			return nil, fmt.Errorf("初始化租户 %s 的数据库失败: %w", tenant, err)
+		}
+	}
+	log.Printf("已打开租户 %s 的数据库", tenant)
+	return db, nil
+}
+
+// Evict 关闭并移除租户的数据库，下次使用时重新打开（例如租户的 DSN 发生变化后）
+// 该租户正在执行的查询会失败
+func (m *TenantDBs) Evict(tenant string) {
+	m.mu.Lock()
+	entry, ok := m.dbs[tenant]
+	delete(m.dbs, tenant)
+	m.mu.Unlock()
+
+	if ok {
+		<-entry.ready
+		if entry.err == nil {
+			closeDB(entry.db)
+		}
+	}
+}
+
+// Close 关闭所有租户的数据库
+func (m *TenantDBs) Close() {
+	m.mu.Lock()
+	entries := m.dbs
+	m.dbs = make(map[string]*tenantDB)
+	m.mu.Unlock()
+
+	for _, entry := range entries {
+		<-entry.ready
+		if entry.err == nil {
+			closeDB(entry.db)
+		}
+	}
+}
+
+// closeDB 关闭数据库的连接池
+func closeDB(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("关闭数据库连接失败: %v", err)
+	}
+}
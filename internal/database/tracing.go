@@ -0,0 +1,75 @@
+package database
+
+import (
+	"go-viewset/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+// tracingSpanKey 在语句上记录 span 的 key
+const tracingSpanKey = "tracing:span"
+
+// RegisterTracing 注册 GORM 回调，为每条语句创建子 span（见 tracing.Middleware）
+// 语句的 context 中没有 span 时（例如启动时的迁移）不记录
+func RegisterTracing(db *gorm.DB) error {
+	before := func(operation string) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			if tracing.SpanFromContext(db.Statement.Context) == nil {
+				return
+			}
+			_, span := tracing.Start(db.Statement.Context, "gorm."+operation, tracing.KindClient)
+			db.InstanceSet(tracingSpanKey, span)
+		}
+	}
+	after := func(db *gorm.DB) {
+		value, ok := db.InstanceGet(tracingSpanKey)
+		if !ok {
+			return
+		}
+		span := value.(*tracing.Span)
+		span.SetAttribute("db.system", db.Dialector.Name())
+		span.SetAttribute("db.sql.table", db.Statement.Table)
+		span.SetAttribute("db.statement", db.Statement.SQL.String())
+		span.SetAttribute("db.rows_affected", db.Statement.RowsAffected)
+		if db.Error != nil && db.Error != gorm.ErrRecordNotFound {
+			span.SetError(db.Error)
+		}
+		span.End()
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("tracing:before_create", before("create")); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("tracing:after_create", after); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("tracing:before_query", before("query")); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("tracing:after_query", after); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tracing:before_update", before("update")); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("tracing:after_update", after); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete")); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("tracing:after_delete", after); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("tracing:before_row", before("row")); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("tracing:after_row", after); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("tracing:after_raw", after)
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Action 数据变更类型
+type Action string
+
+// 数据变更类型
+const (
+	Created Action = "created"
+	Updated Action = "updated"
+	Deleted Action = "deleted"
+)
+
+// Event 模型变更事件
+type Event struct {
+	Resource string      `json:"resource"` // 资源名称，使用表名，例如 "users"
+	Action   Action      `json:"action"`
+	ObjectID string      `json:"object_id,omitempty"`
+	Object   interface{} `json:"-"`                   // 模型对象，只在进程内使用
+	Payload  interface{} `json:"payload,omitempty"`   // 对象序列化后的内容（不包含只写字段），用于 Webhook 等对外发送的场景
+	Actor    string      `json:"actor,omitempty"`     // 操作人（用户 ID），未登录时为空
+	TenantID string      `json:"tenant_id,omitempty"` // 租户 ID，未开启多租户时为空
+	Time     time.Time   `json:"time"`
+}
+
+// Name 事件名称，例如 users.created
+func (e Event) Name() string {
+	return e.Resource + "." + string(e.Action)
+}
+
+// Encode 编码为对外发送（Webhook、消息队列）的 JSON，比 Event 多一个 event 字段（事件名称）
+func Encode(e Event) ([]byte, error) {
+	return json.Marshal(struct {
+		Name string `json:"event"`
+		Event
+	}{e.Name(), e})
+}
+
+// Handler 事件处理函数
+type Handler func(Event)
+
+// Bus 进程内事件总线
+// ViewSet 在写操作成功后发布事件，缓存失效、统计等组件通过订阅感知数据变更。
+// 处理函数同步执行，耗时操作应自行异步处理。
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []subscription
+	nextID   uint64
+}
+
+// subscription 一个订阅
+type subscription struct {
+	id      uint64
+	handler Handler
+}
+
+// Default 默认事件总线
+var Default = NewBus()
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe 订阅所有事件，返回取消订阅的函数（可以重复调用）
+// 长期存在的订阅者（缓存失效、Webhook 等）可以忽略返回值；按请求订阅的（例如 SSE）需要在结束时取消
+func (b *Bus) Subscribe(h Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	// 写时复制，Publish 持有的旧切片不受影响
+	handlers := make([]subscription, len(b.handlers), len(b.handlers)+1)
+	copy(handlers, b.handlers)
+	b.handlers = append(handlers, subscription{id: id, handler: h})
+
+	return func() { b.unsubscribe(id) }
+}
+
+// unsubscribe 取消订阅
+func (b *Bus) unsubscribe(id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	handlers := make([]subscription, 0, len(b.handlers))
+	for _, s := range b.handlers {
+		if s.id != id {
+			handlers = append(handlers, s)
+		}
+	}
+	b.handlers = handlers
+}
+
+// Publish 发布事件
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, s := range handlers {
+		s.handler(e)
+	}
+}
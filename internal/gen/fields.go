@@ -0,0 +1,186 @@
+package gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Field 模型的一个字段
+type Field struct {
+	Column   string // 数据库列名（也是 JSON 字段名），例如 unit_price
+	Type     string // Go 类型，例如 string、float64、time.Time
+	Size     int    // 字符串长度，0 表示不限制（text）
+	Required bool   // binding:"required"，数据库中为 NOT NULL
+	Unique   bool   // 唯一索引，同时生成 UniqueValidator
+	Index    bool   // 普通索引
+	ReadOnly bool   // access:"readonly"
+}
+
+// fieldTypes 命令行中的字段类型 -> Go 类型和默认长度
+var fieldTypes = map[string]struct {
+	goType string
+	size   int
+}{
+	"string":  {"string", 255},
+	"text":    {"string", 0},
+	"int":     {"int", 0},
+	"int64":   {"int64", 0},
+	"uint":    {"uint", 0},
+	"float":   {"float64", 0},
+	"bool":    {"bool", 0},
+	"time":    {"time.Time", 0},
+	"decimal": {"float64", 0},
+}
+
+// reservedColumns 模型固定包含的字段
+var reservedColumns = map[string]bool{"id": true, "created_at": true, "updated_at": true, "deleted_at": true}
+
+// ParseFields 解析命令行中的字段定义，格式为 名称:类型[:选项,...]，例如 sku:string:required,unique
+// 名称可以是 unit_price 或 UnitPrice，类型省略时为 string
+func ParseFields(specs []string) ([]Field, error) {
+	fields := make([]Field, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 3)
+		column := schema.NamingStrategy{}.ColumnName("", parts[0])
+		if !isIdentifier(column) {
+			return nil, fmt.Errorf("字段名 %q 无效", parts[0])
+		}
+		if reservedColumns[column] {
+			return nil, fmt.Errorf("%s 是模型固定包含的字段，不需要指定", column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("字段 %s 重复", column)
+		}
+		seen[column] = true
+
+		typeName := "string"
+		if len(parts) > 1 && parts[1] != "" {
+			typeName = strings.ToLower(parts[1])
+		}
+		t, ok := fieldTypes[typeName]
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 的类型 %q 不支持，可选: %s", column, typeName, strings.Join(typeNames(), "、"))
+		}
+		field := Field{Column: column, Type: t.goType, Size: t.size}
+
+		if len(parts) > 2 {
+			for _, opt := range strings.Split(parts[2], ",") {
+				switch strings.TrimSpace(opt) {
+				case "required":
+					field.Required = true
+				case "unique":
+					field.Unique = true
+				case "index":
+					field.Index = true
+				case "readonly":
+					field.ReadOnly = true
+				case "":
+				default:
+					return nil, fmt.Errorf("字段 %s 的选项 %q 不支持", column, opt)
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// FieldsFromColumns 由数据库表的列生成字段，ID、CreatedAt、UpdatedAt、DeletedAt 由模型固定包含，
+// 同时返回表中是否有 deleted_at 列
+func FieldsFromColumns(columns []gorm.ColumnType) ([]Field, bool, error) {
+	var fields []Field
+	softDelete := false
+	for _, col := range columns {
+		name := col.Name()
+		if name == "deleted_at" {
+			softDelete = true
+		}
+		if reservedColumns[name] {
+			continue
+		}
+		if !isIdentifier(name) {
+			return nil, false, fmt.Errorf("列名 %q 无法作为字段名", name)
+		}
+
+		field := Field{Column: name, Type: columnGoType(col.DatabaseTypeName())}
+		if field.Type == "string" {
+			if length, ok := col.Length(); ok && length > 0 && length < 65535 {
+				field.Size = int(length)
+			}
+		}
+		if nullable, ok := col.Nullable(); ok && !nullable {
+			if _, hasDefault := col.DefaultValue(); !hasDefault {
+				field.Required = true
+			}
+		}
+		if unique, ok := col.Unique(); ok && unique {
+			field.Unique = true
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, false, fmt.Errorf("表中除了固定字段之外没有其他列")
+	}
+	return fields, softDelete, nil
+}
+
+// columnGoType 数据库类型 -> Go 类型，无法识别的按字符串处理
+func columnGoType(dbType string) string {
+	dbType = strings.ToLower(dbType)
+	switch {
+	case strings.Contains(dbType, "bigint") || dbType == "int8":
+		return "int64"
+	case strings.Contains(dbType, "int") || dbType == "serial":
+		return "int"
+	case strings.Contains(dbType, "bool"):
+		return "bool"
+	case strings.Contains(dbType, "float") || strings.Contains(dbType, "double") ||
+		strings.Contains(dbType, "decimal") || strings.Contains(dbType, "numeric") || dbType == "real":
+		return "float64"
+	case strings.Contains(dbType, "date") || strings.Contains(dbType, "time"):
+		return "time.Time"
+	}
+	return "string"
+}
+
+// typeNames 支持的字段类型，按名称排序
+func typeNames() []string {
+	names := make([]string, 0, len(fieldTypes))
+	for name := range fieldTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isIdentifier 是否为小写字母、数字和下划线组成的列名
+func isIdentifier(s string) bool {
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// isExported 是否为大写字母开头、只包含字母和数字的 Go 标识符
+func isExported(s string) bool {
+	if s == "" || !unicode.IsUpper(rune(s[0])) {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,260 @@
+// Package gen 实现 go-viewset gen 命令：生成模型、ViewSet，并注册路由和自动迁移
+//
+// 用法：
+//
+//	go-viewset gen Product name:string:required sku:string:required,unique price:float stock:int
+//	go-viewset gen -from-table products Product
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go-viewset/internal/config"
+	"go-viewset/internal/database"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// 生成的代码插入到这些标记所在行之前
+const (
+	routerMarker  = "// go-viewset gen: viewsets"
+	migrateMarker = "// go-viewset gen: models"
+)
+
+// Options 生成选项
+type Options struct {
+	Model      string  // 模型名，例如 Product
+	Table      string  // 表名，默认按 GORM 命名规则由模型名得到，例如 products
+	Fields     []Field // 模型字段（不含 ID、CreatedAt、UpdatedAt、DeletedAt）
+	SoftDelete bool    // 是否带 DeletedAt 软删除字段
+	Dir        string  // 项目根目录
+	Force      bool    // 覆盖已存在的文件
+	DryRun     bool    // 只输出将要生成的内容，不写文件
+}
+
+// Main 执行 gen 命令，返回进程退出码
+func Main(args []string) int {
+	fs := flag.NewFlagSet("gen", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: go-viewset gen [选项] <模型名> [字段名:类型[:选项,...] ...]")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "类型: "+strings.Join(typeNames(), "、"))
+		fmt.Fprintln(fs.Output(), "字段选项: required（必填）、unique（唯一）、index（索引）、readonly（只读）")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "示例:")
+		fmt.Fprintln(fs.Output(), "  go-viewset gen Product name:string:required sku:string:required,unique price:float stock:int")
+		fmt.Fprintln(fs.Output(), "  go-viewset gen -from-table products Product")
+		fmt.Fprintln(fs.Output(), "")
+		fs.PrintDefaults()
+	}
+	table := fs.String("table", "", "表名，默认由模型名得到（例如 Product -> products）")
+	fromTable := fs.String("from-table", "", "从数据库中已有的表读取字段（连接信息见 CONFIG_FILE 和环境变量）")
+	dir := fs.String("dir", ".", "项目根目录")
+	noSoftDelete := fs.Bool("no-soft-delete", false, "不生成 DeletedAt 软删除字段")
+	force := fs.Bool("force", false, "覆盖已存在的文件")
+	dryRun := fs.Bool("dry-run", false, "只输出生成的代码，不写文件")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := Options{
+		Model:      fs.Arg(0),
+		Table:      *table,
+		SoftDelete: !*noSoftDelete,
+		Dir:        *dir,
+		Force:      *force,
+		DryRun:     *dryRun,
+	}
+
+	var err error
+	if *fromTable != "" {
+		if fs.NArg() > 1 {
+			fmt.Fprintln(os.Stderr, "使用 -from-table 时不能再指定字段")
+			return 2
+		}
+		if opts.Table == "" {
+			opts.Table = *fromTable
+		}
+		opts.Fields, opts.SoftDelete, err = fieldsFromTable(*fromTable)
+	} else {
+		opts.Fields, err = ParseFields(fs.Args()[1:])
+	}
+	if err == nil {
+		err = Generate(opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// Generate 生成模型和 ViewSet 文件，并在 router.go 中注册路由、在 main.go 中加入自动迁移
+func Generate(opts Options) error {
+	if !isExported(opts.Model) {
+		return fmt.Errorf("模型名 %q 必须以大写字母开头，且只能包含字母和数字", opts.Model)
+	}
+	if len(opts.Fields) == 0 {
+		return fmt.Errorf("至少需要一个字段")
+	}
+	if opts.Table == "" {
+		opts.Table = schema.NamingStrategy{}.TableName(opts.Model)
+	}
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+
+	data := newTemplateData(opts)
+	name := schema.NamingStrategy{}.ColumnName("", opts.Model)
+
+	files := []struct {
+		path string
+		tmpl string
+	}{
+		{filepath.Join(opts.Dir, "internal", "models", name+".go"), modelTemplate},
+		{filepath.Join(opts.Dir, "internal", "viewset", name+"_viewset.go"), viewSetTemplate},
+	}
+	for _, f := range files {
+		src, err := render(f.tmpl, data)
+		if err != nil {
+			return err
+		}
+		if opts.DryRun {
+			fmt.Printf("// ==== %s ====\n%s\n", f.path, src)
+			continue
+		}
+		if _, err := os.Stat(f.path); err == nil && !opts.Force {
+			return fmt.Errorf("%s 已存在，使用 -force 覆盖", f.path)
+		}
+		if err := os.WriteFile(f.path, src, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("已生成 %s\n", f.path)
+	}
+
+	// 注册路由和自动迁移，已经注册过的跳过
+	edits := []struct {
+		path   string
+		marker string
+		exists string
+		code   string
+	}{
+		{
+			path:   filepath.Join(opts.Dir, "internal", "router", "router.go"),
+			marker: routerMarker,
+			exists: "viewset.New" + opts.Model + "ViewSet(",
+			code: fmt.Sprintf("// 注册 %s 路由\n%s := viewset.New%sViewSet(db)\napi.Register(%q, %s, limits.group(%q)...)\n\n",
+				opts.Table, data.Var, opts.Model, data.Path, data.Var, "/api"+data.Path),
+		},
+		{
+			path:   filepath.Join(opts.Dir, "main.go"),
+			marker: migrateMarker,
+			exists: "&models." + opts.Model + "{},",
+			code:   "&models." + opts.Model + "{},\n",
+		},
+	}
+	for _, e := range edits {
+		if opts.DryRun {
+			fmt.Printf("// ==== %s（插入到 %q 之前）====\n%s\n", e.path, e.marker, e.code)
+			continue
+		}
+		if err := insertBeforeMarker(e.path, e.marker, e.exists, e.code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render 执行模板并用 gofmt 格式化
+func render(tmpl string, data *templateData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, tmpl, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化 %s 失败: %w", tmpl, err)
+	}
+	return src, nil
+}
+
+// insertBeforeMarker 在 path 中标记所在行之前插入 code（按标记行缩进），文件中已包含 exists 时跳过
+func insertBeforeMarker(path, marker, exists, code string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	src := string(content)
+	if strings.Contains(src, exists) {
+		fmt.Printf("%s 中已存在 %s，跳过\n", path, exists)
+		return nil
+	}
+
+	i := strings.Index(src, marker)
+	if i < 0 {
+		return fmt.Errorf("%s 中找不到标记 %q，请手动注册", path, marker)
+	}
+	lineStart := strings.LastIndex(src[:i], "\n") + 1
+	indent := src[lineStart:i]
+
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(code, "\n") {
+		if strings.TrimSpace(line) != "" {
+			b.WriteString(indent)
+		}
+		b.WriteString(line)
+	}
+
+	out, err := format.Source([]byte(src[:lineStart] + b.String() + src[lineStart:]))
+	if err != nil {
+		return fmt.Errorf("格式化 %s 失败: %w", path, err)
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("已更新 %s\n", path)
+	return nil
+}
+
+// fieldsFromTable 读取数据库中已有表的字段，同时返回表中是否有 deleted_at 列
+func fieldsFromTable(table string) ([]Field, bool, error) {
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return nil, false, fmt.Errorf("加载配置失败: %w", err)
+	}
+	dialector, err := database.Open(cfg.Database.Type, cfg.Database.GetDSN())
+	if err != nil {
+		return nil, false, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, false, fmt.Errorf("连接数据库失败: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	if !db.Migrator().HasTable(table) {
+		return nil, false, fmt.Errorf("表 %s 不存在", table)
+	}
+	columns, err := db.Migrator().ColumnTypes(table)
+	if err != nil {
+		return nil, false, fmt.Errorf("读取 %s 的字段失败: %w", table, err)
+	}
+	return FieldsFromColumns(columns)
+}
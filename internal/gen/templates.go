@@ -0,0 +1,175 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// templateData 模板参数
+type templateData struct {
+	Model        string
+	Table        string
+	Path         string // 路由路径，例如 /order-items
+	Var          string // router.go 中的变量名，例如 orderItemViewSet
+	SoftDelete   bool
+	Fields       []templateField
+	UniqueFields []string
+	SearchFields []string
+}
+
+// templateField 模型字段
+type templateField struct {
+	Name string // Go 字段名
+	Type string
+	Tag  string
+}
+
+// commonInitialisms 转换为 Go 字段名时全部大写的单词
+var commonInitialisms = map[string]bool{"id": true, "ip": true, "url": true, "uuid": true, "api": true, "http": true, "json": true, "sku": true}
+
+// newTemplateData 由生成选项构建模板参数
+func newTemplateData(opts Options) *templateData {
+	data := &templateData{
+		Model:      opts.Model,
+		Table:      opts.Table,
+		Path:       "/" + strings.ReplaceAll(opts.Table, "_", "-"),
+		Var:        strings.ToLower(opts.Model[:1]) + opts.Model[1:] + "ViewSet",
+		SoftDelete: opts.SoftDelete,
+	}
+	for _, f := range opts.Fields {
+		data.Fields = append(data.Fields, templateField{Name: goName(f.Column), Type: f.Type, Tag: fieldTag(f)})
+		if f.Unique {
+			data.UniqueFields = append(data.UniqueFields, f.Column)
+		}
+		if f.Type == "string" {
+			data.SearchFields = append(data.SearchFields, f.Column)
+		}
+	}
+	return data
+}
+
+// fieldTag 生成字段的 struct tag
+func fieldTag(f Field) string {
+	var gormTag []string
+	if f.Size > 0 {
+		gormTag = append(gormTag, fmt.Sprintf("size:%d", f.Size))
+	}
+	if f.Required {
+		gormTag = append(gormTag, "not null")
+	}
+	if f.Unique {
+		gormTag = append(gormTag, "uniqueIndex")
+	} else if f.Index {
+		gormTag = append(gormTag, "index")
+	}
+
+	var tags []string
+	if len(gormTag) > 0 {
+		tags = append(tags, `gorm:"`+strings.Join(gormTag, ";")+`"`)
+	}
+	tags = append(tags, `json:"`+f.Column+`"`)
+	if f.Required && f.Type != "bool" {
+		tags = append(tags, `binding:"required"`)
+	}
+	if f.ReadOnly {
+		tags = append(tags, `access:"readonly"`)
+	}
+	return strings.Join(tags, " ")
+}
+
+// goName 列名转换为 Go 字段名，例如 unit_price -> UnitPrice、user_id -> UserID
+func goName(column string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(column, "_") {
+		if word == "" {
+			continue
+		}
+		if commonInitialisms[word] {
+			b.WriteString(strings.ToUpper(word))
+		} else {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// 模板名
+const (
+	modelTemplate   = "model"
+	viewSetTemplate = "viewset"
+)
+
+// templates 模型和 ViewSet 的模板，struct tag 中的反引号用 {{bq}} 表示
+var templates = template.Must(template.Must(template.New(modelTemplate).Funcs(template.FuncMap{
+	"bq": func() string { return "`" },
+}).Parse(modelSource)).New(viewSetTemplate).Parse(viewSetSource))
+
+const modelSource = `package models
+
+import (
+	"time"
+{{- if .SoftDelete}}
+
+	"gorm.io/gorm"
+{{- end}}
+)
+
+// {{.Model}} {{.Table}} 模型
+// access:"readonly" 的字段由服务端维护，客户端传入的值会被忽略
+type {{.Model}} struct {
+	ID        uint           {{bq}}gorm:"primarykey" json:"id" access:"readonly"{{bq}}
+	CreatedAt time.Time      {{bq}}json:"created_at" access:"readonly"{{bq}}
+	UpdatedAt time.Time      {{bq}}json:"updated_at" access:"readonly"{{bq}}
+{{- if .SoftDelete}}
+	DeletedAt gorm.DeletedAt {{bq}}gorm:"index" json:"deleted_at,omitempty" access:"readonly"{{bq}}
+{{- end}}
+{{- range .Fields}}
+	{{.Name}} {{.Type}} {{bq}}{{.Tag}}{{bq}}
+{{- end}}
+}
+
+// TableName 指定表名
+func ({{.Model}}) TableName() string {
+	return "{{.Table}}"
+}
+`
+
+const viewSetSource = `package viewset
+
+import (
+	"go-viewset/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// {{.Model}}ViewSet {{.Table}} 的 ViewSet
+type {{.Model}}ViewSet struct {
+	*GenericViewSet
+}
+
+// New{{.Model}}ViewSet 创建 {{.Model}}ViewSet
+func New{{.Model}}ViewSet(db *gorm.DB) *{{.Model}}ViewSet {
+	v := &{{.Model}}ViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.{{.Model}}{}),
+	}
+{{- range .UniqueFields}}
+
+	// {{.}} 不能重复（创建和更新时检查）
+	v.Validators = append(v.Validators, UniqueValidator(db, &models.{{$.Model}}{}, "{{.}}"))
+{{- end}}
+{{- if .SearchFields}}
+
+	// ?search= 模糊搜索的字段
+	v.SearchFields = []string{ {{- range $i, $f := .SearchFields}}{{if $i}}, {{end}}"{{$f}}"{{end -}} }
+{{- end}}
+
+	return v
+}
+
+// RegisterRoutes 注册路由
+func (v *{{.Model}}ViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	v.RegisterMixins(group, v, ModelMixins...)
+}
+`
@@ -0,0 +1,759 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// resolveFunc 根字段的解析函数，args 为已按参数类型转换的参数（省略的参数不出现）
+type resolveFunc func(ex *executor, args map[string]interface{}) (interface{}, error)
+
+// Error GraphQL 响应中的错误
+type Error struct {
+	Message    string                 `json:"message"`
+	Locations  []position             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Response GraphQL 响应，请求无法执行（语法或校验错误）时没有 data
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// executor 一次请求的执行状态
+type executor struct {
+	schema *schema
+	doc    *document
+	op     *operation
+	vars   map[string]interface{}
+	errors []*Error
+
+	// call 以内部请求调用 ViewSet 的接口（见 handler.go）
+	call func(method, path string, query map[string]string, body interface{}) (*result, error)
+}
+
+// addError 记录错误
+func (ex *executor) addError(err error, sel *selection, path []interface{}) {
+	e := &Error{Message: err.Error(), Path: append([]interface{}(nil), path...)}
+	if sel != nil {
+		e.Locations = []position{sel.pos}
+	}
+	if ce, ok := err.(*callError); ok {
+		e.Extensions = map[string]interface{}{"status": ce.status}
+		if ce.errors != nil {
+			e.Extensions["errors"] = ce.errors
+		}
+	}
+	ex.errors = append(ex.errors, e)
+}
+
+// inputError 参数、变量或查询本身的错误
+type inputError struct {
+	msg string
+	pos position
+}
+
+func (e *inputError) Error() string { return e.msg }
+
+func errorf(pos position, format string, args ...interface{}) *inputError {
+	return &inputError{msg: fmt.Sprintf(format, args...), pos: pos}
+}
+
+// selectOperation 按名称选择要执行的操作，文档中只有一个操作时名称可以省略
+func (doc *document) selectOperation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errorf(doc.operations[1].pos, "请求中有多个操作，需要指定 operationName")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, errorf(position{Line: 1, Column: 1}, "操作 %s 不存在", name)
+}
+
+// canonical 命名类型在 schema 中的定义（内省字段引用的类型只有名称）
+func (s *schema) canonical(t *Type) *Type {
+	if def, ok := s.types[t.named().Name]; ok {
+		return def
+	}
+	return t.named()
+}
+
+// fieldDef 对象类型上的字段，查询根类型上还有内省字段
+func (s *schema) fieldDef(t *Type, name string) *Field {
+	if t == s.query {
+		if f, ok := metaFields[name]; ok {
+			return f
+		}
+	}
+	return t.field(name)
+}
+
+// typeOf 变量声明的类型，必须是输入类型
+func (s *schema) typeOf(ref *typeRef, pos position) (*Type, error) {
+	var t *Type
+	if ref.elem != nil {
+		elem, err := s.typeOf(ref.elem, pos)
+		if err != nil {
+			return nil, err
+		}
+		t = listOf(elem)
+	} else {
+		named, ok := s.types[ref.name]
+		if !ok {
+			return nil, errorf(pos, "未知的类型 %s", ref.name)
+		}
+		if named.Kind == kindObject {
+			return nil, errorf(pos, "变量的类型 %s 不是输入类型", ref.name)
+		}
+		t = named
+	}
+	if ref.nonNull {
+		t = nonNull(t)
+	}
+	return t, nil
+}
+
+// coerceVariables 按声明的类型转换请求中的变量，未提供的变量使用默认值
+func (ex *executor) coerceVariables(op *operation, input map[string]interface{}) error {
+	ex.vars = make(map[string]interface{}, len(op.vars))
+	for _, def := range op.vars {
+		t, err := ex.schema.typeOf(def.typ, def.pos)
+		if err != nil {
+			return err
+		}
+		raw, ok := input[def.name]
+		switch {
+		case ok:
+			v, err := coerceJSON(ex.schema, t, raw, "$"+def.name)
+			if err != nil {
+				return errorf(def.pos, "%s", err.Error())
+			}
+			ex.vars[def.name] = v
+		case def.def != nil:
+			v, _, err := ex.coerceLiteral(t, def.def)
+			if err != nil {
+				return err
+			}
+			ex.vars[def.name] = v
+		case t.Kind == kindNonNull:
+			return errorf(def.pos, "缺少变量 $%s（%s）", def.name, t)
+		}
+	}
+	return nil
+}
+
+// coerceJSON 按类型转换 JSON 形式的变量值
+func coerceJSON(s *schema, t *Type, v interface{}, path string) (interface{}, error) {
+	if t.Kind == kindNonNull {
+		if v == nil {
+			return nil, fmt.Errorf("%s 不能为 null", path)
+		}
+		return coerceJSON(s, t.OfType, v, path)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	switch t.Kind {
+	case kindList:
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			c, err := coerceJSON(s, t.OfType, item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	case kindInputObject:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s 应为 %s 对象", path, t.Name)
+		}
+		out := make(map[string]interface{}, len(obj))
+		for key := range obj {
+			if inputField(t, key) == nil {
+				return nil, fmt.Errorf("%s 中没有字段 %s", t.Name, key)
+			}
+		}
+		for _, f := range t.InputFields {
+			raw, ok := obj[f.Name]
+			if !ok {
+				if f.Type.Kind == kindNonNull {
+					return nil, fmt.Errorf("%s.%s 必填", path, f.Name)
+				}
+				continue
+			}
+			c, err := coerceJSON(s, f.Type, raw, path+"."+f.Name)
+			if err != nil {
+				return nil, err
+			}
+			out[f.Name] = c
+		}
+		return out, nil
+	case kindEnum:
+		name, ok := v.(string)
+		if !ok || !containsString(t.EnumValues, name) {
+			return nil, fmt.Errorf("%s 不是 %s 的取值", path, t.Name)
+		}
+		return name, nil
+	}
+	return coerceScalar(t, v, path)
+}
+
+// coerceScalar 转换 JSON 形式的标量
+func coerceScalar(t *Type, v interface{}, path string) (interface{}, error) {
+	switch t.Name {
+	case "Int":
+		if n, ok := v.(json.Number); ok {
+			if _, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case "Float":
+		if n, ok := v.(json.Number); ok {
+			return n, nil
+		}
+	case "String":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case "Boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case "ID":
+		switch id := v.(type) {
+		case string:
+			return id, nil
+		case json.Number:
+			if _, err := strconv.ParseInt(string(id), 10, 64); err == nil {
+				return string(id), nil
+			}
+		}
+	default:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s 的类型应为 %s", path, t.Name)
+}
+
+// coerceLiteral 按类型转换查询中的字面量或变量，第二个返回值为 false 表示引用了未提供的变量（视为省略）
+func (ex *executor) coerceLiteral(t *Type, v *value) (interface{}, bool, error) {
+	if v.kind == valVariable {
+		val, ok := ex.vars[v.raw]
+		if !ok {
+			if !ex.declared(v.raw) {
+				return nil, false, errorf(v.pos, "变量 $%s 没有声明", v.raw)
+			}
+			if t.Kind == kindNonNull {
+				return nil, false, errorf(v.pos, "缺少变量 $%s（%s）", v.raw, t)
+			}
+			return nil, false, nil
+		}
+		if val == nil && t.Kind == kindNonNull {
+			return nil, false, errorf(v.pos, "变量 $%s 不能为 null", v.raw)
+		}
+		return val, true, nil
+	}
+	if t.Kind == kindNonNull {
+		if v.kind == valNull {
+			return nil, false, errorf(v.pos, "参数值不能为 null（%s）", t)
+		}
+		return ex.coerceLiteral(t.OfType, v)
+	}
+	if v.kind == valNull {
+		return nil, true, nil
+	}
+
+	switch t.Kind {
+	case kindList:
+		items := v.list
+		if v.kind != valList {
+			items = []*value{v}
+		}
+		out := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			c, _, err := ex.coerceLiteral(t.OfType, item)
+			if err != nil {
+				return nil, false, err
+			}
+			out = append(out, c)
+		}
+		return out, true, nil
+	case kindInputObject:
+		if v.kind != valObject {
+			return nil, false, errorf(v.pos, "应为 %s 对象", t.Name)
+		}
+		out := make(map[string]interface{}, len(v.fields))
+		for _, field := range v.fields {
+			def := inputField(t, field.name)
+			if def == nil {
+				return nil, false, errorf(field.pos, "%s 中没有字段 %s", t.Name, field.name)
+			}
+			c, present, err := ex.coerceLiteral(def.Type, field.value)
+			if err != nil {
+				return nil, false, err
+			}
+			if present {
+				out[field.name] = c
+			}
+		}
+		for _, def := range t.InputFields {
+			if _, ok := out[def.Name]; !ok && def.Type.Kind == kindNonNull {
+				return nil, false, errorf(v.pos, "%s.%s 必填", t.Name, def.Name)
+			}
+		}
+		return out, true, nil
+	case kindEnum:
+		if v.kind != valEnum || !containsString(t.EnumValues, v.raw) {
+			return nil, false, errorf(v.pos, "不是 %s 的取值", t.Name)
+		}
+		return v.raw, true, nil
+	}
+
+	switch {
+	case t.Name == "Int" && v.kind == valInt:
+		return json.Number(v.raw), true, nil
+	case t.Name == "Float" && (v.kind == valInt || v.kind == valFloat):
+		return json.Number(v.raw), true, nil
+	case t.Name == "String" && v.kind == valString:
+		return v.raw, true, nil
+	case t.Name == "Boolean" && v.kind == valBool:
+		return v.raw == "true", true, nil
+	case t.Name == "ID" && (v.kind == valString || v.kind == valInt):
+		return v.raw, true, nil
+	case t == typeJSON || t.Name == typeJSON.Name:
+		return ex.literalValue(v)
+	}
+	return nil, false, errorf(v.pos, "参数值的类型应为 %s", t.Name)
+}
+
+// literalValue JSON 标量的字面量，对象和列表中可以引用变量
+func (ex *executor) literalValue(v *value) (interface{}, bool, error) {
+	switch v.kind {
+	case valVariable:
+		return ex.coerceLiteral(typeJSON, v)
+	case valInt, valFloat:
+		return json.Number(v.raw), true, nil
+	case valString, valEnum:
+		return v.raw, true, nil
+	case valBool:
+		return v.raw == "true", true, nil
+	case valList:
+		out := make([]interface{}, 0, len(v.list))
+		for _, item := range v.list {
+			c, _, err := ex.literalValue(item)
+			if err != nil {
+				return nil, false, err
+			}
+			out = append(out, c)
+		}
+		return out, true, nil
+	case valObject:
+		out := make(map[string]interface{}, len(v.fields))
+		for _, field := range v.fields {
+			c, present, err := ex.literalValue(field.value)
+			if err != nil {
+				return nil, false, err
+			}
+			if present {
+				out[field.name] = c
+			}
+		}
+		return out, true, nil
+	}
+	return nil, true, nil
+}
+
+// declared 变量是否在当前操作中声明
+func (ex *executor) declared(name string) bool {
+	for _, def := range ex.op.vars {
+		if def.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// coerceArgs 转换字段的参数，检查未知参数和必填参数
+func (ex *executor) coerceArgs(field *Field, sel *selection) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(sel.args))
+	for _, arg := range sel.args {
+		var def *InputValue
+		for _, a := range field.Args {
+			if a.Name == arg.name {
+				def = a
+			}
+		}
+		if def == nil {
+			return nil, errorf(arg.pos, "字段 %s 没有参数 %s", field.Name, arg.name)
+		}
+		if _, dup := args[arg.name]; dup {
+			return nil, errorf(arg.pos, "参数 %s 重复", arg.name)
+		}
+		v, present, err := ex.coerceLiteral(def.Type, arg.value)
+		if err != nil {
+			if ie, ok := err.(*inputError); ok {
+				ie.msg = "参数 " + arg.name + ": " + ie.msg
+			}
+			return nil, err
+		}
+		if present {
+			args[arg.name] = v
+		}
+	}
+	for _, def := range field.Args {
+		if _, ok := args[def.Name]; !ok && def.Type.Kind == kindNonNull {
+			return nil, errorf(sel.pos, "字段 %s 缺少参数 %s（%s）", field.Name, def.Name, def.Type)
+		}
+	}
+	return args, nil
+}
+
+// included 按 @skip 和 @include 判断是否包含
+func (ex *executor) included(dirs []*directive) (bool, error) {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			return false, errorf(d.pos, "未知的指令 @%s", d.name)
+		}
+		if len(d.args) != 1 || d.args[0].name != "if" {
+			return false, errorf(d.pos, "@%s 需要参数 if", d.name)
+		}
+		v, _, err := ex.coerceLiteral(nonNull(typeBoolean), d.args[0].value)
+		if err != nil {
+			return false, err
+		}
+		if v.(bool) == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// fieldGroup 响应中的一个字段，同名（别名）的多个选择合并执行
+type fieldGroup struct {
+	key  string
+	sels []*selection
+}
+
+// collectFields 展开片段并按响应中的名称合并字段，保持出现顺序
+func (ex *executor) collectFields(t *Type, sels []*selection, groups []*fieldGroup, visited map[string]bool) ([]*fieldGroup, error) {
+	for _, sel := range sels {
+		ok, err := ex.included(sel.directives)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		switch {
+		case sel.spread != "":
+			if visited[sel.spread] {
+				continue
+			}
+			f, ok := ex.doc.fragments[sel.spread]
+			if !ok {
+				return nil, errorf(sel.pos, "片段 %s 不存在", sel.spread)
+			}
+			if _, ok := ex.schema.types[f.typeCond]; !ok {
+				return nil, errorf(f.pos, "未知的类型 %s", f.typeCond)
+			}
+			if f.typeCond != t.Name {
+				continue
+			}
+			visited[sel.spread] = true
+			if groups, err = ex.collectFields(t, f.selections, groups, visited); err != nil {
+				return nil, err
+			}
+			delete(visited, sel.spread)
+		case sel.inline:
+			if sel.typeCond != "" {
+				if _, ok := ex.schema.types[sel.typeCond]; !ok {
+					return nil, errorf(sel.pos, "未知的类型 %s", sel.typeCond)
+				}
+				if sel.typeCond != t.Name {
+					continue
+				}
+			}
+			if groups, err = ex.collectFields(t, sel.selections, groups, visited); err != nil {
+				return nil, err
+			}
+		default:
+			key := sel.responseKey()
+			merged := false
+			for _, g := range groups {
+				if g.key == key {
+					if g.sels[0].name != sel.name {
+						return nil, errorf(sel.pos, "%s 同时指向字段 %s 和 %s", key, g.sels[0].name, sel.name)
+					}
+					g.sels = append(g.sels, sel)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				groups = append(groups, &fieldGroup{key: key, sels: []*selection{sel}})
+			}
+		}
+	}
+	return groups, nil
+}
+
+// subSelections 合并同一字段的子选择集
+func (g *fieldGroup) subSelections() []*selection {
+	if len(g.sels) == 1 {
+		return g.sels[0].selections
+	}
+	var out []*selection
+	for _, sel := range g.sels {
+		out = append(out, sel.selections...)
+	}
+	return out
+}
+
+// validate 执行前检查整个操作：字段、参数、片段和子选择集，变更和查询使用各自的根类型
+func (ex *executor) validate(t *Type, sels []*selection, depth int) error {
+	if depth > maxDepth {
+		return errorf(sels[0].pos, "查询嵌套超过 %d 层", maxDepth)
+	}
+	groups, err := ex.collectFields(t, sels, nil, map[string]bool{})
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		for _, sel := range g.sels {
+			if sel.name == "__typename" {
+				if sel.selections != nil || sel.args != nil {
+					return errorf(sel.pos, "__typename 不能有参数和子选择集")
+				}
+				continue
+			}
+			field := ex.schema.fieldDef(t, sel.name)
+			if field == nil {
+				return errorf(sel.pos, "类型 %s 没有字段 %s", t.Name, sel.name)
+			}
+			if _, err := ex.coerceArgs(field, sel); err != nil {
+				return err
+			}
+			if field.Type.leaf() {
+				if sel.selections != nil {
+					return errorf(sel.pos, "字段 %s（%s）不能有子选择集", sel.name, field.Type)
+				}
+			} else if sel.selections == nil {
+				return errorf(sel.pos, "字段 %s（%s）需要子选择集", sel.name, field.Type)
+			}
+		}
+		if sel := g.sels[0]; sel.name != "__typename" {
+			if field := ex.schema.fieldDef(t, sel.name); !field.Type.leaf() {
+				if err := ex.validate(ex.schema.canonical(field.Type), g.subSelections(), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// maxDepth 选择集的最大嵌套层数，内省查询约为 10 层
+const maxDepth = 15
+
+// executeRoot 执行根字段，变更按顺序执行；根字段出错时该字段为 null
+func (ex *executor) executeRoot(root *Type, sels []*selection) (*orderedMap, error) {
+	groups, err := ex.collectFields(root, sels, nil, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	data := &orderedMap{}
+	for _, g := range groups {
+		sel := g.sels[0]
+		path := []interface{}{g.key}
+		if sel.name == "__typename" {
+			data.set(g.key, root.Name)
+			continue
+		}
+		field := ex.schema.fieldDef(root, sel.name)
+		args, err := ex.coerceArgs(field, sel)
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		switch sel.name {
+		case "__schema":
+			value = ex.schema.schemaValue()
+		case "__type":
+			if t, ok := ex.schema.types[args["name"].(string)]; ok {
+				value = typeValue(t)
+			}
+		default:
+			if value, err = field.resolve(ex, args); err != nil {
+				ex.addError(err, sel, path)
+				if field.Type.Kind == kindNonNull {
+					return nil, nil
+				}
+				data.set(g.key, nil)
+				continue
+			}
+		}
+		completed, ok := ex.complete(field.Type, value, g, path)
+		if !ok {
+			if field.Type.Kind == kindNonNull {
+				return nil, nil
+			}
+			completed = nil
+		}
+		data.set(g.key, completed)
+	}
+	return data, nil
+}
+
+// complete 按字段类型和子选择集生成结果
+// 第二个返回值为 false 表示非空字段的值为 null，需要由上层可以为 null 的位置置为 null
+func (ex *executor) complete(t *Type, v interface{}, g *fieldGroup, path []interface{}) (interface{}, bool) {
+	if f, ok := v.(lazy); ok {
+		v = f()
+	}
+	if t.Kind == kindNonNull {
+		r, ok := ex.complete(t.OfType, v, g, path)
+		if !ok {
+			return nil, false
+		}
+		if r == nil {
+			ex.addError(fmt.Errorf("非空字段 %s 的值为 null", g.sels[0].name), g.sels[0], path)
+			return nil, false
+		}
+		return r, true
+	}
+	if v == nil {
+		return nil, true
+	}
+
+	switch t.Kind {
+	case kindList:
+		items, ok := v.([]interface{})
+		if !ok {
+			ex.addError(fmt.Errorf("字段的值不是列表"), g.sels[0], path)
+			return nil, true
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			r, ok := ex.complete(t.OfType, item, g, append(path[:len(path):len(path)], i))
+			if !ok {
+				if t.OfType.Kind == kindNonNull {
+					return nil, false
+				}
+				r = nil
+			}
+			out[i] = r
+		}
+		return out, true
+	case kindObject:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			ex.addError(fmt.Errorf("字段的值不是对象"), g.sels[0], path)
+			return nil, true
+		}
+		def := ex.schema.canonical(t)
+		groups, err := ex.collectFields(def, g.subSelections(), nil, map[string]bool{})
+		if err != nil {
+			ex.addError(err, g.sels[0], path)
+			return nil, true
+		}
+		out := &orderedMap{}
+		for _, child := range groups {
+			name := child.sels[0].name
+			if name == "__typename" {
+				out.set(child.key, def.Name)
+				continue
+			}
+			field := def.field(name)
+			r, ok := ex.complete(field.Type, obj[name], child, append(path[:len(path):len(path)], child.key))
+			if !ok {
+				if field.Type.Kind == kindNonNull {
+					return nil, false
+				}
+				r = nil
+			}
+			out.set(child.key, r)
+		}
+		return out, true
+	}
+	return serialize(t, v), true
+}
+
+// serialize 输出标量，ID 统一为字符串
+func serialize(t *Type, v interface{}) interface{} {
+	if t.Name == "ID" {
+		switch id := v.(type) {
+		case json.Number:
+			return string(id)
+		case float64:
+			return strconv.FormatFloat(id, 'f', -1, 64)
+		}
+	}
+	return v
+}
+
+// orderedMap 保持字段顺序的对象（GraphQL 要求结果中的字段与选择集的顺序一致）
+type orderedMap struct {
+	keys   []string
+	values []interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// inputField 按名称查找输入对象的字段
+func inputField(t *Type, name string) *InputValue {
+	for _, f := range t.InputFields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// containsString 判断 list 中是否包含 s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
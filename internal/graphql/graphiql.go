@@ -0,0 +1,33 @@
+package graphql
+
+import "strconv"
+
+// graphiQLVersion 使用的 GraphiQL 版本（从 CDN 加载）
+const graphiQLVersion = "3"
+
+// graphiQL 返回请求 endpoint 的 GraphiQL 页面，请求带上当前页面的 Cookie
+func graphiQL(endpoint string) string {
+	cdn := "https://unpkg.com"
+	return `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>GraphiQL</title>
+<style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>
+<link rel="stylesheet" href="` + cdn + `/graphiql@` + graphiQLVersion + `/graphiql.min.css">
+</head>
+<body>
+<div id="graphiql"></div>
+<script src="` + cdn + `/react@18/umd/react.production.min.js"></script>
+<script src="` + cdn + `/react-dom@18/umd/react-dom.production.min.js"></script>
+<script src="` + cdn + `/graphiql@` + graphiQLVersion + `/graphiql.min.js"></script>
+<script>
+var fetcher = GraphiQL.createFetcher({url: ` + strconv.Quote(endpoint) + `, fetch: function (url, init) {
+  return fetch(url, Object.assign({}, init, {credentials: "same-origin"}));
+}});
+ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, {fetcher: fetcher}));
+</script>
+</body>
+</html>
+`
+}
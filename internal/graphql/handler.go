@@ -0,0 +1,553 @@
+// Package graphql 由 ViewSet 注册表生成的 GraphQL 接口
+//
+// 每个资源生成列表查询（分页、过滤、搜索、排序参数与 HTTP 接口相同）、按 ID 查询，
+// 以及 create / update / delete 变更。根字段以内部请求调用对应的 ViewSet 接口执行，
+// 请求头（认证信息、会话 Cookie、租户等）取自 GraphQL 请求，因此权限、校验、限流、审计与 HTTP 接口完全相同
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"go-viewset/internal/viewset"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求的限制
+const (
+	maxBodyBytes         = 1 << 20
+	defaultMaxRootFields = 20
+)
+
+// Handler GraphQL 接口
+type Handler struct {
+	// MaxRootFields 单个请求最多的根字段数（每个根字段是一次内部请求），默认 20
+	MaxRootFields int
+
+	mu        sync.Mutex
+	resources []resource
+
+	engine *gin.Engine
+	once   sync.Once
+	schema *schema
+}
+
+// resource 一个 ViewSet 对应的资源
+type resource struct {
+	prefix string
+	model  *meta.Model
+
+	filters []string
+	search  bool
+}
+
+// New 创建 GraphQL 接口
+func New() *Handler {
+	return &Handler{MaxRootFields: defaultMaxRootFields}
+}
+
+// Add 添加资源，prefix 为 ViewSet 注册的完整路径；没有模型元数据的 ViewSet 不添加，返回 false
+func (h *Handler) Add(prefix string, vs viewset.BaseViewSet) bool {
+	m, ok := vs.(interface{ Meta() *meta.Model })
+	if !ok || m.Meta() == nil {
+		return false
+	}
+	r := resource{prefix: strings.TrimSuffix(prefix, "/"), model: m.Meta()}
+	if q, ok := vs.(interface {
+		QueryFields() (filters, ordering []string, search bool)
+	}); ok {
+		r.filters, _, r.search = q.QueryFields()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.resources = append(h.resources, r)
+	return true
+}
+
+// Register 注册 GraphQL 接口：POST path 执行查询和变更，GET path 只能执行查询（?query=&variables=&operationName=），
+// 浏览器访问 GET path 时返回 GraphiQL 页面
+// schema 在第一次请求时按路由表生成，只包含已注册的接口（例如只读的 ViewSet 没有变更）
+func (h *Handler) Register(r *gin.Engine, path string) {
+	h.engine = r
+	r.POST(path, h.serve)
+	r.GET(path, h.serve)
+}
+
+// request GraphQL 请求
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// serve 处理 GraphQL 请求
+// 语法和校验错误返回 400，执行中的错误（接口返回的 4xx/5xx）与部分结果一起以 200 返回
+func (h *Handler) serve(c *gin.Context) {
+	if c.Request.Method == http.MethodGet && c.Query("query") == "" && strings.Contains(c.GetHeader("Accept"), "text/html") {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(graphiQL(c.Request.URL.Path)))
+		return
+	}
+	h.once.Do(func() { h.schema = h.build(h.engine.Routes()) })
+
+	req, err := readRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{Errors: []*Error{{Message: err.Error()}}})
+		return
+	}
+	status, resp := h.execute(c, req)
+	c.JSON(status, resp)
+}
+
+// readRequest 读取 GET 的查询参数或 POST 的 JSON 请求体
+func readRequest(c *gin.Context) (*request, error) {
+	req := &request{}
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if vars := c.Query("variables"); vars != "" {
+			if err := decodeJSON(strings.NewReader(vars), &req.Variables); err != nil {
+				return nil, errors.New("variables 不是有效的 JSON 对象")
+			}
+		}
+	} else if err := decodeJSON(io.LimitReader(c.Request.Body, maxBodyBytes), req); err != nil {
+		return nil, errors.New("请求体应为 JSON：{\"query\": ..., \"variables\": {...}}")
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, errors.New("缺少 query")
+	}
+	return req, nil
+}
+
+// decodeJSON 解码 JSON，数字保留为 json.Number
+func decodeJSON(r io.Reader, out interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec.Decode(out)
+}
+
+// execute 解析、校验并执行请求，返回 HTTP 状态码和响应
+func (h *Handler) execute(c *gin.Context, req *request) (int, Response) {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return http.StatusBadRequest, Response{Errors: []*Error{requestError(err)}}
+	}
+	op, err := doc.selectOperation(req.OperationName)
+	if err != nil {
+		return http.StatusBadRequest, Response{Errors: []*Error{requestError(err)}}
+	}
+
+	ex := &executor{schema: h.schema, doc: doc, op: op, call: internalCaller(h.engine, c.Request)}
+	root := h.schema.query
+	switch op.kind {
+	case "mutation":
+		if c.Request.Method == http.MethodGet {
+			return http.StatusMethodNotAllowed, Response{Errors: []*Error{{Message: "变更需要使用 POST 请求"}}}
+		}
+		if h.schema.mutation == nil {
+			return http.StatusBadRequest, Response{Errors: []*Error{requestError(errorf(op.pos, "没有可用的变更"))}}
+		}
+		root = h.schema.mutation
+	case "subscription":
+		return http.StatusBadRequest, Response{Errors: []*Error{requestError(errorf(op.pos, "不支持订阅，请使用 WebSocket 或 SSE 接口"))}}
+	}
+
+	if err := ex.coerceVariables(op, req.Variables); err != nil {
+		return http.StatusBadRequest, Response{Errors: []*Error{requestError(err)}}
+	}
+	if ok, err := ex.included(op.directives); err != nil || !ok {
+		if err == nil {
+			err = errorf(op.pos, "操作上不能使用 @skip / @include")
+		}
+		return http.StatusBadRequest, Response{Errors: []*Error{requestError(err)}}
+	}
+	if err := ex.validate(root, op.selections, 1); err != nil {
+		return http.StatusBadRequest, Response{Errors: []*Error{requestError(err)}}
+	}
+	if groups, _ := ex.collectFields(root, op.selections, nil, map[string]bool{}); len(groups) > h.maxRootFields() {
+		return http.StatusBadRequest, Response{Errors: []*Error{requestError(errorf(op.pos, "根字段不能超过 %d 个", h.maxRootFields()))}}
+	}
+
+	data, err := ex.executeRoot(root, op.selections)
+	if err != nil {
+		return http.StatusBadRequest, Response{Errors: []*Error{requestError(err)}}
+	}
+	resp := Response{Data: data, Errors: ex.errors}
+	if data == nil {
+		resp.Data = json.RawMessage("null")
+	}
+	return http.StatusOK, resp
+}
+
+func (h *Handler) maxRootFields() int {
+	if h.MaxRootFields > 0 {
+		return h.MaxRootFields
+	}
+	return defaultMaxRootFields
+}
+
+// requestError 语法、变量或校验错误
+func requestError(err error) *Error {
+	e := &Error{Message: err.Error()}
+	switch te := err.(type) {
+	case *syntaxError:
+		e.Locations = []position{te.pos}
+	case *inputError:
+		e.Locations = []position{te.pos}
+	}
+	return e
+}
+
+// build 按路由表生成 schema：列表、详情、创建、修改（PATCH）和删除接口存在时才生成对应的字段
+func (h *Handler) build(routes gin.RoutesInfo) *schema {
+	registered := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		registered[r.Method+" "+r.Path] = true
+	}
+
+	h.mu.Lock()
+	resources := append([]resource(nil), h.resources...)
+	h.mu.Unlock()
+
+	s := newSchema()
+	for _, r := range resources {
+		plural, single := fieldName(r.prefix), lowerFirst(r.model.Name)
+		if plural == single {
+			plural += "List"
+		}
+		object := objectType(r.model)
+		if plural == "" || !s.addType(object) {
+			log.Printf("[警告] GraphQL: %s 的模型 %s 已由其他资源生成，跳过", r.prefix, r.model.Name)
+			continue
+		}
+		if s.query.field(plural) != nil || s.query.field(single) != nil {
+			log.Printf("[警告] GraphQL: %s 生成的字段 %s 与其他资源重复，跳过", r.prefix, plural)
+			continue
+		}
+		input := inputType(r.model)
+		id := &InputValue{Name: "id", Type: nonNull(typeID)}
+		collection, detail := r.prefix+"/", r.prefix+"/:id"
+
+		if registered["GET "+collection] {
+			list := listType(object)
+			s.addType(list)
+			s.query.Fields = append(s.query.Fields, &Field{
+				Name:        plural,
+				Description: "GET " + collection,
+				Args:        listArgs(r),
+				Type:        nonNull(list),
+				resolve:     listResolver(collection),
+			})
+		}
+		if registered["GET "+detail] {
+			s.query.Fields = append(s.query.Fields, &Field{
+				Name:        single,
+				Description: "GET " + detail + "，不存在时为 null",
+				Args:        []*InputValue{id},
+				Type:        object,
+				resolve:     retrieveResolver(r.prefix),
+			})
+		}
+		if len(input.InputFields) > 0 && (registered["POST "+collection] || registered["PATCH "+detail]) {
+			s.addType(input)
+		}
+		if registered["POST "+collection] && len(input.InputFields) > 0 {
+			s.mutation.Fields = append(s.mutation.Fields, &Field{
+				Name:        "create" + r.model.Name,
+				Description: "POST " + collection,
+				Args:        []*InputValue{{Name: "input", Type: nonNull(input)}},
+				Type:        object,
+				resolve:     writeResolver(http.MethodPost, r.prefix, false),
+			})
+		}
+		if registered["PATCH "+detail] && len(input.InputFields) > 0 {
+			s.mutation.Fields = append(s.mutation.Fields, &Field{
+				Name:        "update" + r.model.Name,
+				Description: "PATCH " + detail + "，只修改 input 中出现的字段",
+				Args:        []*InputValue{id, {Name: "input", Type: nonNull(input)}},
+				Type:        object,
+				resolve:     writeResolver(http.MethodPatch, r.prefix, true),
+			})
+		}
+		if registered["DELETE "+detail] {
+			s.mutation.Fields = append(s.mutation.Fields, &Field{
+				Name:        "delete" + r.model.Name,
+				Description: "DELETE " + detail,
+				Args:        []*InputValue{id},
+				Type:        nonNull(typeBoolean),
+				resolve:     deleteResolver(r.prefix),
+			})
+		}
+	}
+	s.finish()
+	return s
+}
+
+// listArgs 列表查询的参数：分页、排序、搜索，可过滤字段的精确匹配，
+// 以及 filter（键值与 HTTP 接口的查询参数相同，可以使用 name__icontains 等查找表达式）
+func listArgs(r resource) []*InputValue {
+	args := []*InputValue{
+		{Name: "page", Type: typeInt},
+		{Name: "page_size", Type: typeInt},
+		{Name: "cursor", Type: typeString, Description: "游标分页时上一页返回的 next_cursor"},
+		{Name: "ordering", Type: typeString, Description: "排序字段，逗号分隔，- 开头为降序"},
+	}
+	if r.search {
+		args = append(args, &InputValue{Name: "search", Type: typeString})
+	}
+	args = append(args, &InputValue{Name: "filter", Type: typeJSON, Description: "过滤条件，例如 {\"age__gte\": 18}"})
+
+	reserved := make(map[string]bool, len(args))
+	for _, a := range args {
+		reserved[a.Name] = true
+	}
+	for _, name := range r.filters {
+		if f, ok := r.model.Lookup(name); ok && !reserved[name] {
+			args = append(args, &InputValue{Name: name, Type: scalarFor(f)})
+		}
+	}
+	return args
+}
+
+// listResolver 列表查询：参数转换为查询参数调用 GET <prefix>/
+func listResolver(path string) resolveFunc {
+	return func(ex *executor, args map[string]interface{}) (interface{}, error) {
+		query := make(map[string]string, len(args))
+		for key, value := range args {
+			if key == "filter" {
+				filter, ok := value.(map[string]interface{})
+				if !ok && value != nil {
+					return nil, errors.New("filter 应为对象")
+				}
+				for k, v := range filter {
+					query[k] = queryValue(v)
+				}
+				continue
+			}
+			if value != nil {
+				query[key] = queryValue(value)
+			}
+		}
+
+		res, err := ex.call(http.MethodGet, path, query, nil)
+		if err != nil {
+			return nil, err
+		}
+		list := map[string]interface{}{"items": res.data}
+		for key, value := range res.pagination {
+			list[key] = value
+		}
+		return list, nil
+	}
+}
+
+// retrieveResolver 按 ID 查询，不存在时返回 null
+func retrieveResolver(prefix string) resolveFunc {
+	return func(ex *executor, args map[string]interface{}) (interface{}, error) {
+		res, err := ex.call(http.MethodGet, prefix+"/"+url.PathEscape(args["id"].(string)), nil, nil)
+		if ce, ok := err.(*callError); ok && ce.status == http.StatusNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return res.data, nil
+	}
+}
+
+// writeResolver 创建（POST <prefix>/）或修改（PATCH <prefix>/:id），返回保存后的对象
+func writeResolver(method, prefix string, detail bool) resolveFunc {
+	return func(ex *executor, args map[string]interface{}) (interface{}, error) {
+		path := prefix + "/"
+		if detail {
+			path = prefix + "/" + url.PathEscape(args["id"].(string))
+		}
+		res, err := ex.call(method, path, nil, args["input"])
+		if err != nil {
+			return nil, err
+		}
+		return res.data, nil
+	}
+}
+
+// deleteResolver 删除，成功时返回 true
+func deleteResolver(prefix string) resolveFunc {
+	return func(ex *executor, args map[string]interface{}) (interface{}, error) {
+		if _, err := ex.call(http.MethodDelete, prefix+"/"+url.PathEscape(args["id"].(string)), nil, nil); err != nil {
+			return nil, err
+		}
+		return true, nil
+	}
+}
+
+// queryValue 参数值转换为查询参数，列表和对象使用 JSON
+func queryValue(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case json.Number:
+		return string(x)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// result 内部请求成功时的响应
+type result struct {
+	data       interface{}
+	pagination map[string]interface{}
+}
+
+// callError 内部请求返回的错误
+type callError struct {
+	status  int
+	message string
+	errors  interface{} // 字段错误（见 utils.ErrorMap）
+}
+
+func (e *callError) Error() string { return e.message }
+
+// internalCaller 以内部请求调用 ViewSet 接口
+// 请求头取自 GraphQL 请求，去掉内容协商、条件请求和请求体相关的请求头
+func internalCaller(handler http.Handler, origin *http.Request) func(method, path string, query map[string]string, body interface{}) (*result, error) {
+	return func(method, path string, query map[string]string, body interface{}) (*result, error) {
+		values := make(url.Values, len(query))
+		for key, value := range query {
+			values.Set(key, value)
+		}
+		u := &url.URL{Path: path, RawQuery: values.Encode()}
+
+		var payload []byte
+		if body != nil {
+			var err error
+			if payload, err = json.Marshal(body); err != nil {
+				return nil, err
+			}
+		}
+		req := (&http.Request{
+			Method:        method,
+			URL:           u,
+			RequestURI:    u.RequestURI(),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        make(http.Header, len(origin.Header)),
+			Body:          io.NopCloser(bytes.NewReader(payload)),
+			ContentLength: int64(len(payload)),
+			Host:          origin.Host,
+			RemoteAddr:    origin.RemoteAddr,
+			TLS:           origin.TLS,
+		}).WithContext(origin.Context())
+		for key, values := range origin.Header {
+			switch key {
+			case "Accept", "Accept-Encoding", "Content-Type", "Content-Length",
+				"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since":
+				continue
+			}
+			req.Header[key] = values
+		}
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		w := &responseWriter{header: make(http.Header)}
+		handler.ServeHTTP(w, req)
+		return parseResponse(w)
+	}
+}
+
+// parseResponse 按响应配置（见 utils.SetResponseConfig）解析内部请求的响应
+func parseResponse(w *responseWriter) (*result, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status == http.StatusNoContent {
+		return &result{}, nil
+	}
+	rc := utils.CurrentResponseConfig()
+	var body map[string]interface{}
+	var raw interface{}
+	if err := decodeJSON(bytes.NewReader(w.body.Bytes()), &raw); err != nil {
+		return nil, &callError{status: w.status, message: fmt.Sprintf("接口返回了无法解析的响应（HTTP %d）", w.status)}
+	}
+	body, _ = raw.(map[string]interface{})
+
+	failed := w.status >= http.StatusBadRequest
+	if !failed && !rc.DisableEnvelope && body != nil {
+		if code, ok := body[rc.CodeKey].(json.Number); ok && string(code) != strconv.Itoa(rc.SuccessCode) {
+			failed = true
+		}
+	}
+	if failed {
+		ce := &callError{status: w.status, message: http.StatusText(w.status)}
+		if body != nil {
+			for _, key := range []string{rc.MsgKey, "detail", "title"} {
+				if msg, ok := body[key].(string); ok && msg != "" {
+					ce.message = msg
+					break
+				}
+			}
+			if errs, ok := body[rc.ErrorsKey]; ok {
+				ce.errors = errs
+			} else if errs, ok := body["errors"]; ok {
+				ce.errors = errs
+			}
+		}
+		return nil, ce
+	}
+
+	if rc.DisableEnvelope {
+		res := &result{data: raw, pagination: map[string]interface{}{}}
+		for header, key := range map[string]string{"X-Page": "page", "X-Page-Size": "page_size", "X-Total-Count": "total"} {
+			if v := w.header.Get(header); v != "" {
+				res.pagination[key] = json.Number(v)
+			}
+		}
+		if v := w.header.Get("X-Next-Cursor"); v != "" {
+			res.pagination["next_cursor"] = v
+		}
+		return res, nil
+	}
+	res := &result{data: body[rc.DataKey]}
+	res.pagination, _ = body[rc.PaginationKey].(map[string]interface{})
+	return res, nil
+}
+
+// responseWriter 接收内部请求的响应
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header { return w.header }
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(p)
+}
+
+// Flush 实现 http.Flusher，流式输出的列表同样写入 body
+func (w *responseWriter) Flush() {}
@@ -0,0 +1,136 @@
+package graphql
+
+// lazy 延迟求值的字段值，内省中类型之间互相引用，按选择集展开时才生成
+type lazy func() interface{}
+
+// metaFields 查询根类型上的内省字段，不出现在 Query 的字段列表中
+var metaFields = map[string]*Field{
+	"__schema": {Name: "__schema", Type: nonNull(&Type{Kind: kindObject, Name: "__Schema"})},
+	"__type": {Name: "__type", Args: []*InputValue{{Name: "name", Type: nonNull(typeString)}},
+		Type: &Type{Kind: kindObject, Name: "__Type"}},
+}
+
+// builtinDirectives 支持的指令
+var builtinDirectives = []struct {
+	name, description string
+}{
+	{"include", "if 为 true 时包含该字段或片段"},
+	{"skip", "if 为 true 时跳过该字段或片段"},
+}
+
+// schemaValue __schema 的值
+func (s *schema) schemaValue() map[string]interface{} {
+	v := map[string]interface{}{
+		"description":      nil,
+		"queryType":        lazy(func() interface{} { return typeValue(s.query) }),
+		"mutationType":     nil,
+		"subscriptionType": nil,
+		"types": lazy(func() interface{} {
+			types := s.sortedTypes()
+			out := make([]interface{}, len(types))
+			for i, t := range types {
+				out[i] = typeValue(t)
+			}
+			return out
+		}),
+		"directives": lazy(func() interface{} {
+			out := make([]interface{}, len(builtinDirectives))
+			for i, d := range builtinDirectives {
+				out[i] = map[string]interface{}{
+					"name":         d.name,
+					"description":  d.description,
+					"locations":    []interface{}{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+					"args":         []interface{}{inputValueValue(&InputValue{Name: "if", Type: nonNull(typeBoolean)})},
+					"isRepeatable": false,
+				}
+			}
+			return out
+		}),
+	}
+	if s.mutation != nil {
+		v["mutationType"] = lazy(func() interface{} { return typeValue(s.mutation) })
+	}
+	return v
+}
+
+// typeValue __Type 的值
+func typeValue(t *Type) map[string]interface{} {
+	v := map[string]interface{}{
+		"kind":           t.Kind,
+		"name":           optional(t.Name),
+		"description":    optional(t.Description),
+		"specifiedByURL": nil,
+		"fields":         nil,
+		"interfaces":     nil,
+		"possibleTypes":  nil,
+		"enumValues":     nil,
+		"inputFields":    nil,
+		"ofType":         nil,
+		"isOneOf":        nil,
+	}
+	switch t.Kind {
+	case kindObject:
+		v["interfaces"] = []interface{}{}
+		v["fields"] = lazy(func() interface{} {
+			out := make([]interface{}, len(t.Fields))
+			for i, f := range t.Fields {
+				out[i] = fieldValue(f)
+			}
+			return out
+		})
+	case kindInputObject:
+		v["isOneOf"] = false
+		v["inputFields"] = lazy(func() interface{} {
+			out := make([]interface{}, len(t.InputFields))
+			for i, f := range t.InputFields {
+				out[i] = inputValueValue(f)
+			}
+			return out
+		})
+	case kindEnum:
+		out := make([]interface{}, len(t.EnumValues))
+		for i, name := range t.EnumValues {
+			out[i] = map[string]interface{}{"name": name, "description": nil, "isDeprecated": false, "deprecationReason": nil}
+		}
+		v["enumValues"] = out
+	case kindList, kindNonNull:
+		v["ofType"] = lazy(func() interface{} { return typeValue(t.OfType) })
+	}
+	return v
+}
+
+// fieldValue __Field 的值
+func fieldValue(f *Field) map[string]interface{} {
+	args := make([]interface{}, len(f.Args))
+	for i, a := range f.Args {
+		args[i] = inputValueValue(a)
+	}
+	return map[string]interface{}{
+		"name":              f.Name,
+		"description":       optional(f.Description),
+		"args":              args,
+		"type":              lazy(func() interface{} { return typeValue(f.Type) }),
+		"isDeprecated":      false,
+		"deprecationReason": nil,
+	}
+}
+
+// inputValueValue __InputValue 的值
+func inputValueValue(iv *InputValue) map[string]interface{} {
+	return map[string]interface{}{
+		"name":              iv.Name,
+		"description":       optional(iv.Description),
+		"type":              lazy(func() interface{} { return typeValue(iv.Type) }),
+		"defaultValue":      nil,
+		"isDeprecated":      false,
+		"deprecationReason": nil,
+	}
+}
+
+// optional 空字符串为 null
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
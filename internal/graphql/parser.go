@@ -0,0 +1,760 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 词法单元的类型
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+// token 词法单元
+type token struct {
+	kind  int
+	value string
+	pos   position
+}
+
+// position 源文本中的位置，从 1 开始
+type position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// syntaxError 语法错误
+type syntaxError struct {
+	pos position
+	msg string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("语法错误（第 %d 行第 %d 列）: %s", e.pos.Line, e.pos.Column, e.msg)
+}
+
+// lexer 词法分析，逗号与空白、注释一样被忽略
+type lexer struct {
+	src       string
+	offset    int
+	line      int
+	lineStart int
+}
+
+func (l *lexer) pos() position {
+	return position{Line: l.line, Column: utf8.RuneCountInString(l.src[l.lineStart:l.offset]) + 1}
+}
+
+func (l *lexer) fail(msg string) error {
+	return &syntaxError{pos: l.pos(), msg: msg}
+}
+
+// next 读取下一个词法单元
+func (l *lexer) next() (token, error) {
+	for l.offset < len(l.src) {
+		switch ch := l.src[l.offset]; {
+		case ch == '\n':
+			l.offset++
+			l.line++
+			l.lineStart = l.offset
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == ',':
+			l.offset++
+		case ch == '#':
+			for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+				l.offset++
+			}
+		case strings.HasPrefix(l.src[l.offset:], "\ufeff"):
+			l.offset += len("\ufeff")
+		default:
+			return l.scan()
+		}
+	}
+	return token{kind: tokEOF, pos: l.pos()}, nil
+}
+
+// scan 读取一个非空白的词法单元
+func (l *lexer) scan() (token, error) {
+	pos := l.pos()
+	start := l.offset
+	ch := l.src[l.offset]
+	switch {
+	case strings.HasPrefix(l.src[l.offset:], "..."):
+		l.offset += 3
+		return token{kind: tokPunct, value: "...", pos: pos}, nil
+	case strings.IndexByte("!$&():=@[]{}|", ch) >= 0:
+		l.offset++
+		return token{kind: tokPunct, value: string(ch), pos: pos}, nil
+	case ch == '_' || isLetter(ch):
+		for l.offset < len(l.src) && (l.src[l.offset] == '_' || isLetter(l.src[l.offset]) || isDigit(l.src[l.offset])) {
+			l.offset++
+		}
+		return token{kind: tokName, value: l.src[start:l.offset], pos: pos}, nil
+	case ch == '-' || isDigit(ch):
+		return l.scanNumber(pos)
+	case ch == '"':
+		if strings.HasPrefix(l.src[l.offset:], `"""`) {
+			return l.scanBlockString(pos)
+		}
+		return l.scanString(pos)
+	}
+	return token{}, l.fail(fmt.Sprintf("无法识别的字符 %q", ch))
+}
+
+// scanNumber 读取整数或浮点数
+func (l *lexer) scanNumber(pos position) (token, error) {
+	start := l.offset
+	kind := tokInt
+	if l.src[l.offset] == '-' {
+		l.offset++
+	}
+	digits := func() int {
+		n := 0
+		for l.offset < len(l.src) && isDigit(l.src[l.offset]) {
+			l.offset++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return token{}, l.fail("数字格式错误")
+	}
+	if l.offset < len(l.src) && l.src[l.offset] == '.' {
+		kind = tokFloat
+		l.offset++
+		if digits() == 0 {
+			return token{}, l.fail("数字格式错误")
+		}
+	}
+	if l.offset < len(l.src) && (l.src[l.offset] == 'e' || l.src[l.offset] == 'E') {
+		kind = tokFloat
+		l.offset++
+		if l.offset < len(l.src) && (l.src[l.offset] == '+' || l.src[l.offset] == '-') {
+			l.offset++
+		}
+		if digits() == 0 {
+			return token{}, l.fail("数字格式错误")
+		}
+	}
+	if l.offset < len(l.src) && (l.src[l.offset] == '_' || isLetter(l.src[l.offset]) || l.src[l.offset] == '.') {
+		return token{}, l.fail("数字格式错误")
+	}
+	return token{kind: kind, value: l.src[start:l.offset], pos: pos}, nil
+}
+
+// scanString 读取字符串，处理转义
+func (l *lexer) scanString(pos position) (token, error) {
+	l.offset++
+	var b strings.Builder
+	for l.offset < len(l.src) {
+		ch := l.src[l.offset]
+		switch {
+		case ch == '"':
+			l.offset++
+			return token{kind: tokString, value: b.String(), pos: pos}, nil
+		case ch == '\n' || ch == '\r':
+			return token{}, l.fail("字符串没有结束")
+		case ch == '\\':
+			if l.offset+1 >= len(l.src) {
+				return token{}, l.fail("字符串没有结束")
+			}
+			esc := l.src[l.offset+1]
+			l.offset += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.offset+4 > len(l.src) {
+					return token{}, l.fail("无效的 Unicode 转义")
+				}
+				code, err := strconv.ParseUint(l.src[l.offset:l.offset+4], 16, 32)
+				if err != nil {
+					return token{}, l.fail("无效的 Unicode 转义")
+				}
+				l.offset += 4
+				b.WriteRune(rune(code))
+			default:
+				return token{}, l.fail(fmt.Sprintf("无效的转义 \\%c", esc))
+			}
+		default:
+			b.WriteByte(ch)
+			l.offset++
+		}
+	}
+	return token{}, l.fail("字符串没有结束")
+}
+
+// scanBlockString 读取块字符串 """..."""，按规范去掉公共缩进和首尾空行
+func (l *lexer) scanBlockString(pos position) (token, error) {
+	l.offset += 3
+	var b strings.Builder
+	for l.offset < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.offset:], `"""`):
+			l.offset += 3
+			return token{kind: tokString, value: blockStringValue(b.String()), pos: pos}, nil
+		case strings.HasPrefix(l.src[l.offset:], `\"""`):
+			b.WriteString(`"""`)
+			l.offset += 4
+		default:
+			if l.src[l.offset] == '\n' {
+				l.line++
+				l.lineStart = l.offset + 1
+			}
+			b.WriteByte(l.src[l.offset])
+			l.offset++
+		}
+	}
+	return token{}, l.fail("块字符串没有结束")
+}
+
+// blockStringValue 块字符串的值
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(ch byte) bool { return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' }
+func isDigit(ch byte) bool  { return ch >= '0' && ch <= '9' }
+
+// document 解析后的请求文档
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation 查询或变更操作
+type operation struct {
+	kind       string // query / mutation / subscription
+	name       string
+	vars       []*varDef
+	directives []*directive
+	selections []*selection
+	pos        position
+}
+
+// varDef 变量定义
+type varDef struct {
+	name string
+	typ  *typeRef
+	def  *value
+	pos  position
+}
+
+// typeRef 变量声明中的类型
+type typeRef struct {
+	name    string
+	elem    *typeRef // 列表的元素类型，name 为空
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// fragment 命名片段
+type fragment struct {
+	name       string
+	typeCond   string
+	directives []*directive
+	selections []*selection
+	pos        position
+}
+
+// selection 选择集中的一项：字段、片段展开（spread 不为空）或内联片段（inline 为 true）
+type selection struct {
+	alias      string
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []*selection
+
+	spread   string
+	inline   bool
+	typeCond string
+
+	pos position
+}
+
+// responseKey 字段在结果中的名称
+func (s *selection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// argument 参数
+type argument struct {
+	name  string
+	value *value
+	pos   position
+}
+
+// directive 指令，例如 @include(if: $flag)
+type directive struct {
+	name string
+	args []*argument
+	pos  position
+}
+
+// 参数值的类型
+const (
+	valVariable = iota
+	valInt
+	valFloat
+	valString
+	valBool
+	valNull
+	valEnum
+	valList
+	valObject
+)
+
+// value 字面量或变量
+type value struct {
+	kind   int
+	raw    string // 变量名、数字、字符串、枚举值
+	list   []*value
+	fields []*argument // 对象的字段
+	pos    position
+}
+
+// parser 语法分析
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// parse 解析请求文档，只接受可执行的定义（操作和片段）
+func parse(src string) (*document, error) {
+	p := &parser{lex: &lexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek("{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: sels, pos: sels[0].pos})
+		case p.tok.kind == tokName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.tok.kind == tokName && p.tok.value == "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[f.name]; ok {
+				return nil, &syntaxError{pos: f.pos, msg: "片段 " + f.name + " 重复定义"}
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &syntaxError{pos: p.tok.pos, msg: "请求中没有操作"}
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// peek 当前是否为指定的标点
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.value == punct
+}
+
+// skip 当前为指定的标点时跳过并返回 true
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+// expect 跳过指定的标点，不是时返回错误
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return &syntaxError{pos: p.tok.pos, msg: fmt.Sprintf("应为 %q，实际为 %s", punct, p.describe())}
+	}
+	return p.advance()
+}
+
+// name 读取名称
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", &syntaxError{pos: p.tok.pos, msg: "应为名称，实际为 " + p.describe()}
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) unexpected() error {
+	return &syntaxError{pos: p.tok.pos, msg: "意外的 " + p.describe()}
+}
+
+func (p *parser) describe() string {
+	if p.tok.kind == tokEOF {
+		return "文本结尾"
+	}
+	return strconv.Quote(p.tok.value)
+}
+
+// operation 读取 query / mutation / subscription 操作
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.value, pos: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.tok.kind == tokName {
+		if op.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(")") {
+			v, err := p.varDef()
+			if err != nil {
+				return nil, err
+			}
+			op.vars = append(op.vars, v)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if op.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	op.selections, err = p.selectionSet()
+	return op, err
+}
+
+// varDef 读取变量定义 $name: Type = default
+func (p *parser) varDef() (*varDef, error) {
+	v := &varDef{pos: p.tok.pos}
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	var err error
+	if v.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if v.typ, err = p.typeRef(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if v.def, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// typeRef 读取类型 Name、[Type]、Type!
+func (p *parser) typeRef() (*typeRef, error) {
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if t.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	ok, err := p.skip("!")
+	t.nonNull = ok
+	return t, err
+}
+
+// fragment 读取片段定义 fragment Name on Type { ... }
+func (p *parser) fragment() (*fragment, error) {
+	f := &fragment{pos: p.tok.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.name == "on" {
+		return nil, &syntaxError{pos: f.pos, msg: "片段名称不能为 on"}
+	}
+	if on, err := p.name(); err != nil {
+		return nil, err
+	} else if on != "on" {
+		return nil, &syntaxError{pos: f.pos, msg: "片段缺少类型条件 on"}
+	}
+	if f.typeCond, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	f.selections, err = p.selectionSet()
+	return f, err
+}
+
+// selectionSet 读取选择集 { ... }
+func (p *parser) selectionSet() ([]*selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []*selection
+	for !p.peek("}") {
+		if p.tok.kind == tokEOF {
+			return nil, p.unexpected()
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, &syntaxError{pos: p.tok.pos, msg: "选择集不能为空"}
+	}
+	return sels, p.advance()
+}
+
+// selection 读取字段、片段展开或内联片段
+func (p *parser) selection() (*selection, error) {
+	sel := &selection{pos: p.tok.pos}
+	var err error
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			if sel.spread, err = p.name(); err != nil {
+				return nil, err
+			}
+			sel.directives, err = p.directives()
+			return sel, err
+		}
+		sel.inline = true
+		if p.tok.kind == tokName {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if sel.typeCond, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if sel.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		sel.selections, err = p.selectionSet()
+		return sel, err
+	}
+
+	if sel.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		sel.alias = sel.name
+		if sel.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if sel.args, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if sel.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		sel.selections, err = p.selectionSet()
+	}
+	return sel, err
+}
+
+// arguments 读取参数列表 (name: value ...)，没有时返回 nil
+func (p *parser) arguments(constant bool) ([]*argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var args []*argument
+	for !p.peek(")") {
+		arg := &argument{pos: p.tok.pos}
+		var err error
+		if arg.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.value, err = p.value(constant); err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil, &syntaxError{pos: p.tok.pos, msg: "参数列表不能为空"}
+	}
+	return args, p.advance()
+}
+
+// directives 读取指令
+func (p *parser) directives() ([]*directive, error) {
+	var dirs []*directive
+	for p.peek("@") {
+		d := &directive{pos: p.tok.pos}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.args, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// value 读取值，constant 为 true 时不允许变量（变量的默认值）
+func (p *parser) value(constant bool) (*value, error) {
+	v := &value{pos: p.tok.pos}
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.kind = valVariable
+		var err error
+		v.raw, err = p.name()
+		return v, err
+	case p.peek("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.kind = valList
+		v.list = []*value{}
+		for !p.peek("]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+		}
+		return v, p.advance()
+	case p.peek("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v.kind = valObject
+		for !p.peek("}") {
+			field := &argument{pos: p.tok.pos}
+			var err error
+			if field.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if field.value, err = p.value(constant); err != nil {
+				return nil, err
+			}
+			v.fields = append(v.fields, field)
+		}
+		return v, p.advance()
+	case p.tok.kind == tokInt:
+		v.kind, v.raw = valInt, p.tok.value
+	case p.tok.kind == tokFloat:
+		v.kind, v.raw = valFloat, p.tok.value
+	case p.tok.kind == tokString:
+		v.kind, v.raw = valString, p.tok.value
+	case p.tok.kind == tokName && (p.tok.value == "true" || p.tok.value == "false"):
+		v.kind, v.raw = valBool, p.tok.value
+	case p.tok.kind == tokName && p.tok.value == "null":
+		v.kind = valNull
+	case p.tok.kind == tokName:
+		v.kind, v.raw = valEnum, p.tok.value
+	default:
+		return nil, p.unexpected()
+	}
+	return v, p.advance()
+}
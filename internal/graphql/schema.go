@@ -0,0 +1,319 @@
+package graphql
+
+import (
+	"go-viewset/internal/meta"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 类型的种类，与内省中的 __TypeKind 相同
+const (
+	kindScalar      = "SCALAR"
+	kindObject      = "OBJECT"
+	kindInputObject = "INPUT_OBJECT"
+	kindEnum        = "ENUM"
+	kindList        = "LIST"
+	kindNonNull     = "NON_NULL"
+)
+
+// Type GraphQL 类型
+type Type struct {
+	Kind        string
+	Name        string
+	Description string
+
+	Fields      []*Field      // OBJECT
+	InputFields []*InputValue // INPUT_OBJECT
+	EnumValues  []string      // ENUM
+	OfType      *Type         // LIST / NON_NULL
+}
+
+// Field 对象类型的字段
+type Field struct {
+	Name        string
+	Description string
+	Args        []*InputValue
+	Type        *Type
+
+	// resolve 根字段的解析函数，对象字段为 nil（从父对象中按名称取值）
+	resolve resolveFunc
+}
+
+// InputValue 参数或输入对象的字段
+type InputValue struct {
+	Name        string
+	Description string
+	Type        *Type
+}
+
+// field 按名称查找字段
+func (t *Type) field(name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// named 去掉 LIST 和 NON_NULL 后的类型
+func (t *Type) named() *Type {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+	return t
+}
+
+// leaf 是否为标量或枚举（不能有子选择集）
+func (t *Type) leaf() bool {
+	n := t.named()
+	return n.Kind == kindScalar || n.Kind == kindEnum
+}
+
+// String 类型在 SDL 中的写法，例如 [User!]!
+func (t *Type) String() string {
+	switch t.Kind {
+	case kindList:
+		return "[" + t.OfType.String() + "]"
+	case kindNonNull:
+		return t.OfType.String() + "!"
+	}
+	return t.Name
+}
+
+func nonNull(t *Type) *Type { return &Type{Kind: kindNonNull, OfType: t} }
+func listOf(t *Type) *Type  { return &Type{Kind: kindList, OfType: t} }
+
+// 内置标量
+var (
+	typeInt     = &Type{Kind: kindScalar, Name: "Int"}
+	typeFloat   = &Type{Kind: kindScalar, Name: "Float"}
+	typeString  = &Type{Kind: kindScalar, Name: "String"}
+	typeBoolean = &Type{Kind: kindScalar, Name: "Boolean"}
+	typeID      = &Type{Kind: kindScalar, Name: "ID"}
+
+	// typeJSON 任意 JSON 值：对象类型的字段、附件信息和列表的 filter 参数
+	typeJSON = &Type{Kind: kindScalar, Name: "JSON", Description: "任意 JSON 值"}
+)
+
+// scalarFor 模型字段类型对应的标量，时间为 RFC 3339 格式的字符串
+func scalarFor(f *meta.Field) *Type {
+	if f.PrimaryKey {
+		return typeID
+	}
+	switch f.TypeName {
+	case "int":
+		return typeInt
+	case "float":
+		return typeFloat
+	case "bool":
+		return typeBoolean
+	case "string", "time":
+		return typeString
+	}
+	return typeJSON
+}
+
+// schema 生成的 GraphQL schema
+type schema struct {
+	query    *Type
+	mutation *Type
+	types    map[string]*Type
+}
+
+// newSchema 创建只包含内置类型和内省字段的 schema
+func newSchema() *schema {
+	s := &schema{
+		query:    &Type{Kind: kindObject, Name: "Query"},
+		mutation: &Type{Kind: kindObject, Name: "Mutation"},
+		types:    make(map[string]*Type),
+	}
+	for _, t := range []*Type{typeInt, typeFloat, typeString, typeBoolean, typeID, typeJSON} {
+		s.types[t.Name] = t
+	}
+	for _, t := range introspectionTypes() {
+		s.types[t.Name] = t
+	}
+	return s
+}
+
+// addType 添加命名类型，同名的类型已存在时返回 false
+func (s *schema) addType(t *Type) bool {
+	if _, ok := s.types[t.Name]; ok {
+		return false
+	}
+	s.types[t.Name] = t
+	return true
+}
+
+// finish 添加根类型，没有变更字段时不生成 Mutation
+func (s *schema) finish() {
+	s.types[s.query.Name] = s.query
+	if len(s.mutation.Fields) > 0 {
+		s.types[s.mutation.Name] = s.mutation
+	} else {
+		s.mutation = nil
+	}
+}
+
+// sortedTypes 按名称排序的全部命名类型
+func (s *schema) sortedTypes() []*Type {
+	types := make([]*Type, 0, len(s.types))
+	for _, t := range s.types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// objectType 模型的输出类型：不含只写字段，附件 ID 字段附带的附件信息为 JSON
+func objectType(model *meta.Model) *Type {
+	t := &Type{Kind: kindObject, Name: model.Name}
+	for _, f := range model.Fields {
+		if f.JSONName == "" || f.JSONName == "-" || f.WriteOnly {
+			continue
+		}
+		typ := scalarFor(f)
+		if f.PrimaryKey {
+			typ = nonNull(typ)
+		}
+		t.Fields = append(t.Fields, &Field{Name: f.JSONName, Type: typ})
+		if f.File != "" {
+			t.Fields = append(t.Fields, &Field{Name: f.File, Type: typeJSON, Description: f.JSONName + " 对应的附件信息"})
+		}
+	}
+	return t
+}
+
+// inputType 模型的输入类型：可写的字段，都可以省略（创建时的必填字段由接口校验）
+func inputType(model *meta.Model) *Type {
+	t := &Type{Kind: kindInputObject, Name: model.Name + "Input"}
+	for _, f := range model.Fields {
+		if f.JSONName == "" || f.JSONName == "-" || f.ReadOnly || f.PrimaryKey {
+			continue
+		}
+		desc := ""
+		if f.Required {
+			desc = "创建时必填"
+		}
+		typ := scalarFor(f)
+		t.InputFields = append(t.InputFields, &InputValue{Name: f.JSONName, Type: typ, Description: desc})
+	}
+	return t
+}
+
+// listType 列表查询的结果：当前页的对象和分页信息
+func listType(object *Type) *Type {
+	return &Type{
+		Kind: kindObject,
+		Name: object.Name + "List",
+		Fields: []*Field{
+			{Name: "items", Type: nonNull(listOf(nonNull(object)))},
+			{Name: "total", Type: typeInt, Description: "总数，游标分页或关闭计数时为 null"},
+			{Name: "page", Type: typeInt},
+			{Name: "page_size", Type: typeInt},
+			{Name: "next_cursor", Type: typeString, Description: "游标分页时下一页的游标"},
+		},
+	}
+}
+
+// fieldName 由路由前缀的最后一段生成字段名，例如 /api/audit-logs -> auditLogs
+func fieldName(prefix string) string {
+	segment := prefix[strings.LastIndex(prefix, "/")+1:]
+	var b strings.Builder
+	upper := false
+	for _, r := range segment {
+		switch {
+		case r == '-' || r == '_' || r == '.':
+			upper = b.Len() > 0
+		case unicode.IsLetter(r) || unicode.IsDigit(r) && b.Len() > 0:
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			upper = false
+		}
+	}
+	return b.String()
+}
+
+// lowerFirst 首字母小写，例如 AuditLog -> auditLog
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// 内省类型（GraphQL 规范第 4 章），字段按规范定义，值由 introspect.go 生成
+func introspectionTypes() []*Type {
+	typeKind := &Type{Kind: kindEnum, Name: "__TypeKind", EnumValues: []string{
+		"SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL",
+	}}
+	location := &Type{Kind: kindEnum, Name: "__DirectiveLocation", EnumValues: []string{
+		"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD", "INLINE_FRAGMENT",
+		"VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INTERFACE",
+		"UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION",
+	}}
+	typ := &Type{Kind: kindObject, Name: "__Type"}
+	field := &Type{Kind: kindObject, Name: "__Field"}
+	inputValue := &Type{Kind: kindObject, Name: "__InputValue"}
+	enumValue := &Type{Kind: kindObject, Name: "__EnumValue"}
+	dir := &Type{Kind: kindObject, Name: "__Directive"}
+	includeDeprecated := []*InputValue{{Name: "includeDeprecated", Type: typeBoolean}}
+
+	typ.Fields = []*Field{
+		{Name: "kind", Type: nonNull(typeKind)},
+		{Name: "name", Type: typeString},
+		{Name: "description", Type: typeString},
+		{Name: "specifiedByURL", Type: typeString},
+		{Name: "fields", Args: includeDeprecated, Type: listOf(nonNull(field))},
+		{Name: "interfaces", Type: listOf(nonNull(typ))},
+		{Name: "possibleTypes", Type: listOf(nonNull(typ))},
+		{Name: "enumValues", Args: includeDeprecated, Type: listOf(nonNull(enumValue))},
+		{Name: "inputFields", Args: includeDeprecated, Type: listOf(nonNull(inputValue))},
+		{Name: "ofType", Type: typ},
+		{Name: "isOneOf", Type: typeBoolean},
+	}
+	field.Fields = []*Field{
+		{Name: "name", Type: nonNull(typeString)},
+		{Name: "description", Type: typeString},
+		{Name: "args", Args: includeDeprecated, Type: nonNull(listOf(nonNull(inputValue)))},
+		{Name: "type", Type: nonNull(typ)},
+		{Name: "isDeprecated", Type: nonNull(typeBoolean)},
+		{Name: "deprecationReason", Type: typeString},
+	}
+	inputValue.Fields = []*Field{
+		{Name: "name", Type: nonNull(typeString)},
+		{Name: "description", Type: typeString},
+		{Name: "type", Type: nonNull(typ)},
+		{Name: "defaultValue", Type: typeString},
+		{Name: "isDeprecated", Type: nonNull(typeBoolean)},
+		{Name: "deprecationReason", Type: typeString},
+	}
+	enumValue.Fields = []*Field{
+		{Name: "name", Type: nonNull(typeString)},
+		{Name: "description", Type: typeString},
+		{Name: "isDeprecated", Type: nonNull(typeBoolean)},
+		{Name: "deprecationReason", Type: typeString},
+	}
+	dir.Fields = []*Field{
+		{Name: "name", Type: nonNull(typeString)},
+		{Name: "description", Type: typeString},
+		{Name: "locations", Type: nonNull(listOf(nonNull(location)))},
+		{Name: "args", Args: includeDeprecated, Type: nonNull(listOf(nonNull(inputValue)))},
+		{Name: "isRepeatable", Type: nonNull(typeBoolean)},
+	}
+	schemaType := &Type{Kind: kindObject, Name: "__Schema", Fields: []*Field{
+		{Name: "description", Type: typeString},
+		{Name: "types", Type: nonNull(listOf(nonNull(typ)))},
+		{Name: "queryType", Type: nonNull(typ)},
+		{Name: "mutationType", Type: typ},
+		{Name: "subscriptionType", Type: typ},
+		{Name: "directives", Type: nonNull(listOf(nonNull(dir)))},
+	}}
+	return []*Type{schemaType, typ, field, inputValue, enumValue, dir, typeKind, location}
+}
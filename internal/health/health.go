@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Check 检查一个依赖是否可用，不可用时返回错误
+type Check func(ctx context.Context) error
+
+// Status 检查结果
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Result 单个依赖的检查结果
+type Result struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report 就绪检查的响应
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// namedCheck 带名称的依赖检查
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker 存活和就绪检查
+// 存活检查只表示进程能处理请求，不检查依赖，避免数据库故障时所有实例被同时重启；
+// 就绪检查并发执行全部依赖检查，任意一项失败返回 503，让负载均衡暂时摘除该实例
+type Checker struct {
+	// Timeout 单次就绪检查的超时时间
+	Timeout time.Duration
+
+	checks []namedCheck
+}
+
+// New 创建检查器
+func New() *Checker {
+	return &Checker{Timeout: 2 * time.Second}
+}
+
+// Add 添加依赖检查，name 为响应中的名称，例如 database、redis
+func (h *Checker) Add(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Liveness 存活检查处理函数
+func (h *Checker) Liveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+	}
+}
+
+// Readiness 就绪检查处理函数
+func (h *Checker) Readiness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := h.Run(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
+
+// Run 执行全部依赖检查
+func (h *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(h.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range h.checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := nc.check(ctx)
+			result := Result{Status: StatusOK, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = StatusUnavailable
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = result
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+		}(nc)
+	}
+	wg.Wait()
+
+	return report
+}
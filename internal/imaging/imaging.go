@@ -0,0 +1,97 @@
+// Package imaging 生成图片缩略图：解码 JPEG、PNG、GIF，按面积平均缩小，编码为 JPEG 或 PNG
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // 注册 GIF 解码
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// ErrTooLarge 图片的像素数超过限制
+var ErrTooLarge = errors.New("imaging: 图片尺寸过大")
+
+// jpegQuality 缩略图的 JPEG 质量
+const jpegQuality = 85
+
+// Decode 解码图片，返回图片和格式（jpeg / png / gif）
+// 先读取图片尺寸，像素数超过 maxPixels 时返回 ErrTooLarge，避免解码超大图片占用过多内存
+func Decode(r io.ReadSeeker, maxPixels int) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, "", fmt.Errorf("imaging: 图片尺寸无效 %dx%d", cfg.Width, cfg.Height)
+	}
+	if maxPixels > 0 && cfg.Width*cfg.Height > maxPixels {
+		return nil, "", ErrTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// Thumbnail 将图片等比缩小到宽高都不超过 size，图片本身更小时不放大
+// 每个目标像素取对应源区域的平均值（面积平均），在预乘 alpha 的 RGBA 上计算，透明边缘不会发黑
+func Thumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(srcH*size/srcW, 1)
+		} else {
+			dstW, dstH = max(srcW*size/srcH, 1), size
+		}
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	if dstW == srcW && dstH == srcH {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					b += uint64(row[i+2])
+					a += uint64(row[i+3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Encode 编码缩略图，format 为 png 时编码为 PNG（保留透明度），否则为 JPEG
+func Encode(w io.Writer, img image.Image, format string) error {
+	if format == "png" {
+		return png.Encode(w, img)
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+}
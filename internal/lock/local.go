@@ -0,0 +1,75 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LocalLocker 进程内的锁，只在单实例部署（例如 SQLite）时使用
+type LocalLocker struct {
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*localLock
+}
+
+// localLock 一个 key 的锁，refs 为持有或等待该锁的数量，归零时删除
+type localLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// NewLocalLocker 创建进程内的锁
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{
+		Timeout: 5 * time.Second,
+		locks:   make(map[string]*localLock),
+	}
+}
+
+// WithLock 获取 key 对应的锁后执行 fn，执行完毕释放锁
+// 在 Timeout 内未拿到锁时返回 ErrNotAcquired
+func (l *LocalLocker) WithLock(ctx context.Context, key string, fn func() error) error {
+	lk := l.acquireRef(key)
+	defer l.releaseRef(key, lk)
+
+	timer := time.NewTimer(l.Timeout)
+	defer timer.Stop()
+
+	select {
+	case lk.ch <- struct{}{}:
+	case <-timer.C:
+		return ErrNotAcquired
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-lk.ch }()
+
+	return fn()
+}
+
+// acquireRef 取得 key 对应的锁并增加引用计数
+func (l *LocalLocker) acquireRef(key string) *localLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lk, ok := l.locks[key]
+	if !ok {
+		lk = &localLock{ch: make(chan struct{}, 1)}
+		l.locks[key] = lk
+	}
+	lk.refs++
+	return lk
+}
+
+// releaseRef 减少引用计数，没有人使用时删除
+func (l *LocalLocker) releaseRef(key string, lk *localLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lk.refs--
+	if lk.refs == 0 {
+		delete(l.locks, key)
+	}
+}
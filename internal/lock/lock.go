@@ -0,0 +1,80 @@
+package lock
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotAcquired 在超时时间内没有拿到锁
+var ErrNotAcquired = errors.New("lock not acquired")
+
+// Locker 分布式锁接口
+// 同一个 key 的 fn 在所有实例之间串行执行
+type Locker interface {
+	WithLock(ctx context.Context, key string, fn func() error) error
+}
+
+// New 按数据库类型创建锁
+// MySQL 使用 GET_LOCK，PostgreSQL 使用 advisory lock，其他数据库（SQLite 等单机场景）使用进程内的锁
+func New(db *gorm.DB) Locker {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return NewMySQLLocker(db)
+	case "postgres":
+		return NewPostgresLocker(db)
+	}
+	return NewLocalLocker()
+}
+
+// MySQLLocker 基于 MySQL GET_LOCK 的咨询锁实现
+// 锁绑定在数据库会话上，因此加锁、执行和释放都在同一个连接上完成
+type MySQLLocker struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+// NewMySQLLocker 创建 MySQL 咨询锁
+func NewMySQLLocker(db *gorm.DB) *MySQLLocker {
+	return &MySQLLocker{
+		DB:      db,
+		Timeout: 5 * time.Second,
+	}
+}
+
+// WithLock 获取 key 对应的锁后执行 fn，执行完毕释放锁
+// 在 Timeout 内未拿到锁时返回 ErrNotAcquired
+func (l *MySQLLocker) WithLock(ctx context.Context, key string, fn func() error) error {
+	name := lockName(key)
+
+	return l.DB.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var acquired sql.NullInt64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", name, int(l.Timeout.Seconds())).Row().Scan(&acquired); err != nil {
+			return err
+		}
+		if !acquired.Valid || acquired.Int64 != 1 {
+			return ErrNotAcquired
+		}
+
+		// 请求上下文可能已经取消，释放锁时使用独立的上下文，
+		// 否则连接归还连接池时仍持有锁
+		defer conn.WithContext(context.Background()).Exec("SELECT RELEASE_LOCK(?)", name)
+
+		return fn()
+	})
+}
+
+// lockName 生成锁名称
+// MySQL 锁名最长 64 个字符，超长的 key 使用哈希值
+func lockName(key string) string {
+	if len(key) <= 64 {
+		return key
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
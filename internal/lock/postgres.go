@@ -0,0 +1,65 @@
+package lock
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// postgresPollInterval 未拿到锁时重试的间隔
+const postgresPollInterval = 50 * time.Millisecond
+
+// PostgresLocker 基于 PostgreSQL pg_try_advisory_lock 的咨询锁实现
+// 与 MySQLLocker 一样，加锁、执行和释放都在同一个连接上完成
+type PostgresLocker struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+}
+
+// NewPostgresLocker 创建 PostgreSQL 咨询锁
+func NewPostgresLocker(db *gorm.DB) *PostgresLocker {
+	return &PostgresLocker{
+		DB:      db,
+		Timeout: 5 * time.Second,
+	}
+}
+
+// WithLock 获取 key 对应的锁后执行 fn，执行完毕释放锁
+// pg_advisory_lock 没有超时参数，因此用 pg_try_advisory_lock 轮询，在 Timeout 内未拿到锁时返回 ErrNotAcquired
+func (l *PostgresLocker) WithLock(ctx context.Context, key string, fn func() error) error {
+	id := advisoryKey(key)
+
+	return l.DB.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		deadline := time.Now().Add(l.Timeout)
+		for {
+			var acquired bool
+			if err := conn.Raw("SELECT pg_try_advisory_lock(?)", id).Row().Scan(&acquired); err != nil {
+				return err
+			}
+			if acquired {
+				break
+			}
+			if time.Now().After(deadline) {
+				return ErrNotAcquired
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(postgresPollInterval):
+			}
+		}
+
+		defer conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", id)
+
+		return fn()
+	})
+}
+
+// advisoryKey 把 key 转换为 advisory lock 使用的 bigint
+func advisoryKey(key string) int64 {
+	sum := sha1.Sum([]byte(key))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
@@ -0,0 +1,232 @@
+// Package mailer 发送邮件：按模板生成内容，通过可替换的发送方式（SMTP、日志）异步发送
+//
+// 邮件先进入内存队列，由后台 goroutine 按顺序发送，失败时按 1s、2s、4s... 重试 MaxAttempts 次后丢弃；
+// SMTP 返回 5xx（永久错误，例如收件人不存在）时不再重试。
+// 模板见 Templates，内置的模板在 templates 目录下，可以通过 mail.templatesDir 覆盖
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-viewset/internal/config"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认配置
+const (
+	defaultQueueSize   = 100
+	defaultMaxAttempts = 3
+	sendTimeout        = 30 * time.Second
+)
+
+// ErrQueueFull 发送队列已满或已关闭
+var ErrQueueFull = errors.New("邮件发送队列已满")
+
+// Message 一封邮件
+type Message struct {
+	From    string // 为空时使用配置的发件人
+	To      []string
+	Subject string
+	HTML    string
+	Text    string // 纯文本内容，为空时只发送 HTML
+}
+
+// Provider 邮件的发送方式
+type Provider interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// PermanentError 不需要重试的发送错误，Provider 返回它时 Mailer 不再重试
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Opener 按配置创建发送方式
+type Opener func(cfg config.MailConfig) (Provider, error)
+
+// providers 已注册的发送方式，key 为 MailConfig.Provider
+var providers = map[string]Opener{
+	"smtp": func(cfg config.MailConfig) (Provider, error) { return NewSMTP(cfg.SMTP) },
+	"log":  func(cfg config.MailConfig) (Provider, error) { return LogProvider{}, nil },
+}
+
+// RegisterProvider 注册发送方式，例如通过第三方邮件服务的 API 发送
+func RegisterProvider(name string, open Opener) {
+	providers[name] = open
+}
+
+// Open 按配置创建发送方式，未配置时为 smtp
+func Open(cfg config.MailConfig) (Provider, error) {
+	name := cfg.Provider
+	if name == "" {
+		name = "smtp"
+	}
+	open, ok := providers[name]
+	if !ok {
+		names := make([]string, 0, len(providers))
+		for name := range providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("不支持的邮件发送方式 %q（支持：%s）", name, strings.Join(names, ", "))
+	}
+	return open(cfg)
+}
+
+// LogProvider 只把邮件写到日志，用于开发环境
+type LogProvider struct{}
+
+// Send 实现 Provider
+func (LogProvider) Send(ctx context.Context, msg *Message) error {
+	body := msg.Text
+	if body == "" {
+		body = msg.HTML
+	}
+	log.Printf("[邮件] From: %s To: %s Subject: %s\n%s", msg.From, strings.Join(msg.To, ", "), msg.Subject, body)
+	return nil
+}
+
+// Mailer 按模板生成邮件并异步发送
+type Mailer struct {
+	provider    Provider
+	templates   *Templates
+	from        string
+	baseURL     string
+	maxAttempts int
+
+	queue chan *Message
+	stop  chan struct{}
+	once  sync.Once
+	done  chan struct{}
+}
+
+// New 创建 Mailer 并加载模板，调用 Start 后开始发送
+func New(provider Provider, cfg config.MailConfig) (*Mailer, error) {
+	templates, err := LoadTemplates(cfg.TemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mailer{
+		provider:    provider,
+		templates:   templates,
+		from:        cfg.From,
+		baseURL:     strings.TrimSuffix(cfg.BaseURL, "/"),
+		maxAttempts: cfg.MaxAttempts,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if m.maxAttempts <= 0 {
+		m.maxAttempts = defaultMaxAttempts
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	m.queue = make(chan *Message, queueSize)
+	return m, nil
+}
+
+// URL 邮件中链接的完整地址：BaseURL + path
+func (m *Mailer) URL(path string) string {
+	return m.baseURL + path
+}
+
+// Send 按模板 name 生成邮件并放入发送队列
+// 模板错误立即返回；发送是异步的，发送失败只记录日志
+func (m *Mailer) Send(to []string, name string, data interface{}) error {
+	msg, err := m.templates.Render(name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return m.SendMessage(msg)
+}
+
+// SendMessage 将邮件放入发送队列，队列已满或已关闭时返回 ErrQueueFull
+func (m *Mailer) SendMessage(msg *Message) error {
+	if msg.From == "" {
+		msg.From = m.from
+	}
+	select {
+	case <-m.stop:
+		return ErrQueueFull
+	default:
+	}
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Start 启动发送的 goroutine
+func (m *Mailer) Start() {
+	go m.run()
+}
+
+// Close 发送完队列中剩余的邮件，ctx 结束时不再等待
+func (m *Mailer) Close(ctx context.Context) error {
+	m.once.Do(func() { close(m.stop) })
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 按顺序发送队列中的邮件，停止后发送完剩余的邮件再退出
+func (m *Mailer) run() {
+	defer close(m.done)
+	for {
+		select {
+		case msg := <-m.queue:
+			m.send(msg)
+		case <-m.stop:
+			for {
+				select {
+				case msg := <-m.queue:
+					m.send(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send 发送一封邮件，失败时按 1s、2s、4s... 重试，永久错误不重试
+func (m *Mailer) send(msg *Message) {
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := m.provider.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		var permanent *PermanentError
+		if errors.As(err, &permanent) || attempt >= m.maxAttempts {
+			log.Printf("发送邮件「%s」到 %s 失败，已尝试 %d 次: %v", msg.Subject, strings.Join(msg.To, ", "), attempt, err)
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-m.stop:
+			// 停止时不再等待重试
+			log.Printf("发送邮件「%s」到 %s 失败: %v", msg.Subject, strings.Join(msg.To, ", "), err)
+			return
+		}
+		delay *= 2
+	}
+}
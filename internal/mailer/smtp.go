@@ -0,0 +1,211 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-viewset/internal/config"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP 的加密方式
+const (
+	SMTPStartTLS = "starttls" // 明文连接后通过 STARTTLS 升级（默认，端口 587）
+	SMTPTLS      = "tls"      // 直接建立 TLS 连接（端口 465）
+	SMTPNone     = "none"     // 不加密，只用于本地调试（例如 MailHog）
+)
+
+// SMTP 通过 SMTP 服务器发送邮件，每封邮件使用一个新连接
+type SMTP struct {
+	host     string
+	addr     string
+	security string
+	username string
+	password string
+}
+
+// NewSMTP 创建 SMTP 发送方式
+func NewSMTP(cfg config.SMTPConfig) (*SMTP, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("SMTP 需要配置 host")
+	}
+	s := &SMTP{host: cfg.Host, security: cfg.Security, username: cfg.Username, password: cfg.Password}
+	port := cfg.Port
+	switch s.security {
+	case "":
+		s.security = SMTPStartTLS
+		if port == 465 {
+			s.security = SMTPTLS
+		}
+	case SMTPStartTLS, SMTPTLS, SMTPNone:
+	default:
+		return nil, fmt.Errorf("不支持的 SMTP 加密方式 %q（支持 starttls、tls、none）", s.security)
+	}
+	if port == 0 {
+		port = map[string]int{SMTPStartTLS: 587, SMTPTLS: 465, SMTPNone: 25}[s.security]
+	}
+	s.addr = net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	return s, nil
+}
+
+// Send 实现 Provider，服务器返回 5xx 时为 PermanentError
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("发件人地址无效 %q: %w", msg.From, err)}
+	}
+	to := make([]*mail.Address, 0, len(msg.To))
+	for _, addr := range msg.To {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return &PermanentError{Err: fmt.Errorf("收件人地址无效 %q: %w", addr, err)}
+		}
+		to = append(to, parsed)
+	}
+	if len(to) == 0 {
+		return &PermanentError{Err: errors.New("没有收件人")}
+	}
+	data, err := buildMessage(from, to, msg)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	err = s.deliver(ctx, from.Address, to, data)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// deliver 连接服务器并发送
+func (s *SMTP) deliver(ctx context.Context, from string, to []*mail.Address, data []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if s.security == SMTPTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.host})
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if s.security == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP 服务器不支持 STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage 生成 MIME 格式的邮件：有纯文本内容时为 multipart/alternative，否则只有 HTML
+func buildMessage(from *mail.Address, to []*mail.Address, msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.String()
+	}
+	messageID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	header := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	header("To", strings.Join(recipients, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+messageID+"@"+domain+">")
+	header("MIME-Version", "1.0")
+
+	if msg.Text == "" {
+		writePart(&buf, "text/html", msg.HTML)
+		return buf.Bytes(), nil
+	}
+
+	boundary, err := randomHex(12)
+	if err != nil {
+		return nil, err
+	}
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		buf.WriteString("--" + boundary + "\r\n")
+		writePart(&buf, part.contentType, part.body)
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("--" + boundary + "--\r\n")
+	return buf.Bytes(), nil
+}
+
+// writePart 写出一个 quoted-printable 编码的内容（包括 Content-Type 头）
+func writePart(buf *bytes.Buffer, contentType, body string) {
+	buf.WriteString("Content-Type: " + contentType + "; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(buf)
+	w.Write([]byte(body))
+	w.Close()
+}
+
+// randomHex n 字节随机数的十六进制
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
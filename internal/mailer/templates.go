@@ -0,0 +1,105 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// builtin 内置的模板
+//
+//go:embed templates/*.html
+var builtin embed.FS
+
+// Templates 邮件模板
+// 每个模板是一个 <名称>.html 文件，其中用 define 定义三部分：
+//
+//	{{define "subject"}}重置密码{{end}}
+//	{{define "html"}}<p>{{.Name}}，你好：</p>...{{end}}
+//	{{define "text"}}{{.Name}}，你好：...{{end}}（可选，纯文本内容）
+//
+// html 部分按 HTML 转义，subject 和 text 部分不转义
+type Templates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// LoadTemplates 加载内置模板，dir 不为空时再加载该目录下的 *.html，同名模板覆盖内置模板
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{
+		html: make(map[string]*htmltemplate.Template),
+		text: make(map[string]*texttemplate.Template),
+	}
+	if err := t.load(builtin, "templates"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := t.load(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// load 加载 fsys 中 root 目录下的 *.html
+func (t *Templates) load(fsys fs.FS, root string) error {
+	files, err := fs.Glob(fsys, path.Join(root, "*.html"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(path.Base(file), ".html")
+
+		h, err := htmltemplate.New(name).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("解析邮件模板 %s 失败: %w", file, err)
+		}
+		x, err := texttemplate.New(name).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("解析邮件模板 %s 失败: %w", file, err)
+		}
+		if h.Lookup("subject") == nil || h.Lookup("html") == nil {
+			return fmt.Errorf("邮件模板 %s 需要定义 subject 和 html", file)
+		}
+		t.html[name] = h
+		t.text[name] = x
+	}
+	return nil
+}
+
+// Render 按模板 name 生成邮件的主题和内容
+func (t *Templates) Render(name string, data interface{}) (*Message, error) {
+	h, ok := t.html[name]
+	if !ok {
+		return nil, fmt.Errorf("邮件模板 %s 不存在", name)
+	}
+	x := t.text[name]
+
+	var subject, html, text bytes.Buffer
+	if err := x.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := h.ExecuteTemplate(&html, "html", data); err != nil {
+		return nil, err
+	}
+	if x.Lookup("text") != nil {
+		if err := x.ExecuteTemplate(&text, "text", data); err != nil {
+			return nil, err
+		}
+	}
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()),
+	}, nil
+}
@@ -0,0 +1,160 @@
+package meta
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Field 模型字段的元数据
+type Field struct {
+	Name       string       `json:"name"`      // Go 字段名
+	JSONName   string       `json:"json_name"` // JSON 中的字段名
+	Column     string       `json:"column"`    // 数据库列名
+	Type       reflect.Type `json:"-"`         // 字段类型（去掉指针）
+	TypeName   string       `json:"type"`      // 字段类型名称，例如 string、int、time
+	PrimaryKey bool         `json:"primary_key"`
+	Required   bool         `json:"required"` // binding 规则中包含 required
+	Binding    string       `json:"binding,omitempty"`
+	Filterable bool         `json:"filterable"`     // 是否允许作为过滤条件，通过 filter:"-" 关闭
+	Orderable  bool         `json:"orderable"`      // 是否允许作为排序字段，通过 order:"-" 关闭
+	ReadOnly   bool         `json:"read_only"`      // 只出现在响应中，客户端不能写入，通过 access:"readonly" 标记
+	WriteOnly  bool         `json:"write_only"`     // 只能由客户端写入，不出现在响应中，通过 access:"writeonly" 标记
+	File       string       `json:"file,omitempty"` // 附件 ID 字段在响应中输出附件信息时使用的字段名，通过 file:"avatar" 标记
+}
+
+// Model 模型的元数据
+type Model struct {
+	Type   reflect.Type
+	Name   string
+	Table  string
+	Schema *schema.Schema
+	Fields []*Field
+
+	byJSON   map[string]*Field
+	byColumn map[string]*Field
+}
+
+// registry 已解析的模型元数据，reflect.Type -> *Model
+var registry sync.Map
+
+// Of 获取模型的元数据
+// 每个模型类型只解析一次，结果在所有 ViewSet 以及过滤、序列化、文档生成等子系统间共享
+func Of(db *gorm.DB, model interface{}) (*Model, error) {
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+
+	if m, ok := registry.Load(modelType); ok {
+		return m.(*Model), nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	m := build(modelType, stmt.Schema)
+	actual, _ := registry.LoadOrStore(modelType, m)
+	return actual.(*Model), nil
+}
+
+// build 根据 GORM schema 构建元数据
+func build(modelType reflect.Type, s *schema.Schema) *Model {
+	m := &Model{
+		Type:     modelType,
+		Name:     modelType.Name(),
+		Table:    s.Table,
+		Schema:   s,
+		byJSON:   make(map[string]*Field),
+		byColumn: make(map[string]*Field),
+	}
+
+	for _, sf := range s.Fields {
+		// 跳过没有对应数据库列的字段（例如关联字段）
+		if sf.DBName == "" {
+			continue
+		}
+
+		jsonName := jsonFieldName(sf.StructField)
+		binding := sf.StructField.Tag.Get("binding")
+		access := sf.StructField.Tag.Get("access")
+		f := &Field{
+			Name:       sf.Name,
+			JSONName:   jsonName,
+			Column:     sf.DBName,
+			Type:       sf.IndirectFieldType,
+			TypeName:   typeName(sf.IndirectFieldType),
+			PrimaryKey: sf.PrimaryKey,
+			Binding:    binding,
+			Required:   hasRule(binding, "required"),
+			Filterable: jsonName != "-" && access != "writeonly" && sf.StructField.Tag.Get("filter") != "-",
+			Orderable:  jsonName != "-" && access != "writeonly" && sf.StructField.Tag.Get("order") != "-",
+			ReadOnly:   access == "readonly",
+			WriteOnly:  access == "writeonly",
+			File:       sf.StructField.Tag.Get("file"),
+		}
+
+		m.Fields = append(m.Fields, f)
+		m.byColumn[f.Column] = f
+		if jsonName != "-" {
+			m.byJSON[jsonName] = f
+		}
+	}
+
+	return m
+}
+
+// Lookup 根据 JSON 字段名或数据库列名查找字段
+func (m *Model) Lookup(name string) (*Field, bool) {
+	if f, ok := m.byJSON[name]; ok {
+		return f, true
+	}
+	f, ok := m.byColumn[name]
+	return f, ok
+}
+
+// jsonFieldName 获取字段在 JSON 中的名称，与 encoding/json 的规则一致
+func jsonFieldName(sf reflect.StructField) string {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "-"
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return sf.Name
+}
+
+// hasRule 判断 binding 规则中是否包含某条规则
+func hasRule(binding, rule string) bool {
+	for _, r := range strings.Split(binding, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName 将 Go 类型归类为简单的类型名称
+func typeName(t reflect.Type) string {
+	if t.String() == "time.Time" || t.String() == "gorm.DeletedAt" {
+		return "time"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	}
+	return "object"
+}
@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets 默认的直方图分桶（秒），与 Prometheus 客户端一致
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector 可以按 Prometheus 文本格式输出的指标
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表，按 Prometheus 文本格式（0.0.4）输出全部指标
+// 只实现本项目用到的 Counter 和 Histogram，不依赖 Prometheus 客户端库
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry 创建注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default 默认的注册表
+var Default = NewRegistry()
+
+// register 添加指标
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo 按 Prometheus 文本格式输出全部指标
+func (r *Registry) WriteTo(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// vec 按标签值分组的指标
+type vec struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// key 标签值组合的 key
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("指标 %s 需要 %d 个标签值，实际为 %d", v.name, len(v.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// header 输出 HELP 和 TYPE 行
+func (v *vec) header(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, typ)
+}
+
+// labelPairs 输出 {a="x",b="y"}，extra 为附加的标签（如 le）
+func (v *vec) labelPairs(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+	for i, name := range v.labels {
+		pairs = append(pairs, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号和换行
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// formatFloat 按 Prometheus 的格式输出浮点数
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// CounterVec 按标签分组的计数器
+type CounterVec struct {
+	vec
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+// counterValue 一组标签值的计数
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec 在注册表中创建计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: vec{name: name, help: help, labels: labels}, values: make(map[string]*counterValue)}
+	r.register(c)
+	return c
+}
+
+// Inc 计数加一，values 与创建时的标签一一对应
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add 计数增加 delta
+func (c *CounterVec) Add(delta float64, values ...string) {
+	key := c.key(values)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labels: append([]string(nil), values...)}
+		c.values[key] = v
+	}
+	v.value += delta
+}
+
+// write 实现 collector
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(w, "counter")
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(v.labels), formatFloat(v.value))
+	}
+}
+
+// HistogramVec 按标签分组的直方图
+type HistogramVec struct {
+	vec
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+// histogramValue 一组标签值的直方图数据
+type histogramValue struct {
+	labels []string
+	counts []uint64 // 每个分桶的计数（非累计）
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec 在注册表中创建直方图，buckets 为 nil 时使用 DefBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{vec: vec{name: name, help: help, labels: labels}, buckets: buckets, values: make(map[string]*histogramValue)}
+	r.register(h)
+	return h
+}
+
+// Observe 记录一个观测值，values 与创建时的标签一一对应
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	key := h.key(values)
+	i := sort.SearchFloat64s(h.buckets, value)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{labels: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+	if i < len(h.buckets) {
+		v.counts[i]++
+	}
+	v.count++
+	v.sum += value
+}
+
+// write 实现 collector
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(w, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += v.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(v.labels, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(v.labels, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(v.labels), formatFloat(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(v.labels), v.count)
+	}
+}
+
+// sortedKeys 按 key 排序，保证输出顺序稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
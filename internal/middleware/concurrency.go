@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"go-viewset/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimit 并发限制中间件
+// 同时处理中的请求数超过 max 时，新请求最多排队等待 wait，
+// 仍拿不到名额则直接返回 503 并通过 Retry-After 告诉客户端稍后重试，
+// 避免大量并发请求把数据库连接耗尽。
+// 可以挂在引擎上做全局限制，也可以挂在路由组上单独限制。
+func ConcurrencyLimit(max int, wait, retryAfter time.Duration) gin.HandlerFunc {
+	sem := make(chan struct{}, max)
+	retryAfterSeconds := strconv.Itoa(int(retryAfter.Seconds()))
+
+	return func(c *gin.Context) {
+		if !acquire(sem, wait) {
+			c.Header("Retry-After", retryAfterSeconds)
+			utils.ServiceUnavailable(c, "服务繁忙，请稍后重试")
+			c.Abort()
+			return
+		}
+		defer func() { <-sem }()
+
+		c.Next()
+	}
+}
+
+// acquire 获取一个并发名额，最多等待 wait
+func acquire(sem chan struct{}, wait time.Duration) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	default:
+	}
+
+	if wait <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
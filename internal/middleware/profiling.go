@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"go-viewset/internal/utils"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProfileHeader 开启请求级性能分析的请求头
+const ProfileHeader = "X-Profile"
+
+// Profiling 请求级性能分析中间件
+// 请求头 X-Profile 与配置的 token 一致时，记录参数绑定、数据库、序列化各阶段的耗时，
+// 通过 Server-Timing 响应头返回并输出日志。token 为空时不启用。
+func Profiling(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" || c.GetHeader(ProfileHeader) != token {
+			c.Next()
+			return
+		}
+
+		p := utils.NewProfile()
+		c.Request = c.Request.WithContext(utils.WithProfile(c.Request.Context(), p))
+
+		c.Next()
+
+		log.Printf("[性能分析] %s %s request_id=%s %s",
+			c.Request.Method, c.FullPath(), c.GetString("request_id"), p.ServerTiming())
+	}
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"go-viewset/internal/config"
+	"go-viewset/internal/models"
+	"go-viewset/internal/throttle"
+	"go-viewset/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 默认配额设置
+const (
+	defaultQuotaHeader = "X-API-Key"
+	defaultQuotaLimit  = 10000
+)
+
+// errUnknownAPIKey 请求中的 API Key 不是通过 /admin/quotas/issue 签发的
+var errUnknownAPIKey = errors.New("unknown api key")
+
+// QuotaMiddleware API 配额中间件
+// 按 API Key 统计当前周期（每日/每月）内的调用次数并持久化到数据库，
+// 超出配额时返回 429，并附带配额重置时间。
+// API Key 需要由管理员签发（见 viewset.QuotaViewSet.Issue），未签发的返回 401；
+// 没有携带 API Key 的请求按用户统计，未登录时按客户端 IP 统计，这两类只在限流存储中计数（见 allowBucket）。
+func QuotaMiddleware(db *gorm.DB, cfg config.QuotaConfig) gin.HandlerFunc {
+	header := cfg.Header
+	if header == "" {
+		header = defaultQuotaHeader
+	}
+
+	return func(c *gin.Context) {
+		key := quotaKey(c, header)
+		if !strings.HasPrefix(key, models.APIKeyQuotaPrefix) {
+			if allowBucket(c, key, cfg) {
+				c.Next()
+			}
+			return
+		}
+
+		quota, err := loadQuota(db, key)
+		if errors.Is(err, errUnknownAPIKey) {
+			utils.Unauthorized(c, "无效的 API Key")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Printf("读取配额失败: key=%s: %v", key, err)
+			utils.InternalServerError(c, "读取配额失败")
+			c.Abort()
+			return
+		}
+
+		// 原子地增加已用次数，条件更新保证多实例部署下也不会超额
+		result := db.Model(&models.APIQuota{}).
+			Where("id = ? AND used < quota_limit", quota.ID).
+			UpdateColumn("used", gorm.Expr("used + ?", 1))
+		if result.Error != nil {
+			log.Printf("更新配额失败: key=%s: %v", key, result.Error)
+			utils.InternalServerError(c, "更新配额失败")
+			c.Abort()
+			return
+		}
+
+		remaining := quota.Limit - quota.Used - 1
+		if result.RowsAffected == 0 || remaining < 0 {
+			remaining = 0
+		}
+		c.Header("X-Quota-Limit", strconv.FormatInt(quota.Limit, 10))
+		c.Header("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+		c.Header("X-Quota-Reset", strconv.FormatInt(quota.ResetAt.Unix(), 10))
+
+		if result.RowsAffected == 0 {
+			quotaExceeded(c, quota)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allowBucket 用户和客户端 IP 的配额在限流存储（throttle.Default，多实例部署时为 Redis）中计数，不写入数据库，
+// 避免大量不同的 IP 使 api_quotas 表无限增长；每个周期一个计数 key，过期后由存储清理。
+// 超出配额时写出 429 并返回 false
+func allowBucket(c *gin.Context, key string, cfg config.QuotaConfig) bool {
+	quota := models.APIQuota{Key: key, Period: cfg.Period, Limit: cfg.Limit}
+	if quota.Period != models.QuotaPeriodMonthly {
+		quota.Period = models.QuotaPeriodDaily
+	}
+	if quota.Limit <= 0 {
+		quota.Limit = defaultQuotaLimit
+	}
+	quota.ResetAt = quota.NextResetAt(time.Now())
+
+	// 窗口长度不短于一个周期，计数 key 带有周期的重置时间，周期结束后换用新的 key
+	period := 24 * time.Hour
+	if quota.Period == models.QuotaPeriodMonthly {
+		period = 31 * 24 * time.Hour
+	}
+	bucket := fmt.Sprintf("quota:%s:%d", key, quota.ResetAt.Unix())
+	allowed, _ := throttle.Default.Allow(bucket, throttle.Rate{Limit: int(quota.Limit), Period: period})
+
+	c.Header("X-Quota-Limit", strconv.FormatInt(quota.Limit, 10))
+	c.Header("X-Quota-Reset", strconv.FormatInt(quota.ResetAt.Unix(), 10))
+	if !allowed {
+		quotaExceeded(c, &quota)
+	}
+	return allowed
+}
+
+// quotaExceeded 写出配额用尽的 429 响应
+func quotaExceeded(c *gin.Context, quota *models.APIQuota) {
+	utils.ErrorWithData(c, http.StatusTooManyRequests, http.StatusTooManyRequests, "API 配额已用尽", gin.H{
+		"limit":    quota.Limit,
+		"period":   quota.Period,
+		"reset_at": quota.ResetAt,
+	})
+	c.Abort()
+}
+
+// quotaKey 获取配额统计的标识
+// 优先使用请求头中的 API Key（保存的是哈希，见 models.APIKeyQuotaKey），其次使用认证中间件写入的用户 ID，
+// 都没有时使用客户端 IP
+func quotaKey(c *gin.Context, header string) string {
+	if key := c.GetHeader(header); key != "" {
+		return models.APIKeyQuotaKey(key)
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// loadQuota 读取 API Key 的配额记录，不存在时返回 errUnknownAPIKey；周期已过则先重置
+func loadQuota(db *gorm.DB, key string) (*models.APIQuota, error) {
+	now := time.Now()
+
+	quota := &models.APIQuota{}
+	err := db.Where(models.APIQuota{Key: key}).First(quota).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errUnknownAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if now.Before(quota.ResetAt) {
+		return quota, nil
+	}
+
+	// 条件更新，避免多个实例重复重置
+	if err := db.Model(&models.APIQuota{}).
+		Where("id = ? AND reset_at <= ?", quota.ID, now).
+		Updates(map[string]interface{}{
+			"used":     0,
+			"reset_at": quota.NextResetAt(now),
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.First(quota, quota.ID).Error; err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// adminRole 拥有该角色的用户是管理员（is_admin 为 true，见 viewset.IsAdmin），第一个管理员通过 go-viewset createadmin 创建
+const adminRole = "admin"
+
+// LoadRoles 加载当前用户的角色和权限
+// 需要放在认证中间件之后：根据 user_id 查询用户的角色名写入 roles，
+// 角色拥有的权限合并到 permissions（见 viewset.RequireRole、viewset.HasPerm），
+// 拥有 admin 角色时写入 is_admin。未登录的请求不做处理
+func LoadRoles(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		tx := db.WithContext(c.Request.Context())
+
+		var roles []string
+		err := tx.Table("roles").
+			Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+			Where("user_roles.user_id = ?", userID).
+			Pluck("roles.name", &roles).Error
+		if err != nil {
+			log.Printf("加载用户 %v 的角色失败: %v", userID, err)
+			c.Next()
+			return
+		}
+
+		var perms []string
+		if len(roles) > 0 {
+			err = tx.Table("permissions").Distinct("permissions.codename").
+				Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+				Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+				Where("user_roles.user_id = ?", userID).
+				Pluck("permissions.codename", &perms).Error
+			if err != nil {
+				log.Printf("加载用户 %v 的权限失败: %v", userID, err)
+			}
+		}
+
+		roles = append(c.GetStringSlice("roles"), roles...)
+		c.Set("roles", roles)
+		c.Set("permissions", append(c.GetStringSlice("permissions"), perms...))
+		for _, role := range roles {
+			if role == adminRole {
+				c.Set("is_admin", true)
+				break
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"go-viewset/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求 ID 请求头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 沿用客户端传入的请求 ID 的最大长度
+const maxRequestIDLength = 64
+
+// RequestID 请求 ID 中间件
+// 沿用客户端传入的 X-Request-ID（不超过 64 个字符，只包含字母、数字和 -_.:），
+// 没有或不符合要求时生成一个，并写入响应头和请求 context
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(utils.WithRequestInfo(c.Request.Context(), utils.RequestInfo{
+			ID:     id,
+			Method: c.Request.Method,
+			Route:  c.FullPath(),
+		}))
+
+		c.Next()
+	}
+}
+
+// newRequestID 生成 16 字节的随机请求 ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID 客户端传入的请求 ID 是否可以沿用，避免在响应头和日志中写入任意内容
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch ch := id[i]; {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '_', ch == '.', ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
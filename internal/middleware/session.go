@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"errors"
+	"go-viewset/internal/session"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Session 会话认证：请求带有有效的会话 Cookie 时写入 user_id（见 viewset.ContextUserID）
+// 需要注册在 LoadRoles 之前；没有会话或会话已过期的请求作为未登录处理，由权限类决定是否允许。
+// 已经由其他认证中间件写入 user_id 的请求不做处理
+func Session(m *session.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("user_id"); ok {
+			c.Next()
+			return
+		}
+		s, err := m.Load(c)
+		if err != nil {
+			if !errors.Is(err, session.ErrNotFound) {
+				log.Printf("读取会话失败: %v", err)
+			}
+			c.Next()
+			return
+		}
+		c.Set("user_id", s.UserID)
+		c.Next()
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"errors"
+	"go-viewset/internal/config"
+	"go-viewset/internal/database"
+	"go-viewset/internal/models"
+	"go-viewset/internal/utils"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultTenantHeader 默认读取租户的请求头
+const defaultTenantHeader = "X-Tenant-ID"
+
+// Tenant 租户中间件
+// 优先按请求头（默认 X-Tenant-ID，值为租户 ID 或 Slug）确定租户，其次按 BaseDomain 下的子域名
+// （acme.example.com -> acme）；确定后将租户 ID 写入 tenant_id、租户写入 tenant（见 viewset.ContextTenantID）。
+// 无法确定租户时返回 400，租户不存在返回 404，已停用返回 403
+func Tenant(db *gorm.DB, cfg config.TenantConfig) gin.HandlerFunc {
+	header := cfg.Header
+	if header == "" {
+		header = defaultTenantHeader
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(header)
+		if key == "" {
+			key = subdomain(c.Request.Host, cfg.BaseDomain)
+		}
+		if key == "" {
+			utils.BadRequest(c, "缺少租户，请通过子域名或 "+header+" 请求头指定")
+			c.Abort()
+			return
+		}
+
+		tenant, err := loadTenant(db.WithContext(c.Request.Context()), key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.NotFound(c, "租户不存在")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Printf("读取租户失败: key=%s: %v", key, err)
+			utils.InternalServerError(c, "读取租户失败")
+			c.Abort()
+			return
+		}
+		if !tenant.Active {
+			utils.Forbidden(c, "租户已停用")
+			c.Abort()
+			return
+		}
+
+		c.Set("tenant_id", tenant.ID)
+		c.Set("tenant", tenant)
+		c.Next()
+	}
+}
+
+// loadTenant 按 ID 或 Slug 读取租户
+func loadTenant(db *gorm.DB, key string) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	if id, err := strconv.ParseUint(key, 10, 64); err == nil {
+		return tenant, db.First(tenant, id).Error
+	}
+	return tenant, db.Where("slug = ?", strings.ToLower(key)).First(tenant).Error
+}
+
+// subdomain 取出 host 在 baseDomain 下的一级子域名，例如 acme.example.com -> acme；
+// 不在 baseDomain 下或为多级子域名时返回空
+func subdomain(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	name, ok := strings.CutSuffix(host, "."+strings.ToLower(strings.TrimPrefix(baseDomain, ".")))
+	if !ok || name == "" || strings.Contains(name, ".") {
+		return ""
+	}
+	return name
+}
+
+// TenantDatabase 每个租户使用独立数据库时，将当前租户的数据库写入 tenant_db（见 viewset.ContextTenantDB），
+// ViewSet 的查询和事务使用该数据库；需要注册在 Tenant 之后
+func TenantDatabase(dbs *database.TenantDBs) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, ok := c.Get("tenant")
+		if !ok {
+			c.Next()
+			return
+		}
+		slug := tenant.(*models.Tenant).Slug
+		db, err := dbs.Get(c.Request.Context(), slug)
+		if err != nil {
+			log.Printf("打开租户数据库失败: tenant=%s: %v", slug, err)
+			utils.InternalServerError(c, "连接租户数据库失败")
+			c.Abort()
+			return
+		}
+		c.Set("tenant_db", db)
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// 配额周期
+const (
+	QuotaPeriodDaily   = "daily"
+	QuotaPeriodMonthly = "monthly"
+)
+
+// APIKeyQuotaPrefix API Key 配额记录的 Key 前缀
+const APIKeyQuotaPrefix = "key:"
+
+// APIQuota API 配额模型
+// 每个签发的 API Key 一条记录，记录当前周期内的已用次数和下次重置时间（用户和客户端 IP 的配额不写入数据库，见 middleware.QuotaMiddleware）
+type APIQuota struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Key       string    `gorm:"size:100;uniqueIndex;not null" json:"key" binding:"required"`
+	Period    string    `gorm:"size:20;default:daily" json:"period"`
+	Limit     int64     `gorm:"column:quota_limit;not null" json:"limit"`
+	Used      int64     `gorm:"default:0" json:"used"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// TableName 指定表名
+func (APIQuota) TableName() string {
+	return "api_quotas"
+}
+
+// NextResetAt 计算从 now 开始的下一个重置时间
+// daily 在次日零点重置，monthly 在下月 1 日零点重置
+func (q *APIQuota) NextResetAt(now time.Time) time.Time {
+	year, month, day := now.Date()
+	if q.Period == QuotaPeriodMonthly {
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+}
+
+// APIKeyQuotaKey API Key 对应的配额记录 Key，只保存 API Key 的 SHA-256，泄露后也无法还原
+func APIKeyQuotaKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return APIKeyQuotaPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment 附件（上传的文件）
+// 文件保存在存储后端（本地磁盘或 S3，见 internal/storage），这里只记录元数据；
+// 模型通过带 file 标签的附件 ID 字段引用附件，例如 AvatarID *uint `json:"avatar_id" file:"avatar"`
+type Attachment struct {
+	ID          uint      `gorm:"primarykey" json:"id" access:"readonly"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at" access:"readonly"`
+	Name        string    `gorm:"size:255" json:"name" access:"readonly"`         // 上传时的文件名
+	ContentType string    `gorm:"size:100" json:"content_type" access:"readonly"` // 根据文件内容识别的类型
+	Size        int64     `json:"size" access:"readonly"`
+	Checksum    string    `gorm:"size:64" json:"checksum" access:"readonly"` // SHA-256 的十六进制
+	Storage     string    `gorm:"size:20" json:"storage" access:"readonly"`  // 存储后端：local / s3
+	Key         string    `gorm:"size:255" json:"-"`                         // 存储后端中的路径
+	Thumbnails  string    `gorm:"size:100" json:"-"`                         // 已生成的缩略图尺寸，逗号分隔，例如 64,256
+	UploadedBy  string    `gorm:"size:64;index" json:"uploaded_by" access:"readonly"`
+	TenantID    uint      `gorm:"index" json:"tenant_id" access:"readonly"` // 开启多租户时按租户隔离
+}
+
+// TableName 指定表名
+func (Attachment) TableName() string {
+	return "attachments"
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog 审计日志模型
+// 每次通过 ViewSet 写入数据（创建、更新、删除以及自定义的写操作）记录一条，
+// Changes 为变更内容：创建时为新值，删除时为旧值，更新时为 {"字段": {"old": 旧值, "new": 新值}}
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Resource  string    `gorm:"size:100;index:idx_audit_logs_object" json:"resource"`
+	ObjectID  string    `gorm:"size:64;index:idx_audit_logs_object" json:"object_id"`
+	Action    string    `gorm:"size:20" json:"action"` // created / updated / deleted
+	Actor     string    `gorm:"size:100;index" json:"actor"`
+	IP        string    `gorm:"size:45" json:"ip"`
+	RequestID string    `gorm:"size:64" json:"request_id"`
+	Changes   JSON      `gorm:"type:text" json:"changes"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// HistoryRecord 模型的一个历史版本
+// 每个开启了历史记录的模型一张表（<表名>_history，见 viewset.GenericViewSet.EnableHistory），
+// Data 为写入后（删除时为删除前）记录的完整快照，列名 -> 值
+type HistoryRecord struct {
+	ID        uint      `gorm:"primarykey" json:"history_id"`
+	CreatedAt time.Time `json:"history_date"`
+	ObjectID  string    `gorm:"size:64;not null" json:"object_id"`
+	Version   int       `gorm:"not null" json:"version"`
+	Type      string    `gorm:"size:20" json:"history_type"` // created / updated / deleted
+	User      string    `gorm:"size:100" json:"history_user"`
+	Data      JSON      `gorm:"type:text" json:"-"`
+}
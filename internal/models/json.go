@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// JSON 以文本形式保存在数据库中的 JSON，响应中原样输出（不会被转义为字符串）
+type JSON []byte
+
+// Value 实现 driver.Valuer
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// Scan 实现 sql.Scanner
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return fmt.Errorf("无法将 %T 转换为 JSON", value)
+	}
+	return nil
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetToken 重置密码令牌
+// 只保存令牌的哈希（见 auth.HashToken），令牌在过期或使用后失效，用户修改密码后删除其全部令牌
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TableName 指定表名
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
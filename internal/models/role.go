@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Role 角色模型
+// 用户和角色、角色和权限都是多对多关系（user_roles、role_permissions 表）
+type Role struct {
+	ID          uint         `gorm:"primarykey" json:"id" access:"readonly"`
+	CreatedAt   time.Time    `json:"created_at" access:"readonly"`
+	UpdatedAt   time.Time    `json:"updated_at" access:"readonly"`
+	Name        string       `gorm:"size:50;uniqueIndex;not null" json:"name" binding:"required"`
+	Description string       `gorm:"size:255" json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions,omitempty" access:"readonly"`
+	Users       []User       `gorm:"many2many:user_roles" json:"-"`
+}
+
+// TableName 指定表名
+func (Role) TableName() string {
+	return "roles"
+}
+
+// Permission 权限模型
+// Codename 与 viewset.HasPerm 使用的权限名一致，例如 "users.manage"
+type Permission struct {
+	ID          uint      `gorm:"primarykey" json:"id" access:"readonly"`
+	CreatedAt   time.Time `json:"created_at" access:"readonly"`
+	Codename    string    `gorm:"size:100;uniqueIndex;not null" json:"codename" binding:"required"`
+	Description string    `gorm:"size:255" json:"description"`
+}
+
+// TableName 指定表名
+func (Permission) TableName() string {
+	return "permissions"
+}
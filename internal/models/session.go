@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Session 登录会话（session.store 为 db 时使用）
+// 只保存会话 ID 的 SHA-256 哈希，数据库泄露时无法用来冒充用户
+type Session struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
+
+// TableName 指定表名
+func (Session) TableName() string {
+	return "sessions"
+}
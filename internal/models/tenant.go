@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Tenant 租户模型
+// 请求通过子域名（Slug）或 X-Tenant-ID 请求头（ID 或 Slug）确定所属租户（见 middleware.Tenant），
+// 带 tenant_id 字段的模型按租户隔离
+type Tenant struct {
+	ID        uint      `gorm:"primarykey" json:"id" access:"readonly"`
+	CreatedAt time.Time `json:"created_at" access:"readonly"`
+	UpdatedAt time.Time `json:"updated_at" access:"readonly"`
+	Name      string    `gorm:"size:100;not null" json:"name" binding:"required"`
+	Slug      string    `gorm:"size:63;uniqueIndex;not null" json:"slug" binding:"required"` // 子域名，例如 acme 对应 acme.example.com
+	Active    bool      `gorm:"default:true" json:"active"`                                  // 停用的租户不能访问
+
+	// 每个租户独立数据库时（tenant.isolation 为 database）使用的数据库，也可以在配置文件的 tenant.databases 中指定
+	DatabaseType string `gorm:"size:20" json:"database_type"`                      // mysql（默认）/ postgres / sqlite
+	DSN          string `gorm:"size:1000" json:"dsn,omitempty" access:"writeonly"` // 连接字符串，不出现在响应中
+}
+
+// TableName 指定表名
+func (Tenant) TableName() string {
+	return "tenants"
+}
@@ -7,16 +7,26 @@ import (
 )
 
 // User 用户模型
+// access:"readonly" 的字段由服务端维护，客户端传入的值会被忽略；
+// Password 只用于接收请求中的密码，数据库中只保存 bcrypt 哈希 PasswordHash，两者都不会出现在响应中；
+// file:"名称" 的字段为附件 ID，开启附件后响应中增加该名称的字段（见 viewset.EnableFileFields）
 type User struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	ID        uint           `gorm:"primarykey" json:"id" access:"readonly"`
+	CreatedAt time.Time      `json:"created_at" access:"readonly"`
+	UpdatedAt time.Time      `json:"updated_at" access:"readonly"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" access:"readonly"`
 	Name      string         `gorm:"size:100;not null" json:"name" binding:"required"`
 	Email     string         `gorm:"size:100;uniqueIndex;not null" json:"email" binding:"required,email"`
 	Status    string         `gorm:"size:20;default:inactive" json:"status"`
 	Age       int            `gorm:"default:0" json:"age"`
 	Phone     string         `gorm:"size:20" json:"phone"`
+	AvatarID  *uint          `json:"avatar_id" file:"avatar"` // 头像附件，响应中的 avatar 为附件信息和下载地址
+
+	Password     string `gorm:"-" json:"password,omitempty"`
+	PasswordHash string `gorm:"size:100" json:"-"`
+
+	// EmailVerifiedAt 邮箱验证的时间，未验证时为 nil：通过邮件中的令牌重置过密码，或由第三方登录按已验证的邮箱创建
+	EmailVerifiedAt *time.Time `json:"email_verified_at" access:"readonly"`
 }
 
 // TableName 指定表名
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// UserIdentity 用户关联的第三方账号（见 internal/oidc）
+// 同一身份提供方的同一账号（Provider + Subject）只能关联一个用户
+type UserIdentity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject" json:"subject"`
+	Email     string    `gorm:"size:100" json:"email"` // 关联时第三方账号的邮箱
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// Webhook 投递状态
+const (
+	DeliveryPending = "pending" // 等待投递或等待重试
+	DeliverySuccess = "success" // 目标返回 2xx
+	DeliveryFailed  = "failed"  // 重试次数用尽，可以通过 replay 重新投递
+)
+
+// WebhookDelivery Webhook 投递记录
+// 每个事件发送到每个目标记录一条，Payload 为发送的请求体
+type WebhookDelivery struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Target       string     `gorm:"size:500;index" json:"target"` // 目标 URL
+	Event        string     `gorm:"size:100;index" json:"event"`  // 事件名称，例如 users.created
+	ObjectID     string     `gorm:"size:64" json:"object_id"`
+	Payload      JSON       `gorm:"type:text" json:"payload"`
+	Status       string     `gorm:"size:20;index" json:"status"` // pending / success / failed
+	Attempts     int        `json:"attempts"`
+	ResponseCode int        `json:"response_code"`
+	LastError    string     `gorm:"size:1000" json:"last_error"`
+	DeliveredAt  *time.Time `json:"delivered_at"`
+}
+
+// TableName 指定表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
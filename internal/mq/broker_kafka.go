@@ -0,0 +1,55 @@
+//go:build kafka
+
+package mq
+
+import (
+	"context"
+	"go-viewset/internal/config"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func init() {
+	RegisterBroker("kafka", openKafka)
+}
+
+// kafkaBroker 发布到 Kafka，消息 key 为对象 ID
+type kafkaBroker struct {
+	writer *kafka.Writer
+}
+
+// openKafka URL 为逗号分隔的 broker 地址，例如 10.0.0.1:9092,10.0.0.2:9092
+func openKafka(cfg config.MQConfig) (Broker, error) {
+	var addrs []string
+	for _, addr := range strings.Split(cfg.URL, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return &kafkaBroker{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(addrs...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}}, nil
+}
+
+// Publish 实现 Broker
+func (b *kafkaBroker) Publish(ctx context.Context, msg Message) error {
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return b.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   msg.Topic,
+		Key:     []byte(msg.Key),
+		Value:   msg.Body,
+		Headers: headers,
+	})
+}
+
+// Close 实现 Broker
+func (b *kafkaBroker) Close() error {
+	return b.writer.Close()
+}
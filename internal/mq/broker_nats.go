@@ -0,0 +1,46 @@
+//go:build nats
+
+package mq
+
+import (
+	"context"
+	"go-viewset/internal/config"
+
+	"github.com/nats-io/nats.go"
+)
+
+func init() {
+	RegisterBroker("nats", openNATS)
+}
+
+// natsBroker 发布到 NATS，subject 为 主题名.动作
+type natsBroker struct {
+	conn *nats.Conn
+}
+
+// openNATS URL 例如 nats://127.0.0.1:4222，多个地址用逗号分隔
+func openNATS(cfg config.MQConfig) (Broker, error) {
+	conn, err := nats.Connect(cfg.URL, nats.Name("go-viewset"))
+	if err != nil {
+		return nil, err
+	}
+	return &natsBroker{conn: conn}, nil
+}
+
+// Publish 实现 Broker，等待服务端确认收到后返回
+func (b *natsBroker) Publish(ctx context.Context, msg Message) error {
+	m := nats.NewMsg(msg.Subject())
+	m.Data = msg.Body
+	for k, v := range msg.Headers {
+		m.Header.Set(k, v)
+	}
+	if err := b.conn.PublishMsg(m); err != nil {
+		return err
+	}
+	return b.conn.FlushWithContext(ctx)
+}
+
+// Close 实现 Broker
+func (b *natsBroker) Close() error {
+	return b.conn.Drain()
+}
@@ -0,0 +1,227 @@
+// Package mq 将模型变更事件发布到消息队列（Kafka、NATS、RabbitMQ）
+//
+// 主题名按表名生成：TopicPrefix + 表名，例如 go-viewset.users（可以通过 Topics 按表名指定）。
+// Kafka 发布到该主题，消息 key 为对象 ID（同一对象的事件进入同一分区，保持顺序）；
+// NATS 的 subject 和 RabbitMQ 的 routing key 为 主题名.动作，例如 go-viewset.users.created。
+//
+// 发布是异步的：事件先进入内存队列，由后台 goroutine 按顺序发布，失败时重试 MaxAttempts 次后丢弃。
+// 需要可靠投递时使用 Webhook（有投递记录和重新投递）
+package mq
+
+import (
+	"context"
+	"fmt"
+	"go-viewset/internal/config"
+	"go-viewset/internal/events"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认配置
+const (
+	defaultTopicPrefix = "go-viewset."
+	defaultQueueSize   = 1000
+	defaultMaxAttempts = 3
+	publishTimeout     = 10 * time.Second
+)
+
+// Message 发布到消息队列的消息
+type Message struct {
+	Topic   string            // 主题名，例如 go-viewset.users
+	Action  string            // created / updated / deleted
+	Key     string            // 对象 ID
+	Headers map[string]string // event、resource、action、object_id
+	Body    []byte            // 事件的 JSON（见 events.Encode）
+}
+
+// Subject 主题名.动作，NATS 的 subject 和 RabbitMQ 的 routing key
+func (m Message) Subject() string {
+	return m.Topic + "." + m.Action
+}
+
+// Broker 消息队列的连接
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// Opener 按配置连接消息队列
+type Opener func(cfg config.MQConfig) (Broker, error)
+
+// brokers 已注册的消息队列，key 为 MQConfig.Broker
+var brokers = map[string]Opener{}
+
+// RegisterBroker 注册消息队列
+// kafka、nats、rabbitmq 分别在 -tags kafka、-tags nats、-tags rabbitmq 构建时注册（见 broker_*.go），
+// 默认构建不包含任何消息队列的客户端
+func RegisterBroker(name string, open Opener) {
+	brokers[name] = open
+}
+
+// Open 按配置连接消息队列
+func Open(cfg config.MQConfig) (Broker, error) {
+	open, ok := brokers[cfg.Broker]
+	if !ok {
+		names := make([]string, 0, len(brokers))
+		for name := range brokers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("不支持的消息队列 %q（当前构建支持：%s），请使用 -tags %s 构建", cfg.Broker, strings.Join(names, ", "), cfg.Broker)
+	}
+	return open(cfg)
+}
+
+// Publisher 订阅事件总线，将事件发布到消息队列
+type Publisher struct {
+	broker      Broker
+	prefix      string
+	topics      map[string]string
+	maxAttempts int
+
+	queue chan events.Event
+	stop  chan struct{}
+	once  sync.Once
+	done  chan struct{}
+}
+
+// NewPublisher 创建 Publisher，调用 Start 后开始发布
+func NewPublisher(broker Broker, cfg config.MQConfig) *Publisher {
+	p := &Publisher{
+		broker:      broker,
+		prefix:      cfg.TopicPrefix,
+		topics:      cfg.Topics,
+		maxAttempts: cfg.MaxAttempts,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if p.prefix == "" {
+		p.prefix = defaultTopicPrefix
+	}
+	if p.maxAttempts <= 0 {
+		p.maxAttempts = defaultMaxAttempts
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	p.queue = make(chan events.Event, queueSize)
+	return p
+}
+
+// Subscribe 订阅事件总线上的模型变更事件
+func (p *Publisher) Subscribe(bus *events.Bus) {
+	bus.Subscribe(p.Handle)
+}
+
+// Handle 将事件放入队列，队列已满或已停止时丢弃
+func (p *Publisher) Handle(e events.Event) {
+	select {
+	case <-p.stop:
+		return
+	default:
+	}
+	select {
+	case p.queue <- e:
+	default:
+		log.Printf("消息队列发布队列已满，丢弃事件 %s（object_id=%s）", e.Name(), e.ObjectID)
+	}
+}
+
+// Start 启动发布的 goroutine
+func (p *Publisher) Start() {
+	go p.run()
+}
+
+// Close 发布完队列中剩余的事件后关闭连接，ctx 结束时不再等待
+func (p *Publisher) Close(ctx context.Context) error {
+	p.once.Do(func() { close(p.stop) })
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.broker.Close()
+}
+
+// run 按顺序发布队列中的事件，停止后发布完剩余的事件再退出
+func (p *Publisher) run() {
+	defer close(p.done)
+	for {
+		select {
+		case e := <-p.queue:
+			p.publish(e)
+		case <-p.stop:
+			for {
+				select {
+				case e := <-p.queue:
+					p.publish(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// publish 发布一个事件，失败时按 1s、2s、4s... 重试
+func (p *Publisher) publish(e events.Event) {
+	msg, err := p.message(e)
+	if err != nil {
+		log.Printf("序列化事件 %s 失败: %v", e.Name(), err)
+		return
+	}
+
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		err = p.broker.Publish(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= p.maxAttempts {
+			log.Printf("发布事件 %s 到 %s 失败，已尝试 %d 次: %v", e.Name(), msg.Topic, attempt, err)
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-p.stop:
+			// 停止时不再等待重试
+			log.Printf("发布事件 %s 到 %s 失败: %v", e.Name(), msg.Topic, err)
+			return
+		}
+		delay *= 2
+	}
+}
+
+// message 由事件构建消息
+func (p *Publisher) message(e events.Event) (Message, error) {
+	body, err := events.Encode(e)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Topic:  p.Topic(e.Resource),
+		Action: string(e.Action),
+		Key:    e.ObjectID,
+		Headers: map[string]string{
+			"event":     e.Name(),
+			"resource":  e.Resource,
+			"action":    string(e.Action),
+			"object_id": e.ObjectID,
+		},
+		Body: body,
+	}, nil
+}
+
+// Topic 表对应的主题名
+func (p *Publisher) Topic(resource string) string {
+	if topic, ok := p.topics[resource]; ok {
+		return topic
+	}
+	return p.prefix + resource
+}
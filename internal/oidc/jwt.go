@@ -0,0 +1,236 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 验证 id_token 的参数
+const (
+	clockSkew      = time.Minute // 允许的时钟误差
+	keysRefreshMin = time.Minute // 遇到未知的 kid 时重新获取 JWKS 的最短间隔
+)
+
+// idClaims id_token 中用到的声明
+type idClaims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	Expiry          int64    `json:"exp"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   flexBool `json:"email_verified"`
+	Name            string   `json:"name"`
+}
+
+// audience aud 可以是字符串或字符串数组
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// verify 验证 id_token 的签名和声明
+func (p *OIDC) verify(ctx context.Context, raw, nonce string) (*idClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("oidc: id_token 格式错误")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("oidc: id_token 签名格式错误")
+	}
+	key, err := p.keys.get(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims idClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != p.issuer:
+		return nil, fmt.Errorf("oidc: id_token 的 iss %q 不正确", claims.Issuer)
+	case !claims.Audience.contains(p.clientID):
+		return nil, errors.New("oidc: id_token 的 aud 不包含 client_id")
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.clientID:
+		return nil, errors.New("oidc: id_token 的 azp 不正确")
+	case time.Unix(claims.Expiry, 0).Add(clockSkew).Before(time.Now()):
+		return nil, errors.New("oidc: id_token 已过期")
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return nil, errors.New("oidc: id_token 的 nonce 不正确")
+	case claims.Subject == "":
+		return nil, errors.New("oidc: id_token 缺少 sub")
+	}
+	return &claims, nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, item := range a {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature 验证 RS256 或 ES256 签名，其他算法（包括 none 和 HMAC）一律拒绝
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("oidc: RS256 的公钥类型不匹配")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("oidc: id_token 签名无效")
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return errors.New("oidc: ES256 的公钥或签名格式不匹配")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return errors.New("oidc: id_token 签名无效")
+		}
+		return nil
+	}
+	return fmt.Errorf("oidc: 不支持的签名算法 %q", alg)
+}
+
+// decodeSegment 解码 JWT 的 base64url 段并解析 JSON
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("oidc: id_token 格式错误")
+	}
+	return json.Unmarshal(data, out)
+}
+
+// keySet 身份提供方的公钥（JWKS），按 kid 缓存，遇到未知的 kid 时重新获取（轮换密钥）
+type keySet struct {
+	uri string
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+func newKeySet(uri string) *keySet {
+	return &keySet{uri: uri}
+}
+
+// get 返回 kid 对应的公钥
+func (s *keySet) get(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(s.fetched) < keysRefreshMin {
+		return nil, fmt.Errorf("oidc: 未知的公钥 kid %q", kid)
+	}
+	keys, err := fetchKeys(ctx, s.uri)
+	if err != nil {
+		return nil, err
+	}
+	s.keys, s.fetched = keys, time.Now()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc: 未知的公钥 kid %q", kid)
+}
+
+// jwk JWKS 中的一个公钥
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchKeys 获取 JWKS，跳过不支持的密钥类型和非签名用途的密钥
+func fetchKeys(ctx context.Context, uri string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, uri, "", &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey 解析 RSA 或 P-256 公钥
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("oidc: RSA 公钥格式错误")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("oidc: 不支持的曲线 %q", k.Crv)
+		}
+		x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("oidc: EC 公钥格式错误")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("oidc: EC 公钥不在曲线上")
+		}
+		return pub, nil
+	}
+	return nil, fmt.Errorf("oidc: 不支持的密钥类型 %q", k.Kty)
+}
@@ -0,0 +1,291 @@
+// Package oidc 第三方登录：OpenID Connect（Google、企业身份提供方等）和 GitHub OAuth2
+//
+// 登录使用授权码流程并带有 PKCE；OpenID Connect 的端点通过 <issuer>/.well-known/openid-configuration 获取，
+// id_token 用身份提供方公布的 JWKS 验证签名（RS256、ES256），并检查 iss、aud、exp 和 nonce
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-viewset/internal/config"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpTimeout 请求身份提供方的超时时间
+const httpTimeout = 10 * time.Second
+
+// httpClient 请求身份提供方使用的客户端
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// presetIssuers 按名称预置的 issuer，配置中可以省略
+var presetIssuers = map[string]string{
+	"google": "https://accounts.google.com",
+}
+
+// Identity 第三方账号的信息
+type Identity struct {
+	Subject       string // 第三方账号在身份提供方的唯一标识
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider 一个身份提供方
+// state 防止 CSRF，nonce 与 id_token 绑定，verifier 为 PKCE 的 code_verifier，三者都由调用方生成并在回调时传回
+type Provider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error)
+}
+
+// Open 按配置创建身份提供方，name 为配置中的名称，redirectURL 为回调地址
+// type 为空时名称为 github 的是 GitHub，其余为 OpenID Connect
+func Open(name string, cfg config.OIDCProviderConfig, redirectURL string) (Provider, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, fmt.Errorf("%s 需要配置 clientId 和 clientSecret", name)
+	}
+	kind := cfg.Type
+	if kind == "" {
+		kind = "oidc"
+		if name == "github" {
+			kind = "github"
+		}
+	}
+	switch kind {
+	case "oidc":
+		issuer := cfg.Issuer
+		if issuer == "" {
+			issuer = presetIssuers[name]
+		}
+		if issuer == "" {
+			return nil, fmt.Errorf("%s 需要配置 issuer", name)
+		}
+		return NewOIDC(issuer, cfg, redirectURL), nil
+	case "github":
+		return NewGitHub(cfg, redirectURL), nil
+	}
+	return nil, fmt.Errorf("%s 的类型 %q 不支持（支持 oidc、github）", name, cfg.Type)
+}
+
+// OIDC OpenID Connect 身份提供方，端点和公钥在第一次使用时获取
+type OIDC struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	redirectURL  string
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     *keySet
+}
+
+// metadata OpenID Connect 的发现文档中用到的字段
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewOIDC 创建 OpenID Connect 身份提供方
+func NewOIDC(issuer string, cfg config.OIDCProviderConfig, redirectURL string) *OIDC {
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDC{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		scopes:       scopes,
+		redirectURL:  redirectURL,
+	}
+}
+
+// AuthCodeURL 实现 Provider
+func (p *OIDC) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return authCodeURL(md.AuthorizationEndpoint, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.clientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {strings.Join(p.scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	})
+}
+
+// Exchange 实现 Provider：用授权码换取 id_token 并验证，id_token 中没有邮箱时从 userinfo 端点获取
+func (p *OIDC) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := exchangeCode(ctx, md.TokenEndpoint, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc: 响应中没有 id_token")
+	}
+	claims, err := p.verify(ctx, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}
+	if identity.Email == "" && md.UserinfoEndpoint != "" {
+		var info struct {
+			Subject       string   `json:"sub"`
+			Email         string   `json:"email"`
+			EmailVerified flexBool `json:"email_verified"`
+			Name          string   `json:"name"`
+		}
+		if err := getJSON(ctx, md.UserinfoEndpoint, token.AccessToken, &info); err != nil {
+			return nil, err
+		}
+		if info.Subject != claims.Subject {
+			return nil, errors.New("oidc: userinfo 的 sub 与 id_token 不一致")
+		}
+		identity.Email, identity.EmailVerified = info.Email, bool(info.EmailVerified)
+		if identity.Name == "" {
+			identity.Name = info.Name
+		}
+	}
+	return identity, nil
+}
+
+// discover 获取并缓存发现文档，失败时下次调用重新获取
+func (p *OIDC) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	var md metadata
+	if err := getJSON(ctx, p.issuer+"/.well-known/openid-configuration", "", &md); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(md.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("oidc: 发现文档的 issuer %q 与配置的 %q 不一致", md.Issuer, p.issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, errors.New("oidc: 发现文档缺少 authorization_endpoint、token_endpoint 或 jwks_uri")
+	}
+	p.metadata = &md
+	p.keys = newKeySet(md.JWKSURI)
+	return p.metadata, nil
+}
+
+// tokenResponse 令牌端点的响应
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeCode 请求令牌端点
+// GitHub 出错时也返回 200，错误在响应体的 error 字段中
+func exchangeCode(ctx context.Context, endpoint string, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("oidc: 解析令牌响应失败（HTTP %d）: %w", resp.StatusCode, err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("oidc: 换取令牌失败: %s %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("oidc: 换取令牌失败（HTTP %d）", resp.StatusCode)
+	}
+	return &token, nil
+}
+
+// getJSON GET 请求并解析 JSON 响应，accessToken 不为空时作为 Bearer 令牌
+func getJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: 请求 %s 失败（HTTP %d）", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// authCodeURL 在授权端点的地址上添加参数（端点本身可能带有参数）
+func authCodeURL(endpoint string, params url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// codeChallenge PKCE 的 S256 code_challenge
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// flexBool 兼容布尔值和字符串形式（"true"）的 email_verified
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"go-viewset/internal/meta"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version 生成的文档遵循的 OpenAPI 版本
+const Version = "3.0.3"
+
+// Generator 根据已注册的 ViewSet 生成 OpenAPI 文档
+// 资源的路由从 gin 的路由表中读取，字段从模型元数据（见 meta.Of）中读取
+type Generator struct {
+	Title   string
+	Version string
+
+	mu        sync.Mutex
+	resources []resource
+}
+
+// resource 一个 ViewSet 对应的资源
+type resource struct {
+	prefix string
+	model  *meta.Model
+	params []string // 嵌套路由中父资源的参数名
+}
+
+// New 创建文档生成器
+func New(title, version string) *Generator {
+	return &Generator{Title: title, Version: version}
+}
+
+// Add 添加一个资源，prefix 为 ViewSet 注册的路由前缀，例如 "/api/users"
+// 嵌套路由的 prefix 形如 "/api/users/:id/orders"，parentParams 为父资源参数在文档中的名称（例如 "user_id"），
+// 依次替换 prefix 中的路径参数
+func (g *Generator) Add(prefix string, model *meta.Model, parentParams ...string) {
+	if model == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.resources = append(g.resources, resource{prefix: strings.TrimSuffix(prefix, "/"), model: model, params: parentParams})
+}
+
+// Build 根据路由表生成 OpenAPI 文档
+// 只包含属于已添加资源的路由，OPTIONS 和 HEAD 不出现在文档中
+func (g *Generator) Build(routes gin.RoutesInfo) map[string]interface{} {
+	g.mu.Lock()
+	resources := append([]resource(nil), g.resources...)
+	g.mu.Unlock()
+
+	// 前缀较长的资源优先匹配，避免嵌套前缀被外层资源吞掉
+	sort.SliceStable(resources, func(i, j int) bool {
+		return len(resources[i].prefix) > len(resources[j].prefix)
+	})
+
+	paths := map[string]map[string]interface{}{}
+	schemas := map[string]interface{}{}
+	var tags []map[string]interface{}
+	for _, res := range resources {
+		schemas[res.model.Name] = modelSchema(res.model)
+		tags = append(tags, map[string]interface{}{"name": res.model.Name})
+	}
+
+	for _, route := range routes {
+		if route.Method == http.MethodOptions || route.Method == http.MethodHead {
+			continue
+		}
+		for _, res := range resources {
+			if route.Path != res.prefix && !strings.HasPrefix(route.Path, res.prefix+"/") {
+				continue
+			}
+			path := toOpenAPIPath(route.Path, res.params)
+			if paths[path] == nil {
+				paths[path] = map[string]interface{}{}
+			}
+			paths[path][strings.ToLower(route.Method)] = operation(res, route)
+			break
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i]["name"].(string) < tags[j]["name"].(string) })
+
+	return map[string]interface{}{
+		"openapi": Version,
+		"info": map[string]interface{}{
+			"title":   g.Title,
+			"version": g.Version,
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
+
+// Register 注册文档路由：
+//   - GET <base>/openapi.json 返回 OpenAPI 文档
+//   - GET <base>/docs 返回 Swagger UI 页面
+//
+// 文档在第一次请求时根据 r 的路由表生成，因此应在所有 ViewSet 注册完成后提供服务
+func (g *Generator) Register(r *gin.Engine, base string) {
+	base = strings.TrimSuffix(base, "/")
+	specPath := base + "/openapi.json"
+
+	var (
+		once sync.Once
+		spec map[string]interface{}
+	)
+	r.GET(specPath, func(c *gin.Context) {
+		once.Do(func() { spec = g.Build(r.Routes()) })
+		c.JSON(http.StatusOK, spec)
+	})
+	r.GET(base+"/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI(g.Title, specPath)))
+	})
+}
+
+// toOpenAPIPath 将 gin 的路径参数 :id 转换为 OpenAPI 的 {id}
+// 前 len(rename) 个参数依次改名为 rename 中的名称
+func toOpenAPIPath(path string, rename []string) string {
+	parts := strings.Split(path, "/")
+	names := pathParams(path, rename)
+	n := 0
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + names[n] + "}"
+			n++
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// pathParams 路径中的参数名，前 len(rename) 个参数依次改名为 rename 中的名称
+func pathParams(path string, rename []string) []string {
+	var names []string
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			if len(names) < len(rename) {
+				names = append(names, rename[len(names)])
+			} else {
+				names = append(names, part[1:])
+			}
+		}
+	}
+	return names
+}
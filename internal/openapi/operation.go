@@ -0,0 +1,177 @@
+package openapi
+
+import (
+	"go-viewset/internal/meta"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// operation 生成一个路由的 Operation
+// 标准路由（列表、详情、创建、更新、删除、批量创建）按 ViewSet 的约定描述请求和响应，
+// 其他路由按自定义 action 处理
+func operation(res resource, route gin.RouteInfo) map[string]interface{} {
+	ref := schemaRef(res.model.Name)
+	rel := strings.TrimPrefix(route.Path, res.prefix)
+
+	op := map[string]interface{}{
+		"tags": []string{res.model.Name},
+	}
+	var params []interface{}
+	for _, name := range pathParams(route.Path, res.params) {
+		params = append(params, map[string]interface{}{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+
+	switch {
+	case (rel == "/" || rel == "") && route.Method == http.MethodGet:
+		op["summary"] = "获取" + res.model.Name + "列表"
+		op["operationId"] = "list" + res.model.Name
+		params = append(params, listParams(res.model)...)
+		op["responses"] = responses(map[string]interface{}{"type": "array", "items": ref}, true)
+	case (rel == "/" || rel == "") && route.Method == http.MethodPost:
+		op["summary"] = "创建" + res.model.Name
+		op["operationId"] = "create" + res.model.Name
+		op["requestBody"] = requestBody(ref)
+		op["responses"] = responses(ref, false)
+	case rel == "/bulk" && route.Method == http.MethodPost:
+		op["summary"] = "批量创建" + res.model.Name
+		op["operationId"] = "bulkCreate" + res.model.Name
+		op["requestBody"] = requestBody(map[string]interface{}{"type": "array", "items": ref})
+		op["responses"] = responses(map[string]interface{}{"type": "object"}, false)
+	case rel == "/:id":
+		switch route.Method {
+		case http.MethodGet:
+			op["summary"] = "获取单个" + res.model.Name
+			op["operationId"] = "retrieve" + res.model.Name
+			op["responses"] = responses(ref, false)
+		case http.MethodPut:
+			op["summary"] = "更新" + res.model.Name
+			op["operationId"] = "update" + res.model.Name
+			op["requestBody"] = requestBody(ref)
+			op["responses"] = responses(ref, false)
+		case http.MethodPatch:
+			op["summary"] = "部分更新" + res.model.Name
+			op["operationId"] = "partialUpdate" + res.model.Name
+			op["requestBody"] = requestBody(map[string]interface{}{"type": "object"})
+			op["responses"] = responses(ref, false)
+		case http.MethodDelete:
+			op["summary"] = "删除" + res.model.Name
+			op["operationId"] = "destroy" + res.model.Name
+			op["responses"] = responses(map[string]interface{}{"type": "object"}, false)
+		}
+	}
+
+	// 自定义 action
+	if _, ok := op["summary"]; !ok {
+		name := rel[strings.LastIndex(rel, "/")+1:]
+		op["summary"] = name
+		op["operationId"] = strings.ToLower(route.Method) + res.model.Name + "_" + name
+		if route.Method == http.MethodPost || route.Method == http.MethodPut || route.Method == http.MethodPatch {
+			op["requestBody"] = map[string]interface{}{
+				"required": false,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]interface{}{"type": "object"}},
+				},
+			}
+		}
+		op["responses"] = responses(map[string]interface{}{"type": "object"}, false)
+	}
+
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	return op
+}
+
+// listParams 列表接口的查询参数：分页、排序以及可过滤的字段
+func listParams(m *meta.Model) []interface{} {
+	params := []interface{}{
+		queryParam("page", "页码", map[string]interface{}{"type": "integer", "minimum": 1}),
+		queryParam("page_size", "每页条数", map[string]interface{}{"type": "integer", "minimum": 0}),
+		queryParam("ordering", "排序字段，逗号分隔，- 前缀表示降序", map[string]interface{}{"type": "string"}),
+		queryParam("count", "总数统计方式：exact 准确统计，none 不统计（total 为 null），estimated 按执行计划估算", map[string]interface{}{"type": "string", "enum": []string{"exact", "none", "estimated"}}),
+		queryParam("with_count", "是否统计总数（兼容参数，建议使用 count）", map[string]interface{}{"type": "boolean"}),
+		queryParam("fields", "只返回指定的字段，逗号分隔", map[string]interface{}{"type": "string"}),
+		queryParam("expand", "一并返回的关联，逗号分隔", map[string]interface{}{"type": "string"}),
+		queryParam("format", "csv：以 CSV 导出全部符合条件的记录（不分页）", map[string]interface{}{"type": "string", "enum": []string{"csv"}}),
+	}
+	for _, f := range m.Fields {
+		if !f.Filterable || f.JSONName == "-" {
+			continue
+		}
+		params = append(params, queryParam(f.JSONName, "按 "+f.JSONName+" 过滤", fieldSchema(f)))
+	}
+	return params
+}
+
+// queryParam 查询参数
+func queryParam(name, description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"in":          "query",
+		"required":    false,
+		"description": description,
+		"schema":      schema,
+	}
+}
+
+// requestBody JSON 请求体
+func requestBody(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+// responses 统一响应格式 {code, msg, data, pagination} 的响应描述
+func responses(data map[string]interface{}, paginated bool) map[string]interface{} {
+	props := map[string]interface{}{
+		"code": map[string]interface{}{"type": "integer"},
+		"msg":  map[string]interface{}{"type": "string"},
+		"data": data,
+	}
+	if paginated {
+		props["pagination"] = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"page":        map[string]interface{}{"type": "integer"},
+				"page_size":   map[string]interface{}{"type": "integer"},
+				"total":       map[string]interface{}{"type": "integer", "nullable": true},
+				"next_cursor": map[string]interface{}{"type": "string"},
+			},
+		}
+	}
+
+	errorSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"code":   map[string]interface{}{"type": "integer"},
+			"msg":    map[string]interface{}{"type": "string"},
+			"errors": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+		},
+	}
+	return map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "success",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "object", "properties": props},
+				},
+			},
+		},
+		"default": map[string]interface{}{
+			"description": "错误",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": errorSchema},
+			},
+		},
+	}
+}
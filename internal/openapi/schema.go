@@ -0,0 +1,74 @@
+package openapi
+
+import (
+	"go-viewset/internal/meta"
+	"strings"
+)
+
+// schemaRef 引用 components/schemas 中的模型
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// modelSchema 根据模型元数据生成 Schema
+// access 标签对应 readOnly/writeOnly，binding 中的 required 对应 required
+func modelSchema(m *meta.Model) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	for _, f := range m.Fields {
+		if f.JSONName == "-" {
+			continue
+		}
+		s := fieldSchema(f)
+		if f.ReadOnly || f.PrimaryKey {
+			s["readOnly"] = true
+		}
+		if f.WriteOnly {
+			s["writeOnly"] = true
+		}
+		props[f.JSONName] = s
+		if f.Required && !f.ReadOnly {
+			required = append(required, f.JSONName)
+		}
+	}
+
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// fieldSchema 字段类型对应的 Schema
+func fieldSchema(f *meta.Field) map[string]interface{} {
+	s := map[string]interface{}{}
+	switch f.TypeName {
+	case "bool":
+		s["type"] = "boolean"
+	case "int":
+		s["type"] = "integer"
+	case "float":
+		s["type"] = "number"
+	case "string":
+		s["type"] = "string"
+	case "time":
+		s["type"] = "string"
+		s["format"] = "date-time"
+	default:
+		s["type"] = "object"
+	}
+
+	// 常用的 binding 规则
+	for _, rule := range strings.Split(f.Binding, ",") {
+		switch rule {
+		case "email":
+			s["format"] = "email"
+		case "url":
+			s["format"] = "uri"
+		}
+	}
+	return s
+}
@@ -0,0 +1,32 @@
+package openapi
+
+import (
+	"html"
+	"strconv"
+)
+
+// swaggerUIVersion 使用的 Swagger UI 版本（从 CDN 加载）
+const swaggerUIVersion = "5"
+
+// swaggerUI 返回加载 specURL 的 Swagger UI 页面
+func swaggerUI(title, specURL string) string {
+	cdn := "https://unpkg.com/swagger-ui-dist@" + swaggerUIVersion
+	return `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>` + html.EscapeString(title) + `</title>
+<link rel="stylesheet" href="` + cdn + `/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="` + cdn + `/swagger-ui-bundle.js"></script>
+<script>
+window.onload = function () {
+  SwaggerUIBundle({url: ` + strconv.Quote(specURL) + `, dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`
+}
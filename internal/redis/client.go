@@ -0,0 +1,252 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrNil 键不存在（RESP 空回复）
+var ErrNil = errors.New("redis: nil")
+
+// Client 轻量的 Redis 客户端
+// 只实现了 RESP2 协议的命令收发，满足缓存、限流等场景的需要
+type Client struct {
+	Addr        string
+	Password    string
+	DB          int
+	DialTimeout time.Duration
+
+	pool chan *conn
+}
+
+// conn 单个 Redis 连接
+type conn struct {
+	nc net.Conn
+	rd *bufio.Reader
+}
+
+// NewClient 创建 Redis 客户端，poolSize 为最多保留的空闲连接数
+func NewClient(addr, password string, db, poolSize int) *Client {
+	if poolSize <= 0 {
+		poolSize = 10
+	}
+	return &Client{
+		Addr:        addr,
+		Password:    password,
+		DB:          db,
+		DialTimeout: 5 * time.Second,
+		pool:        make(chan *conn, poolSize),
+	}
+}
+
+// Do 执行一条命令并返回结果
+// 返回值类型：string（简单字符串/批量字符串）、int64、[]interface{}，键不存在时返回 ErrNil
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		cn.nc.SetDeadline(deadline)
+	} else {
+		cn.nc.SetDeadline(time.Time{})
+	}
+
+	reply, err := cn.do(args...)
+	if err != nil && !isReplyError(err) && err != ErrNil {
+		// 网络错误后连接状态未知，直接丢弃
+		cn.nc.Close()
+		return nil, err
+	}
+
+	c.put(cn)
+	return reply, err
+}
+
+// String 执行命令并返回字符串结果
+func (c *Client) String(ctx context.Context, args ...string) (string, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	switch v := reply.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	}
+	return "", fmt.Errorf("redis: 非预期的回复类型 %T", reply)
+}
+
+// Int 执行命令并返回整数结果
+func (c *Client) Int(ctx context.Context, args ...string) (int64, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("redis: 非预期的回复类型 %T", reply)
+}
+
+// Strings 执行命令并返回字符串数组结果
+func (c *Client) Strings(ctx context.Context, args ...string) ([]string, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redis: 非预期的回复类型 %T", reply)
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// Ping 检查 Redis 是否可用
+func (c *Client) Ping(ctx context.Context) error {
+	reply, err := c.String(ctx, "PING")
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("redis: 非预期的 PING 回复 %q", reply)
+	}
+	return nil
+}
+
+// get 从池中获取连接，没有空闲连接时新建
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.pool:
+		return cn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.DialTimeout}
+	nc, err := dialer.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接 Redis 失败: %w", err)
+	}
+
+	cn := &conn{nc: nc, rd: bufio.NewReader(nc)}
+	if c.Password != "" {
+		if _, err := cn.do("AUTH", c.Password); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if c.DB != 0 {
+		if _, err := cn.do("SELECT", strconv.Itoa(c.DB)); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// put 归还连接，池已满时关闭连接
+func (c *Client) put(cn *conn) {
+	select {
+	case c.pool <- cn:
+	default:
+		cn.nc.Close()
+	}
+}
+
+// replyError Redis 返回的错误回复
+type replyError string
+
+func (e replyError) Error() string { return "redis: " + string(e) }
+
+func isReplyError(err error) bool {
+	var re replyError
+	return errors.As(err, &re)
+}
+
+// do 发送命令并读取回复
+func (cn *conn) do(args ...string) (interface{}, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := cn.nc.Write(buf); err != nil {
+		return nil, err
+	}
+	return cn.readReply()
+}
+
+// readReply 读取一个 RESP 回复
+func (cn *conn) readReply() (interface{}, error) {
+	line, err := cn.rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: 无效的回复 %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, replyError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.rd, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := cn.readReply()
+			if err != nil && err != ErrNil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: 无效的回复 %q", line)
+}
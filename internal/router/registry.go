@@ -0,0 +1,169 @@
+package router
+
+import (
+	"context"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"go-viewset/internal/viewset"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Entry 已注册的 ViewSet
+type Entry struct {
+	Prefix  string // 完整的路由前缀，例如 "/api/users"
+	ViewSet viewset.BaseViewSet
+
+	// ParentParams 嵌套路由中父资源的路径参数名，按在路径中出现的顺序排列
+	ParentParams []string
+}
+
+// Meta 返回 ViewSet 模型的元数据，ViewSet 没有提供时返回 nil
+func (e Entry) Meta() *meta.Model {
+	if m, ok := e.ViewSet.(interface{ Meta() *meta.Model }); ok {
+		return m.Meta()
+	}
+	return nil
+}
+
+// registry 所有 Router 共享的注册表
+type registry struct {
+	mu       sync.RWMutex
+	entries  []Entry
+	versions []*Versions
+}
+
+// Router ViewSet 注册表，类似 DRF 的 DefaultRouter
+// 通过 Register 注册的 ViewSet 可以通过 Entries 查询（用于生成文档、管理页面等）。
+// 集合路由统一以 / 结尾（例如 /api/users/），不带 / 的请求在内部转发，不返回重定向
+type Router struct {
+	engine   *gin.Engine
+	group    *gin.RouterGroup
+	registry *registry
+}
+
+// NewRouter 创建挂载在 engine 根路径上的 Router
+func NewRouter(engine *gin.Engine) *Router {
+	r := &Router{
+		engine:   engine,
+		group:    &engine.RouterGroup,
+		registry: &registry{},
+	}
+
+	// 关闭 gin 的尾部斜杠重定向，由 NoRoute 在内部转发（POST 等请求重定向后客户端可能丢失请求体）
+	engine.RedirectTrailingSlash = false
+	engine.NoRoute(r.handleTrailingSlash)
+	return r
+}
+
+// Group 创建子路由组，子 Router 与当前 Router 共享注册表
+func (r *Router) Group(relativePath string, handlers ...gin.HandlerFunc) *Router {
+	return &Router{
+		engine:   r.engine,
+		group:    r.group.Group(relativePath, handlers...),
+		registry: r.registry,
+	}
+}
+
+// Use 为当前路由组添加中间件，只对之后注册的 ViewSet 生效
+func (r *Router) Use(handlers ...gin.HandlerFunc) *Router {
+	r.group.Use(handlers...)
+	return r
+}
+
+// Register 在 prefix 下注册 ViewSet 的全部路由，handlers 为只作用于该 ViewSet 的中间件
+func (r *Router) Register(prefix string, vs viewset.BaseViewSet, handlers ...gin.HandlerFunc) {
+	group := r.group.Group("/"+strings.Trim(prefix, "/"), handlers...)
+	vs.RegisterRoutes(group)
+
+	r.registry.mu.Lock()
+	defer r.registry.mu.Unlock()
+	r.registry.entries = append(r.registry.entries, Entry{Prefix: group.BasePath(), ViewSet: vs})
+}
+
+// RegisterNested 将子 ViewSet 注册在父资源之下，例如：
+//
+//	api.Register("/users", userViewSet)
+//	api.RegisterNested("/users", viewset.ParentLookup{Param: "user_id", Field: "user_id"}, "/orders", orderViewSet)
+//
+// 注册后 /users/:user_id/orders/ 只返回该用户的订单，创建订单时 user_id 自动取自路径。
+// gin 要求同一位置的路径参数同名，路由中父资源的参数仍为 :id，由中间件重命名为 lookup.Param
+func (r *Router) RegisterNested(parent string, lookup viewset.ParentLookup, prefix string, vs viewset.BaseViewSet, handlers ...gin.HandlerFunc) {
+	if nested, ok := vs.(interface{ AddParentLookup(viewset.ParentLookup) }); ok {
+		nested.AddParentLookup(lookup)
+	}
+
+	path := "/" + strings.Trim(parent, "/") + "/:id/" + strings.Trim(prefix, "/")
+	handlers = append([]gin.HandlerFunc{renameParam("id", lookup.Param)}, handlers...)
+	group := r.group.Group(path, handlers...)
+	vs.RegisterRoutes(group)
+
+	r.registry.mu.Lock()
+	defer r.registry.mu.Unlock()
+	r.registry.entries = append(r.registry.entries, Entry{
+		Prefix:       group.BasePath(),
+		ViewSet:      vs,
+		ParentParams: []string{lookup.Param},
+	})
+}
+
+// renameParam 将第一个名为 from 的路径参数重命名为 to
+// 子资源自己的 :id 排在父资源之后，不受影响
+func renameParam(from, to string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for i := range c.Params {
+			if c.Params[i].Key == from {
+				c.Params[i].Key = to
+				break
+			}
+		}
+		c.Next()
+	}
+}
+
+// Entries 返回已注册的 ViewSet，按注册顺序排列
+func (r *Router) Entries() []Entry {
+	r.registry.mu.RLock()
+	defer r.registry.mu.RUnlock()
+	return append([]Entry(nil), r.registry.entries...)
+}
+
+// handleTrailingSlash 处理未匹配的请求
+// 不带版本的请求先按版本转发（见 Versions）；请求路径属于已注册的 ViewSet 时，
+// 补上或去掉尾部的 / 后重新路由一次，否则返回 404
+func (r *Router) handleTrailingSlash(c *gin.Context) {
+	if r.handleVersion(c) {
+		return
+	}
+	path := c.Request.URL.Path
+	if c.Request.Context().Value(trailingSlashRetried{}) != nil || !r.owns(path) {
+		utils.NotFound(c, "接口不存在")
+		return
+	}
+
+	if strings.HasSuffix(path, "/") {
+		path = strings.TrimSuffix(path, "/")
+	} else {
+		path += "/"
+	}
+	// HandleContext 会重置 gin.Context 的 Keys，标记写在请求的 context 中
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), trailingSlashRetried{}, true))
+	c.Request.URL.Path = path
+	r.engine.HandleContext(c)
+}
+
+// trailingSlashRetried 标记请求已经转发过一次，避免循环
+type trailingSlashRetried struct{}
+
+// owns 判断路径是否属于已注册的 ViewSet
+func (r *Router) owns(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	for _, e := range r.Entries() {
+		if path == e.Prefix || strings.HasPrefix(path, e.Prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,39 +1,393 @@
 package router
 
 import (
+	"context"
+	"go-viewset/internal/admin"
+	"go-viewset/internal/auth"
+	"go-viewset/internal/cache"
+	"go-viewset/internal/config"
+	"go-viewset/internal/database"
+	"go-viewset/internal/graphql"
+	"go-viewset/internal/health"
+	"go-viewset/internal/mailer"
+	"go-viewset/internal/metrics"
+	"go-viewset/internal/middleware"
+	"go-viewset/internal/oidc"
+	"go-viewset/internal/openapi"
+	"go-viewset/internal/redis"
+	"go-viewset/internal/session"
+	"go-viewset/internal/storage"
+	"go-viewset/internal/throttle"
+	"go-viewset/internal/tracing"
 	"go-viewset/internal/viewset"
+	"go-viewset/internal/webhook"
+	"go-viewset/internal/ws"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // SetupRouter 设置路由
-func SetupRouter(db *gorm.DB) *gin.Engine {
+// tenantDBs 为每个租户使用独立数据库时的连接管理，webhooks 为 Webhook 投递，files 为附件的存储后端，
+// mail 为邮件发送，未开启时均为 nil
+func SetupRouter(db *gorm.DB, cfg *config.Config, tenantDBs *database.TenantDBs, webhooks *webhook.Dispatcher, files storage.Storage, mail *mailer.Mailer) *gin.Engine {
 	r := gin.Default()
 
 	// 添加全局中间件
+	r.Use(middleware.RequestID())
+	if cfg.Tracing.Enabled {
+		r.Use(tracing.Middleware())
+	}
+	r.Use(middleware.Profiling(cfg.Server.ProfileToken))
+	if cfg.Server.Metrics {
+		r.Use(metrics.Middleware())
+		r.GET("/metrics", metrics.Handler())
+	}
+	if cfg.Concurrency.MaxInFlight > 0 {
+		r.Use(concurrencyLimit(cfg.Concurrency, cfg.Concurrency.MaxInFlight))
+	}
+	limits := newRateLimiter(cfg.RateLimit)
+	r.Use(limits.global()...)
 	r.Use(CORSMiddleware())
 	r.Use(LoggerMiddleware())
 	r.Use(RecoveryMiddleware())
 
+	// 查询缓存、action 限流和会话共用的 Redis 连接
+	var redisClient *redis.Client
+	if cfg.Cache.Type == "redis" || cfg.RateLimit.Store == "redis" || (cfg.Session.Enabled && cfg.Session.Store == "redis") {
+		redisClient = redis.NewClient(cfg.Cache.Redis.Addr, cfg.Cache.Redis.Password, cfg.Cache.Redis.DB, cfg.Cache.Redis.PoolSize)
+	}
+
+	// 会话认证：带有会话 Cookie 的请求写入 user_id
+	var sessions *session.Manager
+	if cfg.Session.Enabled {
+		sessions = newSessions(db, cfg.Session, redisClient, cfg.Cache.Redis.Prefix)
+	}
+	if sessions != nil {
+		r.Use(middleware.Session(sessions))
+	}
+
+	// 加载已登录用户的角色和权限（认证中间件需要注册在它之前）
+	r.Use(middleware.LoadRoles(db))
+
+	// 多实例部署时限流计数通过 Redis 共享
+	if cfg.RateLimit.Store == "redis" {
+		throttle.Default = throttle.NewRedisStore(redisClient, cfg.Cache.Redis.Prefix)
+	}
+	queryCache := newCache(cfg.Cache, redisClient)
+	if queryCache != nil {
+		if err := database.RegisterCacheInvalidation(db, queryCache); err != nil {
+			log.Printf("注册缓存失效回调失败: %v", err)
+		}
+	}
+
+	// 密码哈希的计算强度
+	if cfg.Auth.BcryptCost > 0 {
+		auth.Cost = cfg.Auth.BcryptCost
+	}
+
+	routes := NewRouter(r)
+
 	// API 路由组
-	api := r.Group("/api")
+	api := routes.Group("/api", limits.group("/api")...)
+
+	// 多租户：/api 下的请求按子域名或请求头确定租户（管理接口不受限制）
+	if cfg.Tenant.Enabled {
+		api.Use(middleware.Tenant(db, cfg.Tenant))
+		if tenantDBs != nil {
+			api.Use(middleware.TenantDatabase(tenantDBs))
+		}
+	}
+
+	// API 配额统计（管理接口不计入配额）
+	if cfg.Quota.Enabled {
+		api.Use(middleware.QuotaMiddleware(db, cfg.Quota))
+	}
 
 	// 注册用户路由
 	userViewSet := viewset.NewUserViewSet(db)
-	userViewSet.RegisterRoutes(api.Group("/users"))
-
-	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-			"message": "Go ViewSet is running",
-		})
+	if cfg.Auth.ResetTokenMinutes > 0 {
+		userViewSet.ResetTokenTTL = time.Duration(cfg.Auth.ResetTokenMinutes) * time.Minute
+	}
+	userViewSet.Mailer = mail
+	enableHistory(userViewSet.GenericViewSet, cfg.Audit)
+	enableCache(userViewSet.GenericViewSet, "/api/users", queryCache, cfg.Cache)
+
+	// 开发模式下检查过滤和排序字段的索引
+	if cfg.Server.Mode == gin.DebugMode {
+		userViewSet.IndexAdvisor = database.NewIndexAdvisor(db)
+	}
+
+	// 开启附件时可以上传头像（POST /api/users/:id/avatar）
+	if cfg.Attachment.Enabled {
+		userViewSet.EnableAvatar(viewset.NewUploader(files, cfg.Attachment))
+	}
+	api.Register("/users", userViewSet, append(groupLimit("/api/users", cfg.Concurrency), limits.group("/api/users")...)...)
+
+	// 注册认证路由：忘记密码、通过令牌重置密码，开启会话时还有登录、登出和当前用户
+	authViewSet := viewset.NewAuthViewSet(db)
+	authViewSet.ResetTokenTTL = userViewSet.ResetTokenTTL
+	authViewSet.Mailer = mail
+	if sessions != nil {
+		authViewSet.EnableSessions(sessions)
+	}
+	if cfg.OIDC.Enabled {
+		enableOIDC(authViewSet, cfg.OIDC, sessions)
+	}
+	api.Register("/auth", authViewSet, limits.group("/api/auth")...)
+
+	// 管理接口
+	admin := routes.Group("/admin", limits.group("/admin")...)
+
+	// 注册配额管理路由
+	quotaViewSet := viewset.NewQuotaViewSet(db)
+	enableHistory(quotaViewSet.GenericViewSet, cfg.Audit)
+	enableCache(quotaViewSet.GenericViewSet, "/admin/quotas", queryCache, cfg.Cache)
+	admin.Register("/quotas", quotaViewSet, limits.group("/admin/quotas")...)
+
+	// 注册角色和权限管理路由
+	roleViewSet := viewset.NewRoleViewSet(db)
+	permissionViewSet := viewset.NewPermissionViewSet(db)
+	enableHistory(roleViewSet.GenericViewSet, cfg.Audit)
+	enableHistory(permissionViewSet.GenericViewSet, cfg.Audit)
+	admin.Register("/roles", roleViewSet, limits.group("/admin/roles")...)
+	admin.Register("/permissions", permissionViewSet, limits.group("/admin/permissions")...)
+
+	// 注册租户管理路由
+	if cfg.Tenant.Enabled {
+		tenantViewSet := viewset.NewTenantViewSet(db)
+		tenantViewSet.DBs = tenantDBs
+		admin.Register("/tenants", tenantViewSet, limits.group("/admin/tenants")...)
+	}
+
+	// go-viewset gen: viewsets
+
+	// Webhook：模型变更事件发送到配置的目标，投递记录通过 /admin/webhook-deliveries/ 查询和重新投递
+	if cfg.Webhook.Enabled {
+		admin.Register("/webhook-deliveries", viewset.NewWebhookDeliveryViewSet(db, webhooks), limits.group("/admin/webhook-deliveries")...)
+	}
+
+	// 附件：通过 /api/attachments/ 上传，模型中带 file 标签的附件 ID 字段在响应中附带附件信息和签名的下载地址
+	if cfg.Attachment.Enabled {
+		attachmentViewSet := viewset.NewAttachmentViewSet(db, files, cfg.Attachment)
+		api.Register("/attachments", attachmentViewSet, limits.group("/api/attachments")...)
+		if local, ok := files.(*storage.Local); ok {
+			r.GET(local.Prefix()+"/*key", local.Handler())
+		}
+
+		expiry := time.Duration(cfg.Attachment.URLExpirySeconds) * time.Second
+		if expiry <= 0 {
+			expiry = time.Hour
+		}
+		for _, e := range routes.Entries() {
+			if f, ok := e.ViewSet.(interface {
+				EnableFileFields(storage.Storage, time.Duration) bool
+			}); ok && f.EnableFileFields(files, expiry) {
+				log.Printf("%s 启用附件字段", e.Prefix)
+			}
+		}
+	}
+
+	// 审计日志：记录以上 ViewSet 的写操作，通过 /api/audit-logs/ 查询
+	if cfg.Audit.Enabled {
+		for _, v := range []*viewset.GenericViewSet{
+			userViewSet.GenericViewSet,
+			quotaViewSet.GenericViewSet,
+			roleViewSet.GenericViewSet,
+			permissionViewSet.GenericViewSet,
+		} {
+			v.EnableAudit = true
+		}
+		api.Register("/audit-logs", viewset.NewAuditLogViewSet(db), limits.group("/api/audit-logs")...)
+	}
+
+	// 多租户：/api 下模型带 tenant_id 字段的 ViewSet 按租户隔离
+	if cfg.Tenant.Enabled {
+		for _, e := range routes.Entries() {
+			if !strings.HasPrefix(e.Prefix, "/api/") {
+				continue
+			}
+			if t, ok := e.ViewSet.(interface{ EnableTenantScope() bool }); ok && t.EnableTenantScope() {
+				log.Printf("%s 按租户隔离", e.Prefix)
+			}
+		}
+	}
+
+	// WebSocket：/api 下的资源作为频道，客户端通过一个连接订阅变更（需要开启 EnableStream）和调用列表、详情接口
+	if cfg.WebSocket.Enabled {
+		server := ws.NewServer(r, cfg.WebSocket)
+		for _, e := range routes.Entries() {
+			if !strings.HasPrefix(e.Prefix, "/api/") || strings.Contains(e.Prefix, ":") {
+				continue
+			}
+			server.Channel(strings.TrimPrefix(e.Prefix, "/api/"), e.Prefix)
+		}
+		path := cfg.WebSocket.Path
+		if path == "" {
+			path = "/ws"
+		}
+		r.GET(path, server.Handle)
+	}
+
+	// 响应中的链接：对象和列表带 _links，关联对象的地址取自注册表
+	if cfg.Response.Links {
+		enableLinks(routes.Entries())
+	}
+
+	// API 文档：/api/openapi.json 和 Swagger UI /api/docs
+	docs := openapi.New("Go ViewSet API", "1.0.0")
+	for _, e := range routes.Entries() {
+		docs.Add(e.Prefix, e.Meta(), e.ParentParams...)
+	}
+	docs.Register(r, "/api")
+
+	// 管理后台：为以上 ViewSet 生成增删改查页面，默认 /admin/
+	if cfg.Admin.Enabled {
+		registerAdmin(r, routes.Entries(), cfg.Admin)
+	}
+
+	// GraphQL：由以上 ViewSet 生成查询和变更，默认 /api/graphql
+	if cfg.GraphQL.Enabled {
+		registerGraphQL(r, routes.Entries(), cfg.GraphQL)
+	}
+
+	// 健康检查：/healthz 存活检查，/readyz 就绪检查（数据库和 Redis），/health 与 /healthz 相同
+	checker := health.New()
+	checker.Add("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
 	})
+	if redisClient != nil {
+		checker.Add("redis", redisClient.Ping)
+	}
+	r.GET("/healthz", checker.Liveness())
+	r.GET("/readyz", checker.Readiness())
+	r.GET("/health", checker.Liveness())
 
 	return r
 }
 
+// enableLinks 为 ViewSet 开启 _links，并记录各模型的集合路径（嵌套路由需要父资源的 ID，不作为关联对象的地址）
+func enableLinks(entries []Entry) {
+	for _, e := range entries {
+		if m := e.Meta(); m != nil && len(e.ParentParams) == 0 {
+			viewset.RegisterResourcePath(m.Table, e.Prefix)
+		}
+		if l, ok := e.ViewSet.(interface{ EnableLinks() }); ok {
+			l.EnableLinks()
+		}
+	}
+}
+
+// registerAdmin 注册管理后台，嵌套路由的 ViewSet 需要父资源的 ID，不在管理后台中列出
+func registerAdmin(r *gin.Engine, entries []Entry, cfg config.AdminConfig) {
+	site := admin.New("Go ViewSet Admin")
+
+	// 默认只允许管理员，只有显式配置了权限或角色时才放宽
+	switch {
+	case cfg.Permission != "":
+		site.Permission = viewset.HasPerm(cfg.Permission)
+	case len(cfg.Roles) > 0:
+		site.Permission = viewset.RequireRole(cfg.Roles...)
+	default:
+		site.Permission = viewset.IsAdmin
+	}
+	for _, e := range entries {
+		if len(e.ParentParams) == 0 {
+			site.Add(e.Prefix, e.ViewSet)
+		}
+	}
+	path := cfg.Path
+	if path == "" {
+		path = "/admin"
+	}
+	site.Register(r, path)
+}
+
+// registerGraphQL 注册 GraphQL 接口，嵌套路由的 ViewSet 需要父资源的 ID，不生成字段
+func registerGraphQL(r *gin.Engine, entries []Entry, cfg config.GraphQLConfig) {
+	handler := graphql.New()
+	if cfg.MaxRootFields > 0 {
+		handler.MaxRootFields = cfg.MaxRootFields
+	}
+	for _, e := range entries {
+		if len(e.ParentParams) == 0 {
+			handler.Add(e.Prefix, e.ViewSet)
+		}
+	}
+	path := cfg.Path
+	if path == "" {
+		path = "/api/graphql"
+	}
+	handler.Register(r, path)
+}
+
+// groupLimit 按配置返回路由组的并发限制中间件，path 为路由组的完整路径
+func groupLimit(path string, cfg config.ConcurrencyConfig) []gin.HandlerFunc {
+	if max := cfg.Groups[path]; max > 0 {
+		return []gin.HandlerFunc{concurrencyLimit(cfg, max)}
+	}
+	return nil
+}
+
+// concurrencyLimit 创建并发限制中间件
+func concurrencyLimit(cfg config.ConcurrencyConfig, max int) gin.HandlerFunc {
+	retryAfter := time.Duration(cfg.RetryAfterSeconds) * time.Second
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+	return middleware.ConcurrencyLimit(max, time.Duration(cfg.WaitMs)*time.Millisecond, retryAfter)
+}
+
+// newCache 根据配置创建查询结果缓存，未配置时返回 nil
+// redis 类型使用传入的客户端（与就绪检查共用）
+func newCache(cfg config.CacheConfig, client *redis.Client) cache.Cache {
+	switch cfg.Type {
+	case "memory":
+		return cache.NewLRUCache(cfg.Size)
+	case "redis":
+		return cache.NewRedisCache(client, cfg.Redis.Prefix)
+	}
+	return nil
+}
+
+// enableCache 按配置为 ViewSet 开启查询结果缓存，path 为路由组的完整路径
+// 所有 ViewSet 共用同一个缓存，按资源标签分别失效
+func enableCache(v *viewset.GenericViewSet, path string, c cache.Cache, cfg config.CacheConfig) {
+	if c == nil {
+		return
+	}
+	groups := cfg.Groups
+	if groups == nil {
+		groups = map[string]int{"/api/users": 0}
+	}
+	ttl, ok := groups[path]
+	if !ok {
+		return
+	}
+	if ttl <= 0 {
+		ttl = cfg.TTLSeconds
+	}
+	v.EnableCache(c, time.Duration(ttl)*time.Second)
+}
+
+// enableHistory 按配置为 ViewSet 开启历史记录，需要在注册路由之前调用
+// 建表失败时只输出日志，不影响服务启动
+func enableHistory(v *viewset.GenericViewSet, cfg config.AuditConfig) {
+	if !cfg.History {
+		return
+	}
+	if err := v.EnableHistory(); err != nil {
+		log.Printf("开启历史记录失败: %v", err)
+	}
+}
+
 // CORSMiddleware CORS 中间件
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -42,7 +396,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
-		if c.Request.Method == "OPTIONS" {
+		// 只拦截 CORS 预检请求，其他 OPTIONS 请求交给路由处理（例如返回模型元数据）
+		if c.Request.Method == "OPTIONS" && c.GetHeader("Access-Control-Request-Method") != "" {
 			c.AbortWithStatus(204)
 			return
 		}
@@ -63,3 +418,35 @@ func LoggerMiddleware() gin.HandlerFunc {
 func RecoveryMiddleware() gin.HandlerFunc {
 	return gin.Recovery()
 }
+
+// newSessions 创建会话管理，配置错误时只输出日志，不开启会话认证
+func newSessions(db *gorm.DB, cfg config.SessionConfig, client *redis.Client, prefix string) *session.Manager {
+	store, err := session.Open(cfg, db, client, prefix)
+	if err == nil {
+		var m *session.Manager
+		if m, err = session.NewManager(store, cfg); err == nil {
+			return m
+		}
+	}
+	log.Printf("开启会话认证失败: %v", err)
+	return nil
+}
+
+// enableOIDC 按配置开启第三方登录，配置错误的身份提供方只输出日志并跳过
+func enableOIDC(v *viewset.AuthViewSet, cfg config.OIDCConfig, sessions *session.Manager) {
+	if sessions == nil {
+		log.Printf("第三方登录需要开启会话认证（session.enabled）")
+		return
+	}
+	base := strings.TrimSuffix(cfg.CallbackBaseURL, "/")
+	providers := make(map[string]oidc.Provider, len(cfg.Providers))
+	for name, pc := range cfg.Providers {
+		p, err := oidc.Open(name, pc, base+"/api/auth/oidc/"+name+"/callback")
+		if err != nil {
+			log.Printf("开启第三方登录 %s 失败: %v", name, err)
+			continue
+		}
+		providers[name] = p
+	}
+	v.EnableOIDC(providers, cfg.RedirectURL)
+}
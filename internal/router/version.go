@@ -0,0 +1,176 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"go-viewset/internal/utils"
+	"go-viewset/internal/viewset"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version API 版本
+type Version struct {
+	Name string // URL 中的版本，例如 "v1"
+
+	// Deprecated 宣布废弃的时间，非零时该版本的响应带 Deprecation 头（RFC 9745）
+	Deprecated time.Time
+	// Sunset 计划停止服务的时间，非零时响应带 Sunset 头（RFC 8594）
+	Sunset time.Time
+	// Link 迁移说明的地址，废弃后作为 Link 头（rel="deprecation"）返回
+	Link string
+}
+
+// Versions 同一路由组下的多个 API 版本，例如 /api/v1、/api/v2
+// 每个版本是一个子 Router，可以注册不同的 ViewSet；不带版本的请求（例如 /api/orders/）没有匹配的路由时，
+// 按 Accept 头中的版本（application/json; version=2 或 application/vnd.example.v2+json）转发，未指定时使用默认版本
+type Versions struct {
+	base           string
+	routers        map[string]*Router
+	names          []string
+	defaultVersion string
+}
+
+// Versions 在当前路由组下创建版本化的子路由组，defaultVersion 为不带版本的请求默认使用的版本
+// 已经直接注册在当前路由组下的 ViewSet（例如 /api/users/）优先，不受版本转发影响
+func (r *Router) Versions(defaultVersion string, versions ...Version) *Versions {
+	vs := &Versions{
+		base:           r.group.BasePath(),
+		routers:        make(map[string]*Router, len(versions)),
+		defaultVersion: defaultVersion,
+	}
+	for _, v := range versions {
+		vs.routers[v.Name] = r.Group("/"+v.Name, versionHeaders(v))
+		vs.names = append(vs.names, v.Name)
+	}
+	if _, ok := vs.routers[defaultVersion]; !ok {
+		panic(fmt.Sprintf("默认 API 版本 %q 不在 %v 中", defaultVersion, vs.names))
+	}
+
+	r.registry.mu.Lock()
+	r.registry.versions = append(r.registry.versions, vs)
+	r.registry.mu.Unlock()
+	return vs
+}
+
+// Version 返回版本的路由组，在其中注册只属于该版本的 ViewSet；版本不存在时 panic
+func (vs *Versions) Version(name string) *Router {
+	r, ok := vs.routers[name]
+	if !ok {
+		panic(fmt.Sprintf("API 版本 %q 不存在", name))
+	}
+	return r
+}
+
+// Register 在多个版本下注册同一个 ViewSet，versions 为空时注册在全部版本下
+// ViewSet 中可以通过 viewset.APIVersion 区分请求的版本
+func (vs *Versions) Register(prefix string, v viewset.BaseViewSet, versions []string, handlers ...gin.HandlerFunc) {
+	if len(versions) == 0 {
+		versions = vs.names
+	}
+	for _, name := range versions {
+		vs.Version(name).Register(prefix, v, handlers...)
+	}
+}
+
+// versionHeaders 写入当前请求的版本，废弃的版本添加 Deprecation、Sunset 和 Link 头
+func versionHeaders(v Version) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(viewset.ContextAPIVersion, v.Name)
+		if !v.Deprecated.IsZero() {
+			c.Header("Deprecation", "@"+strconv.FormatInt(v.Deprecated.Unix(), 10))
+			if v.Link != "" {
+				c.Header("Link", `<`+v.Link+`>; rel="deprecation"`)
+			}
+		}
+		if !v.Sunset.IsZero() {
+			c.Header("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Next()
+	}
+}
+
+// vendorVersion Accept 中厂商媒体类型的版本，例如 application/vnd.example.v2+json
+var vendorVersion = regexp.MustCompile(`^application/vnd\.[^+]*\.(v\d+)\+json$`)
+
+// resolve 不带版本的请求路径转发到的版本路径，路径不属于该版本组或已带有版本时返回 false
+// Accept 头指定了不存在的版本时返回错误
+func (vs *Versions) resolve(c *gin.Context) (string, bool, error) {
+	path := c.Request.URL.Path
+	if !strings.HasPrefix(path, vs.base+"/") {
+		return "", false, nil
+	}
+	rest := strings.TrimPrefix(path, vs.base)
+	segment, _, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+	if _, ok := vs.routers[segment]; ok {
+		return "", false, nil
+	}
+
+	version := vs.defaultVersion
+	if requested := acceptVersion(c.GetHeader("Accept")); requested != "" {
+		if _, ok := vs.routers[requested]; !ok {
+			return "", false, fmt.Errorf("不支持的 API 版本 %s（支持：%s）", requested, strings.Join(vs.names, ", "))
+		}
+		version = requested
+	}
+	return vs.base + "/" + version + rest, true, nil
+}
+
+// acceptVersion 从 Accept 头中取出请求的版本（如 "v2"），没有指定时返回空字符串
+// 支持 version 参数（version=2 或 version=v2）和厂商媒体类型（application/vnd.example.v2+json）
+func acceptVersion(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if version := params["version"]; version != "" {
+			if !strings.HasPrefix(version, "v") {
+				version = "v" + version
+			}
+			return version
+		}
+		if m := vendorVersion.FindStringSubmatch(mediaType); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// handleVersion 将不带版本的请求转发到对应的版本，返回是否已处理
+// 属于直接注册的 ViewSet 的路径（例如不带 / 的 /api/users）交给尾部斜杠的处理
+func (r *Router) handleVersion(c *gin.Context) bool {
+	if c.Request.Context().Value(versionResolved{}) != nil || r.owns(c.Request.URL.Path) {
+		return false
+	}
+	r.registry.mu.RLock()
+	versions := r.registry.versions
+	r.registry.mu.RUnlock()
+
+	for _, vs := range versions {
+		path, ok, err := vs.resolve(c)
+		if err != nil {
+			utils.ErrorWithStatus(c, http.StatusNotAcceptable, http.StatusNotAcceptable, err.Error())
+			return true
+		}
+		if !ok {
+			continue
+		}
+		// 响应随 Accept 头变化，缓存需要区分
+		c.Writer.Header().Add("Vary", "Accept")
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), versionResolved{}, true))
+		c.Request.URL.Path = path
+		r.engine.HandleContext(c)
+		return true
+	}
+	return false
+}
+
+// versionResolved 标记请求已经按版本转发过一次，避免循环
+type versionResolved struct{}
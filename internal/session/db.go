@@ -0,0 +1,63 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"go-viewset/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DBStore 会话保存在数据库的 sessions 表中（见 models.Session）
+type DBStore struct {
+	db *gorm.DB
+}
+
+// NewDBStore 创建 DBStore
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// Get 实现 Store
+func (s *DBStore) Get(ctx context.Context, tokenHash string) (*Session, error) {
+	var record models.Session
+	err := s.db.WithContext(ctx).
+		Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Session{UserID: record.UserID, CreatedAt: record.CreatedAt, ExpiresAt: record.ExpiresAt}, nil
+}
+
+// Save 实现 Store：已有的会话只更新过期时间，新会话写入时顺便清理该用户已过期的会话
+func (s *DBStore) Save(ctx context.Context, tokenHash string, sess *Session) error {
+	db := s.db.WithContext(ctx)
+	result := db.Model(&models.Session{}).Where("token_hash = ?", tokenHash).Update("expires_at", sess.ExpiresAt)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	if err := db.Where("user_id = ? AND expires_at <= ?", sess.UserID, time.Now()).Delete(&models.Session{}).Error; err != nil {
+		return err
+	}
+	return db.Create(&models.Session{
+		CreatedAt: sess.CreatedAt,
+		TokenHash: tokenHash,
+		UserID:    sess.UserID,
+		ExpiresAt: sess.ExpiresAt,
+	}).Error
+}
+
+// Delete 实现 Store
+func (s *DBStore) Delete(ctx context.Context, tokenHash string) error {
+	return s.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Delete(&models.Session{}).Error
+}
+
+// DeleteUser 实现 Store
+func (s *DBStore) DeleteUser(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
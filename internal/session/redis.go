@@ -0,0 +1,94 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-viewset/internal/redis"
+	"strconv"
+	"time"
+)
+
+// redisUserScript 将会话加入用户的会话集合，集合的过期时间延长到不短于该会话
+const redisUserScript = `
+redis.call("SADD", KEYS[1], ARGV[1])
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`
+
+// RedisStore 会话保存在 Redis 中，key 的过期时间与会话相同，过期后由 Redis 删除
+// 每个用户一个集合记录其会话的 key，用于删除用户的全部会话
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore 创建 RedisStore，prefix 为所有 key 的前缀
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Get 实现 Store
+func (s *RedisStore) Get(ctx context.Context, tokenHash string) (*Session, error) {
+	value, err := s.client.String(ctx, "GET", s.key(tokenHash))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sess Session
+	if err := json.Unmarshal([]byte(value), &sess); err != nil {
+		return nil, err
+	}
+	if !sess.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &sess, nil
+}
+
+// Save 实现 Store
+func (s *RedisStore) Save(ctx context.Context, tokenHash string, sess *Session) error {
+	ttl := time.Until(sess.ExpiresAt)
+	if ttl <= 0 {
+		return s.Delete(ctx, tokenHash)
+	}
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	px := strconv.FormatInt(ttl.Milliseconds(), 10)
+	if _, err := s.client.Do(ctx, "SET", s.key(tokenHash), string(data), "PX", px); err != nil {
+		return err
+	}
+	_, err = s.client.Do(ctx, "EVAL", redisUserScript, "1", s.userKey(sess.UserID), s.key(tokenHash), px)
+	return err
+}
+
+// Delete 实现 Store
+func (s *RedisStore) Delete(ctx context.Context, tokenHash string) error {
+	_, err := s.client.Do(ctx, "DEL", s.key(tokenHash))
+	return err
+}
+
+// DeleteUser 实现 Store
+func (s *RedisStore) DeleteUser(ctx context.Context, userID uint) error {
+	keys, err := s.client.Strings(ctx, "SMEMBERS", s.userKey(userID))
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return err
+	}
+	_, err = s.client.Do(ctx, append([]string{"DEL", s.userKey(userID)}, keys...)...)
+	return err
+}
+
+// key 会话的 key
+func (s *RedisStore) key(tokenHash string) string {
+	return s.prefix + "session:" + tokenHash
+}
+
+// userKey 用户的会话集合的 key
+func (s *RedisStore) userKey(userID uint) string {
+	return s.prefix + "session:user:" + strconv.FormatUint(uint64(userID), 10)
+}
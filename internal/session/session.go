@@ -0,0 +1,186 @@
+// Package session 基于 Cookie 的登录会话，供浏览器中的单页应用使用
+//
+// 登录后生成随机的会话 ID 写入 HttpOnly Cookie，存储（数据库或 Redis）中只保存 ID 的哈希；
+// 会话在 TTL 内有请求时自动延长（剩余时间不足一半时），登出时删除
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-viewset/internal/auth"
+	"go-viewset/internal/config"
+	"go-viewset/internal/redis"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 默认配置
+const (
+	DefaultCookieName = "session_id"
+	defaultTTL        = 7 * 24 * time.Hour
+)
+
+// ErrNotFound 会话不存在或已过期
+var ErrNotFound = errors.New("会话不存在或已过期")
+
+// Session 一个登录会话
+type Session struct {
+	Token     string    `json:"-"` // 会话 ID，只在 Cookie 中出现，存储中为它的哈希
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Store 会话的存储，tokenHash 为会话 ID 的哈希（见 auth.HashToken）
+type Store interface {
+	Get(ctx context.Context, tokenHash string) (*Session, error) // 不存在或已过期时返回 ErrNotFound
+	Save(ctx context.Context, tokenHash string, s *Session) error
+	Delete(ctx context.Context, tokenHash string) error
+	DeleteUser(ctx context.Context, userID uint) error // 删除用户的全部会话
+}
+
+// Open 按配置创建会话存储，redis 存储使用 client
+func Open(cfg config.SessionConfig, db *gorm.DB, client *redis.Client, prefix string) (Store, error) {
+	switch cfg.Store {
+	case "", "db":
+		return NewDBStore(db), nil
+	case "redis":
+		if client == nil {
+			return nil, errors.New("会话存储为 redis 时需要配置 cache.redis")
+		}
+		return NewRedisStore(client, prefix), nil
+	}
+	return nil, fmt.Errorf("不支持的会话存储 %q（支持 db、redis）", cfg.Store)
+}
+
+// Manager 创建、读取和删除会话，并维护对应的 Cookie
+type Manager struct {
+	store    Store
+	cookie   string
+	domain   string
+	ttl      time.Duration
+	secure   bool
+	sameSite http.SameSite
+}
+
+// NewManager 创建 Manager
+func NewManager(store Store, cfg config.SessionConfig) (*Manager, error) {
+	m := &Manager{
+		store:  store,
+		cookie: cfg.CookieName,
+		domain: cfg.Domain,
+		ttl:    time.Duration(cfg.TTLMinutes) * time.Minute,
+		secure: cfg.Secure,
+	}
+	if m.cookie == "" {
+		m.cookie = DefaultCookieName
+	}
+	if m.ttl <= 0 {
+		m.ttl = defaultTTL
+	}
+	switch strings.ToLower(cfg.SameSite) {
+	case "", "lax":
+		m.sameSite = http.SameSiteLaxMode
+	case "strict":
+		m.sameSite = http.SameSiteStrictMode
+	case "none":
+		if !cfg.Secure {
+			return nil, errors.New("session.sameSite 为 none 时必须开启 session.secure")
+		}
+		m.sameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("不支持的 SameSite %q（支持 lax、strict、none）", cfg.SameSite)
+	}
+	return m, nil
+}
+
+// Login 为用户创建新会话并写入 Cookie，请求中已有的会话被删除（防止会话固定攻击）
+func (m *Manager) Login(c *gin.Context, userID uint) (*Session, error) {
+	if old, err := c.Cookie(m.cookie); err == nil && old != "" {
+		if err := m.store.Delete(c.Request.Context(), auth.HashToken(old)); err != nil {
+			return nil, err
+		}
+	}
+
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s := &Session{Token: token, UserID: userID, CreatedAt: now, ExpiresAt: now.Add(m.ttl)}
+	if err := m.store.Save(c.Request.Context(), hash, s); err != nil {
+		return nil, err
+	}
+	m.setCookie(c, token, s.ExpiresAt)
+	return s, nil
+}
+
+// Load 读取请求的会话，剩余有效期不足一半时延长并更新 Cookie
+// 没有 Cookie、会话不存在或已过期时返回 ErrNotFound
+func (m *Manager) Load(c *gin.Context) (*Session, error) {
+	token, err := c.Cookie(m.cookie)
+	if err != nil || token == "" {
+		return nil, ErrNotFound
+	}
+	hash := auth.HashToken(token)
+	s, err := m.store.Get(c.Request.Context(), hash)
+	if err != nil {
+		return nil, err
+	}
+	s.Token = token
+
+	now := time.Now()
+	if s.ExpiresAt.Sub(now) < m.ttl/2 {
+		s.ExpiresAt = now.Add(m.ttl)
+		if err := m.store.Save(c.Request.Context(), hash, s); err != nil {
+			return nil, err
+		}
+		m.setCookie(c, token, s.ExpiresAt)
+	}
+	return s, nil
+}
+
+// Logout 删除请求的会话并清除 Cookie，请求没有会话 Cookie 时不做处理
+func (m *Manager) Logout(c *gin.Context) error {
+	token, err := c.Cookie(m.cookie)
+	if err != nil || token == "" {
+		return nil
+	}
+	m.setCookie(c, "", time.Time{})
+	return m.store.Delete(c.Request.Context(), auth.HashToken(token))
+}
+
+// LogoutUser 删除用户的全部会话（例如重置密码后），其他客户端的会话随之失效
+func (m *Manager) LogoutUser(ctx context.Context, userID uint) error {
+	return m.store.DeleteUser(ctx, userID)
+}
+
+// Secure 会话 Cookie 是否只通过 HTTPS 发送，登录过程中的其他 Cookie 应与它一致
+func (m *Manager) Secure() bool {
+	return m.secure
+}
+
+// setCookie 写入会话 Cookie，expires 为零值时删除 Cookie
+func (m *Manager) setCookie(c *gin.Context, token string, expires time.Time) {
+	cookie := &http.Cookie{
+		Name:     m.cookie,
+		Value:    token,
+		Path:     "/",
+		Domain:   m.domain,
+		Secure:   m.secure,
+		HttpOnly: true,
+		SameSite: m.sameSite,
+	}
+	if expires.IsZero() {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = expires
+		cookie.MaxAge = int(time.Until(expires).Seconds())
+	}
+	http.SetCookie(c.Writer, cookie)
+}
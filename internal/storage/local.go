@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-viewset/internal/config"
+	"go-viewset/internal/utils"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Local 本地磁盘存储
+// 下载地址为 URLPrefix/key?expires=...&name=...&signature=...，由 Handler 校验签名后返回文件
+type Local struct {
+	dir    string
+	prefix string
+	secret []byte
+}
+
+// NewLocal 创建本地磁盘存储，目录不存在时创建
+func NewLocal(cfg config.LocalStorageConfig) (*Local, error) {
+	l := &Local{dir: cfg.Dir, prefix: strings.TrimSuffix(cfg.URLPrefix, "/")}
+	if l.dir == "" {
+		l.dir = "uploads"
+	}
+	if l.prefix == "" {
+		l.prefix = "/files"
+	}
+	if cfg.Secret != "" {
+		l.secret = []byte(cfg.Secret)
+	} else {
+		l.secret = make([]byte, 32)
+		if _, err := rand.Read(l.secret); err != nil {
+			return nil, err
+		}
+		log.Printf("未配置 attachment.local.secret，使用随机密钥签名下载地址（重启后之前的地址失效）")
+	}
+	if err := os.MkdirAll(l.dir, 0o755); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Name 实现 Storage
+func (l *Local) Name() string {
+	return "local"
+}
+
+// Prefix 下载地址的路径前缀，Handler 需要注册在 Prefix + "/*key"
+func (l *Local) Prefix() string {
+	return l.prefix
+}
+
+// path key 对应的文件路径，key 不能跳出存储目录
+func (l *Local) path(key string) (string, error) {
+	if !fs.ValidPath(key) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put 实现 Storage，先写入临时文件再重命名，避免读到写了一半的文件
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Open 实现 Storage
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete 实现 Storage
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL 实现 Storage
+func (l *Local) URL(key, name string, expires time.Time) (string, error) {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", exp)
+	if name != "" {
+		query.Set("name", name)
+	}
+	query.Set("signature", l.sign(key, name, exp))
+	return l.prefix + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// sign HMAC-SHA256(secret, key + "\n" + name + "\n" + expires) 的十六进制
+func (l *Local) sign(key, name, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + name + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Handler 校验签名和有效期后返回文件，注册在 GET Prefix + "/*key"
+// 签名无效返回 403，已过期返回 410
+func (l *Local) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		exp := c.Query("expires")
+		name := c.Query("name")
+		if !hmac.Equal([]byte(c.Query("signature")), []byte(l.sign(key, name, exp))) {
+			utils.Forbidden(c, "下载地址无效")
+			return
+		}
+		expires, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil || time.Now().Unix() > expires {
+			utils.ErrorWithStatus(c, http.StatusGone, http.StatusGone, "下载地址已过期")
+			return
+		}
+
+		file, err := l.path(key)
+		if err != nil {
+			utils.NotFound(c, "文件不存在")
+			return
+		}
+		if _, err := os.Stat(file); err != nil {
+			utils.NotFound(c, "文件不存在")
+			return
+		}
+		if name == "" {
+			name = path.Base(key)
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(max(expires-time.Now().Unix(), 0), 10))
+		c.File(file)
+	}
+}
@@ -0,0 +1,50 @@
+// Package storage 附件的存储后端：本地磁盘（local）和 S3 兼容的对象存储（s3）
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-viewset/internal/config"
+	"io"
+	"time"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("文件不存在")
+
+// Storage 存储后端
+// key 为 / 分隔的相对路径，例如 2026/10/16/5f0c...e1.png，由调用方生成
+type Storage interface {
+	// Name 存储后端的名称，记录在附件中
+	Name() string
+	// Put 保存文件，size 为文件大小（S3 需要 Content-Length）
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取文件，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// URL 生成在 expires 之前有效的签名下载地址，name 为下载时的文件名（Content-Disposition）
+	URL(key, name string, expires time.Time) (string, error)
+}
+
+// Open 按配置创建存储后端
+func Open(cfg config.AttachmentConfig) (Storage, error) {
+	switch cfg.Storage {
+	case "", "local":
+		return NewLocal(cfg.Local)
+	case "s3":
+		return NewS3(cfg.S3)
+	}
+	return nil, fmt.Errorf("不支持的存储类型 %q（支持 local、s3）", cfg.Storage)
+}
+
+// Expires 有效期为 ttl 的下载地址的过期时间
+// 按 ttl/2 取整：同一时间窗口内生成的地址相同，响应可以被缓存（包括 ETag），地址至少还有 ttl/2 的有效期
+func Expires(ttl time.Duration) time.Time {
+	window := ttl / 2
+	if window <= 0 {
+		return time.Now().Add(ttl)
+	}
+	return time.Now().Truncate(window).Add(ttl)
+}
@@ -0,0 +1,73 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket 一个 key 的令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket 令牌桶限流器（进程内）
+// 桶容量为 rate.Limit，令牌以 rate.Limit/rate.Period 的速度匀速补充，
+// 允许短时间内的突发请求，长期平均速率不超过 rate
+type TokenBucket struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	maxAge  time.Duration
+}
+
+// NewTokenBucket 创建 TokenBucket
+func NewTokenBucket() *TokenBucket {
+	return &TokenBucket{buckets: make(map[string]*bucket)}
+}
+
+// Allow 从 key 的桶中取一个令牌
+// 没有令牌时返回下一个令牌补充到的时间，供 Retry-After 使用
+func (l *TokenBucket) Allow(key string, rate Rate) (bool, time.Duration) {
+	now := time.Now()
+	perToken := rate.Period / time.Duration(rate.Limit)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rate.Period > l.maxAge {
+		l.maxAge = rate.Period
+	}
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rate.Limit), last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens += float64(now.Sub(b.last)) / float64(perToken)
+		if b.tokens > float64(rate.Limit) {
+			b.tokens = float64(rate.Limit)
+		}
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep 清理已经补满的桶，调用方需持有锁
+// 超过最长周期没有请求的桶一定已经补满，删除后与新建的桶等价
+func (l *TokenBucket) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.maxAge {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"go-viewset/internal/redis"
+)
+
+// Store 限流计数的存储
+// Allow 判断 key 在 rate 限制下能否再执行一次，不允许时返回需要等待的时间
+type Store interface {
+	Allow(key string, rate Rate) (bool, time.Duration)
+}
+
+// Default ViewSet 限流（Throttles、ThrottleClasses）默认使用的存储
+// 多实例部署时可以替换为 RedisStore，使限流在实例之间共享
+var Default Store = NewLimiter()
+
+// redisTimeout 单次 Redis 限流检查的超时时间
+const redisTimeout = 500 * time.Millisecond
+
+// redisWindowScript 固定窗口计数：窗口内第一次请求时设置过期时间，返回计数和剩余时间（毫秒）
+const redisWindowScript = `
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {n, redis.call('PTTL', KEYS[1])}`
+
+// RedisStore 基于 Redis 的固定窗口限流，与 Limiter 的行为一致，计数在所有实例之间共享
+// Redis 不可用时放行请求（只输出日志），避免限流存储故障导致服务不可用
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore 创建 RedisStore，prefix 为所有 key 的前缀
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Allow 实现 Store
+func (s *RedisStore) Allow(key string, rate Rate) (bool, time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	count, ttl, err := s.incr(ctx, s.prefix+"throttle:"+key, rate.Period)
+	if err != nil {
+		log.Printf("限流检查失败，放行请求: %v", err)
+		return true, 0
+	}
+	if count > int64(rate.Limit) {
+		if ttl < 0 {
+			ttl = 0
+		}
+		return false, ttl
+	}
+	return true, 0
+}
+
+// incr 增加 key 在当前窗口内的计数，返回计数和窗口剩余时间
+func (s *RedisStore) incr(ctx context.Context, key string, period time.Duration) (int64, time.Duration, error) {
+	reply, err := s.client.Do(ctx, "EVAL", redisWindowScript, "1", key, strconv.FormatInt(period.Milliseconds(), 10))
+	if err != nil {
+		return 0, 0, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 2 {
+		return 0, 0, fmt.Errorf("redis: 非预期的回复 %v", reply)
+	}
+	count, ok1 := items[0].(int64)
+	ttl, ok2 := items[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, 0, fmt.Errorf("redis: 非预期的回复 %v", reply)
+	}
+	return count, time.Duration(ttl) * time.Millisecond, nil
+}
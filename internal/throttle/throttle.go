@@ -0,0 +1,103 @@
+package throttle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate 限流速率，例如 3/hour 表示每小时最多 3 次
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// periods 支持的时间单位
+var periods = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hour": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour,
+}
+
+// ParseRate 解析 "次数/单位" 格式的速率，例如 "3/hour"、"10/minute"、"100/d"
+func ParseRate(s string) (Rate, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "/", 2)
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("无效的限流速率: %q", s)
+	}
+
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return Rate{}, fmt.Errorf("无效的限流次数: %q", s)
+	}
+
+	period, ok := periods[strings.ToLower(parts[1])]
+	if !ok {
+		return Rate{}, fmt.Errorf("无效的限流周期: %q", s)
+	}
+
+	return Rate{Limit: limit, Period: period}, nil
+}
+
+// window 一个 key 在当前固定窗口内的计数
+type window struct {
+	start time.Time
+	count int
+}
+
+// sweepEvery 每处理多少次请求清理一次过期的窗口
+const sweepEvery = 1024
+
+// Limiter 固定窗口限流器（进程内）
+type Limiter struct {
+	mu      sync.Mutex
+	windows map[string]*window
+	calls   int
+	maxAge  time.Duration
+}
+
+// NewLimiter 创建 Limiter
+func NewLimiter() *Limiter {
+	return &Limiter{windows: make(map[string]*window)}
+}
+
+// Allow 判断 key 在 rate 限制下能否再执行一次
+// 不允许时返回距离窗口重置的时间，供 Retry-After 使用
+func (l *Limiter) Allow(key string, rate Rate) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rate.Period > l.maxAge {
+		l.maxAge = rate.Period
+	}
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= rate.Period {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= rate.Limit {
+		return false, w.start.Add(rate.Period).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// sweep 清理已经过期的窗口，调用方需持有锁
+func (l *Limiter) sweep(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.maxAge {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize     = 512              // 攒够多少个 span 立即发送
+	flushInterval = 5 * time.Second  // 最长发送间隔
+	queueSize     = 4096             // 队列满时丢弃新的 span
+	exportTimeout = 10 * time.Second // 单次发送的超时时间
+)
+
+// exporter 以 OTLP/HTTP JSON 格式批量发送 span（POST {endpoint}/v1/traces）
+type exporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+
+	queue chan *Span
+	done  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// newExporter 创建导出器并启动后台发送
+func newExporter(endpoint, serviceName string) *exporter {
+	e := &exporter{
+		url:         strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		queue:       make(chan *Span, queueSize),
+		done:        make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// enqueue 加入发送队列，队列已满时丢弃
+func (e *exporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+	}
+}
+
+// run 后台按批发送
+func (e *exporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			log.Printf("发送 trace 失败: %v", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case s := <-e.queue:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown 发送剩余的 span，ctx 结束时不再等待
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.once.Do(func() { close(e.done) })
+
+	finished := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// export 发送一批 span
+func (e *exporter) export(spans []*Span) error {
+	body, err := json.Marshal(e.payload(spans))
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s 返回 %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+// payload 生成 ExportTraceServiceRequest 的 JSON 结构
+func (e *exporter) payload(spans []*Span) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		item := map[string]interface{}{
+			"traceId":           s.Context.TraceID.String(),
+			"spanId":            s.Context.SpanID.String(),
+			"name":              s.Name,
+			"kind":              int(s.Kind),
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        attributes(s.attributes),
+		}
+		if s.ParentID != (SpanID{}) {
+			item["parentSpanId"] = s.ParentID.String()
+		}
+		if s.errMsg != "" {
+			item["status"] = map[string]interface{}{"code": 2, "message": s.errMsg}
+		}
+		s.mu.Unlock()
+		items = append(items, item)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": attributes(map[string]interface{}{"service.name": e.serviceName}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "go-viewset/internal/tracing"},
+						"spans": items,
+					},
+				},
+			},
+		},
+	}
+}
+
+// attributes 转换为 OTLP 的 KeyValue 列表
+func attributes(attrs map[string]interface{}) []interface{} {
+	list := make([]interface{}, 0, len(attrs))
+	for key, value := range attrs {
+		var v map[string]interface{}
+		switch value := value.(type) {
+		case bool:
+			v = map[string]interface{}{"boolValue": value}
+		case int:
+			v = map[string]interface{}{"intValue": strconv.Itoa(value)}
+		case int64:
+			v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+		case float64:
+			v = map[string]interface{}{"doubleValue": value}
+		default:
+			v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+		}
+		list = append(list, map[string]interface{}{"key": key, "value": v})
+	}
+	return list
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TraceparentHeader W3C Trace Context 请求头
+const TraceparentHeader = "traceparent"
+
+// Middleware 为每个请求创建 server span，沿用请求头 traceparent 中的 trace
+// span 放入 c.Request 的 context，ViewSet action 和通过请求 context 执行的 SQL 都是它的子 span
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parent, _ := ParseTraceparent(c.GetHeader(TraceparentHeader))
+		ctx, span := Default().StartWithParent(c.Request.Context(), parent, c.Request.Method, KindServer)
+		if span == nil {
+			c.Next()
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(TraceparentHeader, span.Context.Traceparent())
+
+		c.Next()
+
+		// 路由匹配后才能得到路由模板，按 "GET /api/users/:id" 命名
+		route := c.FullPath()
+		if route != "" {
+			span.Name = c.Request.Method + " " + route
+		}
+		status := c.Writer.Status()
+		span.SetAttribute("http.request.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("url.path", c.Request.URL.Path)
+		span.SetAttribute("http.response.status_code", status)
+		if status >= 500 {
+			span.SetError(fmt.Errorf("HTTP %d", status))
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,252 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanKind span 类型，取值与 OTLP 一致
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// TraceID 16 字节的 trace ID
+type TraceID [16]byte
+
+// SpanID 8 字节的 span ID
+type SpanID [8]byte
+
+// String 十六进制表示
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// String 十六进制表示
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// SpanContext 在进程间传递的 span 信息（见 W3C Trace Context）
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// Traceparent 生成 traceparent 请求头
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent 解析 traceparent 请求头，格式不正确时返回 false
+func ParseTraceparent(header string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil || sc.TraceID == (TraceID{}) {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil || sc.SpanID == (SpanID{}) {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// Span 一次操作的耗时和属性
+// 未采样的 span 只用于传递 trace ID，不记录属性也不导出
+type Span struct {
+	tracer *Tracer
+
+	Name     string
+	Kind     SpanKind
+	Context  SpanContext
+	ParentID SpanID
+	Start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes map[string]interface{}
+	errMsg     string
+	ended      bool
+}
+
+// Recording span 是否会被导出
+func (s *Span) Recording() bool {
+	return s != nil && s.Context.Sampled
+}
+
+// SetAttribute 设置属性，值为 string、bool、int、int64 或 float64
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if !s.Recording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]interface{})
+	}
+	s.attributes[key] = value
+}
+
+// SetError 将 span 标记为失败
+func (s *Span) SetError(err error) {
+	if err == nil || !s.Recording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errMsg = err.Error()
+}
+
+// End 结束 span 并交给导出器，重复调用无效
+func (s *Span) End() {
+	if !s.Recording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.exporter.enqueue(s)
+}
+
+// spanKey span 在 context 中的 key
+type spanKey struct{}
+
+// ContextWithSpan 将 span 放入 context
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext 取出 context 中的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Tracer 创建 span，按采样率决定是否导出
+type Tracer struct {
+	sampleRate float64
+	exporter   *exporter
+}
+
+// Config Tracer 配置
+type Config struct {
+	ServiceName string
+	Endpoint    string  // OTLP/HTTP 地址，例如 http://localhost:4318
+	SampleRate  float64 // 新 trace 的采样率 0~1，上游已决定采样时沿用上游的结果
+}
+
+// New 创建 Tracer，span 以 OTLP/HTTP JSON 格式批量发送到 cfg.Endpoint
+func New(cfg Config) (*Tracer, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("tracing endpoint 不能为空")
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, fmt.Errorf("无效的采样率 %v，取值范围为 0~1", cfg.SampleRate)
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "go-viewset"
+	}
+	return &Tracer{
+		sampleRate: cfg.SampleRate,
+		exporter:   newExporter(cfg.Endpoint, cfg.ServiceName),
+	}, nil
+}
+
+// Start 创建 span，ctx 中已有 span 时作为其子 span，返回包含新 span 的 context
+// t 为 nil（未开启追踪）时原样返回 ctx 和 nil，Span 的方法都可以在 nil 上调用
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if parent := SpanFromContext(ctx); parent != nil {
+		return t.StartWithParent(ctx, parent.Context, name, kind)
+	}
+	return t.StartWithParent(ctx, SpanContext{}, name, kind)
+}
+
+// StartWithParent 以 parent 为父 span 创建 span，parent 为空时开始新的 trace
+func (t *Tracer) StartWithParent(ctx context.Context, parent SpanContext, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{tracer: t, Name: name, Kind: kind, Start: time.Now()}
+	if parent.TraceID != (TraceID{}) {
+		span.Context.TraceID = parent.TraceID
+		span.Context.Sampled = parent.Sampled
+		span.ParentID = parent.SpanID
+	} else {
+		rand.Read(span.Context.TraceID[:])
+		span.Context.Sampled = t.sample(span.Context.TraceID)
+	}
+	rand.Read(span.Context.SpanID[:])
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample 根据 trace ID 决定是否采样，同一个 trace 的结果总是一致
+func (t *Tracer) sample(id TraceID) bool {
+	if t.sampleRate >= 1 {
+		return true
+	}
+	var n uint64
+	for _, b := range id[8:] {
+		n = n<<8 | uint64(b)
+	}
+	return float64(n>>11)/float64(uint64(1)<<53) < t.sampleRate
+}
+
+// Shutdown 发送剩余的 span 并停止导出
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.exporter.shutdown(ctx)
+}
+
+// 默认的 Tracer，由 SetDefault 设置，未设置时不记录任何 span
+var (
+	defaultMu     sync.RWMutex
+	defaultTracer *Tracer
+)
+
+// SetDefault 设置默认的 Tracer
+func SetDefault(t *Tracer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTracer = t
+}
+
+// Default 返回默认的 Tracer，可能为 nil
+func Default() *Tracer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultTracer
+}
+
+// Start 使用默认的 Tracer 创建 span
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	return Default().Start(ctx, name, kind)
+}
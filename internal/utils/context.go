@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"context"
+)
+
+// requestInfoKey 请求信息在 context 中的 key
+type requestInfoKey struct{}
+
+// RequestInfo 请求信息
+// 由中间件写入请求的 context，供数据库回调等无法访问 gin.Context 的地方使用
+type RequestInfo struct {
+	ID     string
+	Method string
+	Route  string
+}
+
+// WithRequestInfo 将请求信息写入 context
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom 从 context 中读取请求信息
+func RequestInfoFrom(ctx context.Context) (RequestInfo, bool) {
+	if ctx == nil {
+		return RequestInfo{}, false
+	}
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// EstimateCount 根据数据库执行计划估算查询结果的行数，不实际扫描数据
+// MySQL 使用 EXPLAIN 的 rows × filtered，PostgreSQL 使用 EXPLAIN (FORMAT JSON) 的 Plan Rows；
+// 其他数据库不支持估算，返回 false，调用方应退回 COUNT 查询
+func EstimateCount(db *gorm.DB) (int64, bool, error) {
+	var explain string
+	switch db.Dialector.Name() {
+	case "mysql":
+		explain = "EXPLAIN "
+	case "postgres":
+		explain = "EXPLAIN (FORMAT JSON) "
+	default:
+		return 0, false, nil
+	}
+
+	// 只生成 SELECT 语句，参数仍然通过占位符传入
+	stmt := db.Session(&gorm.Session{DryRun: true}).Find(&[]map[string]interface{}{}).Statement
+	if stmt.Error != nil {
+		return 0, true, stmt.Error
+	}
+	raw := db.Session(&gorm.Session{NewDB: true}).Raw(explain+stmt.SQL.String(), stmt.Vars...)
+
+	if db.Dialector.Name() == "postgres" {
+		var plan string
+		if err := raw.Row().Scan(&plan); err != nil {
+			return 0, true, err
+		}
+		var result []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal([]byte(plan), &result); err != nil || len(result) == 0 {
+			return 0, true, fmt.Errorf("无法解析执行计划: %s", plan)
+		}
+		return int64(result[0].Plan.Rows), true, nil
+	}
+
+	var rows []map[string]interface{}
+	if err := raw.Scan(&rows).Error; err != nil {
+		return 0, true, err
+	}
+	if len(rows) == 0 {
+		return 0, true, nil
+	}
+	estimated := explainNumber(rows[0]["rows"])
+	if filtered := explainNumber(rows[0]["filtered"]); filtered > 0 {
+		estimated = estimated * filtered / 100
+	}
+	return int64(estimated), true, nil
+}
+
+// explainNumber 读取 EXPLAIN 结果中的数值列，驱动可能以数字、字符串或 []byte 返回
+func explainNumber(value interface{}) float64 {
+	switch x := value.(type) {
+	case int64:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	case float64:
+		return x
+	case float32:
+		return float64(x)
+	case []byte:
+		f, _ := strconv.ParseFloat(string(x), 64)
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(x, 64)
+		return f
+	}
+	return 0
+}
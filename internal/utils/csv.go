@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"encoding/csv"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WantCSV 客户端是否请求 CSV 格式：?format=csv 或 Accept: text/csv
+func WantCSV(c *gin.Context) bool {
+	if format := c.Query("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	for _, item := range strings.Split(c.GetHeader("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(item)); err == nil && mediaType == "text/csv" {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamCSV 以附件形式流式写出 CSV，第一行为 header
+// 记录通过 write 逐行写出，每 streamFlushEvery 行刷新一次缓冲区；
+// iterate 中返回的错误无法再改变已经写出的状态码，只会记录到 c.Errors 中。
+func StreamCSV(c *gin.Context, filename string, header []string, iterate func(write func(record []string) error) error) error {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	count := 0
+	err := iterate(func(record []string) error {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		count++
+		if count%streamFlushEvery == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+		return w.Error()
+	})
+	if err != nil {
+		c.Error(err)
+	}
+
+	w.Flush()
+	c.Writer.Flush()
+	if werr := w.Error(); werr != nil {
+		return werr
+	}
+	return err
+}
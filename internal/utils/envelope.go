@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseConfig 响应外层结构的配置
+// 零值为默认的 {"code":0,"msg":"success","data":...,"pagination":...,"errors":...}
+type ResponseConfig struct {
+	// DisableEnvelope 成功响应直接返回 data（对象或数组），分页信息通过响应头
+	// X-Page、X-Page-Size、X-Total-Count、X-Next-Cursor 返回；错误响应仍使用外层结构
+	DisableEnvelope bool
+
+	// SuccessCode 成功响应的 code，默认 0
+	SuccessCode int
+
+	// 外层结构的字段名，为空时使用默认名称
+	CodeKey       string
+	MsgKey        string
+	DataKey       string
+	PaginationKey string
+	ErrorsKey     string
+}
+
+// withDefaults 补全默认的字段名
+func (cfg ResponseConfig) withDefaults() ResponseConfig {
+	for _, f := range []struct {
+		key *string
+		def string
+	}{
+		{&cfg.CodeKey, "code"},
+		{&cfg.MsgKey, "msg"},
+		{&cfg.DataKey, "data"},
+		{&cfg.PaginationKey, "pagination"},
+		{&cfg.ErrorsKey, "errors"},
+	} {
+		if *f.key == "" {
+			*f.key = f.def
+		}
+	}
+	return cfg
+}
+
+var (
+	// responseConfig 当前的响应配置（已补全默认值）
+	responseConfig = ResponseConfig{}.withDefaults()
+
+	// customEnvelope 外层结构与默认的 Response 不同，需要按配置生成
+	customEnvelope bool
+)
+
+// SetResponseConfig 设置全局的响应结构，应在服务启动前调用
+func SetResponseConfig(cfg ResponseConfig) error {
+	cfg = cfg.withDefaults()
+
+	seen := make(map[string]bool)
+	for _, key := range []string{cfg.CodeKey, cfg.MsgKey, cfg.DataKey, cfg.PaginationKey, cfg.ErrorsKey} {
+		if seen[key] {
+			return fmt.Errorf("响应结构的字段名重复: %s", key)
+		}
+		seen[key] = true
+	}
+
+	responseConfig = cfg
+	customEnvelope = cfg != ResponseConfig{}.withDefaults()
+	return nil
+}
+
+// CurrentResponseConfig 返回当前的响应配置（已补全默认值），前端页面按它解析响应
+func CurrentResponseConfig() ResponseConfig {
+	return responseConfig
+}
+
+// writeEnvelope 按响应配置写出响应，code 为 0 表示成功（替换为 SuccessCode）
+func writeEnvelope(c *gin.Context, httpStatus int, code int, msg string, data interface{}, pagination *Pagination, errs ErrorMap) {
+	success := code == 0
+	if success {
+		code = responseConfig.SuccessCode
+	}
+
+	if success && responseConfig.DisableEnvelope {
+		setPaginationHeaders(c, pagination)
+		Render(c, httpStatus, bareData{data: wrapMarshaler(data), pagination: pagination})
+		return
+	}
+
+	if customEnvelope {
+		Render(c, httpStatus, envelope{code: code, msg: msg, data: wrapMarshaler(data), pagination: pagination, errors: errs})
+		return
+	}
+
+	// 默认结构：响应结构从池中获取，渲染时同步完成序列化后即可归还
+	resp := acquireResponse()
+	defer releaseResponse(resp)
+
+	resp.Code = code
+	resp.Msg = msg
+	resp.Data = wrapMarshaler(data)
+	resp.Pagination = pagination
+	resp.Errors = errs
+	Render(c, httpStatus, resp)
+}
+
+// setPaginationHeaders 通过响应头返回分页信息（关闭外层结构时使用）
+func setPaginationHeaders(c *gin.Context, p *Pagination) {
+	if p == nil {
+		return
+	}
+	if p.Page > 0 {
+		c.Header("X-Page", strconv.Itoa(p.Page))
+	}
+	c.Header("X-Page-Size", strconv.Itoa(p.PageSize))
+	if p.Total != nil {
+		c.Header("X-Total-Count", strconv.FormatInt(*p.Total, 10))
+	}
+	if p.NextCursor != "" {
+		c.Header("X-Next-Cursor", p.NextCursor)
+	}
+}
+
+// bareData 不带外层结构的 data，nil 序列化为 null
+type bareData struct {
+	data       interface{}
+	pagination *Pagination // 已通过响应头返回，供 ResponseParts 使用
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (b bareData) MarshalJSON() ([]byte, error) {
+	return jsonEncoder.Marshal(b.data)
+}
+
+// envelope 按 ResponseConfig 的字段名生成的外层结构
+type envelope struct {
+	code       int
+	msg        string
+	data       interface{}
+	pagination *Pagination
+	errors     ErrorMap
+}
+
+// MarshalJSON 实现 json.Marshaler，字段顺序与默认结构一致，空的 data、pagination、errors 省略
+func (e envelope) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeMember(&buf, responseConfig.CodeKey, strconv.Itoa(e.code), true)
+	msg, _ := json.Marshal(e.msg)
+	writeMember(&buf, responseConfig.MsgKey, string(msg), false)
+
+	members := []struct {
+		key   string
+		value interface{}
+		empty bool
+	}{
+		{responseConfig.DataKey, e.data, e.data == nil},
+		{responseConfig.PaginationKey, e.pagination, e.pagination == nil},
+		{responseConfig.ErrorsKey, e.errors, len(e.errors) == 0},
+	}
+	for _, m := range members {
+		if m.empty {
+			continue
+		}
+		body, err := jsonEncoder.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		writeMember(&buf, m.key, string(body), false)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// ResponseParts 响应的各部分，供需要重新组织响应结构的 Renderer 使用（例如 JSON:API）
+type ResponseParts struct {
+	Success    bool
+	Code       int
+	Msg        string
+	Data       interface{}
+	Pagination *Pagination
+	Errors     ErrorMap
+}
+
+// PartsOf 从传给 Renderer 的响应中取出各部分，v 不是响应辅助函数生成的响应时返回 false
+func PartsOf(v interface{}) (ResponseParts, bool) {
+	switch r := v.(type) {
+	case *Response:
+		return ResponseParts{Success: r.Code == responseConfig.SuccessCode, Code: r.Code, Msg: r.Msg, Data: r.Data, Pagination: r.Pagination, Errors: r.Errors}, true
+	case envelope:
+		return ResponseParts{Success: r.code == responseConfig.SuccessCode, Code: r.code, Msg: r.msg, Data: r.data, Pagination: r.pagination, Errors: r.errors}, true
+	case bareData:
+		return ResponseParts{Success: true, Code: responseConfig.SuccessCode, Data: r.data, Pagination: r.pagination}, true
+	}
+	return ResponseParts{}, false
+}
+
+// writeMember 写出 "key":value，value 为已序列化的 JSON
+func writeMember(buf *bytes.Buffer, key, value string, first bool) {
+	if !first {
+		buf.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.WriteString(value)
+}
+
+// streamPrefix 流式列表响应中 data 数组之前的内容（见 StreamWithPagination）
+func streamPrefix(c *gin.Context, pagination *Pagination) string {
+	if responseConfig.DisableEnvelope {
+		setPaginationHeaders(c, pagination)
+		return "["
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeMember(&buf, responseConfig.CodeKey, strconv.Itoa(responseConfig.SuccessCode), true)
+	writeMember(&buf, responseConfig.MsgKey, `"success"`, false)
+	writeMember(&buf, responseConfig.DataKey, "[", false)
+	return buf.String()
+}
+
+// streamSuffix 流式列表响应中 data 数组之后的内容
+func streamSuffix(pagination *Pagination) (string, error) {
+	if responseConfig.DisableEnvelope {
+		return "]", nil
+	}
+	body, err := MarshalJSON(pagination)
+	if err != nil {
+		return "", err
+	}
+	k, _ := json.Marshal(responseConfig.PaginationKey)
+	return "]," + string(k) + ":" + string(body) + "}", nil
+}
@@ -1,36 +1,69 @@
 package utils
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// OrderField 一个排序字段
+type OrderField struct {
+	Field string
+	Desc  bool
+}
+
+// FilterTerm OR 条件组中的一个条件
+type FilterTerm struct {
+	Key   string
+	Value interface{}
+}
+
 // FilterParams 过滤参数
 type FilterParams struct {
 	Filters  map[string]interface{}
-	OrderBy  string
-	OrderDir string
+	Ordering []OrderField
+
+	// OrGroups OR 条件组，组内的条件之间为 OR，组与组、组与其他过滤条件之间为 AND
+	OrGroups [][]FilterTerm
+
+	// Conditions 类型化的过滤条件（见 BindFilterSet）
+	Conditions []Condition
+
+	// Search ?search= 的搜索词，在 SearchFields 列上搜索，任一列匹配即可（见 search.go）
+	// SearchFields 由调用方在校验字段后设置，为空时不搜索；SearchMode 为搜索方式
+	Search       string
+	SearchFields []string
+	SearchMode   SearchMode
 }
 
 // GetFilterParams 从 gin.Context 中获取过滤参数
 // 支持：
-// 1. 简单的等值过滤：?name=abc&status=active
-// 2. 排序：?order_by=created_at desc 或 ?ordering=-created_at
+// 1. 简单的等值过滤：?name=abc&status=active，以及 lookup 过滤：?age__gte=18&name__contains=foo&status__in=active,inactive（见 lookup.go）
+// 2. OR 条件组：?or=(status=active,age=60)，可以出现多次
+// 3. 排序：?order_by=status asc,created_at desc 或 ?ordering=-created_at,name（多个字段用逗号分隔）
+// 4. 搜索：?search=keyword，只读取搜索词，搜索的列由调用方设置到 SearchFields
 func GetFilterParams(c *gin.Context, excludeKeys ...string) *FilterParams {
-	params := &FilterParams{
-		Filters: make(map[string]interface{}),
-	}
+	params := acquireFilterParams()
 
 	// 需要排除的特殊参数
 	excludeMap := map[string]bool{
-		"page":      true,
-		"page_size": true,
-		"limit":     true,
-		"offset":    true,
-		"order_by":  true,
-		"ordering":  true,
+		"page":       true,
+		"page_size":  true,
+		"limit":      true,
+		"offset":     true,
+		"cursor":     true,
+		"fields":     true,
+		"expand":     true,
+		"format":     true,
+		"order_by":   true,
+		"ordering":   true,
+		"with_count": true,
+		"count":      true,
+		"or":         true,
+		"search":     true,
 	}
 
 	// 添加用户自定义的排除参数
@@ -49,53 +82,146 @@ func GetFilterParams(c *gin.Context, excludeKeys ...string) *FilterParams {
 		}
 	}
 
+	params.Search = strings.TrimSpace(c.Query("search"))
+
+	// 处理 OR 条件组
+	for _, value := range c.QueryArray("or") {
+		if group := parseOrGroup(value); len(group) > 0 {
+			params.OrGroups = append(params.OrGroups, group)
+		}
+	}
+
 	// 处理排序参数
-	// 支持两种格式：
-	// 1. order_by=created_at desc
-	// 2. ordering=-created_at (DRF 风格)
+	// 支持两种格式，多个字段用逗号分隔：
+	// 1. order_by=status asc,created_at desc（方向无效时按 ASC）
+	// 2. ordering=-created_at,name (DRF 风格)
 	if orderBy := c.Query("order_by"); orderBy != "" {
-		parts := strings.Fields(orderBy)
-		if len(parts) > 0 {
-			params.OrderBy = parts[0]
-			if len(parts) > 1 {
-				params.OrderDir = strings.ToUpper(parts[1])
-			} else {
-				params.OrderDir = "ASC"
+		for _, item := range strings.Split(orderBy, ",") {
+			parts := strings.Fields(item)
+			if len(parts) == 0 {
+				continue
 			}
+			desc := len(parts) > 1 && strings.ToUpper(parts[1]) == "DESC"
+			params.Ordering = append(params.Ordering, OrderField{Field: parts[0], Desc: desc})
 		}
 	} else if ordering := c.Query("ordering"); ordering != "" {
-		if strings.HasPrefix(ordering, "-") {
-			params.OrderBy = ordering[1:]
-			params.OrderDir = "DESC"
-		} else {
-			params.OrderBy = ordering
-			params.OrderDir = "ASC"
+		for _, item := range strings.Split(ordering, ",") {
+			item = strings.TrimSpace(item)
+			desc := strings.HasPrefix(item, "-")
+			item = strings.TrimPrefix(item, "-")
+			if item == "" {
+				continue
+			}
+			params.Ordering = append(params.Ordering, OrderField{Field: item, Desc: desc})
 		}
 	}
 
-	// 验证排序方向
-	if params.OrderDir != "" && params.OrderDir != "ASC" && params.OrderDir != "DESC" {
-		params.OrderDir = "ASC"
+	return params
+}
+
+// Signature 生成过滤条件的签名，相同过滤条件（与顺序无关）得到相同的签名
+// 排序不影响结果数量，因此不参与签名
+func (p *FilterParams) Signature() string {
+	keys := make([]string, 0, len(p.Filters))
+	for key := range p.Filters {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	return params
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%v&", key, p.Filters[key])
+	}
+	for _, cond := range p.Conditions {
+		fmt.Fprintf(&b, "%s%s%v&", cond.Column, cond.Op, cond.Value)
+	}
+	for _, group := range p.OrGroups {
+		b.WriteString("or=(")
+		for _, term := range group {
+			fmt.Fprintf(&b, "%s=%v,", term.Key, term.Value)
+		}
+		b.WriteString(")&")
+	}
+	if p.Search != "" && len(p.SearchFields) > 0 {
+		fmt.Fprintf(&b, "search=%s&", p.Search)
+	}
+	return b.String()
+}
+
+// parseOrGroup 解析 "(status=active,age=60)" 格式的 OR 条件组
+// 条件之间用逗号分隔，值中不能包含逗号；格式错误的条件被忽略
+func parseOrGroup(value string) []FilterTerm {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "("), ")")
+
+	var group []FilterTerm
+	for _, item := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		group = append(group, FilterTerm{Key: key, Value: strings.TrimSpace(val)})
+	}
+	return group
 }
 
 // ApplyFilters 对 GORM 查询应用过滤
 func ApplyFilters(db *gorm.DB, params *FilterParams) *gorm.DB {
-	// 应用等值过滤
+	// 应用等值和 lookup 过滤，无法解析的条件被忽略（需要报错时先调用 ValidateFilters）
 	for key, value := range params.Filters {
+		column, l, parsed, err := parseFilter(key, value)
+		if err != nil {
+			continue
+		}
 		// 使用参数化查询防止 SQL 注入
-		db = db.Where(key+" = ?", value)
+		db = l.Apply(db, column, parsed)
 	}
 
+	// 应用 OR 条件组，每组生成一个带括号的条件
+	for _, terms := range params.OrGroups {
+		var group *gorm.DB
+		for _, term := range terms {
+			column, l, parsed, err := parseFilter(term.Key, term.Value)
+			if err != nil {
+				continue
+			}
+			cond := l.Apply(db.Session(&gorm.Session{NewDB: true}), column, parsed)
+			if group == nil {
+				group = db.Session(&gorm.Session{NewDB: true}).Where(cond)
+			} else {
+				group = group.Or(cond)
+			}
+		}
+		if group != nil {
+			db = db.Where(group)
+		}
+	}
+
+	// 应用类型化的过滤条件
+	for _, cond := range params.Conditions {
+		switch cond.Op {
+		case "IN":
+			db = db.Where(cond.Column+" IN (?)", cond.Value)
+		case "LIKE":
+			db = db.Where(likeClause(cond.Column), cond.Value)
+		default:
+			db = db.Where(cond.Column+" "+cond.Op+" ?", cond.Value)
+		}
+	}
+
+	// 应用搜索，全文搜索且没有指定排序时按相关度排序
+	db = applySearch(db, params.SearchMode, params.SearchFields, params.Search, len(params.Ordering) == 0)
+
 	// 应用排序
-	if params.OrderBy != "" {
+	for _, order := range params.Ordering {
 		// 验证字段名，防止 SQL 注入
-		// 这里简单处理，实际项目中应该有白名单验证
-		orderClause := sanitizeOrderBy(params.OrderBy)
-		if params.OrderDir != "" {
-			orderClause += " " + params.OrderDir
+		// 白名单验证由调用方完成（见 GenericViewSet.resolveFilters）
+		orderClause := sanitizeOrderBy(order.Field)
+		if order.Desc {
+			orderClause += " DESC"
+		} else {
+			orderClause += " ASC"
 		}
 		db = db.Order(orderClause)
 	}
@@ -115,24 +241,3 @@ func sanitizeOrderBy(field string) string {
 	}
 	return result.String()
 }
-
-// ApplySearch 应用模糊搜索（可选功能）
-// 使用方式：?search=keyword
-func ApplySearch(db *gorm.DB, c *gin.Context, fields ...string) *gorm.DB {
-	search := c.Query("search")
-	if search == "" || len(fields) == 0 {
-		return db
-	}
-
-	// 构建 OR 查询
-	query := db
-	for i, field := range fields {
-		if i == 0 {
-			query = query.Where(field+" LIKE ?", "%"+search+"%")
-		} else {
-			query = query.Or(field+" LIKE ?", "%"+search+"%")
-		}
-	}
-
-	return query
-}
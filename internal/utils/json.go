@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// JSONEncoder JSON 序列化接口
+// 响应层统一通过它序列化，可以替换为 sonic、jsoniter 等更快的实现
+type JSONEncoder interface {
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// JSONEncoderFunc 将普通函数适配为 JSONEncoder
+// 例如：utils.SetJSONEncoder(utils.JSONEncoderFunc(sonic.Marshal))
+type JSONEncoderFunc func(v interface{}) ([]byte, error)
+
+// Marshal 实现 JSONEncoder
+func (f JSONEncoderFunc) Marshal(v interface{}) ([]byte, error) {
+	return f(v)
+}
+
+// jsonEncoder 当前使用的 JSON 序列化实现，默认使用标准库
+var jsonEncoder JSONEncoder = JSONEncoderFunc(json.Marshal)
+
+// typeMarshalers 按类型注册的序列化函数，reflect.Type -> func(interface{}) ([]byte, error)
+var typeMarshalers sync.Map
+
+// SetJSONEncoder 替换 JSON 序列化实现，应在服务启动前调用
+func SetJSONEncoder(enc JSONEncoder) {
+	jsonEncoder = enc
+}
+
+// RegisterMarshaler 为某个类型注册专用的序列化函数
+// 例如 easyjson 为模型生成的序列化代码。sample 为该类型的一个值，
+// 如 []*models.User{}；响应数据恰好是该类型时使用注册的函数序列化。
+func RegisterMarshaler(sample interface{}, fn func(v interface{}) ([]byte, error)) {
+	typeMarshalers.Store(reflect.TypeOf(sample), fn)
+}
+
+// MarshalJSON 使用当前配置的实现序列化 v
+func MarshalJSON(v interface{}) ([]byte, error) {
+	return jsonEncoder.Marshal(wrapMarshaler(v))
+}
+
+// registeredValue 包装使用注册函数序列化的值
+type registeredValue struct {
+	value interface{}
+	fn    func(v interface{}) ([]byte, error)
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (r registeredValue) MarshalJSON() ([]byte, error) {
+	return r.fn(r.value)
+}
+
+// wrapMarshaler 如果 v 的类型注册了专用序列化函数，则包装为 json.Marshaler
+func wrapMarshaler(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if fn, ok := typeMarshalers.Load(reflect.TypeOf(v)); ok {
+		return registeredValue{value: v, fn: fn.(func(v interface{}) ([]byte, error))}
+	}
+	return v
+}
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LookupSeparator 字段名与 lookup 之间的分隔符，例如 deleted_at__isnull
+const LookupSeparator = "__"
+
+// Lookup 一种过滤运算，例如 ?age__gte=18 中的 gte
+type Lookup struct {
+	// Parse 解析并校验查询参数中的值，为 nil 时原样使用字符串
+	Parse func(value string) (interface{}, error)
+	// Apply 将条件应用到查询，column 已经过白名单校验，value 为 Parse 的结果
+	Apply func(db *gorm.DB, column string, value interface{}) *gorm.DB
+}
+
+var (
+	lookupsMu sync.RWMutex
+	lookups   = map[string]Lookup{
+		"":      compareLookup("="),
+		"exact": compareLookup("="),
+		"not":   compareLookup("<>"),
+		"gt":    compareLookup(">"),
+		"gte":   compareLookup(">="),
+		"lt":    compareLookup("<"),
+		"lte":   compareLookup("<="),
+		"contains": {
+			Apply: func(db *gorm.DB, column string, value interface{}) *gorm.DB {
+				return db.Where(likeClause(column), "%"+escapeLike(value.(string))+"%")
+			},
+		},
+		"startswith": {
+			Apply: func(db *gorm.DB, column string, value interface{}) *gorm.DB {
+				return db.Where(likeClause(column), escapeLike(value.(string))+"%")
+			},
+		},
+		"in": {
+			Parse: parseList,
+			Apply: func(db *gorm.DB, column string, value interface{}) *gorm.DB {
+				return db.Where(column+" IN ?", value)
+			},
+		},
+		"not_in": {
+			Parse: parseList,
+			Apply: func(db *gorm.DB, column string, value interface{}) *gorm.DB {
+				return db.Where(column+" NOT IN ?", value)
+			},
+		},
+		"isnull": {
+			Parse: func(value string) (interface{}, error) {
+				b, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("只能是 true 或 false")
+				}
+				return b, nil
+			},
+			Apply: func(db *gorm.DB, column string, value interface{}) *gorm.DB {
+				if value.(bool) {
+					return db.Where(column + " IS NULL")
+				}
+				return db.Where(column + " IS NOT NULL")
+			},
+		},
+	}
+)
+
+// RegisterLookup 注册自定义 lookup，同名的会被覆盖，应在服务启动前调用，例如 ?created_at__year=2024：
+//
+//	utils.RegisterLookup("year", utils.Lookup{
+//		Apply: func(db *gorm.DB, column string, value interface{}) *gorm.DB {
+//			return db.Where("YEAR("+column+") = ?", value)
+//		},
+//	})
+func RegisterLookup(name string, l Lookup) {
+	lookupsMu.Lock()
+	defer lookupsMu.Unlock()
+	lookups[name] = l
+}
+
+// compareLookup 比较运算
+func compareLookup(op string) Lookup {
+	return Lookup{
+		Apply: func(db *gorm.DB, column string, value interface{}) *gorm.DB {
+			return db.Where(column+" "+op+" ?", value)
+		},
+	}
+}
+
+// likeEscape LIKE 的转义字符，通过 ESCAPE 子句显式指定：SQLite 没有默认的转义字符，
+// 而反斜杠在 MySQL 的字符串字面量中本身需要转义，'!' 在各数据库的字面量中含义相同
+const likeEscape = "!"
+
+// likeClause 返回 column LIKE ? ESCAPE '!'，参数需要经过 escapeLike 转义
+func likeClause(column string) string {
+	return column + " LIKE ? ESCAPE '" + likeEscape + "'"
+}
+
+// escapeLike 转义 LIKE 中的通配符和转义字符本身
+func escapeLike(s string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(s)
+}
+
+// parseList 解析逗号分隔的值列表，例如 "1,2,3"
+func parseList(value string) (interface{}, error) {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("不能为空")
+	}
+	return items, nil
+}
+
+// timeLayouts ParseTime 支持的时间格式
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseTime 解析过滤参数中的时间，支持 RFC 3339、"2006-01-02T15:04:05"、"2006-01-02 15:04:05" 和 "2006-01-02"，
+// 不带时区的按本地时区解析
+func ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("不是有效的时间，支持 RFC 3339 或 2006-01-02 格式")
+}
+
+// IsCompareLookup 是否为比较运算（=、<>、>、>=、<、<=），这些 lookup 的值可以按字段类型转换
+func IsCompareLookup(name string) bool {
+	switch name {
+	case "", "exact", "not", "gt", "gte", "lt", "lte":
+		return true
+	}
+	return false
+}
+
+// SplitLookup 拆分过滤参数名，例如 "phone__isnull" 返回 "phone" 和 "isnull"
+func SplitLookup(key string) (field, lookup string) {
+	if i := strings.LastIndex(key, LookupSeparator); i > 0 {
+		return key[:i], key[i+len(LookupSeparator):]
+	}
+	return key, ""
+}
+
+// parseFilter 按 lookup 解析过滤参数
+func parseFilter(key string, value interface{}) (string, Lookup, interface{}, error) {
+	column, name := SplitLookup(key)
+
+	lookupsMu.RLock()
+	l, ok := lookups[name]
+	lookupsMu.RUnlock()
+	if !ok {
+		return "", Lookup{}, nil, fmt.Errorf("不支持的过滤条件 %s", name)
+	}
+
+	// 已经按字段类型转换过的值（例如时间，见 ParseTime）原样使用
+	if _, ok := value.(time.Time); ok {
+		return column, l, value, nil
+	}
+
+	var parsed interface{} = fmt.Sprint(value)
+	if l.Parse != nil {
+		var err error
+		if parsed, err = l.Parse(parsed.(string)); err != nil {
+			return "", Lookup{}, nil, err
+		}
+	}
+	return column, l, parsed, nil
+}
+
+// ValidateFilters 校验过滤参数的 lookup 和值，返回字段错误
+func ValidateFilters(params *FilterParams) []FieldError {
+	var errs []FieldError
+	check := func(key string, value interface{}) {
+		if _, _, _, err := parseFilter(key, value); err != nil {
+			errs = append(errs, FieldError{Field: key, Code: CodeInvalid, Message: key + " " + err.Error()})
+		}
+	}
+	for key, value := range params.Filters {
+		check(key, value)
+	}
+	for _, group := range params.OrGroups {
+		for _, term := range group {
+			check(term.Key, term.Value)
+		}
+	}
+	return errs
+}
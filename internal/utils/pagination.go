@@ -1,7 +1,12 @@
 package utils
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,16 +18,50 @@ type PaginationParams struct {
 	PageSize int
 	Offset   int
 	Limit    int
+
+	// Disabled 客户端通过 ?page_size=0 关闭了分页（需要 PaginationConfig.AllowDisablePagination）
+	Disabled bool
+}
+
+// PaginationConfig 分页配置，零值字段使用 DefaultPaginationConfig 中的值
+type PaginationConfig struct {
+	DefaultPageSize int // 未指定 page_size/limit 时的每页条数
+	MaxPageSize     int // page_size/limit 的上限，超出时按上限处理
+
+	// AllowDisablePagination 允许通过 ?page_size=0 一次返回全部结果
+	AllowDisablePagination bool
+}
+
+// DefaultPaginationConfig 默认的分页配置
+var DefaultPaginationConfig = PaginationConfig{
+	DefaultPageSize: 10,
+	MaxPageSize:     100,
+}
+
+// withDefaults 用默认值补齐未配置的字段
+func (cfg PaginationConfig) withDefaults() PaginationConfig {
+	if cfg.DefaultPageSize <= 0 {
+		cfg.DefaultPageSize = DefaultPaginationConfig.DefaultPageSize
+	}
+	if cfg.MaxPageSize <= 0 {
+		cfg.MaxPageSize = DefaultPaginationConfig.MaxPageSize
+	}
+	if cfg.DefaultPageSize > cfg.MaxPageSize {
+		cfg.DefaultPageSize = cfg.MaxPageSize
+	}
+	return cfg
 }
 
 // GetPaginationParams 从 gin.Context 中获取分页参数
 // 支持两种方式：
 // 1. page + page_size
 // 2. limit + offset
-func GetPaginationParams(c *gin.Context) *PaginationParams {
+// 每页条数的默认值和上限由 cfg 指定
+func GetPaginationParams(c *gin.Context, cfg PaginationConfig) *PaginationParams {
+	cfg = cfg.withDefaults()
 	params := &PaginationParams{
 		Page:     1,
-		PageSize: 10,
+		PageSize: cfg.DefaultPageSize,
 	}
 
 	// 优先使用 page + page_size
@@ -36,9 +75,11 @@ func GetPaginationParams(c *gin.Context) *PaginationParams {
 		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil && pageSize > 0 {
 			params.PageSize = pageSize
 			// 限制最大 page_size
-			if params.PageSize > 100 {
-				params.PageSize = 100
+			if params.PageSize > cfg.MaxPageSize {
+				params.PageSize = cfg.MaxPageSize
 			}
+		} else if err == nil && pageSize == 0 && cfg.AllowDisablePagination {
+			params.Disabled = true
 		}
 	}
 
@@ -46,8 +87,8 @@ func GetPaginationParams(c *gin.Context) *PaginationParams {
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
 			params.Limit = limit
-			if params.Limit > 100 {
-				params.Limit = 100
+			if params.Limit > cfg.MaxPageSize {
+				params.Limit = cfg.MaxPageSize
 			}
 		}
 	}
@@ -58,6 +99,15 @@ func GetPaginationParams(c *gin.Context) *PaginationParams {
 		}
 	}
 
+	// 关闭分页时返回全部结果
+	if params.Disabled {
+		params.Page = 1
+		params.PageSize = 0
+		params.Offset = 0
+		params.Limit = 0
+		return params
+	}
+
 	// 如果使用了 limit/offset，则计算对应的 page/page_size
 	if params.Limit > 0 {
 		params.PageSize = params.Limit
@@ -73,8 +123,11 @@ func GetPaginationParams(c *gin.Context) *PaginationParams {
 	return params
 }
 
-// ApplyPagination 对 GORM 查询应用分页
+// ApplyPagination 对 GORM 查询应用分页，关闭分页时不做限制
 func ApplyPagination(db *gorm.DB, params *PaginationParams) *gorm.DB {
+	if params.Disabled {
+		return db
+	}
 	return db.Offset(params.Offset).Limit(params.Limit)
 }
 
@@ -85,11 +138,165 @@ func GetTotal(db *gorm.DB, model interface{}) int64 {
 	return total
 }
 
-// BuildPagination 构建分页信息
+// BuildPagination 构建分页信息，关闭分页时 page_size 为 0
+// 返回的对象来自对象池，响应写出后可以通过 ReleasePagination 归还
 func BuildPagination(params *PaginationParams, total int64) *Pagination {
-	return &Pagination{
-		Page:     params.Page,
-		PageSize: params.PageSize,
-		Total:    total,
+	p := acquirePagination()
+	p.Page = params.Page
+	p.PageSize = params.PageSize
+	p.total = total
+	p.Total = &p.total
+	return p
+}
+
+// BuildPaginationWithoutTotal 构建不带总数的分页信息，total 返回 null
+func BuildPaginationWithoutTotal(params *PaginationParams) *Pagination {
+	p := acquirePagination()
+	p.Page = params.Page
+	p.PageSize = params.PageSize
+	return p
+}
+
+// CountMode 列表总数的统计方式
+type CountMode string
+
+const (
+	// CountExact 执行 COUNT 查询得到准确的总数
+	CountExact CountMode = "exact"
+	// CountNone 不统计总数，total 返回 null，省去 COUNT 查询
+	CountNone CountMode = "none"
+	// CountEstimated 使用数据库执行计划（EXPLAIN）估算的行数，适合大表，不支持的数据库按 exact 处理
+	CountEstimated CountMode = "estimated"
+)
+
+// GetCountMode 获取客户端选择的总数统计方式
+// 通过 ?count=exact/none/estimated 指定，兼容 ?with_count=true/false；未指定或无法识别时使用 def
+func GetCountMode(c *gin.Context, def CountMode) CountMode {
+	switch mode := CountMode(c.Query("count")); mode {
+	case CountExact, CountNone, CountEstimated:
+		return mode
 	}
+	if v, err := strconv.ParseBool(c.Query("with_count")); err == nil {
+		if v {
+			return CountExact
+		}
+		return CountNone
+	}
+	return def
+}
+
+// PaginationMode 列表的分页方式
+type PaginationMode string
+
+const (
+	// PageNumberPagination 页码分页：?page=2&page_size=10 或 ?limit=10&offset=10（默认）
+	PageNumberPagination PaginationMode = ""
+	// CursorPagination 游标分页：?cursor=xxx&page_size=50
+	// 按排序字段的值定位下一页（keyset），不使用 OFFSET，翻页速度与页数无关
+	CursorPagination PaginationMode = "cursor"
+)
+
+// ErrInvalidCursor 游标无法解析，或与当前的排序方式不匹配
+var ErrInvalidCursor = errors.New("无效的游标")
+
+// cursorToken 游标的内容，编码后对客户端不透明
+// Ordering 记录生成游标时的排序方式，排序方式变化后游标失效
+type cursorToken struct {
+	Ordering string            `json:"o"`
+	Values   []json.RawMessage `json:"v"`
+}
+
+// orderingKey 排序方式的字符串表示，例如 "created_at,-id"
+func orderingKey(ordering []OrderField) string {
+	parts := make([]string, len(ordering))
+	for i, order := range ordering {
+		if order.Desc {
+			parts[i] = "-" + order.Field
+		} else {
+			parts[i] = order.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// EncodeCursor 将一页最后一条记录的排序字段值编码为游标
+// values 与 ordering 一一对应
+func EncodeCursor(ordering []OrderField, values []interface{}) (string, error) {
+	token := cursorToken{Ordering: orderingKey(ordering), Values: make([]json.RawMessage, len(values))}
+	for i, value := range values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		token.Values[i] = raw
+	}
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor 解码游标，types 为各排序字段的 Go 类型，值按类型还原（例如 time.Time）
+// 游标格式错误或与 ordering 不匹配时返回 ErrInvalidCursor
+func DecodeCursor(cursor string, ordering []OrderField, types []reflect.Type) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var token cursorToken
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if token.Ordering != orderingKey(ordering) || len(token.Values) != len(types) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(types))
+	for i, typ := range types {
+		ptr := reflect.New(typ)
+		if err := json.Unmarshal(token.Values[i], ptr.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = ptr.Elem().Interface()
+	}
+	return values, nil
+}
+
+// ApplyCursor 只查询排在游标之后的记录
+// 对排序 (a, b DESC) 和游标值 (x, y) 生成 (a > x) OR (a = x AND b < y)；
+// 排序应以唯一字段（通常是主键）结尾，排序字段不应包含 NULL
+func ApplyCursor(db *gorm.DB, ordering []OrderField, values []interface{}) *gorm.DB {
+	if len(values) == 0 || len(values) != len(ordering) {
+		return db
+	}
+
+	var (
+		clauses []string
+		args    []interface{}
+	)
+	for i, order := range ordering {
+		var b strings.Builder
+		b.WriteString("(")
+		for j := 0; j < i; j++ {
+			b.WriteString(sanitizeOrderBy(ordering[j].Field) + " = ? AND ")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if order.Desc {
+			op = " < ?"
+		}
+		b.WriteString(sanitizeOrderBy(order.Field) + op + ")")
+		args = append(args, values[i])
+		clauses = append(clauses, b.String())
+	}
+	return db.Where(strings.Join(clauses, " OR "), args...)
+}
+
+// BuildCursorPagination 构建游标分页的分页信息，next 为空表示没有下一页
+func BuildCursorPagination(pageSize int, next string) *Pagination {
+	p := acquirePagination()
+	p.PageSize = pageSize
+	p.NextCursor = next
+	return p
 }
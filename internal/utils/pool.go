@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"sync"
+)
+
+// 每个请求都会用到的小对象，通过 sync.Pool 复用以降低高 QPS 下的 GC 压力
+
+var responsePool = sync.Pool{
+	New: func() interface{} { return new(Response) },
+}
+
+var paginationPool = sync.Pool{
+	New: func() interface{} { return new(Pagination) },
+}
+
+var filterParamsPool = sync.Pool{
+	New: func() interface{} {
+		return &FilterParams{Filters: make(map[string]interface{})}
+	},
+}
+
+// acquireResponse 从池中获取响应结构
+func acquireResponse() *Response {
+	return responsePool.Get().(*Response)
+}
+
+// releaseResponse 清空响应结构并归还到池中
+func releaseResponse(r *Response) {
+	*r = Response{}
+	responsePool.Put(r)
+}
+
+// acquirePagination 从池中获取分页信息
+func acquirePagination() *Pagination {
+	return paginationPool.Get().(*Pagination)
+}
+
+// ReleasePagination 归还 BuildPagination 创建的分页信息
+// 只能在响应写出之后调用，调用后不能再使用 p
+func ReleasePagination(p *Pagination) {
+	if p == nil {
+		return
+	}
+	*p = Pagination{}
+	paginationPool.Put(p)
+}
+
+// acquireFilterParams 从池中获取过滤参数
+func acquireFilterParams() *FilterParams {
+	return filterParamsPool.Get().(*FilterParams)
+}
+
+// ReleaseFilterParams 归还 GetFilterParams 创建的过滤参数
+// 只能在查询执行完毕之后调用，调用后不能再使用 p
+func ReleaseFilterParams(p *FilterParams) {
+	if p == nil {
+		return
+	}
+	for key := range p.Filters {
+		delete(p.Filters, key)
+	}
+	p.Ordering = p.Ordering[:0]
+	p.OrGroups = p.OrGroups[:0]
+	p.Conditions = p.Conditions[:0]
+	p.Search = ""
+	p.SearchFields = nil
+	p.SearchMode = SearchLike
+	filterParamsPool.Put(p)
+}
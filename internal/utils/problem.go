@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorFormat 错误响应的格式
+type ErrorFormat string
+
+const (
+	// ErrorFormatEnvelope 默认格式：{"code": ..., "msg": ...}
+	ErrorFormatEnvelope ErrorFormat = "envelope"
+	// ErrorFormatProblem RFC 7807 问题详情：application/problem+json
+	ErrorFormatProblem ErrorFormat = "problem"
+)
+
+// errorFormat 当前使用的错误格式
+var errorFormat = ErrorFormatEnvelope
+
+// SetErrorFormat 设置全局的错误响应格式，应在服务启动前调用，空字符串表示默认格式
+func SetErrorFormat(format ErrorFormat) error {
+	switch format {
+	case "":
+		errorFormat = ErrorFormatEnvelope
+	case ErrorFormatEnvelope, ErrorFormatProblem:
+		errorFormat = format
+	default:
+		return fmt.Errorf("未知的错误响应格式: %s", format)
+	}
+	return nil
+}
+
+// Problem RFC 7807 问题详情
+// 除标准字段外，code、errors、data 为扩展字段，含义与默认格式中相同
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	Code   int         `json:"code,omitempty"`
+	Errors ErrorMap    `json:"errors,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// writeProblem 写出 application/problem+json 格式的错误
+// 业务错误码与 HTTP 状态码不同时通过 code 扩展字段返回；
+// 以 200 状态码返回的错误（见 Error）在该格式下使用错误码（400~599）或 400 作为状态码
+func writeProblem(c *gin.Context, httpStatus, code int, msg string, errs ErrorMap, data interface{}) {
+	if httpStatus < http.StatusBadRequest {
+		httpStatus = http.StatusBadRequest
+		if code >= http.StatusBadRequest && code < 600 {
+			httpStatus = code
+		}
+	}
+
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(httpStatus),
+		Status:   httpStatus,
+		Detail:   msg,
+		Instance: c.Request.URL.Path,
+		Errors:   errs,
+		Data:     wrapMarshaler(data),
+	}
+	if code != httpStatus {
+		p.Code = code
+	}
+	c.Render(httpStatus, bodyRender{renderer: problemRenderer{}, data: p, profile: ProfileFrom(c.Request.Context())})
+}
+
+// problemRenderer application/problem+json
+type problemRenderer struct{ jsonRenderer }
+
+// ContentType 实现 Renderer
+func (problemRenderer) ContentType() string { return "application/problem+json; charset=utf-8" }
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// profileKey Profile 在 context 中的 key
+type profileKey struct{}
+
+// Profile 单个请求的耗时分解
+// 只对开启了性能分析的请求创建（见 middleware.Profiling），
+// 各阶段（参数绑定、数据库、序列化）的耗时累加后通过 Server-Timing 响应头返回
+type Profile struct {
+	start time.Time
+
+	mu    sync.Mutex
+	names []string
+	spans map[string]time.Duration
+	count map[string]int
+}
+
+// NewProfile 创建 Profile
+func NewProfile() *Profile {
+	return &Profile{
+		start: time.Now(),
+		spans: make(map[string]time.Duration),
+		count: make(map[string]int),
+	}
+}
+
+// WithProfile 将 Profile 写入 context
+func WithProfile(ctx context.Context, p *Profile) context.Context {
+	return context.WithValue(ctx, profileKey{}, p)
+}
+
+// ProfileFrom 从 context 中读取 Profile，未开启性能分析时返回 nil
+func ProfileFrom(ctx context.Context) *Profile {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(profileKey{}).(*Profile)
+	return p
+}
+
+// Add 累加某个阶段的耗时，p 为 nil 时什么也不做
+func (p *Profile) Add(name string, d time.Duration) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.spans[name]; !ok {
+		p.names = append(p.names, name)
+	}
+	p.spans[name] += d
+	p.count[name]++
+}
+
+// ServerTiming 生成 Server-Timing 响应头的值
+func (p *Profile) ServerTiming() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	parts := make([]string, 0, len(p.names)+1)
+	for _, name := range p.names {
+		parts = append(parts, fmt.Sprintf("%s;desc=\"%d\";dur=%.2f", name, p.count[name], msec(p.spans[name])))
+	}
+	parts = append(parts, fmt.Sprintf("total;dur=%.2f", msec(time.Since(p.start))))
+	return strings.Join(parts, ", ")
+}
+
+// Track 开始记录某个阶段的耗时，返回结束记录的函数
+// 用法：defer utils.Track(c, "bind")()
+func Track(c *gin.Context, name string) func() {
+	p := ProfileFrom(c.Request.Context())
+	if p == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		p.Add(name, time.Since(start))
+	}
+}
+
+func msec(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
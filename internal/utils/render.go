@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内置的响应格式
+const (
+	FormatJSON    = "json"
+	FormatXML     = "xml"
+	FormatMsgPack = "msgpack"
+)
+
+// FormatParam 指定响应格式的查询参数，例如 ?format=xml，优先于 Accept 请求头
+const FormatParam = "format"
+
+// Renderer 响应格式的序列化实现
+type Renderer interface {
+	// ContentType 响应的 Content-Type
+	ContentType() string
+	// Marshal 序列化响应体
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// ContextRenderer 需要请求信息的 Renderer，例如可浏览的 HTML 页面（见 viewset.EnableBrowsableAPI）
+// Render 时使用 RenderContext，Marshal 只在没有请求信息时使用
+type ContextRenderer interface {
+	Renderer
+	RenderContext(c *gin.Context, status int, v interface{}) ([]byte, error)
+}
+
+// boundRenderer 绑定了请求的 ContextRenderer
+type boundRenderer struct {
+	ContextRenderer
+	c      *gin.Context
+	status int
+}
+
+// Marshal 实现 Renderer
+func (r boundRenderer) Marshal(v interface{}) ([]byte, error) {
+	return r.RenderContext(r.c, r.status, v)
+}
+
+// renderer 已注册的格式
+type renderer struct {
+	format     string
+	mediaTypes []string
+	Renderer
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = []renderer{
+		{FormatJSON, []string{"application/json"}, jsonRenderer{}},
+		{FormatXML, []string{"application/xml", "text/xml"}, xmlRenderer{}},
+		{FormatMsgPack, []string{"application/msgpack", "application/x-msgpack"}, msgpackRenderer{}},
+	}
+)
+
+// RegisterRenderer 注册（或替换）响应格式，format 为 ?format= 的取值，
+// mediaTypes 为 Accept 请求头中对应的 MIME 类型。应在服务启动前调用
+func RegisterRenderer(format string, mediaTypes []string, r Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	for i := range renderers {
+		if renderers[i].format == format {
+			renderers[i] = renderer{format, mediaTypes, r}
+			return
+		}
+	}
+	renderers = append(renderers, renderer{format, mediaTypes, r})
+}
+
+// contextRenderer 本次请求固定使用的格式在 gin.Context 中的 key（见 UseRenderer）
+const contextRenderer = "utils_renderer"
+
+// UseRenderer 本次请求固定使用 r 输出，不再按 ?format= 和 Accept 协商，
+// 例如只返回 JSON:API 格式的 ViewSet；r 不需要注册
+func UseRenderer(c *gin.Context, format string, r Renderer) {
+	c.Set(contextRenderer, renderer{format: format, Renderer: r})
+}
+
+// NegotiateFormat 按 ?format= 和 Accept 请求头选择响应格式，无法匹配时为 FormatJSON（UseRenderer 固定的格式优先）
+// Accept 中的类型按 q 值从高到低、同等 q 值按出现顺序匹配
+func NegotiateFormat(c *gin.Context) string {
+	return negotiate(c).format
+}
+
+// negotiate 选择响应格式
+func negotiate(c *gin.Context) renderer {
+	if r, ok := c.Get(contextRenderer); ok {
+		return r.(renderer)
+	}
+
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	if format := strings.ToLower(c.Query(FormatParam)); format != "" {
+		for _, r := range renderers {
+			if r.format == format {
+				return r
+			}
+		}
+	}
+
+	if accept := c.GetHeader("Accept"); accept != "" {
+		best, bestQ := -1, 0.0
+		for _, item := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					continue
+				}
+			}
+			if q <= bestQ {
+				continue
+			}
+			for i, r := range renderers {
+				if containsString(r.mediaTypes, mediaType) {
+					best, bestQ = i, q
+					break
+				}
+			}
+		}
+		if best >= 0 {
+			return renderers[best]
+		}
+	}
+	return renderers[0]
+}
+
+// Render 按内容协商的格式写出 data，所有响应辅助函数都通过它输出
+func Render(c *gin.Context, httpStatus int, data interface{}) {
+	r := negotiate(c).Renderer
+	if cr, ok := r.(ContextRenderer); ok {
+		r = boundRenderer{ContextRenderer: cr, c: c, status: httpStatus}
+	}
+	c.Header("Vary", "Accept")
+	c.Render(httpStatus, bodyRender{renderer: r, data: data, profile: ProfileFrom(c.Request.Context())})
+}
+
+// bodyRender 使用 Renderer 序列化的 gin 渲染器
+type bodyRender struct {
+	renderer Renderer
+	data     interface{}
+
+	// profile 不为 nil 时记录序列化耗时并写入 Server-Timing 响应头
+	profile *Profile
+}
+
+// Render 实现 render.Render
+func (r bodyRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	start := time.Now()
+	body, err := r.renderer.Marshal(r.data)
+	if err != nil {
+		return err
+	}
+	if r.profile != nil {
+		// 响应头必须在写入响应体之前设置
+		r.profile.Add("serialize", time.Since(start))
+		w.Header().Set("Server-Timing", r.profile.ServerTiming())
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// WriteContentType 实现 render.Render
+func (r bodyRender) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = []string{r.renderer.ContentType()}
+	}
+}
+
+// jsonRenderer 使用 jsonEncoder（见 SetJSONEncoder）的 JSON 格式
+type jsonRenderer struct{}
+
+// ContentType 实现 Renderer
+func (jsonRenderer) ContentType() string { return "application/json; charset=utf-8" }
+
+// Marshal 实现 Renderer
+func (jsonRenderer) Marshal(v interface{}) ([]byte, error) { return jsonEncoder.Marshal(v) }
+
+// containsString 判断 list 中是否包含 s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
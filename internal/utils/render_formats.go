@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+)
+
+// XML 和 MessagePack 先按 JSON 序列化，再转换为对应格式，
+// 因此字段名、omitempty、自定义 MarshalJSON 等与 JSON 响应完全一致。
+
+// jsonObject 保持字段顺序的 JSON 对象
+type jsonObject []jsonMember
+
+// jsonMember JSON 对象的一个字段
+type jsonMember struct {
+	Key   string
+	Value interface{}
+}
+
+// toGeneric 序列化为 JSON 后解析为通用结构：
+// jsonObject、[]interface{}、string、json.Number、bool 或 nil
+func toGeneric(v interface{}) (interface{}, error) {
+	body, err := jsonEncoder.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	return decodeGeneric(dec)
+}
+
+// decodeGeneric 从 token 流中读取一个值
+func decodeGeneric(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := jsonObject{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeGeneric(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, jsonMember{Key: keyTok.(string), Value: value})
+			}
+			_, err := dec.Token() // }
+			return obj, err
+		case '[':
+			arr := []interface{}{}
+			for dec.More() {
+				value, err := decodeGeneric(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, value)
+			}
+			_, err := dec.Token() // ]
+			return arr, err
+		}
+		return nil, fmt.Errorf("意外的分隔符 %v", t)
+	default:
+		return t, nil
+	}
+}
+
+// xmlRenderer XML 格式
+// 根元素为 <response>，数组元素为 <item>，不是合法 XML 名称的字段名写为 <entry key="...">
+type xmlRenderer struct{}
+
+// ContentType 实现 Renderer
+func (xmlRenderer) ContentType() string { return "application/xml; charset=utf-8" }
+
+// Marshal 实现 Renderer
+func (xmlRenderer) Marshal(v interface{}) ([]byte, error) {
+	value, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	writeXMLElement(&buf, "response", value)
+	return buf.Bytes(), nil
+}
+
+// writeXMLElement 写出一个元素
+func writeXMLElement(buf *bytes.Buffer, name string, value interface{}) {
+	open, close := name, name
+	if !validXMLName(name) {
+		var key bytes.Buffer
+		xml.EscapeText(&key, []byte(name))
+		open, close = `entry key="`+key.String()+`"`, "entry"
+	}
+
+	if value == nil {
+		buf.WriteString("<" + open + "/>")
+		return
+	}
+
+	buf.WriteString("<" + open + ">")
+	switch x := value.(type) {
+	case jsonObject:
+		for _, m := range x {
+			writeXMLElement(buf, m.Key, m.Value)
+		}
+	case []interface{}:
+		for _, item := range x {
+			writeXMLElement(buf, "item", item)
+		}
+	case string:
+		xml.EscapeText(buf, []byte(x))
+	default:
+		fmt.Fprint(buf, x)
+	}
+	buf.WriteString("</" + close + ">")
+}
+
+// validXMLName 是否可以直接作为元素名（字母或下划线开头，由字母、数字、下划线、连字符、点组成，不以 xml 开头）
+func validXMLName(name string) bool {
+	if name == "" || (len(name) >= 3 && (name[0]|0x20) == 'x' && (name[1]|0x20) == 'm' && (name[2]|0x20) == 'l') {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// msgpackRenderer MessagePack 格式
+type msgpackRenderer struct{}
+
+// ContentType 实现 Renderer
+func (msgpackRenderer) ContentType() string { return "application/msgpack" }
+
+// Marshal 实现 Renderer
+func (msgpackRenderer) Marshal(v interface{}) ([]byte, error) {
+	value, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeMsgPack(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeMsgPack 按 MessagePack 规范编码一个值
+func writeMsgPack(buf *bytes.Buffer, value interface{}) error {
+	switch x := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if x {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			writeMsgPackInt(buf, i)
+		} else if u, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, u)
+		} else {
+			f, err := x.Float64()
+			if err != nil {
+				return err
+			}
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		}
+	case string:
+		writeMsgPackHeader(buf, len(x), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(x)
+	case []interface{}:
+		writeMsgPackHeader(buf, len(x), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range x {
+			if err := writeMsgPack(buf, item); err != nil {
+				return err
+			}
+		}
+	case jsonObject:
+		writeMsgPackHeader(buf, len(x), 0x80, 16, 0, 0xde, 0xdf)
+		for _, m := range x {
+			writeMsgPack(buf, m.Key)
+			if err := writeMsgPack(buf, m.Value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: 不支持的类型 %T", value)
+	}
+	return nil
+}
+
+// writeMsgPackHeader 写出字符串、数组或 map 的类型和长度
+// fix 为定长格式的前缀（长度小于 fixMax 时使用），code8/16/32 为对应长度字段宽度的类型码，0 表示没有该格式
+func writeMsgPackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// writeMsgPackInt 使用最短的格式编码整数
+func writeMsgPackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buf.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		buf.WriteByte(byte(int8(i)))
+	case i > 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(i))
+	case i > 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(i))
+	case i > 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(i))
+	case i > 0:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, uint64(i))
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
@@ -6,54 +6,77 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// StatusClientClosedRequest 客户端在服务端响应之前断开了连接（沿用 nginx 的 499）
+const StatusClientClosedRequest = 499
+
 // Response 统一响应结构
 type Response struct {
 	Code       int         `json:"code"`
 	Msg        string      `json:"msg"`
 	Data       interface{} `json:"data,omitempty"`
 	Pagination *Pagination `json:"pagination,omitempty"`
+
+	// Errors 校验失败时按字段分组的错误（见 ValidationError）
+	Errors ErrorMap `json:"errors,omitempty"`
 }
 
 // Pagination 分页信息
+// 客户端没有要求统计总数时 Total 为 nil，序列化为 null
 type Pagination struct {
-	Page     int   `json:"page"`
-	PageSize int   `json:"page_size"`
-	Total    int64 `json:"total"`
+	Page     int    `json:"page,omitempty"`
+	PageSize int    `json:"page_size"`
+	Total    *int64 `json:"total"`
+
+	// NextCursor 游标分页时下一页的游标，没有下一页时为空
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Links 翻页等链接（HAL 格式，见 viewset.EnableLinks），未开启时为空
+	Links map[string]Link `json:"_links,omitempty"`
+
+	total int64 // Total 指向的存储，避免额外分配
+}
+
+// Link HAL 格式的链接
+type Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"` // 请求方法，GET 时省略
+}
+
+// writeResponse 写出统一格式的响应
+// 外层结构按 ResponseConfig 生成（见 SetResponseConfig），格式按内容协商选择（见 Render），
+// JSON 使用可替换的 JSONEncoder（见 SetJSONEncoder）；
+// 错误响应在 ErrorFormatProblem 下改为 RFC 7807 格式（见 SetErrorFormat）
+func writeResponse(c *gin.Context, httpStatus int, code int, msg string, data interface{}, pagination *Pagination) {
+	if code != 0 && errorFormat == ErrorFormatProblem {
+		writeProblem(c, httpStatus, code, msg, nil, data)
+		return
+	}
+	writeEnvelope(c, httpStatus, code, msg, data, pagination, nil)
 }
 
 // Success 成功响应
 func Success(c *gin.Context, data interface{}) {
-	c.JSON(http.StatusOK, Response{
-		Code: 0,
-		Msg:  "success",
-		Data: data,
-	})
+	writeResponse(c, http.StatusOK, 0, "success", data, nil)
 }
 
 // SuccessWithPagination 带分页的成功响应
 func SuccessWithPagination(c *gin.Context, data interface{}, pagination *Pagination) {
-	c.JSON(http.StatusOK, Response{
-		Code:       0,
-		Msg:        "success",
-		Data:       data,
-		Pagination: pagination,
-	})
+	writeResponse(c, http.StatusOK, 0, "success", data, pagination)
+}
+
+// SuccessWithMessage 自定义提示信息的成功响应，pagination 可以为 nil
+func SuccessWithMessage(c *gin.Context, msg string, data interface{}, pagination *Pagination) {
+	writeResponse(c, http.StatusOK, 0, msg, data, pagination)
 }
 
 // Error 错误响应
 func Error(c *gin.Context, code int, msg string) {
-	c.JSON(http.StatusOK, Response{
-		Code: code,
-		Msg:  msg,
-	})
+	writeResponse(c, http.StatusOK, code, msg, nil, nil)
 }
 
 // ErrorWithStatus 带 HTTP 状态码的错误响应
 func ErrorWithStatus(c *gin.Context, httpStatus int, code int, msg string) {
-	c.JSON(httpStatus, Response{
-		Code: code,
-		Msg:  msg,
-	})
+	writeResponse(c, httpStatus, code, msg, nil, nil)
 }
 
 // BadRequest 400 错误
@@ -80,3 +103,35 @@ func Unauthorized(c *gin.Context, msg string) {
 func Forbidden(c *gin.Context, msg string) {
 	ErrorWithStatus(c, http.StatusForbidden, http.StatusForbidden, msg)
 }
+
+// ErrorWithData 带附加数据的错误响应
+// 用于需要告诉客户端更多上下文的错误，例如配额重置时间
+func ErrorWithData(c *gin.Context, httpStatus int, code int, msg string, data interface{}) {
+	writeResponse(c, httpStatus, code, msg, data, nil)
+}
+
+// TooManyRequests 429 错误
+func TooManyRequests(c *gin.Context, msg string) {
+	ErrorWithStatus(c, http.StatusTooManyRequests, http.StatusTooManyRequests, msg)
+}
+
+// Conflict 409 错误
+func Conflict(c *gin.Context, msg string) {
+	ErrorWithStatus(c, http.StatusConflict, http.StatusConflict, msg)
+}
+
+// AbortIfCanceled 客户端已断开或请求已取消时中止处理并返回 true
+// 此时写出响应已没有意义，只记录 499 状态码，调用方应立即返回，
+// 不再执行后续的数据库查询和序列化
+func AbortIfCanceled(c *gin.Context) bool {
+	if c.Request.Context().Err() == nil {
+		return false
+	}
+	c.AbortWithStatus(StatusClientClosedRequest)
+	return true
+}
+
+// ServiceUnavailable 503 错误
+func ServiceUnavailable(c *gin.Context, msg string) {
+	ErrorWithStatus(c, http.StatusServiceUnavailable, http.StatusServiceUnavailable, msg)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchMode ?search= 的搜索方式
+type SearchMode int
+
+const (
+	// SearchLike 对每个字段做 LIKE '%keyword%' 匹配（默认）
+	SearchLike SearchMode = iota
+
+	// SearchFullText 全文搜索：MySQL 使用 MATCH ... AGAINST，PostgreSQL 使用 to_tsvector @@ plainto_tsquery，
+	// 没有指定排序时按相关度排序；其他数据库（如 SQLite）退回 LIKE 匹配
+	SearchFullText
+)
+
+// FullTextConfig PostgreSQL 全文搜索使用的文本搜索配置
+// simple 不做词干提取，对中文和混合内容更稳妥，需要词干提取时可以改为 english 等
+var FullTextConfig = "simple"
+
+// ApplySearch 应用模糊搜索
+// 使用方式：?search=keyword，fields 为搜索的列名，调用方需要保证列名可信
+func ApplySearch(db *gorm.DB, c *gin.Context, fields ...string) *gorm.DB {
+	return applySearch(db, SearchLike, fields, strings.TrimSpace(c.Query("search")), false)
+}
+
+// applySearch 按 mode 生成搜索条件，rank 为 true 时全文搜索结果按相关度从高到低排序
+func applySearch(db *gorm.DB, mode SearchMode, fields []string, search string, rank bool) *gorm.DB {
+	if search == "" || len(fields) == 0 {
+		return db
+	}
+
+	var expr string
+	if mode == SearchFullText {
+		switch db.Dialector.Name() {
+		case "mysql":
+			expr = MatchAgainst(fields)
+		case "postgres":
+			expr = TSVector(fields) + " @@ plainto_tsquery('" + FullTextConfig + "', ?)"
+		}
+	}
+	if expr == "" {
+		return applyLikeSearch(db, fields, search)
+	}
+
+	db = db.Where(expr, search)
+	if rank {
+		if db.Dialector.Name() == "postgres" {
+			expr = "ts_rank(" + TSVector(fields) + ", plainto_tsquery('" + FullTextConfig + "', ?))"
+		}
+		db = db.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                expr + " DESC",
+			Vars:               []interface{}{search},
+			WithoutParentheses: true,
+		}})
+	}
+	return db
+}
+
+// applyLikeSearch 生成 (a LIKE ? OR b LIKE ?) 条件，搜索词中的 % 和 _ 按字面匹配
+func applyLikeSearch(db *gorm.DB, fields []string, search string) *gorm.DB {
+	pattern := "%" + escapeLike(search) + "%"
+	var group *gorm.DB
+	for _, field := range fields {
+		if group == nil {
+			group = db.Session(&gorm.Session{NewDB: true}).Where(likeClause(field), pattern)
+		} else {
+			group = group.Or(likeClause(field), pattern)
+		}
+	}
+	return db.Where(group)
+}
+
+// MatchAgainst MySQL 全文搜索表达式，需要 fields 上有同样列组合的 FULLTEXT 索引
+func MatchAgainst(fields []string) string {
+	return "MATCH (" + strings.Join(fields, ", ") + ") AGAINST (? IN NATURAL LANGUAGE MODE)"
+}
+
+// TSVector PostgreSQL 中多个字段拼接成的 tsvector 表达式
+// 表达式是 IMMUTABLE 的，可以用于创建 GIN 表达式索引（查询与索引的表达式需要一致）
+func TSVector(fields []string) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = "coalesce(" + field + ", '')"
+	}
+	return "to_tsvector('" + FullTextConfig + "', " + strings.Join(parts, " || ' ' || ") + ")"
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamFlushEvery 每写出多少条记录刷新一次缓冲区
+const streamFlushEvery = 100
+
+// StreamWithPagination 以流式方式写出带分页的成功响应
+// 响应格式与 SuccessWithPagination 相同（包括 ResponseConfig 的配置），但 data 数组中的元素通过 write 逐条编码写出，
+// 不需要在内存中构建完整的响应体，适合大结果集。
+// iterate 中返回的错误无法再改变已经写出的状态码，只会记录到 c.Errors 中并正常结束 JSON。
+func StreamWithPagination(c *gin.Context, pagination *Pagination, iterate func(write func(item interface{}) error) error) error {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	prefix := streamPrefix(c, pagination)
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	count := 0
+
+	if _, err := w.WriteString(prefix); err != nil {
+		return err
+	}
+
+	err := iterate(func(item interface{}) error {
+		if count > 0 {
+			if _, err := w.WriteString(","); err != nil {
+				return err
+			}
+		}
+		body, err := MarshalJSON(item)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+		count++
+		if count%streamFlushEvery == 0 {
+			w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		c.Error(err)
+	}
+
+	suffix, werr := streamSuffix(pagination)
+	if werr != nil {
+		return werr
+	}
+	if _, werr := w.WriteString(suffix); werr != nil {
+		return werr
+	}
+	w.Flush()
+
+	return err
+}
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationFailedMsg 校验失败时响应的 msg
+const ValidationFailedMsg = "validation failed"
+
+// 字段错误码
+const (
+	CodeRequired    = "required"     // 必填字段为空
+	CodeInvalid     = "invalid"      // 格式或取值不合法
+	CodeInvalidType = "invalid_type" // 类型错误，例如字符串传给了数字字段
+	CodeTooShort    = "too_short"    // 小于最小长度/最小值
+	CodeTooLong     = "too_long"     // 超出最大长度/最大值
+	CodeUnique      = "unique"       // 与已有记录重复
+	CodeNotFound    = "not_found"    // 引用的记录不存在
+	CodeParseError  = "parse_error"  // 请求体无法解析
+)
+
+// NonFieldErrorsKey 不属于具体字段的错误（如 JSON 格式错误）在 errors 中的 key
+const NonFieldErrorsKey = "non_field_errors"
+
+// ErrorMap 按字段分组的错误消息，例如 {"email": ["email 格式不正确（email）"], "non_field_errors": ["..."]}
+type ErrorMap map[string][]string
+
+// GroupErrors 将字段错误按字段分组，同一字段的多条错误按出现顺序排列
+func GroupErrors(errs []FieldError) ErrorMap {
+	if len(errs) == 0 {
+		return nil
+	}
+	m := make(ErrorMap, len(errs))
+	for _, fe := range errs {
+		key := fe.Field
+		if key == "" {
+			key = NonFieldErrorsKey
+		}
+		m[key] = append(m[key], fe.Message)
+	}
+	return m
+}
+
+// FieldError 单个字段的校验错误
+// 请求体整体的错误（如 JSON 格式错误）Field 为空
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors 字段错误列表，可以作为 error 返回
+// 模型的 Validate 方法返回它时，会原样作为校验失败的响应
+type ValidationErrors []FieldError
+
+// Error 实现 error
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidationError 校验失败响应，错误按字段分组（见 GroupErrors）
+// 格式：{"code":422,"msg":"validation failed","errors":{"email":["..."],"non_field_errors":["..."]}}
+func ValidationError(c *gin.Context, errs []FieldError) {
+	if errorFormat == ErrorFormatProblem {
+		writeProblem(c, http.StatusUnprocessableEntity, http.StatusUnprocessableEntity, ValidationFailedMsg, GroupErrors(errs), nil)
+		return
+	}
+
+	writeEnvelope(c, http.StatusUnprocessableEntity, http.StatusUnprocessableEntity, ValidationFailedMsg, nil, nil, GroupErrors(errs))
+}
+
+// BindingErrors 将请求绑定或模型校验返回的错误转换为字段错误
+func BindingErrors(err error) []FieldError {
+	var verrs ValidationErrors
+	if errors.As(err, &verrs) {
+		return verrs
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		out := make([]FieldError, len(fieldErrs))
+		for i, fe := range fieldErrs {
+			out[i] = fromValidator(fe)
+		}
+		return out
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Code:    CodeInvalidType,
+			Message: fmt.Sprintf("%s 类型错误，应为 %s", typeErr.Field, typeErr.Type),
+		}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return []FieldError{{Code: CodeParseError, Message: "请求体不是合法的 JSON"}}
+	}
+
+	return []FieldError{{Code: CodeInvalid, Message: err.Error()}}
+}
+
+// fromValidator 转换单个 validator 错误
+func fromValidator(fe validator.FieldError) FieldError {
+	field := fe.Field()
+	switch fe.Tag() {
+	case "required":
+		return FieldError{Field: field, Code: CodeRequired, Message: field + " 不能为空"}
+	case "min", "gte", "gt":
+		return FieldError{Field: field, Code: CodeTooShort, Message: fmt.Sprintf("%s 不能小于 %s", field, fe.Param())}
+	case "max", "lte", "lt":
+		return FieldError{Field: field, Code: CodeTooLong, Message: fmt.Sprintf("%s 不能大于 %s", field, fe.Param())}
+	case "oneof":
+		return FieldError{Field: field, Code: CodeInvalid, Message: fmt.Sprintf("%s 只能是 %s 之一", field, fe.Param())}
+	default:
+		return FieldError{Field: field, Code: CodeInvalid, Message: fmt.Sprintf("%s 格式不正确（%s）", field, fe.Tag())}
+	}
+}
+
+// 校验错误中的字段名使用 JSON 字段名（查询参数使用 form 名），与请求保持一致
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(sf reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.SplitN(sf.Tag.Get(tag), ",", 2)[0]
+				if name != "" && name != "-" {
+					return name
+				}
+			}
+			return sf.Name
+		})
+	}
+}
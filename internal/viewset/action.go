@@ -0,0 +1,224 @@
+package viewset
+
+import (
+	"fmt"
+	"go-viewset/internal/metrics"
+	"go-viewset/internal/tracing"
+	"go-viewset/internal/utils"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Action 自定义 action 的声明
+type Action struct {
+	Method  string // HTTP 方法，例如 "POST"
+	Name    string // action 名称，同时作为 URL 路径，例如 "reset_password"
+	Detail  bool   // true 表示作用于单个对象：/:id/<name>；false 表示作用于集合：/<name>
+	Handler gin.HandlerFunc
+}
+
+// Path 返回 action 的路由路径
+func (a Action) Path() string {
+	if a.Detail {
+		return "/:id/" + a.Name
+	}
+	return "/" + a.Name
+}
+
+// ActionProvider 通过 Actions 方法声明自定义 action 的 ViewSet
+type ActionProvider interface {
+	Actions() []Action
+}
+
+// actionPrefix 按命名约定自动发现的 action 方法前缀
+const actionPrefix = "Action"
+
+// actionMethods 命名约定中支持的 HTTP 方法
+var actionMethods = []string{"Get", "Post", "Put", "Patch", "Delete"}
+
+// DiscoverActions 收集 ViewSet 上的全部自定义 action
+// 包括 Actions() 声明的 action，以及按命名约定 Action<方法>[List]<名称> 定义的方法，例如：
+//
+//	func (v *UserViewSet) ActionPostActivate(c *gin.Context)  // POST /:id/activate
+//	func (v *UserViewSet) ActionGetListStats(c *gin.Context)  // GET /stats
+//
+// 同一路由以 Actions() 中的声明为准，结果按路径排序
+func DiscoverActions(vs interface{}) []Action {
+	var actions []Action
+	seen := make(map[string]bool)
+	add := func(a Action) {
+		key := a.Method + " " + a.Path()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		actions = append(actions, a)
+	}
+
+	if p, ok := vs.(ActionProvider); ok {
+		for _, a := range p.Actions() {
+			add(a)
+		}
+	}
+
+	val := reflect.ValueOf(vs)
+	typ := val.Type()
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		a, ok := parseActionName(m.Name)
+		if !ok {
+			continue
+		}
+		handler, ok := val.Method(i).Interface().(func(*gin.Context))
+		if !ok {
+			continue
+		}
+		a.Handler = handler
+		add(a)
+	}
+
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].Path() < actions[j].Path()
+	})
+	return actions
+}
+
+// parseActionName 解析命名约定 Action<方法>[List]<名称>
+func parseActionName(name string) (Action, bool) {
+	if !strings.HasPrefix(name, actionPrefix) {
+		return Action{}, false
+	}
+	rest := name[len(actionPrefix):]
+
+	for _, method := range actionMethods {
+		if !strings.HasPrefix(rest, method) {
+			continue
+		}
+		rest = rest[len(method):]
+
+		detail := true
+		if strings.HasPrefix(rest, "List") && len(rest) > len("List") {
+			detail = false
+			rest = rest[len("List"):]
+		}
+		if rest == "" {
+			return Action{}, false
+		}
+		return Action{
+			Method: strings.ToUpper(method),
+			Name:   toSnakeCase(rest),
+			Detail: detail,
+		}, true
+	}
+	return Action{}, false
+}
+
+// toSnakeCase 将 ResetPassword 转换为 reset_password
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// 连续的大写字母（如 ID）视为一个单词
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// RegisterActions 注册 ViewSet 上的全部自定义 action（见 DiscoverActions）
+// vs 应传入最外层的 ViewSet，例如 v.RegisterActions(group, v)
+func (v *GenericViewSet) RegisterActions(group *gin.RouterGroup, vs interface{}) {
+	if v.impl == nil {
+		v.impl = vs
+	}
+	for _, a := range DiscoverActions(vs) {
+		v.RegisterAction(group, a.Method, a.Path(), v.HandlerFor(a.Name, a.Handler))
+	}
+}
+
+// HandlerFor 包装 action 的处理函数，执行前检查该 action 配置的权限和限流
+// 自定义 RegisterRoutes 时应通过它注册标准 action，例如：
+//
+//	group.GET("/", v.HandlerFor(ActionList, v.List))
+//
+// 限流配置在注册路由时读取，应在此之前设置好 Throttles 和 ThrottleClasses
+func (v *GenericViewSet) HandlerFor(action string, handler gin.HandlerFunc) gin.HandlerFunc {
+	scopes := v.throttleScopes(action)
+	throttles := v.throttleClasses(action)
+
+	return func(c *gin.Context) {
+		c.Set(ContextAction, action)
+		c.Set(contextViewSet, v)
+		if v.JSONAPI {
+			utils.UseRenderer(c, FormatJSONAPI, jsonapiRenderer{})
+		}
+		metrics.Label(c, v.table, action)
+
+		// 每个 action 一个 span，权限检查和处理函数中的 SQL 都在它之下
+		ctx, span := tracing.Start(c.Request.Context(), v.table+"."+action, tracing.KindInternal)
+		if span != nil {
+			span.SetAttribute("viewset.resource", v.table)
+			span.SetAttribute("viewset.action", action)
+			c.Request = c.Request.WithContext(ctx)
+			defer span.End()
+		}
+
+		if !v.checkPermission(c, action) {
+			return
+		}
+		if !v.checkThrottle(c, action, scopes, throttles) {
+			return
+		}
+		if !v.parseJSONAPI(c, action) {
+			return
+		}
+		if v.Atomic && isWriteMethod(c.Request.Method) {
+			v.atomic(c, handler)
+			return
+		}
+		handler(c)
+	}
+}
+
+// DetailAction 声明作用于单个对象的 action：/:id/<name>
+// 按 URL 中的 :id 取得对象并检查对象级权限，不存在时统一返回 404，之后再把对象传给 fn，例如：
+//
+//	DetailAction(v.GenericViewSet, "POST", "activate", v.Activate) // func (v *UserViewSet) Activate(c *gin.Context, user *models.User)
+func DetailAction[T any](v *GenericViewSet, method, name string, fn func(c *gin.Context, obj *T)) Action {
+	return Action{Method: method, Name: name, Detail: true, Handler: objectHandler(v, fn)}
+}
+
+// ListAction 声明作用于集合的 action：/<name>，例如：
+//
+//	ListAction("GET", "stats", v.GetStats)
+func ListAction(method, name string, handler gin.HandlerFunc) Action {
+	return Action{Method: method, Name: name, Handler: handler}
+}
+
+// objectHandler 取得 :id 对应的对象后调用 fn
+func objectHandler[T any](v *GenericViewSet, fn func(c *gin.Context, obj *T)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj, ok := v.GetObjectOr404(c, c.Param("id"))
+		if !ok {
+			return
+		}
+
+		typed, ok := obj.(*T)
+		if !ok {
+			utils.InternalServerError(c, fmt.Sprintf("对象类型不匹配: %T", obj))
+			return
+		}
+
+		fn(c, typed)
+	}
+}
@@ -0,0 +1,206 @@
+package viewset
+
+import (
+	"fmt"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActionAggregate 聚合统计的 action 名称
+const ActionAggregate = "aggregate"
+
+// defaultAggregateMaxGroups 默认最多返回的分组数
+const defaultAggregateMaxGroups = 1000
+
+// aggregateFuncs 支持的聚合函数，sum 和 avg 只能用于数值字段
+var aggregateFuncs = map[string]string{
+	"count": "COUNT",
+	"sum":   "SUM",
+	"avg":   "AVG",
+	"min":   "MIN",
+	"max":   "MAX",
+}
+
+// aggregateMetric 解析后的统计指标
+type aggregateMetric struct {
+	name    string // 响应中的字段名，例如 count、avg_age
+	expr    string // SQL 表达式，例如 AVG(users.age)
+	numeric bool   // 结果为数值，MySQL 以字符串返回的 DECIMAL 需要转换
+}
+
+// Aggregate 按字段分组统计，过滤参数与 List 相同
+// GET /items/aggregate?group_by=status&metrics=count,avg:age，需要开启 EnableAggregate
+// group_by 为逗号分隔的字段（可以为空，即对全部记录统计）；metrics 为 count、count:字段、sum/avg/min/max:字段，默认 count
+func (v *GenericViewSet) Aggregate(c *gin.Context) {
+	if v.meta == nil {
+		utils.InternalServerError(c, "无法解析模型元数据")
+		return
+	}
+
+	var errs []utils.FieldError
+	var groups []*meta.Field
+	for _, name := range splitList(c.Query("group_by")) {
+		field, ok := v.aggregateField(name)
+		if !ok {
+			errs = append(errs, utils.FieldError{Field: "group_by", Code: utils.CodeInvalid, Message: "不允许按 " + name + " 分组"})
+			continue
+		}
+		groups = append(groups, field)
+	}
+
+	metricNames := splitList(c.Query("metrics"))
+	if len(metricNames) == 0 {
+		metricNames = []string{"count"}
+	}
+	metrics := make([]aggregateMetric, 0, len(metricNames))
+	for _, name := range metricNames {
+		metric, err := v.parseMetric(name)
+		if err != nil {
+			errs = append(errs, utils.FieldError{Field: "metrics", Code: utils.CodeInvalid, Message: err.Error()})
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	if len(errs) > 0 {
+		utils.ValidationError(c, errs)
+		return
+	}
+
+	filterParams, ok := v.filterParams(c, "group_by", "metrics")
+	if !ok {
+		return
+	}
+	defer utils.ReleaseFilterParams(filterParams)
+	// 结果按分组字段排序，?ordering= 不生效
+	filterParams.Ordering = filterParams.Ordering[:0]
+
+	// 列别名使用 g0、m0 等固定名称，避免把字段名拼进 SQL
+	selects := make([]string, 0, len(groups)+len(metrics))
+	columns := make([]string, len(groups))
+	for i, field := range groups {
+		columns[i] = v.table + "." + field.Column
+		selects = append(selects, fmt.Sprintf("%s AS g%d", columns[i], i))
+	}
+	for i, metric := range metrics {
+		selects = append(selects, fmt.Sprintf("%s AS m%d", metric.expr, i))
+	}
+
+	query := utils.ApplyFilters(v.queryset(c).Model(v.Model), filterParams).Select(strings.Join(selects, ", "))
+	if len(columns) > 0 {
+		grouped := strings.Join(columns, ", ")
+		query = query.Group(grouped).Order(grouped).Limit(defaultAggregateMaxGroups)
+	}
+
+	var rows []map[string]interface{}
+	if err := query.Scan(&rows).Error; err != nil {
+		v.dbError(c, "统计失败", err)
+		return
+	}
+
+	results := make([]gin.H, len(rows))
+	for i, row := range rows {
+		result := make(gin.H, len(groups)+len(metrics))
+		for j, field := range groups {
+			result[field.JSONName] = row[fmt.Sprintf("g%d", j)]
+		}
+		for j, metric := range metrics {
+			result[metric.name] = aggregateValue(row[fmt.Sprintf("m%d", j)], metric.numeric)
+		}
+		results[i] = result
+	}
+
+	groupBy := make([]string, len(groups))
+	for i, field := range groups {
+		groupBy[i] = field.JSONName
+	}
+	v.Respond(c, gin.H{
+		"group_by": groupBy,
+		"results":  results,
+	})
+}
+
+// parseMetric 解析统计指标，例如 count、count:email、avg:age
+func (v *GenericViewSet) parseMetric(name string) (aggregateMetric, error) {
+	fn, fieldName, hasField := strings.Cut(name, ":")
+	sqlFunc, ok := aggregateFuncs[fn]
+	if !ok {
+		return aggregateMetric{}, fmt.Errorf("不支持的统计方式 %s", fn)
+	}
+
+	if !hasField {
+		if fn != "count" {
+			return aggregateMetric{}, fmt.Errorf("%s 需要指定字段，例如 %s:age", fn, fn)
+		}
+		return aggregateMetric{name: "count", expr: "COUNT(*)", numeric: true}, nil
+	}
+
+	field, ok := v.aggregateField(fieldName)
+	if !ok {
+		return aggregateMetric{}, fmt.Errorf("不允许统计字段 %s", fieldName)
+	}
+	numeric := field.TypeName == "int" || field.TypeName == "float"
+	if (fn == "sum" || fn == "avg") && !numeric {
+		return aggregateMetric{}, fmt.Errorf("%s 只能用于数值字段，%s 不是数值", fn, fieldName)
+	}
+	return aggregateMetric{
+		name:    fn + "_" + field.JSONName,
+		expr:    sqlFunc + "(" + v.table + "." + field.Column + ")",
+		numeric: numeric || fn == "count",
+	}, nil
+}
+
+// aggregateField 查找允许分组和统计的字段
+// 配置了 AggregateFields 时只能使用其中的字段，否则模型上可以过滤的字段都可以
+func (v *GenericViewSet) aggregateField(name string) (*meta.Field, bool) {
+	field, ok := v.meta.Lookup(name)
+	if !ok || !field.Filterable {
+		return nil, false
+	}
+	if len(v.AggregateFields) == 0 {
+		return field, true
+	}
+	for _, allowed := range v.AggregateFields {
+		if f, ok := v.meta.Lookup(allowed); ok && f.Column == field.Column {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+// aggregateValue 将数值指标统一为数字，MySQL 的 DECIMAL（例如 AVG 的结果）以字符串返回
+func aggregateValue(value interface{}, numeric bool) interface{} {
+	if !numeric {
+		return value
+	}
+	var s string
+	switch x := value.(type) {
+	case string:
+		s = x
+	case []byte:
+		s = string(x)
+	default:
+		return value
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// splitList 拆分逗号分隔的参数，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,87 @@
+package viewset
+
+import (
+	"go-viewset/internal/config"
+	"go-viewset/internal/events"
+	"go-viewset/internal/models"
+	"go-viewset/internal/storage"
+	"go-viewset/internal/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AttachmentViewSet 附件 ViewSet：上传文件，查看、下载和删除已上传的附件
+// 模型通过带 file 标签的附件 ID 字段引用附件（见 EnableFileFields）
+type AttachmentViewSet struct {
+	*GenericViewSet
+
+	uploader *Uploader
+}
+
+// NewAttachmentViewSet 创建附件 ViewSet，文件保存在 store 中
+func NewAttachmentViewSet(db *gorm.DB, store storage.Storage, cfg config.AttachmentConfig) *AttachmentViewSet {
+	v := &AttachmentViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.Attachment{}),
+		uploader:       NewUploader(store, cfg),
+	}
+
+	// 写操作同时修改存储后端，不在请求事务中执行：事务提交失败时无法撤销已写入或删除的文件
+	v.Atomic = false
+
+	v.FilterFields = []string{"content_type", "uploaded_by", "created_at"}
+	v.SearchFields = []string{"name"}
+	v.OrderingFields = []string{"id", "created_at", "size", "name"}
+
+	// 响应中增加签名的下载地址
+	output := &DTOSerializer{Output: func(obj interface{}) interface{} {
+		return v.uploader.Output(obj.(*models.Attachment))
+	}}
+	v.GetSerializer = func(action string) Serializer { return output }
+	return v
+}
+
+// RegisterRoutes 注册路由
+func (v *AttachmentViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	// 附件只能上传和删除，不能修改
+	v.RegisterMixins(group, v, ListMixin, RetrieveMixin, DestroyMixin)
+	group.POST("/", v.HandlerFor(ActionCreate, v.Upload))
+}
+
+// Actions 声明自定义 action
+func (v *AttachmentViewSet) Actions() []Action {
+	return []Action{
+		// GET /api/attachments/:id/download - 重定向到签名的下载地址
+		DetailAction(v.GenericViewSet, "GET", "download", v.Download),
+	}
+}
+
+// Upload 上传文件
+// POST /api/attachments/（multipart/form-data，文件字段为 file）
+func (v *AttachmentViewSet) Upload(c *gin.Context) {
+	obj, ok := v.uploader.Save(c, v.GenericViewSet, false)
+	if !ok {
+		return
+	}
+
+	v.publish(c, events.Created, obj)
+	v.Respond(c, v.serialize(c, ActionCreate, obj))
+}
+
+// Download 重定向到附件的签名下载地址
+// GET /api/attachments/:id/download
+func (v *AttachmentViewSet) Download(c *gin.Context, attachment *models.Attachment) {
+	url, err := v.uploader.store.URL(attachment.Key, attachment.Name, time.Now().Add(v.uploader.expiry))
+	if err != nil {
+		utils.InternalServerError(c, "生成下载地址失败")
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// AfterDestroy 删除附件记录后删除存储后端中的文件
+func (v *AttachmentViewSet) AfterDestroy(c *gin.Context, obj interface{}) {
+	v.uploader.Discard(obj.(*models.Attachment))
+}
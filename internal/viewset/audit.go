@@ -0,0 +1,132 @@
+package viewset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-viewset/internal/events"
+	"go-viewset/internal/models"
+	"log"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextAuditBefore 本次请求中对象修改前的内容（主键 -> 字段值），由 auditSnapshot 写入
+const contextAuditBefore = "viewset_audit_before"
+
+// auditChange 更新时一个字段的变化
+type auditChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// auditSnapshot 记录对象修改前的内容，写请求取得对象后调用（Update、PartialUpdate、Delete、GetObjectOr404）
+// 对象之后可能被原地修改，因此立即序列化
+func (v *GenericViewSet) auditSnapshot(c *gin.Context, obj interface{}) {
+	if !v.EnableAudit || isSafeMethod(c.Request.Method) {
+		return
+	}
+	values := v.auditValues(obj)
+	if values == nil {
+		return
+	}
+
+	snapshots, _ := c.Get(contextAuditBefore)
+	m, _ := snapshots.(map[string]map[string]json.RawMessage)
+	if m == nil {
+		m = make(map[string]map[string]json.RawMessage)
+		c.Set(contextAuditBefore, m)
+	}
+	m[v.table+":"+v.auditObjectID(obj)] = values
+}
+
+// audit 写入审计日志，由 publish 调用
+// 在请求的事务中写入（见 dbFor），写入失败只输出日志
+func (v *GenericViewSet) audit(c *gin.Context, action events.Action, obj interface{}) {
+	if !v.EnableAudit {
+		return
+	}
+
+	id := v.auditObjectID(obj)
+	var before map[string]json.RawMessage
+	if snapshots, ok := c.Get(contextAuditBefore); ok {
+		before = snapshots.(map[string]map[string]json.RawMessage)[v.table+":"+id]
+	}
+
+	var changes interface{}
+	switch action {
+	case events.Created:
+		changes = v.auditValues(obj)
+	case events.Deleted:
+		changes = before
+	default:
+		changes = auditDiff(before, v.auditValues(obj))
+	}
+	body, err := json.Marshal(changes)
+	if err != nil {
+		log.Printf("序列化 %s 审计日志失败: %v", v.table, err)
+		return
+	}
+
+	entry := &models.AuditLog{
+		Resource:  v.table,
+		ObjectID:  id,
+		Action:    string(action),
+		IP:        c.ClientIP(),
+		RequestID: c.GetString("request_id"),
+		Changes:   body,
+	}
+	if userID, ok := c.Get(ContextUserID); ok {
+		entry.Actor = fmt.Sprint(userID)
+	}
+	if err := v.dbFor(c).Create(entry).Error; err != nil {
+		log.Printf("写入 %s 审计日志失败: %v", v.table, err)
+	}
+}
+
+// auditObjectID 对象的主键
+func (v *GenericViewSet) auditObjectID(obj interface{}) string {
+	if v.schema == nil || v.schema.PrioritizedPrimaryField == nil {
+		return ""
+	}
+	id, _ := v.schema.PrioritizedPrimaryField.ValueOf(context.Background(), reflect.Indirect(reflect.ValueOf(obj)))
+	return fmt.Sprint(id)
+}
+
+// auditValues 对象的字段值（JSON 字段名 -> 值），不包含只写字段
+func (v *GenericViewSet) auditValues(obj interface{}) map[string]json.RawMessage {
+	raw, err := json.Marshal(v.modelSerializer.Encode(obj))
+	if err != nil {
+		return nil
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// auditDiff 返回发生变化的字段；没有修改前的内容时（例如自定义 action 直接修改对象）返回全部新值
+func auditDiff(before, after map[string]json.RawMessage) interface{} {
+	if before == nil {
+		return after
+	}
+	diff := make(map[string]auditChange)
+	for key, value := range after {
+		old, ok := before[key]
+		if !ok {
+			old = json.RawMessage("null")
+		}
+		if !bytes.Equal(old, value) {
+			diff[key] = auditChange{Old: old, New: value}
+		}
+	}
+	for key, old := range before {
+		if _, ok := after[key]; !ok {
+			diff[key] = auditChange{Old: old, New: json.RawMessage("null")}
+		}
+	}
+	return diff
+}
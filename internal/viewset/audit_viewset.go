@@ -0,0 +1,33 @@
+package viewset
+
+import (
+	"go-viewset/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AuditLogViewSet 审计日志 ViewSet，只读
+type AuditLogViewSet struct {
+	*GenericViewSet
+}
+
+// NewAuditLogViewSet 创建审计日志 ViewSet
+func NewAuditLogViewSet(db *gorm.DB) *AuditLogViewSet {
+	v := &AuditLogViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.AuditLog{}),
+	}
+
+	// 按资源、对象、操作、操作人和时间过滤，例如 ?resource=users&object_id=1&created_at__gte=2024-01-01
+	v.FilterFields = []string{"resource", "object_id", "action", "actor", "ip", "request_id", "created_at"}
+
+	// 审计日志包含修改前后的数据和操作人 IP，只允许管理员查看
+	v.PermissionClasses = []Permission{RequireRole("admin")}
+
+	return v
+}
+
+// RegisterRoutes 注册路由
+func (v *AuditLogViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	v.RegisterMixins(group, v, ReadOnlyMixins...)
+}
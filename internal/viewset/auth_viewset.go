@@ -0,0 +1,305 @@
+package viewset
+
+import (
+	"errors"
+	"go-viewset/internal/auth"
+	"go-viewset/internal/mailer"
+	"go-viewset/internal/models"
+	"go-viewset/internal/oidc"
+	"go-viewset/internal/session"
+	"go-viewset/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// forgotPasswordEmailRate 同一邮箱发送重置密码邮件的频率上限，避免通过不同 IP 向同一邮箱大量发信
+var forgotPasswordEmailRate = mustParseRate("3/hour")
+
+// errInvalidResetToken 重置密码令牌不存在、已使用或已过期
+var errInvalidResetToken = errors.New("令牌无效或已过期")
+
+// AuthViewSet 认证接口：忘记密码（发送重置密码邮件）、通过令牌重置密码，开启会话后还有登录、登出和当前用户
+// 重置密码令牌保存在 password_reset_tokens 表中（只保存哈希），也可以由管理员通过 POST /users/:id/reset_password 生成
+type AuthViewSet struct {
+	*GenericViewSet
+
+	// sessions 为 nil 时不注册 login、logout、me（见 EnableSessions）
+	sessions *session.Manager
+
+	// oidc 第三方登录的身份提供方，key 为名称（见 EnableOIDC）
+	oidc         map[string]oidc.Provider
+	oidcRedirect string
+
+	// Mailer 发送重置密码邮件，为 nil 时忘记密码接口返回 503
+	Mailer *mailer.Mailer
+
+	// ResetTokenTTL 重置密码令牌的有效期，默认 30 分钟
+	ResetTokenTTL time.Duration
+}
+
+// LoginRequest 登录的请求体
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ForgotPasswordRequest 忘记密码的请求体
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 通过令牌重置密码的请求体
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// NewAuthViewSet 创建认证 ViewSet
+func NewAuthViewSet(db *gorm.DB) *AuthViewSet {
+	v := &AuthViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.PasswordResetToken{}),
+		ResetTokenTTL:  defaultResetTokenTTL,
+	}
+
+	// 按客户端 IP 限流，防止暴力尝试；忘记密码与 POST /users/:id/reset_password 共享 password_reset 限额
+	v.Throttles = map[string]string{
+		"login":          "10/minute",
+		"reset_password": "10/hour",
+		"oidc_callback":  "20/minute",
+	}
+	v.ThrottleClasses = map[string][]Throttle{
+		"forgot_password": {ScopedRateThrottle("password_reset", "3/hour")},
+	}
+	v.Permissions = map[string]Permission{
+		"me": IsAuthenticated,
+	}
+	return v
+}
+
+// EnableSessions 开启基于 Cookie 的会话认证，注册 login、logout、me 三个接口
+// 请求中的会话由 middleware.Session 读取
+func (v *AuthViewSet) EnableSessions(m *session.Manager) {
+	v.sessions = m
+}
+
+// RegisterRoutes 注册路由，只有自定义 action 和第三方登录，令牌表不提供 CRUD 接口
+func (v *AuthViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	v.RegisterActions(group, v)
+	v.registerOIDC(group)
+}
+
+// Actions 声明自定义 action
+func (v *AuthViewSet) Actions() []Action {
+	actions := []Action{
+		// POST /api/auth/forgot_password - 发送重置密码邮件
+		ListAction("POST", "forgot_password", v.ForgotPassword),
+
+		// POST /api/auth/reset_password - 通过令牌设置新密码
+		ListAction("POST", "reset_password", v.ResetPassword),
+	}
+	if v.sessions != nil {
+		actions = append(actions,
+			// POST /api/auth/login - 邮箱和密码登录，写入会话 Cookie
+			ListAction("POST", "login", v.Login),
+
+			// POST /api/auth/logout - 删除会话
+			ListAction("POST", "logout", v.Logout),
+
+			// GET /api/auth/me - 当前登录的用户
+			ListAction("GET", "me", v.Me),
+		)
+	}
+	return actions
+}
+
+// ForgotPassword 向邮箱对应的用户发送重置密码邮件，之前未使用的令牌失效
+// 无论邮箱是否已注册都返回相同的响应，避免被用来探测已注册的邮箱
+// POST /api/auth/forgot_password
+func (v *AuthViewSet) ForgotPassword(c *gin.Context) {
+	if v.Mailer == nil {
+		utils.ErrorWithStatus(c, http.StatusServiceUnavailable, http.StatusServiceUnavailable, "未配置邮件发送，无法找回密码")
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+
+	respond := func() {
+		v.Respond(c, gin.H{"message": "如果该邮箱已注册，重置密码邮件已发送"})
+	}
+
+	var user models.User
+	err := v.dbFor(c).Where("email = ?", strings.TrimSpace(req.Email)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respond()
+		return
+	}
+	if err != nil {
+		v.dbError(c, "查询用户失败", err)
+		return
+	}
+
+	// 同一用户超过频率时不再发信，响应不变
+	if ok, _ := v.throttleStore().Allow("forgot_password:user:"+strconv.FormatUint(uint64(user.ID), 10), forgotPasswordEmailRate); !ok {
+		respond()
+		return
+	}
+
+	token, _, err := issueResetToken(v.dbFor(c), user.ID, v.ResetTokenTTL)
+	if err != nil {
+		v.dbError(c, "生成重置密码令牌失败", err)
+		return
+	}
+
+	// 令牌保存成功（事务提交）后再发送邮件
+	afterCommit(c, func() { sendResetEmail(v.Mailer, &user, token, v.ResetTokenTTL) })
+	respond()
+}
+
+// ResetPassword 校验令牌后设置新密码，令牌只能使用一次，设置后该用户的全部令牌失效
+// 用户已有的会话在同一事务中删除，之前登录的客户端（包括可能盗用账号的人）需要重新登录
+// POST /api/auth/reset_password
+func (v *AuthViewSet) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if errs := passwordErrors("new_password", req.NewPassword); len(errs) > 0 {
+		utils.ValidationError(c, errs)
+		return
+	}
+
+	var user models.User
+	err := v.dbFor(c).Transaction(func(tx *gorm.DB) error {
+		// 在一条 UPDATE 中检查令牌未使用、未过期并标记为已使用，同一令牌的并发请求只有一个成功
+		hash := auth.HashToken(req.Token)
+		now := time.Now()
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hash, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidResetToken
+		}
+
+		var record models.PasswordResetToken
+		if err := tx.Where("token_hash = ?", hash).First(&record).Error; err != nil {
+			return err
+		}
+		err := tx.First(&user, record.UserID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := setPassword(tx, &user, req.NewPassword); err != nil {
+			return err
+		}
+
+		// 令牌只通过邮件发送，能使用令牌说明用户可以收到该邮箱的邮件
+		if user.EmailVerifiedAt == nil {
+			if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error
+	})
+	if errors.Is(err, errInvalidResetToken) {
+		utils.ValidationError(c, []utils.FieldError{
+			{Field: "token", Code: utils.CodeInvalid, Message: "令牌无效或已过期"},
+		})
+		return
+	}
+	if err != nil {
+		v.dbError(c, "重置密码失败", err)
+		return
+	}
+
+	// 数据库中的会话已在事务中删除，会话保存在 Redis 时在提交后删除
+	if v.sessions != nil {
+		afterCommit(c, func() {
+			if err := v.sessions.LogoutUser(c.Request.Context(), user.ID); err != nil {
+				log.Printf("删除用户 %d 的会话失败: %v", user.ID, err)
+			}
+		})
+	}
+
+	v.Respond(c, gin.H{
+		"message": "密码已重置",
+		"user_id": user.ID,
+	})
+}
+
+// Login 校验邮箱和密码，成功后创建会话并写入 Cookie
+// 邮箱不存在和密码错误返回相同的错误，邮箱不存在时同样计算一次哈希，响应时间也相同
+// POST /api/auth/login
+func (v *AuthViewSet) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+
+	var user models.User
+	err := v.dbFor(c).Where("email = ?", strings.TrimSpace(req.Email)).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		v.dbError(c, "查询用户失败", err)
+		return
+	}
+	// 邮箱不存在时 PasswordHash 为空，CheckPassword 仍然计算一次哈希
+	if ok := auth.CheckPassword(user.PasswordHash, req.Password); err != nil || !ok {
+		utils.Unauthorized(c, "邮箱或密码错误")
+		return
+	}
+
+	s, err := v.sessions.Login(c, user.ID)
+	if err != nil {
+		v.dbError(c, "创建会话失败", err)
+		return
+	}
+	v.Respond(c, gin.H{
+		"user":       user,
+		"expires_at": s.ExpiresAt,
+	})
+}
+
+// Logout 删除当前会话并清除 Cookie，未登录时也返回成功
+// POST /api/auth/logout
+func (v *AuthViewSet) Logout(c *gin.Context) {
+	if err := v.sessions.Logout(c); err != nil {
+		v.dbError(c, "删除会话失败", err)
+		return
+	}
+	v.Respond(c, gin.H{"message": "已退出登录"})
+}
+
+// Me 返回当前登录的用户
+// GET /api/auth/me
+func (v *AuthViewSet) Me(c *gin.Context) {
+	userID, _ := c.Get(ContextUserID)
+	var user models.User
+	err := v.dbFor(c).First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.Unauthorized(c, "用户不存在")
+		return
+	}
+	if err != nil {
+		v.dbError(c, "查询用户失败", err)
+		return
+	}
+	v.Respond(c, user)
+}
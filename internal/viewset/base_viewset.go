@@ -1,13 +1,28 @@
 package viewset
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go-viewset/internal/cache"
+	"go-viewset/internal/database"
+	"go-viewset/internal/events"
+	"go-viewset/internal/lock"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/throttle"
 	"go-viewset/internal/utils"
+	"log"
+	"net/http"
 	"reflect"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // BaseViewSet 定义 ViewSet 的基础接口
@@ -26,6 +41,189 @@ type GenericViewSet struct {
 	DB        *gorm.DB
 	Model     interface{}
 	ModelType reflect.Type
+	Locker    lock.Locker
+	Events    *events.Bus
+
+	// CountCache 列表总数缓存，通过 EnableCountCache 开启
+	CountCache *cache.CountCache
+
+	// Cache 查询结果缓存，通过 EnableCache 开启
+	Cache    cache.Cache
+	CacheTTL time.Duration
+
+	// IndexAdvisor 开发模式下检查过滤和排序字段是否缺少索引，nil 表示不检查
+	IndexAdvisor *database.IndexAdvisor
+
+	// Relations 查询时一并加载的关联，例如 []string{"Profile", "Orders.Items"}
+	// MaxExpandDepth 关联路径的最大深度，默认 2
+	Relations      []string
+	MaxExpandDepth int
+
+	// OwnerField 记录所有者的字段（JSON 字段名或列名），例如 "user_id"
+	// 设置后写请求需要登录，创建时自动取当前用户，修改和删除只允许所有者（管理员不受限制），读请求不受影响
+	// ScopeToOwner 列表和详情也只包含当前用户的记录
+	OwnerField   string
+	ScopeToOwner bool
+
+	// TenantField 记录所属租户的字段（JSON 字段名或列名），例如 "tenant_id"（见 EnableTenantScope）
+	// 设置后所有查询只包含当前租户（ContextTenantID）的记录，创建时自动取当前租户，不能通过更新修改
+	TenantField string
+
+	// EnableAudit 写操作（包括批量创建、导入和发布了事件的自定义 action）记录审计日志（见 models.AuditLog）
+	EnableAudit bool
+
+	// Expandable 允许客户端通过 ?expand= 展开的关联路径（Go 字段名），例如 []string{"Orders", "Profile"}，
+	// 客户端使用关联字段的 JSON 名称：?expand=orders,profile
+	Expandable []string
+
+	// Atomic 写请求（POST/PUT/PATCH/DELETE）是否在事务中执行，默认 true
+	// 钩子和自定义 action 通过 dbFor 或 TxFrom 参与同一个事务，返回错误响应时回滚
+	Atomic bool
+
+	// CountByDefault 客户端未指定 ?count=（或 with_count）时是否统计总数，默认 true
+	CountByDefault bool
+
+	// WindowCount 使用 COUNT(*) OVER() 在一次查询中同时取回数据和总数
+	// 需要数据库支持窗口函数（MySQL 8.0+、PostgreSQL、SQLite 3.25+），需要加载关联（Relations 或 ?expand=）时不生效
+	WindowCount bool
+
+	// ConcurrentCount 列表查询时 COUNT 与数据查询并行执行
+	// 可以降低延迟，但每个列表请求会同时占用两个数据库连接
+	ConcurrentCount bool
+
+	// PaginationConfig 每页条数的默认值、上限以及是否允许 ?page_size=0 关闭分页，
+	// 零值字段使用 utils.DefaultPaginationConfig
+	PaginationConfig utils.PaginationConfig
+
+	// PaginationMode 列表的分页方式，默认页码分页；utils.CursorPagination 为游标分页（见 listWithCursor）
+	PaginationMode utils.PaginationMode
+
+	// StreamThreshold 每页条数达到该值（或关闭了分页）时，List 改为逐行读取并流式输出；
+	// 默认 0 表示每页条数的上限（PaginationConfig.MaxPageSize），负数表示不启用
+	StreamThreshold int
+
+	// JSONAPI 始终使用 JSON:API 格式（jsonapi.org）：响应按 JSON:API 文档输出，创建和修改的请求体按 JSON:API 文档解析，
+	// 不需要 EnableJSONAPI；未开启时只有 Accept / Content-Type 为 application/vnd.api+json 的请求使用（见 jsonapi.go）
+	JSONAPI bool
+
+	// GetSerializer 按 action（ActionList、ActionCreate 等）返回使用的 Serializer
+	// 返回 nil 时使用默认的 ModelSerializer（按 access 标签处理只读/只写字段）
+	GetSerializer func(action string) Serializer
+
+	// EnableBulkOperations 开启批量创建接口 POST /bulk（见 BulkCreate）
+	// BulkMaxItems 单次批量操作的最大记录数，默认 1000
+	EnableBulkOperations bool
+	BulkMaxItems         int
+
+	// EnableETag 开启条件请求：GET 响应带弱 ETag，If-None-Match 匹配时返回 304；
+	// Update/PATCH/Delete 带 If-Match 时与当前对象的 ETag 比较，不匹配返回 412（见 etag.go）
+	EnableETag bool
+
+	// EnableImport 开启文件导入接口 POST /import（见 Import）
+	// ImportMaxRows 单个文件的最大数据行数，默认 10000
+	EnableImport  bool
+	ImportMaxRows int
+
+	// EnableAggregate 开启分组统计接口 GET /aggregate（见 Aggregate）
+	// AggregateFields 允许分组和统计的字段（JSON 字段名或列名），为空时模型上可以过滤的字段都可以
+	EnableAggregate bool
+	AggregateFields []string
+
+	// EnableStream 开启变更推送接口 GET /stream（Server-Sent Events，见 Stream）
+	EnableStream bool
+
+	// EnableExport 开启 Excel 导出接口 GET /export.xlsx（见 ExportXLSX）
+	// ExportColumns 导出（CSV 和 Excel）的列、顺序、表头和格式化，为空时导出全部可输出的字段
+	EnableExport  bool
+	ExportColumns []ExportColumn
+
+	// PermissionClasses 对所有 action 生效的权限类，需要全部通过
+	PermissionClasses []Permission
+
+	// Permissions 按 action 配置的权限类，与 PermissionClasses 同时生效，
+	// "*" 为未单独配置的 action 的默认值，例如：
+	//   map[string]Permission{"list": AllowAny, "create": IsAdmin, "activate": HasPerm("users.manage")}
+	Permissions map[string]Permission
+
+	// Messages 按 action 配置成功响应的 msg，未配置时为 "success"
+	// ResponseHook 写出成功响应前调用，可以修改 msg（如本地化）或补充 data
+	Messages     map[string]string
+	ResponseHook func(c *gin.Context, resp *ActionResponse)
+
+	// Throttles 按 action 配置的限流速率，例如 {"reset_password": "3/hour", "stats": "10/minute"}
+	// "*" 为整个 ViewSet 共享的限流；已登录用户按用户 ID 计数，否则按客户端 IP
+	Throttles map[string]string
+
+	// ThrottleClasses 按 action 配置的限流类，"*" 对所有 action 生效，例如：
+	//   map[string][]Throttle{"*": {AnonRateThrottle("60/minute")}, "reset_password": {ScopedRateThrottle("password_reset", "5/hour")}}
+	// ThrottleStore 限流计数的存储，为 nil 时使用 throttle.Default
+	ThrottleClasses map[string][]Throttle
+	ThrottleStore   throttle.Store
+
+	// ParentLookups 嵌套路由的父资源，查询限定在父资源下，创建时自动设置外键（见 AddParentLookup）
+	ParentLookups []ParentLookup
+
+	// Validators 自定义校验函数，创建和更新时执行，错误与 binding 规则的错误合并输出
+	Validators []ValidatorFunc
+
+	// FilterFields 允许过滤的字段（JSON 字段名或列名），为空时模型上的字段都可以过滤
+	// （filter:"-" 标记的除外）。不在列表中的过滤参数被忽略
+	FilterFields []string
+
+	// FilterSet 过滤结构体，例如 UserFilter{}，配置后 List 按它绑定和校验查询参数（见 utils.BindFilterSet），
+	// 代替默认的按字段名等值过滤
+	FilterSet interface{}
+
+	// OrderingFields 允许排序的字段（JSON 字段名或列名），配置后 ?ordering= / ?order_by= 中出现其他字段时返回 400；
+	// 为空时模型上的字段都可以排序（order:"-" 标记的除外），不允许排序的字段被忽略
+	OrderingFields []string
+
+	// SearchFields ?search= 模糊匹配的字段（JSON 字段名或列名），任一字段包含搜索词即匹配，为空时不支持搜索
+	// SearchMode 搜索方式，默认 LIKE 匹配；全文搜索见 EnableFullTextSearch
+	SearchFields []string
+	SearchMode   utils.SearchMode
+
+	// expandNames Expandable 解析后的 展开名 -> 关联路径，首次使用时构建
+	expandNames map[string]string
+	expandOnce  sync.Once
+
+	// filterFields FilterFields 解析后的列名集合，首次使用时构建
+	filterFields     map[string]bool
+	filterFieldsOnce sync.Once
+
+	// orderingFields OrderingFields 解析后的列名集合，首次使用时构建
+	orderingFields     map[string]bool
+	orderingFieldsOnce sync.Once
+
+	// searchColumns SearchFields 解析后的列名，首次使用时构建
+	searchColumns     []string
+	searchColumnsOnce sync.Once
+
+	// 构造时缓存的反射元数据，避免每个请求重复计算
+	sliceType     reflect.Type
+	windowRowType reflect.Type
+	table         string
+	meta          *meta.Model
+	schema        *schema.Schema
+
+	modelSerializer *ModelSerializer
+
+	// historyTable 历史记录表，未开启时为空（见 EnableHistory）
+	historyTable string
+	slicePool    sync.Pool
+
+	// files 附件字段，未开启时为 nil（见 EnableFileFields）
+	files *fileFields
+
+	// links 列表和详情响应带 _links（见 EnableLinks）
+	links bool
+
+	// impl 最外层的 ViewSet，PerformCreate 等钩子在它上面查找（见 SetImpl）
+	impl interface{}
+
+	// newFunc 创建模型实例，ViewSet[T] 使用 new(T) 代替反射；typed 为 ViewSet[T] 的类型化钩子
+	newFunc func() interface{}
+	typed   typedHooks
 }
 
 // NewGenericViewSet 创建一个新的 GenericViewSet
@@ -36,51 +234,687 @@ func NewGenericViewSet(db *gorm.DB, model interface{}) *GenericViewSet {
 		modelType = modelType.Elem()
 	}
 
-	return &GenericViewSet{
+	v := &GenericViewSet{
 		DB:        db,
 		Model:     model,
 		ModelType: modelType,
+		Locker:    lock.New(db),
+		Events:    events.Default,
+
+		CountByDefault: true,
+		Atomic:         true,
+
+		sliceType:     reflect.SliceOf(reflect.PtrTo(modelType)),
+		windowRowType: newWindowRowType(modelType),
 	}
+
+	// 读取模型元数据（表名会考虑模型自定义的 TableName）
+	if m, err := meta.Of(db, model); err == nil {
+		v.meta = m
+		v.table = m.Table
+		v.schema = m.Schema
+	} else {
+		v.table = db.NamingStrategy.TableName(modelType.Name())
+	}
+	v.modelSerializer = NewModelSerializer(v.meta)
+
+	return v
+}
+
+// dbFor 返回绑定了请求 context 的数据库会话，请求在事务中时返回事务
+// 请求信息（路由、请求 ID）随 context 传递给数据库回调
+func (v *GenericViewSet) dbFor(c *gin.Context) *gorm.DB {
+	if tx, ok := TxFrom(c); ok {
+		return tx.WithContext(c.Request.Context())
+	}
+	return v.requestDB(c).WithContext(c.Request.Context())
+}
+
+// requestDB 本次请求使用的数据库：每个租户使用独立数据库时为当前租户的数据库（ContextTenantDB），否则为 v.DB
+func (v *GenericViewSet) requestDB(c *gin.Context) *gorm.DB {
+	if db, ok := c.Get(ContextTenantDB); ok {
+		return db.(*gorm.DB)
+	}
+	return v.DB
+}
+
+// newObject 创建一个模型实例的指针，例如 *User
+func (v *GenericViewSet) newObject() interface{} {
+	if v.newFunc != nil {
+		return v.newFunc()
+	}
+	return reflect.New(v.ModelType).Interface()
+}
+
+// acquireSlice 从池中获取一个模型切片的指针，例如 *[]*User
+// 使用完毕后需要调用 releaseSlice 归还
+func (v *GenericViewSet) acquireSlice() interface{} {
+	if ptr := v.slicePool.Get(); ptr != nil {
+		return ptr
+	}
+	return reflect.New(v.sliceType).Interface()
+}
+
+// releaseSlice 清空切片并归还到池中，保留底层数组以便复用
+// 只能在响应已经写出之后调用
+func (v *GenericViewSet) releaseSlice(ptr interface{}) {
+	slice := reflect.ValueOf(ptr).Elem()
+	// 清除元素引用，避免池中的切片持有上一次请求的对象
+	for i := 0; i < slice.Len(); i++ {
+		slice.Index(i).Set(reflect.Zero(slice.Type().Elem()))
+	}
+	slice.SetLen(0)
+	v.slicePool.Put(ptr)
 }
 
 // List 获取列表
 // 支持分页、过滤和排序
 // GET /items/?page=1&page_size=10&name=abc&order_by=created_at desc
 func (v *GenericViewSet) List(c *gin.Context) {
-	// 创建模型切片
-	sliceType := reflect.SliceOf(reflect.PtrTo(v.ModelType))
-	results := reflect.New(sliceType).Interface()
+	// ?format=csv 导出全部记录，不经过缓存和分页
+	if utils.WantCSV(c) {
+		v.exportCSV(c, nil)
+		return
+	}
+
+	// 优先读取缓存
+	cacheKey := v.listCacheKey(c)
+	if v.serveFromCache(c, cacheKey) {
+		return
+	}
+
+	// 客户端已断开时不再查询
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	// 解析 ?fields= 和 ?expand=
+	if !v.parseFields(c) || !v.parseExpand(c) {
+		return
+	}
 
 	// 获取分页参数
-	paginationParams := utils.GetPaginationParams(c)
+	paginationParams := utils.GetPaginationParams(c, v.PaginationConfig)
 
 	// 获取过滤参数
-	filterParams := utils.GetFilterParams(c)
+	filterParams, ok := v.filterParams(c)
+	if !ok {
+		return
+	}
+	defer utils.ReleaseFilterParams(filterParams)
 
-	// 构建查询
-	query := v.DB.Model(v.Model)
+	if v.PaginationMode == utils.CursorPagination {
+		v.listWithCursor(c, filterParams, cacheKey)
+		return
+	}
 
-	// 应用过滤
-	query = utils.ApplyFilters(query, filterParams)
+	// 统计总数和查询数据由同一个应用了过滤条件的查询派生，各自添加的子句互不影响
+	newQuery := v.listQuery(c, filterParams)
 
-	// 获取总数（在应用分页之前）
-	var total int64
-	query.Count(&total)
+	// 数据和总数在一次查询中取回
+	signature := v.parentScopeKey(c) + filterParams.Signature()
+	// 流式输出只支持 JSON
+	threshold := v.streamThreshold()
+	streaming := threshold > 0 && (paginationParams.Disabled || paginationParams.Limit >= threshold) &&
+		utils.NegotiateFormat(c) == utils.FormatJSON
+	if !streaming && v.useWindowCount(c, signature) {
+		v.listWithWindowCount(c, newQuery, paginationParams, signature, cacheKey)
+		return
+	}
+
+	// 开始统计总数
+	waitPagination := v.startPagination(c, newQuery, paginationParams, signature)
 
-	// 应用分页
-	query = utils.ApplyPagination(query, paginationParams)
+	// 应用分页，只查询 ?fields= 请求的列
+	query := v.selectFields(c, utils.ApplyPagination(newQuery(), paginationParams))
+
+	// 大分页使用流式输出，避免在内存中构建完整响应
+	if streaming {
+		pagination, err := waitPagination()
+		if err != nil {
+			v.dbError(c, "查询失败", err)
+			return
+		}
+		defer utils.ReleasePagination(pagination)
+
+		v.streamList(c, query, pagination)
+		return
+	}
+
+	// 加载配置和 ?expand= 展开的关联（列表查询只使用 Preload）
+	plan, err := v.planPreloads(v.relationsFor(c), false)
+	if err != nil {
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+	query = plan.apply(query)
+
+	// 获取模型切片（从池中复用）
+	results := v.acquireSlice()
+	defer v.releaseSlice(results)
 
 	// 执行查询
 	if err := query.Find(results).Error; err != nil {
-		utils.InternalServerError(c, fmt.Sprintf("查询失败: %v", err))
+		v.dbError(c, "查询失败", err)
 		return
 	}
 
-	// 构建分页信息
-	pagination := utils.BuildPagination(paginationParams, total)
+	// 客户端已断开时跳过序列化
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	// 等待总数统计完成
+	pagination, err := waitPagination()
+	if err != nil {
+		v.dbError(c, "查询失败", err)
+		return
+	}
+	defer utils.ReleasePagination(pagination)
+
+	data := v.pickFields(c, v.serializeList(c, results))
+	v.saveToCache(c, cacheKey, data, pagination)
 
 	// 返回结果
-	utils.SuccessWithPagination(c, results, pagination)
+	v.RespondWithPagination(c, data, pagination)
+}
+
+// ListHead 只返回总数
+// 总数通过 X-Total-Count 响应头返回，不查询数据
+// HEAD /items/?status=active
+func (v *GenericViewSet) ListHead(c *gin.Context) {
+	filterParams, ok := v.filterParams(c)
+	if !ok {
+		return
+	}
+	defer utils.ReleaseFilterParams(filterParams)
+
+	query := utils.ApplyFilters(v.queryset(c).Model(v.Model), filterParams)
+	mode := utils.GetCountMode(c, utils.CountExact)
+	if mode == utils.CountNone {
+		// HEAD 只用于取得总数，none 按 exact 处理
+		mode = utils.CountExact
+	}
+	total, estimated, err := v.count(query, mode, v.parentScopeKey(c)+filterParams.Signature())
+	if err != nil {
+		v.dbError(c, "查询失败", err)
+		return
+	}
+
+	if estimated {
+		c.Header("X-Total-Count-Estimated", "true")
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.Status(http.StatusOK)
+}
+
+// listQuery 返回构建列表查询的函数：过滤条件只应用一次，每次调用都从这个基础查询派生出新的会话，
+// COUNT 和数据查询各自添加的 Select、Order、Limit 等子句不会互相影响
+func (v *GenericViewSet) listQuery(c *gin.Context, filterParams *utils.FilterParams) func() *gorm.DB {
+	base := utils.ApplyFilters(v.queryset(c).Model(v.Model), filterParams).Session(&gorm.Session{})
+	return func() *gorm.DB {
+		return base.Session(&gorm.Session{})
+	}
+}
+
+// streamThreshold 流式输出的每页条数阈值，StreamThreshold 为 0 时取每页条数的上限，不启用时返回 0
+func (v *GenericViewSet) streamThreshold() int {
+	switch {
+	case v.StreamThreshold < 0:
+		return 0
+	case v.StreamThreshold > 0:
+		return v.StreamThreshold
+	case v.PaginationConfig.MaxPageSize > 0:
+		return v.PaginationConfig.MaxPageSize
+	}
+	return utils.DefaultPaginationConfig.MaxPageSize
+}
+
+// countMode 本次请求的总数统计方式，客户端未指定时按 CountByDefault
+func (v *GenericViewSet) countMode(c *gin.Context) utils.CountMode {
+	def := utils.CountNone
+	if v.CountByDefault {
+		def = utils.CountExact
+	}
+	return utils.GetCountMode(c, def)
+}
+
+// count 按 mode 统计总数，estimated 表示结果为估算值（估算值不写入总数缓存）
+// 数据库不支持估算时退回准确的 COUNT
+func (v *GenericViewSet) count(query *gorm.DB, mode utils.CountMode, signature string) (total int64, estimated bool, err error) {
+	if mode == utils.CountEstimated {
+		if total, ok, err := utils.EstimateCount(query); ok {
+			return total, true, err
+		}
+	}
+	total, err = v.countTotal(query, signature)
+	return total, false, err
+}
+
+// startPagination 开始构建分页信息，返回等待结果的函数
+// 按客户端选择的统计方式（见 utils.GetCountMode）统计总数并通过 X-Total-Count 响应头返回，
+// ?count=none 时跳过 COUNT 查询，total 返回 null。
+// newQuery 每次调用都应返回一个新的、应用了过滤条件但未分页的查询；
+// 开启 ConcurrentCount 时 COUNT 在独立的 goroutine 和连接上与数据查询并行执行。
+func (v *GenericViewSet) startPagination(c *gin.Context, newQuery func() *gorm.DB, params *utils.PaginationParams, signature string) func() (*utils.Pagination, error) {
+	mode := v.countMode(c)
+	if mode == utils.CountNone {
+		return func() (*utils.Pagination, error) {
+			return utils.BuildPaginationWithoutTotal(params), nil
+		}
+	}
+
+	// 响应头只在调用方的 goroutine 中写入
+	done := func(total int64, estimated bool, err error) (*utils.Pagination, error) {
+		if err != nil {
+			return nil, err
+		}
+		if estimated {
+			c.Header("X-Total-Count-Estimated", "true")
+		}
+		c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+		return utils.BuildPagination(params, total), nil
+	}
+
+	if total, ok := v.cachedCount(signature); ok && mode == utils.CountExact {
+		return func() (*utils.Pagination, error) { return done(total, false, nil) }
+	}
+
+	// 查询在当前 goroutine 中构建，goroutine 中只执行 COUNT
+	countQuery := newQuery()
+	if !v.ConcurrentCount {
+		total, estimated, err := v.count(countQuery, mode, signature)
+		return func() (*utils.Pagination, error) { return done(total, estimated, err) }
+	}
+
+	type countResult struct {
+		total     int64
+		estimated bool
+		err       error
+	}
+	ch := make(chan countResult, 1)
+	go func() {
+		total, estimated, err := v.count(countQuery, mode, signature)
+		ch <- countResult{total, estimated, err}
+	}()
+
+	ctx := c.Request.Context()
+	return func() (*utils.Pagination, error) {
+		select {
+		case r := <-ch:
+			return done(r.total, r.estimated, r.err)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// EnableCountCache 开启列表总数缓存
+// 本 ViewSet 对应资源发生写操作时（通过事件总线）缓存自动失效，
+// ttl 用于兜底其他实例上的写操作
+func (v *GenericViewSet) EnableCountCache(ttl time.Duration) {
+	v.CountCache = cache.NewCountCache(ttl)
+	v.Events.Subscribe(func(e events.Event) {
+		if e.Resource == v.table {
+			v.CountCache.Invalidate(v.table)
+		}
+	})
+}
+
+// cachedCount 读取缓存的总数
+func (v *GenericViewSet) cachedCount(signature string) (int64, bool) {
+	if v.CountCache == nil || !v.querysetCacheable() {
+		return 0, false
+	}
+	return v.CountCache.Get(v.table, signature)
+}
+
+// countTotal 统计查询结果总数，开启总数缓存时优先读取缓存
+// signature 用于区分不同的过滤条件
+func (v *GenericViewSet) countTotal(query *gorm.DB, signature string) (int64, error) {
+	if total, ok := v.cachedCount(signature); ok {
+		return total, nil
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	if v.CountCache != nil && v.querysetCacheable() {
+		v.CountCache.Set(v.table, signature, total)
+	}
+	return total, nil
+}
+
+// filterParams 读取过滤和排序参数，并解析为数据库列名
+// 配置了 FilterSet 时过滤条件按结构体绑定和校验，其他查询参数不再参与过滤；
+// 排序字段不在 OrderingFields 中时写出 400，校验失败时写出 422，并返回 false
+func (v *GenericViewSet) filterParams(c *gin.Context, excludeKeys ...string) (*utils.FilterParams, bool) {
+	params := utils.GetFilterParams(c, excludeKeys...)
+
+	if field, ok := v.checkOrdering(params.Ordering); !ok {
+		utils.ReleaseFilterParams(params)
+		utils.BadRequest(c, "不允许按 "+field+" 排序")
+		return nil, false
+	}
+
+	if v.FilterSet != nil {
+		conditions, err := utils.BindFilterSet(c, v.FilterSet)
+		if err != nil {
+			utils.ReleaseFilterParams(params)
+			utils.ValidationError(c, utils.BindingErrors(err))
+			return nil, false
+		}
+		for key := range params.Filters {
+			delete(params.Filters, key)
+		}
+		params.OrGroups = params.OrGroups[:0]
+		params.Conditions = append(params.Conditions, conditions...)
+	}
+
+	v.resolveFilters(params)
+	if params.SearchFields = v.resolvedSearchFields(); len(params.SearchFields) == 0 {
+		params.Search = ""
+	}
+	params.SearchMode = v.SearchMode
+	errs := v.parseTimeFilters(params)
+	if errs = append(errs, utils.ValidateFilters(params)...); len(errs) > 0 {
+		utils.ReleaseFilterParams(params)
+		utils.ValidationError(c, errs)
+		return nil, false
+	}
+	v.checkIndexes(params)
+	return params, true
+}
+
+// resolveFilters 将过滤和排序参数中的字段名解析为数据库列名
+// 支持 JSON 字段名和列名两种写法，模型上不存在或不允许过滤/排序的字段会被忽略，
+// 避免把任意查询参数拼进 SQL；无法解析模型元数据时全部忽略
+func (v *GenericViewSet) resolveFilters(params *utils.FilterParams) {
+	if v.meta == nil {
+		for key := range params.Filters {
+			delete(params.Filters, key)
+		}
+		params.OrGroups = params.OrGroups[:0]
+		params.Ordering = params.Ordering[:0]
+		return
+	}
+
+	keys := make([]string, 0, len(params.Filters))
+	for key := range params.Filters {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		value := params.Filters[key]
+		delete(params.Filters, key)
+		if column, ok := v.resolveFilterKey(key); ok {
+			params.Filters[column] = value
+		}
+	}
+
+	groups := params.OrGroups[:0]
+	for _, group := range params.OrGroups {
+		terms := group[:0]
+		for _, term := range group {
+			if column, ok := v.resolveFilterKey(term.Key); ok {
+				terms = append(terms, utils.FilterTerm{Key: column, Value: term.Value})
+			}
+		}
+		if len(terms) > 0 {
+			groups = append(groups, terms)
+		}
+	}
+	params.OrGroups = groups
+
+	// 排序字段逐个校验，不允许排序的字段被忽略，重复的字段只保留第一次
+	ordering := params.Ordering[:0]
+	seen := make(map[string]bool, len(params.Ordering)+1)
+	for _, order := range params.Ordering {
+		field, ok := v.meta.Lookup(order.Field)
+		if !ok || !field.Orderable || seen[field.Column] {
+			continue
+		}
+		seen[field.Column] = true
+		ordering = append(ordering, utils.OrderField{Field: field.Column, Desc: order.Desc})
+	}
+
+	// 以主键作为最后的排序字段，保证排序值相同的记录在分页时顺序稳定
+	if len(ordering) > 0 && v.schema != nil && v.schema.PrioritizedPrimaryField != nil {
+		if pk := v.schema.PrioritizedPrimaryField.DBName; !seen[pk] {
+			ordering = append(ordering, utils.OrderField{Field: pk})
+		}
+	}
+	params.Ordering = ordering
+}
+
+// resolveFilterKey 将过滤参数名解析为列名，保留 lookup 后缀（例如 phone__isnull）
+// 字段不存在或不允许过滤时返回 false
+func (v *GenericViewSet) resolveFilterKey(key string) (string, bool) {
+	name, lookup := utils.SplitLookup(key)
+	field, ok := v.meta.Lookup(name)
+	if !ok || !field.Filterable {
+		return "", false
+	}
+	if allowed := v.allowedFilterFields(); allowed != nil && !allowed[field.Column] {
+		return "", false
+	}
+	if lookup != "" {
+		return field.Column + utils.LookupSeparator + lookup, true
+	}
+	return field.Column, true
+}
+
+// allowedFilterFields 返回 FilterFields 对应的列名集合，未配置时返回 nil
+// 模型上不存在的字段在首次使用时输出告警
+func (v *GenericViewSet) allowedFilterFields() map[string]bool {
+	v.filterFieldsOnce.Do(func() {
+		if len(v.FilterFields) == 0 {
+			return
+		}
+		v.filterFields = make(map[string]bool, len(v.FilterFields))
+		for _, name := range v.FilterFields {
+			field, ok := v.meta.Lookup(name)
+			if !ok || !field.Filterable {
+				log.Printf("[警告] %s 的 FilterFields 中的字段 %s 不存在或不允许过滤", v.table, name)
+				continue
+			}
+			v.filterFields[field.Column] = true
+		}
+	})
+	return v.filterFields
+}
+
+// parseTimeFilters 将时间字段比较条件（例如 ?created_at__gte=2024-01-01）的值解析为 time.Time（见 utils.ParseTime）
+// 应在 resolveFilters 之后调用，返回无法解析的值对应的字段错误
+func (v *GenericViewSet) parseTimeFilters(params *utils.FilterParams) []utils.FieldError {
+	if v.meta == nil {
+		return nil
+	}
+
+	var errs []utils.FieldError
+	parse := func(key string, value interface{}) interface{} {
+		column, lookup := utils.SplitLookup(key)
+		field, ok := v.meta.Lookup(column)
+		if !ok || field.TypeName != "time" || !utils.IsCompareLookup(lookup) {
+			return value
+		}
+		raw, ok := value.(string)
+		if !ok {
+			return value
+		}
+		t, err := utils.ParseTime(raw)
+		if err != nil {
+			errs = append(errs, utils.FieldError{Field: key, Code: utils.CodeInvalid, Message: key + " " + err.Error()})
+			return value
+		}
+		return t
+	}
+
+	for key, value := range params.Filters {
+		params.Filters[key] = parse(key, value)
+	}
+	for _, group := range params.OrGroups {
+		for i := range group {
+			group[i].Value = parse(group[i].Key, group[i].Value)
+		}
+	}
+	return errs
+}
+
+// checkOrdering 检查排序字段是否都在 OrderingFields 中，返回第一个不允许的字段
+// 未配置 OrderingFields 时总是通过
+func (v *GenericViewSet) checkOrdering(ordering []utils.OrderField) (string, bool) {
+	allowed := v.allowedOrderingFields()
+	if allowed == nil {
+		return "", true
+	}
+	for _, order := range ordering {
+		field, ok := v.meta.Lookup(order.Field)
+		if !ok || !allowed[field.Column] {
+			return order.Field, false
+		}
+	}
+	return "", true
+}
+
+// allowedOrderingFields 返回 OrderingFields 对应的列名集合，未配置时返回 nil
+// 模型上不存在或不允许排序的字段在首次使用时输出告警
+func (v *GenericViewSet) allowedOrderingFields() map[string]bool {
+	v.orderingFieldsOnce.Do(func() {
+		if len(v.OrderingFields) == 0 || v.meta == nil {
+			return
+		}
+		v.orderingFields = make(map[string]bool, len(v.OrderingFields))
+		for _, name := range v.OrderingFields {
+			field, ok := v.meta.Lookup(name)
+			if !ok || !field.Orderable {
+				log.Printf("[警告] %s 的 OrderingFields 中的字段 %s 不存在或不允许排序", v.table, name)
+				continue
+			}
+			v.orderingFields[field.Column] = true
+		}
+	})
+	return v.orderingFields
+}
+
+// resolvedSearchFields 返回 SearchFields 对应的列名
+// 模型上不存在或不允许过滤的字段在首次使用时输出告警并被忽略
+func (v *GenericViewSet) resolvedSearchFields() []string {
+	v.searchColumnsOnce.Do(func() {
+		if v.meta == nil {
+			return
+		}
+		for _, name := range v.SearchFields {
+			field, ok := v.meta.Lookup(name)
+			if !ok || !field.Filterable {
+				log.Printf("[警告] %s 的 SearchFields 中的字段 %s 不存在或不允许过滤", v.table, name)
+				continue
+			}
+			v.searchColumns = append(v.searchColumns, v.table+"."+field.Column)
+		}
+	})
+	return v.searchColumns
+}
+
+// checkIndexes 检查过滤和排序字段是否有索引
+// 应在 resolveFilters 之后调用
+func (v *GenericViewSet) checkIndexes(params *utils.FilterParams) {
+	if v.IndexAdvisor == nil {
+		return
+	}
+
+	columns := make([]string, 0, len(params.Filters))
+	for key := range params.Filters {
+		column, _ := utils.SplitLookup(key)
+		columns = append(columns, column)
+	}
+	for _, cond := range params.Conditions {
+		columns = append(columns, cond.Column)
+	}
+	v.IndexAdvisor.Check(v.table, "过滤", columns...)
+
+	for _, order := range params.Ordering {
+		v.IndexAdvisor.Check(v.table, "排序", order.Field)
+	}
+}
+
+// Meta 返回模型的元数据，无法解析时为 nil
+func (v *GenericViewSet) Meta() *meta.Model {
+	return v.meta
+}
+
+// Metadata 返回模型的字段元数据
+// OPTIONS /items/
+func (v *GenericViewSet) Metadata(c *gin.Context) {
+	if v.meta == nil {
+		utils.InternalServerError(c, "无法解析模型元数据")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"name":   v.meta.Name,
+		"table":  v.meta.Table,
+		"fields": v.meta.Fields,
+	})
+}
+
+// publish 发布本资源的变更事件，开启审计和历史记录时同时写入审计日志和快照
+func (v *GenericViewSet) publish(c *gin.Context, action events.Action, obj interface{}) {
+	v.audit(c, action, obj)
+	v.recordHistory(c, action, obj)
+
+	e := events.Event{
+		Resource: v.table,
+		Action:   action,
+		ObjectID: v.auditObjectID(obj),
+		Object:   obj,
+		Payload:  v.modelSerializer.Encode(obj),
+	}
+	if userID, ok := c.Get(ContextUserID); ok {
+		e.Actor = fmt.Sprint(userID)
+	}
+	if tenantID, ok := c.Get(ContextTenantID); ok {
+		e.TenantID = fmt.Sprint(tenantID)
+	}
+
+	// 事务中的事件在提交后发布（见 atomic）
+	if _, ok := TxFrom(c); ok {
+		pending, _ := c.Get(contextPendingEvents)
+		list, _ := pending.([]events.Event)
+		c.Set(contextPendingEvents, append(list, e))
+		return
+	}
+	v.Events.Publish(e)
+}
+
+// streamList 逐行读取查询结果并流式写出响应
+// 只复用一个模型实例，内存占用与结果集大小无关
+func (v *GenericViewSet) streamList(c *gin.Context, query *gorm.DB, pagination *utils.Pagination) {
+	rows, err := query.Rows()
+	if err != nil {
+		v.dbError(c, "查询失败", err)
+		return
+	}
+	defer rows.Close()
+
+	obj := v.newObject()
+	elem := reflect.ValueOf(obj).Elem()
+	zero := reflect.Zero(v.ModelType)
+
+	utils.StreamWithPagination(c, pagination, func(write func(item interface{}) error) error {
+		for rows.Next() {
+			elem.Set(zero)
+			if err := v.DB.ScanRows(rows, obj); err != nil {
+				return err
+			}
+			if err := write(v.pickFields(c, v.serialize(c, ActionList, obj))); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
 }
 
 // Retrieve 获取单个对象
@@ -92,41 +926,105 @@ func (v *GenericViewSet) Retrieve(c *gin.Context) {
 		return
 	}
 
+	// 解析 ?fields= 和 ?expand=
+	if !v.parseFields(c) || !v.parseExpand(c) {
+		return
+	}
+
+	// 优先读取缓存（配置了权限类时需要先取得对象再做对象级检查，不读缓存）
+	cacheKey := v.detailCacheKey(c, id)
+	if query := c.Request.URL.Query(); query.Get(FieldsParam) != "" || query.Get(ExpandParam) != "" {
+		cacheKey += "?" + query.Encode()
+	}
+	if !v.hasPermissions() && v.serveFromCache(c, cacheKey) {
+		return
+	}
+
+	// 加载配置和 ?expand= 展开的关联，一对一关联通过 JOIN 一次取回
+	plan, err := v.planPreloads(v.relationsFor(c), true)
+	if err != nil {
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+
 	// 创建模型实例
-	result := reflect.New(v.ModelType).Interface()
+	result := v.newObject()
 
 	// 查询
-	if err := v.DB.First(result, id).Error; err != nil {
+	if err := v.wherePK(plan.apply(v.selectFields(c, v.queryset(c))), id).First(result).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.NotFound(c, "记录不存在")
 		} else {
-			utils.InternalServerError(c, fmt.Sprintf("查询失败: %v", err))
+			v.dbError(c, "查询失败", err)
 		}
 		return
 	}
 
-	utils.Success(c, result)
+	if !v.checkObjectPermission(c, result) {
+		return
+	}
+
+	// 客户端已断开时跳过序列化
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	v.setETag(c, result)
+	data := v.pickFields(c, v.serialize(c, ActionRetrieve, result))
+	v.saveToCache(c, cacheKey, data, nil)
+
+	v.Respond(c, data)
+}
+
+// bindJSON 绑定请求体，开启性能分析时记录绑定耗时
+func bindJSON(c *gin.Context, obj interface{}) error {
+	defer utils.Track(c, "bind")()
+	return c.ShouldBindJSON(obj)
 }
 
 // Create 创建新对象
 // POST /items/
 func (v *GenericViewSet) Create(c *gin.Context) {
 	// 创建模型实例
-	obj := reflect.New(v.ModelType).Interface()
+	obj := v.newObject()
+
+	// 绑定请求数据，嵌套路由下外键取自路径参数
+	if err := v.bindInput(c, ActionCreate, obj); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if err := v.setParentFields(c, obj); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if err := v.setOwnerField(c, obj, true); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if err := v.setTenantField(c, obj, true); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+
+	// 客户端已断开时不再写入
+	if utils.AbortIfCanceled(c) {
+		return
+	}
 
-	// 绑定请求数据
-	if err := c.ShouldBindJSON(obj); err != nil {
-		utils.BadRequest(c, fmt.Sprintf("请求数据格式错误: %v", err))
+	if !v.performCreate(c, obj) {
 		return
 	}
 
 	// 创建记录
-	if err := v.DB.Create(obj).Error; err != nil {
-		utils.InternalServerError(c, fmt.Sprintf("创建失败: %v", err))
+	if err := v.dbFor(c).Create(obj).Error; err != nil {
+		v.dbError(c, "创建失败", err)
 		return
 	}
 
-	utils.Success(c, obj)
+	v.afterCreate(c, obj)
+	v.publish(c, events.Created, obj)
+
+	v.Respond(c, v.serialize(c, ActionCreate, obj))
 }
 
 // Update 更新对象
@@ -139,34 +1037,188 @@ func (v *GenericViewSet) Update(c *gin.Context) {
 	}
 
 	// 先查询是否存在
-	existing := reflect.New(v.ModelType).Interface()
-	if err := v.DB.First(existing, id).Error; err != nil {
+	existing := v.newObject()
+	if err := v.wherePK(v.lockForIfMatch(c, v.queryset(c)), id).First(existing).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.NotFound(c, "记录不存在")
 		} else {
-			utils.InternalServerError(c, fmt.Sprintf("查询失败: %v", err))
+			v.dbError(c, "查询失败", err)
 		}
 		return
 	}
 
-	// 绑定更新数据
-	updates := reflect.New(v.ModelType).Interface()
-	if err := c.ShouldBindJSON(updates); err != nil {
-		utils.BadRequest(c, fmt.Sprintf("请求数据格式错误: %v", err))
+	if !v.checkObjectPermission(c, existing) || !v.checkIfMatch(c, existing) {
+		return
+	}
+	v.auditSnapshot(c, existing)
+
+	// 绑定更新数据，嵌套路由下不允许修改外键
+	updates := v.newObject()
+	if err := v.bindInput(c, ActionUpdate, updates); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if err := v.setParentFields(c, updates); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if err := v.setOwnerField(c, updates, false); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if err := v.setTenantField(c, updates, false); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+
+	// 客户端已断开时不再写入
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	if !v.performUpdate(c, updates) {
 		return
 	}
 
 	// 更新记录
-	if err := v.DB.Model(existing).Updates(updates).Error; err != nil {
-		utils.InternalServerError(c, fmt.Sprintf("更新失败: %v", err))
+	if err := v.dbFor(c).Model(existing).Updates(updates).Error; err != nil {
+		v.dbError(c, "更新失败", err)
+		return
+	}
+
+	// 重新查询获取最新数据（复用已查询的对象）
+	v.dbFor(c).First(existing)
+
+	v.afterUpdate(c, existing)
+	v.publish(c, events.Updated, existing)
+
+	v.setETag(c, existing)
+	v.Respond(c, v.serialize(c, ActionUpdate, existing))
+}
+
+// PartialUpdate 部分更新对象
+// 只更新请求体中出现的字段，未出现的字段保持不变；出现的字段即使是零值也会写入
+// PATCH /items/:id
+func (v *GenericViewSet) PartialUpdate(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		utils.BadRequest(c, "缺少 ID 参数")
+		return
+	}
+
+	// 先查询是否存在
+	existing := v.newObject()
+	if err := v.wherePK(v.lockForIfMatch(c, v.queryset(c)), id).First(existing).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.NotFound(c, "记录不存在")
+		} else {
+			v.dbError(c, "查询失败", err)
+		}
+		return
+	}
+
+	if !v.checkObjectPermission(c, existing) || !v.checkIfMatch(c, existing) {
+		return
+	}
+	v.auditSnapshot(c, existing)
+
+	// 绑定更新数据
+	updates, errs := v.bindPartial(c)
+	if len(errs) > 0 {
+		utils.ValidationError(c, errs)
 		return
 	}
+	v.dropOwnerColumn(updates)
+	v.dropTenantColumn(updates)
+	if len(updates) == 0 {
+		utils.BadRequest(c, "没有需要更新的字段")
+		return
+	}
+	v.setParentColumns(c, updates)
+
+	// 客户端已断开时不再写入
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	// 钩子可以修改或补充要写入的列
+	if !v.performUpdate(c, updates) {
+		return
+	}
+
+	// 更新记录（按列名更新，零值同样写入）
+	if err := v.dbFor(c).Model(existing).Updates(updates).Error; err != nil {
+		v.dbError(c, "更新失败", err)
+		return
+	}
+
+	// 重新查询获取最新数据（复用已查询的对象）
+	v.dbFor(c).First(existing)
 
-	// 重新查询获取最新数据
-	result := reflect.New(v.ModelType).Interface()
-	v.DB.First(result, id)
+	v.afterUpdate(c, existing)
+	v.publish(c, events.Updated, existing)
 
-	utils.Success(c, result)
+	v.setETag(c, existing)
+	v.Respond(c, v.serialize(c, ActionPartialUpdate, existing))
+}
+
+// bindPartial 绑定部分更新的请求体，返回 列名 -> 值
+// 请求体先解码到模型实例上完成类型转换，再只取出请求中出现的字段；
+// 只校验出现的字段，模型上不存在的字段和主键返回字段错误
+func (v *GenericViewSet) bindPartial(c *gin.Context) (map[string]interface{}, []utils.FieldError) {
+	defer utils.Track(c, "bind")()
+
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, utils.BindingErrors(err)
+	}
+
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(body, &present); err != nil {
+		return nil, utils.BindingErrors(err)
+	}
+
+	obj := v.newObject()
+	if err := json.Unmarshal(body, obj); err != nil {
+		return nil, utils.BindingErrors(err)
+	}
+
+	if v.meta == nil || v.schema == nil {
+		return nil, []utils.FieldError{{Code: utils.CodeInvalid, Message: "无法解析模型元数据"}}
+	}
+
+	var errs []utils.FieldError
+	updates := make(map[string]interface{}, len(present))
+	names := make([]string, 0, len(present))
+	elem := reflect.ValueOf(obj).Elem()
+	for key := range present {
+		field, ok := v.meta.Lookup(key)
+		if !ok || field.JSONName != key || field.PrimaryKey || field.ReadOnly {
+			errs = append(errs, utils.FieldError{Field: key, Code: utils.CodeInvalid, Message: key + " 不允许修改"})
+			continue
+		}
+		sf := v.schema.LookUpField(field.Column)
+		if sf == nil {
+			continue
+		}
+		value, _ := sf.ValueOf(c.Request.Context(), elem)
+		updates[field.Column] = value
+		names = append(names, field.Name)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	// binding 规则只校验请求中出现的字段，之后执行 Validators
+	var bindErr error
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok && len(names) > 0 {
+		bindErr = validate.StructPartial(obj, names...)
+	}
+	if err := v.validatePartial(c, obj, bindErr); err != nil {
+		return nil, utils.BindingErrors(err)
+	}
+
+	return updates, nil
 }
 
 // Delete 删除对象
@@ -179,35 +1231,48 @@ func (v *GenericViewSet) Delete(c *gin.Context) {
 	}
 
 	// 创建模型实例
-	obj := reflect.New(v.ModelType).Interface()
+	obj := v.newObject()
 
 	// 先查询是否存在
-	if err := v.DB.First(obj, id).Error; err != nil {
+	if err := v.wherePK(v.lockForIfMatch(c, v.queryset(c)), id).First(obj).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.NotFound(c, "记录不存在")
 		} else {
-			utils.InternalServerError(c, fmt.Sprintf("查询失败: %v", err))
+			v.dbError(c, "查询失败", err)
 		}
 		return
 	}
 
+	if !v.checkObjectPermission(c, obj) || !v.checkIfMatch(c, obj) {
+		return
+	}
+	v.auditSnapshot(c, obj)
+
+	// 客户端已断开时不再删除
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	if !v.performDestroy(c, obj) {
+		return
+	}
+
 	// 删除记录
-	if err := v.DB.Delete(obj).Error; err != nil {
-		utils.InternalServerError(c, fmt.Sprintf("删除失败: %v", err))
+	if err := v.dbFor(c).Delete(obj).Error; err != nil {
+		v.dbError(c, "删除失败", err)
 		return
 	}
 
-	utils.Success(c, gin.H{"message": "删除成功"})
+	v.afterDestroy(c, obj)
+	v.publish(c, events.Deleted, obj)
+
+	v.Respond(c, gin.H{"message": "删除成功"})
 }
 
 // RegisterRoutes 注册标准 RESTful 路由
-// 子类可以覆盖此方法来添加自定义路由
+// 子类可以覆盖此方法来添加自定义路由，只需要部分路由时使用 RegisterMixins
 func (v *GenericViewSet) RegisterRoutes(group *gin.RouterGroup) {
-	group.GET("/", v.List)
-	group.GET("/:id", v.Retrieve)
-	group.POST("/", v.Create)
-	group.PUT("/:id", v.Update)
-	group.DELETE("/:id", v.Delete)
+	v.RegisterMixins(group, v, ModelMixins...)
 }
 
 // RegisterAction 注册自定义 action
@@ -242,32 +1307,52 @@ func (v *GenericViewSet) GetObjectOr404(c *gin.Context, id string) (interface{},
 	}
 
 	// 创建模型实例
-	obj := reflect.New(v.ModelType).Interface()
+	obj := v.newObject()
 
 	// 查询
-	if err := v.DB.First(obj, idInt).Error; err != nil {
+	if err := v.queryset(c).First(obj, idInt).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.NotFound(c, "记录不存在")
 		} else {
-			utils.InternalServerError(c, fmt.Sprintf("查询失败: %v", err))
+			v.dbError(c, "查询失败", err)
 		}
 		return nil, false
 	}
 
+	if !v.checkObjectPermission(c, obj) {
+		return nil, false
+	}
+	v.auditSnapshot(c, obj)
+
 	return obj, true
 }
 
-// PerformCreate 创建前的钩子，子类可以覆盖
-func (v *GenericViewSet) PerformCreate(c *gin.Context, obj interface{}) error {
-	return nil
+// wherePK 按 URL 中的 id 查询记录，id 作为参数绑定
+// 不使用 First(obj, id)：GORM 会把不是数字的字符串当作 SQL 条件拼接
+func (v *GenericViewSet) wherePK(db *gorm.DB, id string) *gorm.DB {
+	column := "id"
+	if v.schema != nil && v.schema.PrioritizedPrimaryField != nil {
+		column = v.schema.PrioritizedPrimaryField.DBName
+	}
+	return db.Where(v.table+"."+column+" = ?", id)
 }
 
-// PerformUpdate 更新前的钩子，子类可以覆盖
-func (v *GenericViewSet) PerformUpdate(c *gin.Context, obj interface{}) error {
-	return nil
+// LockKey 生成对象级别的锁 key，例如 "users:1"
+func (v *GenericViewSet) LockKey(id string) string {
+	return v.table + ":" + id
 }
 
-// PerformDestroy 删除前的钩子，子类可以覆盖
-func (v *GenericViewSet) PerformDestroy(c *gin.Context, obj interface{}) error {
-	return nil
+// WithLock 在分布式锁内执行 fn
+// 用于自定义 action 中需要对同一条记录串行执行的操作（跨实例生效），
+// 拿不到锁时返回 409，fn 负责写入自己的响应
+func (v *GenericViewSet) WithLock(c *gin.Context, key string, fn func()) {
+	err := v.Locker.WithLock(c.Request.Context(), key, func() error {
+		fn()
+		return nil
+	})
+	if errors.Is(err, lock.ErrNotAcquired) {
+		utils.Conflict(c, "操作正在进行中，请稍后重试")
+	} else if err != nil {
+		v.dbError(c, "获取锁失败", err)
+	}
 }
@@ -0,0 +1,253 @@
+package viewset
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"go-viewset/internal/utils"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FormatBrowsable 可浏览的 HTML 页面的格式名，?format=api 或 Accept: text/html
+const FormatBrowsable = "api"
+
+// contextViewSet 当前请求的 ViewSet，由 HandlerFor 写入，可浏览的页面用它生成表单
+const contextViewSet = "viewset_current"
+
+//go:embed templates/browsable.html
+var browsableFS embed.FS
+
+var browsableTemplate = template.Must(template.ParseFS(browsableFS, "templates/browsable.html"))
+
+// htmlEscapes encoding/json 对 <、>、& 的转义（前面的反斜杠为奇数个时才是转义）
+var htmlEscapes = regexp.MustCompile(`\\+u00(3c|3e|26)`)
+
+// browsableLink JSON 中的 URL（http(s):// 开头或站内 /api/ 路径），转义后的引号为 &#34;
+var browsableLink = regexp.MustCompile(`&#34;((?:https?://|/api/)(?:[^&<\s]|&amp;)*)&#34;`)
+
+// EnableBrowsableAPI 开启可浏览的 API：Accept 优先 text/html 的请求（例如浏览器直接访问）返回 HTML 页面，
+// 页面中有格式化的 JSON 响应、过滤/搜索/排序表单，列表页可以创建对象，详情页可以修改和删除。
+// 只用于开发环境中手动调试接口
+func EnableBrowsableAPI() {
+	utils.RegisterRenderer(FormatBrowsable, []string{"text/html"}, browsableRenderer{})
+}
+
+// browsableRenderer 可浏览的 HTML 页面
+type browsableRenderer struct{}
+
+// ContentType 实现 utils.Renderer
+func (browsableRenderer) ContentType() string { return "text/html; charset=utf-8" }
+
+// Marshal 实现 utils.Renderer，没有请求信息时只显示 JSON
+func (browsableRenderer) Marshal(v interface{}) ([]byte, error) {
+	return renderBrowsable(browsablePage{Title: "API"}, v)
+}
+
+// RenderContext 实现 utils.ContextRenderer
+func (browsableRenderer) RenderContext(c *gin.Context, status int, v interface{}) ([]byte, error) {
+	page := browsablePage{
+		Title:  c.Request.URL.Path,
+		Method: c.Request.Method,
+		URL:    c.Request.URL.RequestURI(),
+		Status: status,
+		Text:   http.StatusText(status),
+	}
+	if value, ok := c.Get(contextViewSet); ok {
+		value.(*GenericViewSet).describe(c, &page, v)
+	}
+	return renderBrowsable(page, v)
+}
+
+// browsablePage 页面模板的数据
+type browsablePage struct {
+	Title  string
+	Method string
+	URL    string
+	Status int
+	Text   string
+	JSON   template.HTML
+
+	Resource string // 模型名称，不是 ViewSet 的请求时为空
+	Action   string
+	ListURL  string // 集合的地址，删除后跳转到这里
+	Query    map[string]string
+
+	Search   bool
+	Filters  []string
+	Ordering []string
+
+	CreateBody string // 列表页创建表单的初始内容，为空时不显示
+	UpdateBody string // 详情页修改表单的初始内容，为空时不显示
+	Actions    []browsableAction
+}
+
+// browsableAction 页面中列出的自定义 action
+type browsableAction struct {
+	Method string
+	Name   string
+	URL    string
+}
+
+// renderBrowsable 格式化 JSON 并渲染页面
+func renderBrowsable(page browsablePage, v interface{}) ([]byte, error) {
+	body, err := utils.MarshalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		pretty.Reset()
+		pretty.Write(body)
+	}
+	escaped := template.HTMLEscapeString(unescapeHTML(pretty.String()))
+	page.JSON = template.HTML(browsableLink.ReplaceAllString(escaped, `&#34;<a href="$1">$1</a>&#34;`))
+
+	var buf bytes.Buffer
+	if err := browsableTemplate.Execute(&buf, page); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// describe 按 ViewSet 的配置补充页面中的表单
+func (v *GenericViewSet) describe(c *gin.Context, page *browsablePage, data interface{}) {
+	if v.meta == nil {
+		return
+	}
+	page.Resource = v.meta.Name
+	page.Action = c.GetString(ContextAction)
+	page.Query = make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		page.Query[key] = values[0]
+	}
+
+	path := strings.TrimSuffix(c.Request.URL.Path, "/")
+	switch page.Action {
+	case ActionList:
+		page.ListURL = path + "/"
+		page.Filters, page.Ordering, page.Search = v.QueryFields()
+		page.CreateBody = v.browsableBody(nil)
+		page.Actions = v.browsableActions(false, page.ListURL)
+	case ActionRetrieve:
+		page.ListURL = path[:strings.LastIndex(path, "/")+1]
+		if obj := v.browsableObject(data); obj != nil {
+			page.UpdateBody = v.browsableBody(obj)
+		}
+		page.Actions = v.browsableActions(true, path+"/")
+	}
+}
+
+// browsableActions 列表页的集合 action 或详情页的对象 action，base 以 / 结尾
+func (v *GenericViewSet) browsableActions(detail bool, base string) []browsableAction {
+	var actions []browsableAction
+	for _, a := range v.actions(detail) {
+		actions = append(actions, browsableAction{Method: a.Method, Name: a.Name, URL: base + a.Name})
+	}
+	return actions
+}
+
+// QueryFields 列表接口可以过滤和排序的字段（JSON 字段名），以及是否支持 ?search=
+// 用于生成可浏览的 API、管理后台等页面中的查询表单
+func (v *GenericViewSet) QueryFields() (filters, ordering []string, search bool) {
+	if v.meta == nil {
+		return nil, nil, false
+	}
+	allowedFilters, allowedOrdering := v.allowedFilterFields(), v.allowedOrderingFields()
+	for _, f := range v.meta.Fields {
+		if f.JSONName == "" || f.JSONName == "-" || f.WriteOnly {
+			continue
+		}
+		if f.Filterable && (allowedFilters == nil || allowedFilters[f.Column]) {
+			filters = append(filters, f.JSONName)
+		}
+		if f.Orderable && (allowedOrdering == nil || allowedOrdering[f.Column]) {
+			ordering = append(ordering, f.JSONName)
+		}
+	}
+	return filters, ordering, len(v.SearchFields) > 0
+}
+
+// browsableBody 创建或修改表单的初始 JSON：可写的字段，obj 不为 nil 时取其中的值
+func (v *GenericViewSet) browsableBody(obj map[string]interface{}) string {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	first := true
+	for _, f := range v.meta.Fields {
+		if f.PrimaryKey || f.ReadOnly || f.JSONName == "" || f.JSONName == "-" {
+			continue
+		}
+		value, ok := obj[f.JSONName]
+		if !ok {
+			value = browsableZero(f.TypeName)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		encoded = []byte(unescapeHTML(string(encoded)))
+		if !first {
+			buf.WriteString(",")
+		}
+		first = false
+		key, _ := json.Marshal(f.JSONName)
+		buf.WriteString("\n  " + string(key) + ": " + string(encoded))
+	}
+	buf.WriteString("\n}")
+	return buf.String()
+}
+
+// browsableObject 从详情响应中找出对象：响应本身或其中包含主键字段的对象（响应包装在信封中时）
+func (v *GenericViewSet) browsableObject(data interface{}) map[string]interface{} {
+	body, err := utils.MarshalJSON(data)
+	if err != nil {
+		return nil
+	}
+	var root map[string]interface{}
+	if json.Unmarshal(body, &root) != nil {
+		return nil
+	}
+	pk := ""
+	for _, f := range v.meta.Fields {
+		if f.PrimaryKey {
+			pk = f.JSONName
+		}
+	}
+	if _, ok := root[pk]; ok {
+		return root
+	}
+	for _, value := range root {
+		if obj, ok := value.(map[string]interface{}); ok {
+			if _, ok := obj[pk]; ok {
+				return obj
+			}
+		}
+	}
+	return nil
+}
+
+// unescapeHTML 还原 JSON 中 <、>、& 的转义，页面输出时统一做 HTML 转义，链接中的 & 也不会被破坏
+func unescapeHTML(s string) string {
+	return htmlEscapes.ReplaceAllStringFunc(s, func(m string) string {
+		slashes := strings.Count(m, "\\")
+		if slashes%2 == 0 {
+			return m
+		}
+		return m[:slashes-1] + map[string]string{"3c": "<", "3e": ">", "26": "&"}[m[len(m)-2:]]
+	})
+}
+
+// browsableZero 字段类型的初始值
+func browsableZero(typeName string) interface{} {
+	switch typeName {
+	case "bool":
+		return false
+	case "int", "uint", "float":
+		return 0
+	}
+	return ""
+}
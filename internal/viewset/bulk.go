@@ -0,0 +1,148 @@
+package viewset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-viewset/internal/events"
+	"go-viewset/internal/utils"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// ActionBulkCreate 批量创建的 action 名称
+const ActionBulkCreate = "bulk_create"
+
+// 批量操作的默认值
+const (
+	defaultBulkMaxItems  = 1000
+	defaultBulkBatchSize = 100
+)
+
+// BulkItemResult 批量操作中单条记录的结果
+type BulkItemResult struct {
+	Index   int            `json:"index"`
+	Success bool           `json:"success"`
+	Data    interface{}    `json:"data,omitempty"`
+	Errors  utils.ErrorMap `json:"errors,omitempty"`
+}
+
+// BulkCreate 批量创建
+// 请求体为对象数组，逐条解码和校验，校验通过的记录在一个事务中分批插入，
+// 返回每条记录的结果；插入失败时整个事务回滚。
+// 通过 EnableBulkOperations 开启
+// POST /items/bulk
+func (v *GenericViewSet) BulkCreate(c *gin.Context) {
+	var items []json.RawMessage
+	if err := bindJSON(c, &items); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+
+	maxItems := v.BulkMaxItems
+	if maxItems <= 0 {
+		maxItems = defaultBulkMaxItems
+	}
+	if len(items) == 0 {
+		utils.BadRequest(c, "请求体不能为空数组")
+		return
+	}
+	if len(items) > maxItems {
+		utils.BadRequest(c, fmt.Sprintf("单次最多创建 %d 条记录", maxItems))
+		return
+	}
+
+	// 逐条解码和校验
+	results := make([]BulkItemResult, len(items))
+	valid := reflect.MakeSlice(v.sliceType, 0, len(items))
+	validIndex := make([]int, 0, len(items))
+	for i, raw := range items {
+		results[i].Index = i
+
+		obj := v.newObject()
+		if err := v.decodeItem(c, ActionBulkCreate, raw, obj); err != nil {
+			results[i].Errors = utils.GroupErrors(utils.BindingErrors(err))
+			continue
+		}
+		if err := v.setParentFields(c, obj); err != nil {
+			results[i].Errors = utils.GroupErrors(utils.BindingErrors(err))
+			continue
+		}
+		if err := v.setOwnerField(c, obj, true); err != nil {
+			results[i].Errors = utils.GroupErrors(utils.BindingErrors(err))
+			continue
+		}
+		if err := v.setTenantField(c, obj, true); err != nil {
+			results[i].Errors = utils.GroupErrors(utils.BindingErrors(err))
+			continue
+		}
+		if err := v.performBulkCreate(c, obj); err != nil {
+			results[i].Errors = utils.GroupErrors(err)
+			continue
+		}
+		valid = reflect.Append(valid, reflect.ValueOf(obj))
+		validIndex = append(validIndex, i)
+	}
+
+	// 客户端已断开时不再写入
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	if valid.Len() > 0 {
+		batch := valid.Interface()
+		err := v.dbFor(c).Transaction(func(tx *gorm.DB) error {
+			return tx.CreateInBatches(batch, defaultBulkBatchSize).Error
+		})
+		if err != nil {
+			v.dbError(c, "批量创建失败", err)
+			return
+		}
+	}
+
+	for n, i := range validIndex {
+		obj := valid.Index(n).Interface()
+		v.afterCreate(c, obj)
+		v.publish(c, events.Created, obj)
+		results[i].Success = true
+		results[i].Data = v.serialize(c, ActionCreate, obj)
+	}
+
+	v.Respond(c, gin.H{
+		"created": len(validIndex),
+		"failed":  len(items) - len(validIndex),
+		"results": results,
+	})
+}
+
+// decodeItem 解码并校验批量创建（以及文件导入）中的一条记录
+// 与 Create 一致：忽略只读字段，执行 binding 规则、模型的 Validate 以及 Validators，收集全部字段错误
+func (v *GenericViewSet) decodeItem(c *gin.Context, action string, raw json.RawMessage, obj interface{}) error {
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return err
+	}
+	v.modelSerializer.clearReadOnly(c.Request.Context(), obj)
+
+	return v.validate(c, action, obj, binding.Validator.ValidateStruct(obj))
+}
+
+// performBulkCreate 对批量创建中的一条记录调用 PerformCreate 钩子
+// 钩子返回的错误记录到该条记录的结果中，不影响其他记录
+func (v *GenericViewSet) performBulkCreate(c *gin.Context, obj interface{}) []utils.FieldError {
+	h, ok := v.hooks().(CreateHook)
+	if !ok {
+		return nil
+	}
+	err := h.PerformCreate(c, obj)
+	if err == nil {
+		return nil
+	}
+	var verrs utils.ValidationErrors
+	if errors.As(err, &verrs) {
+		return verrs
+	}
+	return []utils.FieldError{{Code: utils.CodeInvalid, Message: err.Error()}}
+}
@@ -0,0 +1,92 @@
+package viewset
+
+import (
+	"context"
+	"encoding/json"
+	"go-viewset/internal/cache"
+	"go-viewset/internal/events"
+	"go-viewset/internal/utils"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cachedPayload 缓存的响应内容
+type cachedPayload struct {
+	Data       json.RawMessage   `json:"data"`
+	Pagination *utils.Pagination `json:"pagination,omitempty"`
+	ETag       string            `json:"etag,omitempty"` // Retrieve 由对象计算的 ETag（见 setETag）
+}
+
+// EnableCache 开启查询结果缓存
+// List 和 Retrieve 的结果按请求参数缓存，所有缓存项都带有资源标签，
+// 本资源发生写操作时（通过事件总线）整体失效
+func (v *GenericViewSet) EnableCache(c cache.Cache, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	v.Cache = c
+	v.CacheTTL = ttl
+	v.Events.Subscribe(func(e events.Event) {
+		if e.Resource != v.table {
+			return
+		}
+		if err := v.Cache.Invalidate(context.Background(), v.table); err != nil {
+			log.Printf("清除 %s 缓存失败: %v", v.table, err)
+		}
+	})
+}
+
+// listCacheKey 列表缓存 key，包含嵌套路由的父资源和全部查询参数（按参数名排序）
+func (v *GenericViewSet) listCacheKey(c *gin.Context) string {
+	return "list:" + v.table + ":" + v.parentScopeKey(c) + c.Request.URL.Query().Encode()
+}
+
+// detailCacheKey 详情缓存 key
+func (v *GenericViewSet) detailCacheKey(c *gin.Context, id string) string {
+	return "detail:" + v.table + ":" + v.parentScopeKey(c) + id
+}
+
+// serveFromCache 命中缓存时直接写出响应并返回 true
+func (v *GenericViewSet) serveFromCache(c *gin.Context, key string) bool {
+	if v.Cache == nil || !v.querysetCacheable() {
+		return false
+	}
+
+	value, ok, err := v.Cache.Get(c.Request.Context(), key)
+	if err != nil || !ok {
+		return false
+	}
+
+	var payload cachedPayload
+	if err := json.Unmarshal(value, &payload); err != nil {
+		return false
+	}
+	if payload.ETag != "" {
+		c.Set(contextETag, payload.ETag)
+	}
+
+	v.RespondWithPagination(c, payload.Data, payload.Pagination)
+	return true
+}
+
+// saveToCache 缓存响应内容，缓存失败不影响请求
+func (v *GenericViewSet) saveToCache(c *gin.Context, key string, data interface{}, pagination *utils.Pagination) {
+	if v.Cache == nil || !v.querysetCacheable() {
+		return
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	value, err := json.Marshal(cachedPayload{Data: raw, Pagination: pagination, ETag: c.GetString(contextETag)})
+	if err != nil {
+		return
+	}
+
+	if err := v.Cache.Set(c.Request.Context(), key, value, v.CacheTTL, v.table); err != nil {
+		log.Printf("写入 %s 缓存失败: %v", v.table, err)
+	}
+}
@@ -0,0 +1,114 @@
+package viewset
+
+import (
+	"go-viewset/internal/utils"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/schema"
+)
+
+// cursorOrdering 游标分页使用的排序
+// 未指定排序时按主键升序；排序必须以主键结尾，保证游标能唯一定位一条记录
+func (v *GenericViewSet) cursorOrdering(ordering []utils.OrderField) ([]utils.OrderField, []*schema.Field, bool) {
+	if v.schema == nil || v.schema.PrioritizedPrimaryField == nil {
+		return nil, nil, false
+	}
+	pk := v.schema.PrioritizedPrimaryField.DBName
+	if n := len(ordering); n == 0 || ordering[n-1].Field != pk {
+		ordering = append(ordering, utils.OrderField{Field: pk})
+	}
+
+	fields := make([]*schema.Field, len(ordering))
+	for i, order := range ordering {
+		if fields[i] = v.schema.LookUpField(order.Field); fields[i] == nil {
+			return nil, nil, false
+		}
+	}
+	return ordering, fields, true
+}
+
+// listWithCursor 游标分页的列表查询
+// 多取一条记录判断是否有下一页，下一页的游标由当前页最后一条记录的排序字段值生成
+// GET /items/?cursor=xxx&page_size=50
+func (v *GenericViewSet) listWithCursor(c *gin.Context, filterParams *utils.FilterParams, cacheKey string) {
+	ordering, fields, ok := v.cursorOrdering(filterParams.Ordering)
+	if !ok {
+		utils.InternalServerError(c, "模型没有主键，无法使用游标分页")
+		return
+	}
+	filterParams.Ordering = ordering
+
+	var after []interface{}
+	if cursor := c.Query("cursor"); cursor != "" {
+		types := make([]reflect.Type, len(fields))
+		for i, field := range fields {
+			types[i] = field.FieldType
+		}
+		values, err := utils.DecodeCursor(cursor, ordering, types)
+		if err != nil {
+			utils.ValidationError(c, []utils.FieldError{
+				{Field: "cursor", Code: utils.CodeInvalid, Message: err.Error()},
+			})
+			return
+		}
+		after = values
+	}
+
+	// 游标分页不支持关闭分页
+	cfg := v.PaginationConfig
+	cfg.AllowDisablePagination = false
+	pageSize := utils.GetPaginationParams(c, cfg).PageSize
+	query := utils.ApplyFilters(v.queryset(c).Model(v.Model), filterParams)
+	query = utils.ApplyCursor(query, ordering, after).Limit(pageSize + 1)
+
+	// 生成游标需要排序字段的值，即使没有出现在 ?fields= 中也要查询
+	orderColumns := make([]string, len(ordering))
+	for i, order := range ordering {
+		orderColumns[i] = order.Field
+	}
+	query = v.selectFields(c, query, orderColumns...)
+
+	plan, err := v.planPreloads(v.relationsFor(c), false)
+	if err != nil {
+		utils.InternalServerError(c, err.Error())
+		return
+	}
+	query = plan.apply(query)
+
+	results := v.acquireSlice()
+	defer v.releaseSlice(results)
+
+	if err := query.Find(results).Error; err != nil {
+		v.dbError(c, "查询失败", err)
+		return
+	}
+
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	// 多取的一条只用于判断是否有下一页
+	var next string
+	slice := reflect.ValueOf(results).Elem()
+	if slice.Len() > pageSize {
+		slice.SetLen(pageSize)
+		last := reflect.Indirect(slice.Index(pageSize - 1))
+		values := make([]interface{}, len(fields))
+		for i, field := range fields {
+			values[i], _ = field.ValueOf(c.Request.Context(), last)
+		}
+		if next, err = utils.EncodeCursor(ordering, values); err != nil {
+			utils.InternalServerError(c, "生成游标失败")
+			return
+		}
+	}
+
+	pagination := utils.BuildCursorPagination(pageSize, next)
+	defer utils.ReleasePagination(pagination)
+
+	data := v.pickFields(c, v.serializeList(c, results))
+	v.saveToCache(c, cacheKey, data, pagination)
+
+	v.RespondWithPagination(c, data, pagination)
+}
@@ -0,0 +1,71 @@
+package viewset
+
+import (
+	"go-viewset/internal/database"
+	"go-viewset/internal/utils"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dbErrorFieldCode 与字段相关的数据库错误类型对应的字段错误码和提示信息
+// 这类错误按校验失败返回 422（见 utils.ValidationError）
+var dbErrorFieldCode = map[database.ErrorKind]struct {
+	code string
+	msg  string
+}{
+	database.KindDuplicate:  {utils.CodeUnique, "已存在"},
+	database.KindForeignKey: {utils.CodeNotFound, "引用的记录不存在"},
+	database.KindTooLong:    {utils.CodeTooLong, "超出长度限制"},
+	database.KindNotNull:    {utils.CodeRequired, "不能为空"},
+	database.KindOutOfRange: {utils.CodeInvalid, "超出取值范围"},
+}
+
+// dbError 数据库操作失败时写出错误响应
+// 唯一约束、外键、字段长度等可识别的错误返回对应的 4xx（可重试的返回 503），
+// 其余返回 500，原始错误只记录日志不返回给客户端。
+// 客户端已断开导致的查询取消不再写出响应
+func (v *GenericViewSet) dbError(c *gin.Context, msg string, err error) {
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	if dbErr := database.TranslateError(err, v.table); dbErr != nil {
+		v.translatedError(c, dbErr)
+		return
+	}
+
+	log.Printf("[数据库错误] %s %s request_id=%s: %s: %v",
+		c.Request.Method, c.FullPath(), c.GetString("request_id"), msg, err)
+	utils.InternalServerError(c, msg)
+}
+
+// translatedError 写出可识别的数据库错误
+// 字段相关的错误返回 422 和字段错误（JSON 字段名），记录被引用返回 409，死锁等可重试的错误返回 503
+func (v *GenericViewSet) translatedError(c *gin.Context, dbErr *database.Error) {
+	switch {
+	case dbErr.Retryable():
+		c.Header("Retry-After", "1")
+		utils.ServiceUnavailable(c, "数据库繁忙，请重试")
+		return
+	case dbErr.Kind == database.KindReferenced:
+		utils.Conflict(c, "记录仍被其他数据引用")
+		return
+	}
+
+	info := dbErrorFieldCode[dbErr.Kind]
+
+	field := dbErr.Column
+	if v.meta != nil && field != "" {
+		if f, ok := v.meta.Lookup(field); ok {
+			field = f.JSONName
+		}
+	}
+
+	msg := info.msg
+	if field != "" {
+		msg = field + " " + msg
+	}
+
+	utils.ValidationError(c, []utils.FieldError{{Field: field, Code: info.code, Message: msg}})
+}
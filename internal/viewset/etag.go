@@ -0,0 +1,120 @@
+package viewset
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go-viewset/internal/utils"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// contextETag 本次响应的 ETag 在 gin.Context 中的 key（由 Retrieve、Update 等设置）
+const contextETag = "viewset_etag"
+
+// objectETag 对象的弱 ETag
+// 模型有 UpdatedAt 字段时由主键和更新时间生成（与 ?fields= 等无关），否则为序列化结果的哈希
+func (v *GenericViewSet) objectETag(obj interface{}) string {
+	if v.schema != nil {
+		if f, pk := v.schema.LookUpField("UpdatedAt"), v.schema.PrioritizedPrimaryField; f != nil && pk != nil {
+			elem := reflect.Indirect(reflect.ValueOf(obj))
+			id, _ := pk.ValueOf(context.Background(), elem)
+			updated, _ := f.ValueOf(context.Background(), elem)
+			if t, ok := updated.(time.Time); ok {
+				return fmt.Sprintf(`W/"%v-%d"`, id, t.UnixNano())
+			}
+		}
+	}
+	return bodyETag(v.serialize(nil, ActionRetrieve, obj), nil)
+}
+
+// bodyETag 响应内容的弱 ETag
+func bodyETag(data interface{}, pagination *utils.Pagination) string {
+	h := sha256.New()
+	if body, err := utils.MarshalJSON(data); err == nil {
+		h.Write(body)
+	}
+	if pagination != nil {
+		if body, err := utils.MarshalJSON(pagination); err == nil {
+			h.Write(body)
+		}
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// setETag 设置本次响应的 ETag，未开启 EnableETag 时不做任何事
+func (v *GenericViewSet) setETag(c *gin.Context, obj interface{}) {
+	if v.EnableETag {
+		c.Set(contextETag, v.objectETag(obj))
+	}
+}
+
+// notModified 写出 ETag 响应头，GET/HEAD 请求的 If-None-Match 与之匹配时写出 304 并返回 true
+// Retrieve 等设置了 contextETag 时使用它，否则由响应内容计算
+func (v *GenericViewSet) notModified(c *gin.Context, data interface{}, pagination *utils.Pagination) bool {
+	safe := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+	etag := c.GetString(contextETag)
+	if etag == "" {
+		if !safe {
+			return false
+		}
+		etag = bodyETag(data, pagination)
+	}
+	c.Header("ETag", etag)
+
+	if safe && etagMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// checkIfMatch 检查 If-Match 前置条件，不匹配时写出 412 并返回 false
+// 客户端应使用 Retrieve 返回的 ETag，避免覆盖其他人在此期间所做的修改（lost update）
+func (v *GenericViewSet) checkIfMatch(c *gin.Context, obj interface{}) bool {
+	header := c.GetHeader("If-Match")
+	if !v.EnableETag || header == "" {
+		return true
+	}
+	if etagMatch(header, v.objectETag(obj)) {
+		return true
+	}
+	utils.ErrorWithStatus(c, http.StatusPreconditionFailed, http.StatusPreconditionFailed, "资源已被修改，请重新获取后再提交")
+	return false
+}
+
+// lockForIfMatch 带 If-Match 的写请求在事务中通过 SELECT ... FOR UPDATE 读取对象，
+// 保证检查前置条件和写入之间对象不会被其他请求修改
+func (v *GenericViewSet) lockForIfMatch(c *gin.Context, db *gorm.DB) *gorm.DB {
+	if !v.EnableETag || c.GetHeader("If-Match") == "" {
+		return db
+	}
+	if _, ok := TxFrom(c); !ok {
+		return db
+	}
+	return db.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// etagMatch 按弱比较判断请求头（逗号分隔的 ETag 列表或 *）中是否包含 etag
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, item := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(item), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package viewset
+
+import (
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/schema"
+)
+
+// ExpandParam 关联展开的查询参数，例如 ?expand=orders,profile
+const ExpandParam = "expand"
+
+// 本次请求展开的关联路径，以及它们在响应中的顶层字段名，在 gin.Context 中的 key
+const (
+	contextExpand     = "viewset_expand"
+	contextExpandKeys = "viewset_expand_keys"
+)
+
+// expandables 返回 Expandable 中的关联，展开名 -> 关联路径
+// 展开名由路径中每一段关联字段的 JSON 名称组成，例如 Orders.Items -> orders.items；
+// 不存在的关联在首次使用时输出告警
+func (v *GenericViewSet) expandables() map[string]string {
+	v.expandOnce.Do(func() {
+		if len(v.Expandable) == 0 || v.schema == nil {
+			return
+		}
+		v.expandNames = make(map[string]string, len(v.Expandable))
+		for _, path := range v.Expandable {
+			name, ok := expandName(v.schema, path)
+			if !ok {
+				log.Printf("[警告] %s 的 Expandable 中的关联 %s 不存在", v.table, path)
+				continue
+			}
+			v.expandNames[name] = path
+		}
+	})
+	return v.expandNames
+}
+
+// expandName 将关联路径（Go 字段名）转换为客户端使用的展开名
+func expandName(s *schema.Schema, path string) (string, bool) {
+	segments := strings.Split(path, ".")
+	names := make([]string, len(segments))
+	current := s
+	for i, segment := range segments {
+		rel, ok := current.Relationships.Relations[segment]
+		if !ok {
+			return "", false
+		}
+		names[i] = toSnakeCase(segment)
+		if tag := strings.Split(rel.Field.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			names[i] = tag
+		}
+		current = rel.FieldSchema
+	}
+	return strings.Join(names, "."), true
+}
+
+// parseExpand 解析 ?expand=，只允许展开 Expandable 中的关联
+// 关联不在白名单中时写出 422 并返回 false
+func (v *GenericViewSet) parseExpand(c *gin.Context) bool {
+	raw := c.Query(ExpandParam)
+	if raw == "" {
+		return true
+	}
+
+	allowed := v.expandables()
+	var (
+		paths []string
+		keys  []string
+		errs  []utils.FieldError
+	)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		path, ok := allowed[name]
+		if !ok {
+			errs = append(errs, utils.FieldError{Field: ExpandParam, Code: utils.CodeInvalid, Message: "不支持展开的关联: " + name})
+			continue
+		}
+		paths = append(paths, path)
+		keys = append(keys, strings.SplitN(name, ".", 2)[0])
+	}
+	if len(errs) > 0 {
+		utils.ValidationError(c, errs)
+		return false
+	}
+
+	if len(paths) > 0 {
+		c.Set(contextExpand, paths)
+		c.Set(contextExpandKeys, keys)
+	}
+	return true
+}
+
+// relationsFor 本次请求需要加载的关联：Relations 加上 ?expand= 展开的关联
+func (v *GenericViewSet) relationsFor(c *gin.Context) []string {
+	value, ok := c.Get(contextExpand)
+	if !ok {
+		return v.Relations
+	}
+	expand := value.([]string)
+	relations := make([]string, 0, len(v.Relations)+len(expand))
+	relations = append(relations, v.Relations...)
+	return append(relations, expand...)
+}
+
+// modelRelation 模型上的关联
+type modelRelation struct {
+	name       string      // 关联在响应中的字段名，与 ?expand= 中的名称相同
+	foreignKey string      // belongs-to 关联外键的 JSON 字段名，其他关联为空
+	typ        string      // 关联模型的表名（JSON:API 中的 type）
+	model      *meta.Model // 关联模型的元数据，解析失败时为 nil
+}
+
+// modelRelations 模型上的关联，按名称排序
+func (v *GenericViewSet) modelRelations() []modelRelation {
+	if v.schema == nil || v.meta == nil {
+		return nil
+	}
+	relations := make([]modelRelation, 0, len(v.schema.Relationships.Relations))
+	for goName, rel := range v.schema.Relationships.Relations {
+		name, ok := expandName(v.schema, goName)
+		if !ok {
+			continue
+		}
+		r := modelRelation{name: name, typ: rel.FieldSchema.Table}
+		if m, err := meta.Of(v.DB, reflect.New(rel.FieldSchema.ModelType).Interface()); err == nil {
+			r.model = m
+		}
+		if rel.Type == schema.BelongsTo && len(rel.References) == 1 {
+			if f, ok := v.meta.Lookup(rel.References[0].ForeignKey.DBName); ok {
+				r.foreignKey = f.JSONName
+			}
+		}
+		relations = append(relations, r)
+	}
+	sort.Slice(relations, func(i, j int) bool { return relations[i].name < relations[j].name })
+	return relations
+}
@@ -0,0 +1,246 @@
+package viewset
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"go-viewset/internal/xlsx"
+	"mime"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ActionExport Excel 导出的 action 名称
+const ActionExport = "export"
+
+// ExportColumn 导出（CSV、Excel）的一列
+type ExportColumn struct {
+	Field  string                              // JSON 字段名或列名
+	Header string                              // 表头，默认为 JSON 字段名
+	Format func(value interface{}) interface{} // 转换字段值，nil 指针和 NULL 传入 nil；默认原样输出
+}
+
+// exportColumn 解析后的导出列
+type exportColumn struct {
+	field  *meta.Field
+	header string
+	format func(value interface{}) interface{}
+}
+
+// exportColumns 本次导出的列
+// 配置了 ExportColumns 时按其顺序输出，否则为全部可输出的字段；指定了 ?fields= 时只保留请求的字段。
+// ExportColumns 中的字段不存在时返回错误
+func (v *GenericViewSet) exportColumns(c *gin.Context) ([]exportColumn, error) {
+	requested := requestedFields(c)
+
+	if len(v.ExportColumns) == 0 {
+		fields := requested
+		if len(fields) == 0 {
+			for _, f := range v.meta.Fields {
+				if f.JSONName != "-" && !f.WriteOnly {
+					fields = append(fields, f)
+				}
+			}
+		}
+		columns := make([]exportColumn, len(fields))
+		for i, f := range fields {
+			columns[i] = exportColumn{field: f, header: f.JSONName}
+		}
+		return columns, nil
+	}
+
+	wanted := make(map[*meta.Field]bool, len(requested))
+	for _, f := range requested {
+		wanted[f] = true
+	}
+
+	var columns []exportColumn
+	for _, col := range v.ExportColumns {
+		f, ok := v.meta.Lookup(col.Field)
+		if !ok {
+			return nil, fmt.Errorf("导出列 %s 不存在", col.Field)
+		}
+		if len(wanted) > 0 && !wanted[f] {
+			continue
+		}
+		header := col.Header
+		if header == "" {
+			header = f.JSONName
+		}
+		columns = append(columns, exportColumn{field: f, header: header, format: col.Format})
+	}
+	return columns, nil
+}
+
+// prepareExport 解析导出的列并执行查询，出错时写出错误响应并返回 false
+// scope 不为 nil 时用于添加额外的查询条件，excludeKeys 为不参与过滤的查询参数（同 filterParams）
+func (v *GenericViewSet) prepareExport(c *gin.Context, scope func(*gorm.DB) *gorm.DB, excludeKeys ...string) (*sql.Rows, []exportColumn, bool) {
+	if v.meta == nil {
+		utils.InternalServerError(c, "模型元数据不可用，无法导出")
+		return nil, nil, false
+	}
+	if !v.parseFields(c) {
+		return nil, nil, false
+	}
+
+	columns, err := v.exportColumns(c)
+	if err != nil {
+		utils.InternalServerError(c, err.Error())
+		return nil, nil, false
+	}
+
+	filterParams, ok := v.filterParams(c, excludeKeys...)
+	if !ok {
+		return nil, nil, false
+	}
+	defer utils.ReleaseFilterParams(filterParams)
+
+	query := v.queryset(c).Model(v.Model)
+	if scope != nil {
+		query = scope(query)
+	}
+	rows, err := v.selectFields(c, utils.ApplyFilters(query, filterParams)).Rows()
+	if err != nil {
+		v.dbError(c, "查询失败", err)
+		return nil, nil, false
+	}
+	return rows, columns, true
+}
+
+// eachExportRow 逐行读取导出的记录，fn 收到每一列格式化后的值
+// 与 streamList 一样只复用一个模型实例，内存占用与结果集大小无关；客户端断开时停止
+func (v *GenericViewSet) eachExportRow(c *gin.Context, rows *sql.Rows, columns []exportColumn, fn func(values []interface{}) error) error {
+	obj := v.newObject()
+	elem := reflect.ValueOf(obj).Elem()
+	zero := reflect.Zero(v.ModelType)
+	values := make([]interface{}, len(columns))
+
+	for rows.Next() {
+		if err := c.Request.Context().Err(); err != nil {
+			return err
+		}
+		elem.Set(zero)
+		if err := v.DB.ScanRows(rows, obj); err != nil {
+			return err
+		}
+		for i, col := range columns {
+			values[i] = exportValue(elem.FieldByName(col.field.Name))
+			if col.format != nil {
+				values[i] = col.format(values[i])
+			}
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// exportHeaders 导出的表头
+func exportHeaders(columns []exportColumn) []string {
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.header
+	}
+	return headers
+}
+
+// exportCSV 以 CSV 格式导出全部符合过滤条件的记录（不分页）
+// GET /items/?format=csv 或 Accept: text/csv
+func (v *GenericViewSet) exportCSV(c *gin.Context, scope func(*gorm.DB) *gorm.DB, excludeKeys ...string) {
+	rows, columns, ok := v.prepareExport(c, scope, excludeKeys...)
+	if !ok {
+		return
+	}
+	defer rows.Close()
+
+	record := make([]string, len(columns))
+	utils.StreamCSV(c, v.table+".csv", exportHeaders(columns), func(write func(record []string) error) error {
+		return v.eachExportRow(c, rows, columns, func(values []interface{}) error {
+			for i, value := range values {
+				record[i] = csvValue(value)
+			}
+			return write(record)
+		})
+	})
+}
+
+// ExportXLSX 以 Excel 格式导出全部符合过滤条件的记录（不分页），过滤参数和 ?fields= 与 List 相同
+// GET /items/export.xlsx，需要开启 EnableExport
+func (v *GenericViewSet) ExportXLSX(c *gin.Context) {
+	rows, columns, ok := v.prepareExport(c, nil)
+	if !ok {
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", xlsx.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": v.table + ".xlsx"}))
+	c.Status(http.StatusOK)
+
+	w, err := xlsx.NewWriter(c.Writer, v.table)
+	if err == nil {
+		err = w.WriteHeader(exportHeaders(columns))
+	}
+	if err == nil {
+		err = v.eachExportRow(c, rows, columns, w.WriteRow)
+	}
+	if err != nil {
+		// 状态码已经写出，只能记录错误
+		c.Error(err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		c.Error(err)
+	}
+}
+
+// exportValue 取出字段的值
+// nil 指针和 NULL 返回 nil，实现了 driver.Valuer 的类型（sql.NullString、gorm.DeletedAt 等）返回其数据库值
+func exportValue(value reflect.Value) interface{} {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil
+	}
+
+	x := value.Interface()
+	if _, ok := x.(time.Time); ok {
+		return x
+	}
+	if valuer, ok := x.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+	return x
+}
+
+// csvValue 将导出的值格式化为 CSV 单元格
+// 时间使用 RFC 3339，nil 输出为空
+func csvValue(value interface{}) string {
+	switch x := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		return x.Format(time.RFC3339)
+	case []byte:
+		return string(x)
+	}
+	return fmt.Sprint(value)
+}
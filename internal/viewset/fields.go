@@ -0,0 +1,160 @@
+package viewset
+
+import (
+	"encoding/json"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// FieldsParam 稀疏字段集的查询参数，例如 ?fields=id,name,email
+const FieldsParam = "fields"
+
+// contextFields 本次请求选择的字段在 gin.Context 中的 key
+const contextFields = "viewset_fields"
+
+// parseFields 解析 ?fields=，字段名按 JSON 字段名校验
+// 未指定时返回全部字段；字段不存在或为只写字段时写出 422 并返回 false
+func (v *GenericViewSet) parseFields(c *gin.Context) bool {
+	raw := c.Query(FieldsParam)
+	if raw == "" || v.meta == nil {
+		return true
+	}
+
+	var (
+		fields []*meta.Field
+		errs   []utils.FieldError
+	)
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		f, ok := v.meta.Lookup(name)
+		if !ok || f.JSONName != name || f.WriteOnly {
+			errs = append(errs, utils.FieldError{Field: FieldsParam, Code: utils.CodeInvalid, Message: "未知的字段: " + name})
+			continue
+		}
+		fields = append(fields, f)
+	}
+	if len(errs) > 0 {
+		utils.ValidationError(c, errs)
+		return false
+	}
+
+	if len(fields) > 0 {
+		c.Set(contextFields, fields)
+	}
+	return true
+}
+
+// requestedFields 返回本次请求选择的字段，未指定 ?fields= 时返回 nil
+func requestedFields(c *gin.Context) []*meta.Field {
+	if value, ok := c.Get(contextFields); ok {
+		return value.([]*meta.Field)
+	}
+	return nil
+}
+
+// selectColumns 返回本次请求需要查询的列（带表名前缀），未指定 ?fields= 时返回 nil
+// 除请求的字段外总是查询主键、belongs_to 关联的外键以及 extra 中的列，
+// 保证关联加载和游标等功能正常
+func (v *GenericViewSet) selectColumns(c *gin.Context, extra ...string) []string {
+	fields := requestedFields(c)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	add := func(column string) {
+		if column != "" && !seen[column] {
+			seen[column] = true
+			columns = append(columns, v.table+"."+column)
+		}
+	}
+
+	for _, f := range fields {
+		add(f.Column)
+	}
+	if v.schema != nil {
+		for _, pk := range v.schema.PrimaryFields {
+			add(pk.DBName)
+		}
+		for _, rel := range v.schema.Relationships.Relations {
+			if rel.Type != schema.BelongsTo {
+				continue
+			}
+			for _, ref := range rel.References {
+				if !ref.OwnPrimaryKey && ref.ForeignKey != nil {
+					add(ref.ForeignKey.DBName)
+				}
+			}
+		}
+	}
+	for _, column := range extra {
+		add(column)
+	}
+	return columns
+}
+
+// selectFields 按 ?fields= 只查询需要的列
+func (v *GenericViewSet) selectFields(c *gin.Context, db *gorm.DB, extra ...string) *gorm.DB {
+	if columns := v.selectColumns(c, extra...); columns != nil {
+		return db.Select(columns)
+	}
+	return db
+}
+
+// pickFields 按 ?fields= 只保留响应中请求的字段以及 ?expand= 展开的关联
+// data 为序列化后的单个对象或列表，未指定 ?fields= 时原样返回
+func (v *GenericViewSet) pickFields(c *gin.Context, data interface{}) interface{} {
+	fields := requestedFields(c)
+	if len(fields) == 0 {
+		return data
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+
+	// ?expand= 展开的关联总是保留
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, f.JSONName)
+	}
+	if expanded, ok := c.Get(contextExpandKeys); ok {
+		keys = append(keys, expanded.([]string)...)
+	}
+
+	pick := func(obj map[string]json.RawMessage) map[string]json.RawMessage {
+		out := make(map[string]json.RawMessage, len(keys))
+		for _, key := range keys {
+			if value, ok := obj[key]; ok {
+				out[key] = value
+			}
+		}
+		return out
+	}
+
+	var list []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for i := range list {
+			list[i] = pick(list[i])
+		}
+		return list
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err == nil && obj != nil {
+		return pick(obj)
+	}
+	return data
+}
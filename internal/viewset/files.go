@@ -0,0 +1,196 @@
+package viewset
+
+import (
+	"encoding/json"
+	"go-viewset/internal/models"
+	"go-viewset/internal/storage"
+	"go-viewset/internal/utils"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/schema"
+)
+
+// fileFields EnableFileFields 的配置
+type fileFields struct {
+	store  storage.Storage
+	expiry time.Duration
+	fields []fileField
+}
+
+// fileField 带 file 标签的附件 ID 字段
+type fileField struct {
+	field    *schema.Field
+	jsonName string // 附件 ID 的字段名，例如 avatar_id
+	name     string // 响应中附件信息的字段名，例如 avatar
+}
+
+// AttachmentOutput 附件在响应中的格式：附件信息和签名的下载地址
+type AttachmentOutput struct {
+	*models.Attachment
+	URL string `json:"url"`
+
+	// Thumbnails 缩略图的下载地址，key 为尺寸，例如 {"64": "...", "256": "..."}
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
+}
+
+// NewAttachmentOutput 生成附件和缩略图的下载地址，有效期为 expiry（见 storage.Expires）
+func NewAttachmentOutput(store storage.Storage, a *models.Attachment, expiry time.Duration) AttachmentOutput {
+	expires := storage.Expires(expiry)
+	url, err := store.URL(a.Key, a.Name, expires)
+	if err != nil {
+		log.Printf("生成附件 %d 的下载地址失败: %v", a.ID, err)
+	}
+	out := AttachmentOutput{Attachment: a, URL: url}
+	for _, size := range thumbnailSizes(a) {
+		thumbnail, err := store.URL(thumbnailKey(a, size), "", expires)
+		if err != nil {
+			log.Printf("生成附件 %d 的缩略图下载地址失败: %v", a.ID, err)
+			continue
+		}
+		if out.Thumbnails == nil {
+			out.Thumbnails = make(map[string]string)
+		}
+		out.Thumbnails[strconv.Itoa(size)] = thumbnail
+	}
+	return out
+}
+
+// EnableFileFields 模型中带 file 标签的附件 ID 字段（例如 AvatarID *uint `json:"avatar_id" file:"avatar"`）
+// 在响应中增加标签指定的字段，内容为附件信息和由 store 签名的下载地址（有效期 expiry），附件不存在时为 null；
+// 创建和更新时校验附件存在（开启多租户时属于当前租户）。返回模型是否有附件字段
+func (v *GenericViewSet) EnableFileFields(store storage.Storage, expiry time.Duration) bool {
+	if v.meta == nil {
+		return false
+	}
+	var fields []fileField
+	for _, f := range v.meta.Fields {
+		if f.File == "" {
+			continue
+		}
+		if sf := v.schema.LookUpField(f.Column); sf != nil {
+			fields = append(fields, fileField{field: sf, jsonName: f.JSONName, name: f.File})
+		}
+	}
+	if len(fields) == 0 {
+		return false
+	}
+
+	// 同一 ViewSet 注册在多个路由下（例如多个 API 版本）时会调用多次，校验只添加一次
+	if v.files == nil {
+		v.Validators = append(v.Validators, v.validateFiles)
+	}
+	v.files = &fileFields{store: store, expiry: expiry, fields: fields}
+	return true
+}
+
+// validateFiles 校验请求中的附件 ID 存在，不存在的作为该字段的错误
+func (v *GenericViewSet) validateFiles(c *gin.Context, action string, obj interface{}) error {
+	ids := v.fileIDs(c, obj)
+	if len(ids) == 0 {
+		return nil
+	}
+	found, err := v.loadFiles(c, ids)
+	if err != nil {
+		return err
+	}
+
+	var errs utils.ValidationErrors
+	elem := reflect.ValueOf(obj).Elem()
+	for _, f := range v.files.fields {
+		value, _ := f.field.ValueOf(c.Request.Context(), elem)
+		if id, ok := attachmentID(value); ok && found[id] == nil {
+			errs = append(errs, utils.FieldError{Field: f.jsonName, Code: utils.CodeInvalid, Message: "附件不存在"})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// fileIDs 对象引用的附件 ID
+func (v *GenericViewSet) fileIDs(c *gin.Context, obj interface{}) []uint {
+	elem := reflect.Indirect(reflect.ValueOf(obj))
+	var ids []uint
+	for _, f := range v.files.fields {
+		value, _ := f.field.ValueOf(c.Request.Context(), elem)
+		if id, ok := attachmentID(value); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// loadFiles 按 ID 查询附件，开启多租户时只包含当前租户的附件
+func (v *GenericViewSet) loadFiles(c *gin.Context, ids []uint) (map[uint]*models.Attachment, error) {
+	found := make(map[uint]*models.Attachment, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+	query := v.dbFor(c).Model(&models.Attachment{}).Where("id IN ?", ids)
+	if tenantID, ok := c.Get(ContextTenantID); ok {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	var attachments []*models.Attachment
+	if err := query.Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		found[a.ID] = a
+	}
+	return found, nil
+}
+
+// withFiles 在序列化结果 data 中增加附件字段，obj 为对应的模型对象
+func (v *GenericViewSet) withFiles(c *gin.Context, data, obj interface{}, files map[uint]*models.Attachment) interface{} {
+	raw, err := utils.MarshalJSON(data)
+	if err != nil {
+		return data
+	}
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return data
+	}
+
+	elem := reflect.Indirect(reflect.ValueOf(obj))
+	for _, f := range v.files.fields {
+		out[f.name] = json.RawMessage("null")
+		value, _ := f.field.ValueOf(c.Request.Context(), elem)
+		id, ok := attachmentID(value)
+		if !ok || files[id] == nil {
+			continue
+		}
+		if encoded, err := json.Marshal(NewAttachmentOutput(v.files.store, files[id], v.files.expiry)); err == nil {
+			out[f.name] = encoded
+		}
+	}
+	return out
+}
+
+// serializeFiles 序列化单个对象并增加附件字段，查询失败时附件字段为 null
+func (v *GenericViewSet) serializeFiles(c *gin.Context, data, obj interface{}) interface{} {
+	files, err := v.loadFiles(c, v.fileIDs(c, obj))
+	if err != nil {
+		c.Error(err)
+	}
+	return v.withFiles(c, data, obj, files)
+}
+
+// attachmentID 附件 ID 字段的值，为 nil 或 0 时返回 false
+func attachmentID(value interface{}) (uint, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if !rv.IsValid() {
+		return 0, false
+	}
+	switch {
+	case rv.CanUint():
+		return uint(rv.Uint()), rv.Uint() != 0
+	case rv.CanInt():
+		return uint(rv.Int()), rv.Int() > 0
+	}
+	return 0, false
+}
@@ -0,0 +1,199 @@
+package viewset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-viewset/internal/events"
+	"go-viewset/internal/models"
+	"go-viewset/internal/utils"
+	"log"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 历史记录相关 action 的名称
+const (
+	ActionHistory = "history"
+	ActionRevert  = "revert"
+)
+
+// HistoryVersion 历史版本的响应，Data 为该版本的对象（与详情接口的格式相同）
+type HistoryVersion struct {
+	models.HistoryRecord
+	Data interface{} `json:"data"`
+}
+
+// EnableHistory 开启历史记录
+// 创建 <表名>_history 表，之后每次写操作（与审计日志相同，见 publish）保存对象的完整快照，
+// 并注册 GET /:id/history（RetrieveMixin）和 POST /:id/revert/:version（UpdateMixin），需要在注册路由之前调用
+func (v *GenericViewSet) EnableHistory() error {
+	if v.schema == nil {
+		return fmt.Errorf("%s 的模型解析失败，无法开启历史记录", v.table)
+	}
+	table := v.table + "_history"
+	db := v.DB.Table(table)
+	if err := db.AutoMigrate(&models.HistoryRecord{}); err != nil {
+		return fmt.Errorf("创建 %s 失败: %w", table, err)
+	}
+
+	// 索引名在 PostgreSQL 中全库唯一，因此带上表名
+	index := "idx_" + table + "_object_version"
+	if !db.Migrator().HasIndex(&models.HistoryRecord{}, index) {
+		if err := v.DB.Exec("CREATE UNIQUE INDEX " + index + " ON " + table + " (object_id, version)").Error; err != nil {
+			return fmt.Errorf("创建 %s 的索引失败: %w", table, err)
+		}
+	}
+
+	v.historyTable = table
+	return nil
+}
+
+// recordHistory 保存对象的快照，由 publish 调用
+// 版本号按对象递增，与写操作在同一个事务中
+func (v *GenericViewSet) recordHistory(c *gin.Context, action events.Action, obj interface{}) {
+	if v.historyTable == "" {
+		return
+	}
+
+	data, err := json.Marshal(v.snapshot(c.Request.Context(), obj))
+	if err != nil {
+		log.Printf("序列化 %s 历史记录失败: %v", v.table, err)
+		return
+	}
+
+	db := v.dbFor(c)
+	record := &models.HistoryRecord{
+		ObjectID: v.auditObjectID(obj),
+		Type:     string(action),
+		Data:     data,
+	}
+	if userID, ok := c.Get(ContextUserID); ok {
+		record.User = fmt.Sprint(userID)
+	}
+	if err := db.Table(v.historyTable).Where("object_id = ?", record.ObjectID).
+		Select("COALESCE(MAX(version), 0) + 1").Scan(&record.Version).Error; err != nil {
+		log.Printf("查询 %s 历史版本失败: %v", v.table, err)
+		return
+	}
+	if err := db.Table(v.historyTable).Create(record).Error; err != nil {
+		log.Printf("写入 %s 历史记录失败: %v", v.table, err)
+	}
+}
+
+// snapshot 对象所有数据库列的值（列名 -> 值），包括只写字段，用于回滚
+func (v *GenericViewSet) snapshot(ctx context.Context, obj interface{}) map[string]interface{} {
+	elem := reflect.Indirect(reflect.ValueOf(obj))
+	values := make(map[string]interface{}, len(v.schema.DBNames))
+	for _, name := range v.schema.DBNames {
+		sf := v.schema.FieldsByDBName[name]
+		values[name], _ = sf.ValueOf(ctx, elem)
+	}
+	return values
+}
+
+// restore 将快照写回对象，主键保持不变
+func (v *GenericViewSet) restore(ctx context.Context, obj interface{}, data []byte) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	elem := reflect.Indirect(reflect.ValueOf(obj))
+	for name, raw := range values {
+		sf := v.schema.FieldsByDBName[name]
+		if sf == nil || sf.PrimaryKey {
+			continue
+		}
+		value := reflect.New(sf.FieldType)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return fmt.Errorf("字段 %s: %w", name, err)
+		}
+		if err := sf.Set(ctx, elem, value.Elem().Interface()); err != nil {
+			return fmt.Errorf("字段 %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// History 列出对象的历史版本，新版本在前
+// GET /items/:id/history
+func (v *GenericViewSet) History(c *gin.Context) {
+	obj, ok := v.GetObjectOr404(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var records []models.HistoryRecord
+	if err := v.dbFor(c).Table(v.historyTable).Where("object_id = ?", v.auditObjectID(obj)).
+		Order("version DESC").Find(&records).Error; err != nil {
+		v.dbError(c, "查询历史记录失败", err)
+		return
+	}
+
+	versions := make([]HistoryVersion, len(records))
+	for i, record := range records {
+		versions[i].HistoryRecord = record
+		snapshot := v.newObject()
+		if err := v.restore(c.Request.Context(), snapshot, record.Data); err != nil {
+			utils.InternalServerError(c, fmt.Sprintf("解析历史版本 %d 失败: %v", record.Version, err))
+			return
+		}
+		v.copyPrimaryKey(c.Request.Context(), snapshot, obj)
+		versions[i].Data = v.serialize(c, ActionRetrieve, snapshot)
+	}
+
+	v.Respond(c, versions)
+}
+
+// Revert 将对象恢复到指定版本，恢复本身也会记录为一个新版本
+// POST /items/:id/revert/:version
+func (v *GenericViewSet) Revert(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		utils.BadRequest(c, "无效的版本号")
+		return
+	}
+
+	obj, ok := v.GetObjectOr404(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var record models.HistoryRecord
+	if err := v.dbFor(c).Table(v.historyTable).Where("object_id = ? AND version = ?", v.auditObjectID(obj), version).
+		First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.NotFound(c, "版本不存在")
+		} else {
+			v.dbError(c, "查询历史记录失败", err)
+		}
+		return
+	}
+
+	if err := v.restore(c.Request.Context(), obj, record.Data); err != nil {
+		utils.InternalServerError(c, fmt.Sprintf("解析历史版本 %d 失败: %v", version, err))
+		return
+	}
+	if err := v.dbFor(c).Save(obj).Error; err != nil {
+		v.dbError(c, "恢复失败", err)
+		return
+	}
+
+	v.publish(c, events.Updated, obj)
+
+	v.setETag(c, obj)
+	v.Respond(c, v.serialize(c, ActionUpdate, obj))
+}
+
+// copyPrimaryKey 将 src 的主键复制到 dst
+func (v *GenericViewSet) copyPrimaryKey(ctx context.Context, dst, src interface{}) {
+	pk := v.schema.PrioritizedPrimaryField
+	if pk == nil {
+		return
+	}
+	id, _ := pk.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(src)))
+	pk.Set(ctx, reflect.Indirect(reflect.ValueOf(dst)), id)
+}
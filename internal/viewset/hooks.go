@@ -0,0 +1,183 @@
+package viewset
+
+import (
+	"errors"
+	"go-viewset/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateHook 创建前的钩子，返回错误时不再创建
+type CreateHook interface {
+	PerformCreate(c *gin.Context, obj interface{}) error
+}
+
+// UpdateHook 更新前的钩子，返回错误时不再更新
+// obj 为本次要写入的数据：PUT 时为模型对象（零值字段不会更新），
+// PATCH 时为 列名 -> 值 的 map[string]interface{}
+type UpdateHook interface {
+	PerformUpdate(c *gin.Context, obj interface{}) error
+}
+
+// DestroyHook 删除前的钩子，返回错误时不再删除
+type DestroyHook interface {
+	PerformDestroy(c *gin.Context, obj interface{}) error
+}
+
+// AfterCreateHook 创建成功后的钩子，obj 为已写入的对象
+type AfterCreateHook interface {
+	AfterCreate(c *gin.Context, obj interface{})
+}
+
+// AfterUpdateHook 更新成功后的钩子，obj 为重新查询的最新对象
+type AfterUpdateHook interface {
+	AfterUpdate(c *gin.Context, obj interface{})
+}
+
+// AfterDestroyHook 删除成功后的钩子，obj 为已删除的对象
+type AfterDestroyHook interface {
+	AfterDestroy(c *gin.Context, obj interface{})
+}
+
+// QuerysetHook 按请求定制基础查询，List、Retrieve、Update/PATCH、Delete、导出、聚合等都从它返回的查询开始，
+// 可以在一处加入租户、所有者等过滤条件，而不必覆盖每个处理函数。
+// base 已经包含嵌套路由和 ScopeToOwner 的条件，返回的查询不能为 nil
+type QuerysetHook interface {
+	GetQueryset(c *gin.Context, base *gorm.DB) *gorm.DB
+}
+
+// QuerysetCacheKeyHook 返回区分 GetQueryset 结果的标识（例如按角色过滤时为角色名），
+// 列表、详情的缓存和总数缓存按它分开保存。实现了 QuerysetHook 而没有实现它的 ViewSet 不使用这些缓存
+type QuerysetCacheKeyHook interface {
+	QuerysetCacheKey(c *gin.Context) string
+}
+
+// 钩子名称，用于查找类型化的钩子（见 ViewSet）
+const (
+	hookCreate  = "create"
+	hookUpdate  = "update"
+	hookDestroy = "destroy"
+)
+
+// typedHooks 调用 ViewSet[T] 上类型化的钩子，返回 false 表示没有对应的钩子，
+// 此时回到 CreateHook 等接口
+type typedHooks interface {
+	perform(c *gin.Context, hook string, obj interface{}) (bool, error)
+	after(c *gin.Context, hook string, obj interface{}) bool
+}
+
+// PerformCreate 创建前的钩子，子类可以覆盖
+func (v *GenericViewSet) PerformCreate(c *gin.Context, obj interface{}) error {
+	return nil
+}
+
+// PerformUpdate 更新前的钩子，子类可以覆盖
+func (v *GenericViewSet) PerformUpdate(c *gin.Context, obj interface{}) error {
+	return nil
+}
+
+// PerformDestroy 删除前的钩子，子类可以覆盖
+func (v *GenericViewSet) PerformDestroy(c *gin.Context, obj interface{}) error {
+	return nil
+}
+
+// SetImpl 设置最外层的 ViewSet，钩子方法在它上面查找
+// Go 的嵌入不会把 GenericViewSet 中的调用分派到子类覆盖的方法，
+// 因此通用处理函数通过 impl 上的接口（CreateHook 等）调用钩子。
+// RegisterMixins 和 RegisterActions 会自动设置，一般不需要手动调用
+func (v *GenericViewSet) SetImpl(vs interface{}) {
+	v.impl = vs
+}
+
+// hooks 返回查找钩子的对象
+func (v *GenericViewSet) hooks() interface{} {
+	if v.impl != nil {
+		return v.impl
+	}
+	return v
+}
+
+// performCreate 调用创建前的钩子，失败时写出错误响应并返回 false
+func (v *GenericViewSet) performCreate(c *gin.Context, obj interface{}) bool {
+	if v.typed != nil {
+		if ok, err := v.typed.perform(c, hookCreate, obj); ok {
+			return hookResult(c, err)
+		}
+	}
+	if h, ok := v.hooks().(CreateHook); ok {
+		return hookResult(c, h.PerformCreate(c, obj))
+	}
+	return true
+}
+
+// performUpdate 调用更新前的钩子，失败时写出错误响应并返回 false
+func (v *GenericViewSet) performUpdate(c *gin.Context, obj interface{}) bool {
+	if v.typed != nil {
+		if ok, err := v.typed.perform(c, hookUpdate, obj); ok {
+			return hookResult(c, err)
+		}
+	}
+	if h, ok := v.hooks().(UpdateHook); ok {
+		return hookResult(c, h.PerformUpdate(c, obj))
+	}
+	return true
+}
+
+// performDestroy 调用删除前的钩子，失败时写出错误响应并返回 false
+func (v *GenericViewSet) performDestroy(c *gin.Context, obj interface{}) bool {
+	if v.typed != nil {
+		if ok, err := v.typed.perform(c, hookDestroy, obj); ok {
+			return hookResult(c, err)
+		}
+	}
+	if h, ok := v.hooks().(DestroyHook); ok {
+		return hookResult(c, h.PerformDestroy(c, obj))
+	}
+	return true
+}
+
+// afterCreate 调用创建成功后的钩子
+func (v *GenericViewSet) afterCreate(c *gin.Context, obj interface{}) {
+	if v.typed != nil && v.typed.after(c, hookCreate, obj) {
+		return
+	}
+	if h, ok := v.hooks().(AfterCreateHook); ok {
+		h.AfterCreate(c, obj)
+	}
+}
+
+// afterUpdate 调用更新成功后的钩子
+func (v *GenericViewSet) afterUpdate(c *gin.Context, obj interface{}) {
+	if v.typed != nil && v.typed.after(c, hookUpdate, obj) {
+		return
+	}
+	if h, ok := v.hooks().(AfterUpdateHook); ok {
+		h.AfterUpdate(c, obj)
+	}
+}
+
+// afterDestroy 调用删除成功后的钩子
+func (v *GenericViewSet) afterDestroy(c *gin.Context, obj interface{}) {
+	if v.typed != nil && v.typed.after(c, hookDestroy, obj) {
+		return
+	}
+	if h, ok := v.hooks().(AfterDestroyHook); ok {
+		h.AfterDestroy(c, obj)
+	}
+}
+
+// hookResult 处理钩子返回的错误
+// utils.ValidationErrors 按校验失败返回 422，其他错误返回 400
+func hookResult(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	var verrs utils.ValidationErrors
+	if errors.As(err, &verrs) {
+		utils.ValidationError(c, verrs)
+	} else {
+		utils.BadRequest(c, err.Error())
+	}
+	return false
+}
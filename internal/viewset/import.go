@@ -0,0 +1,276 @@
+package viewset
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-viewset/internal/events"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"go-viewset/internal/xlsx"
+	"mime/multipart"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ActionImport 文件导入的 action 名称
+const ActionImport = "import"
+
+// ImportFileField 上传文件的表单字段名
+const ImportFileField = "file"
+
+// defaultImportMaxRows 单个文件的默认最大数据行数
+const defaultImportMaxRows = 10000
+
+// ImportRowError 导入时一行数据的错误
+type ImportRowError struct {
+	Row    int            `json:"row"` // 文件中的行号，表头为第 1 行
+	Errors utils.ErrorMap `json:"errors"`
+}
+
+// Import 从上传的 CSV 或 Excel（.xlsx）文件批量创建记录
+// 第一行为表头（JSON 字段名、列名或 ExportColumns 中的表头，导出的文件可以直接导入），
+// 只读字段和无法识别的列被忽略；每行与 BulkCreate 一样逐条解码和校验，
+// 校验通过的行在一个事务中分批插入，返回导入结果和每一行的错误。
+// 通过 EnableImport 开启
+// POST /items/import（multipart/form-data，文件字段为 file）
+func (v *GenericViewSet) Import(c *gin.Context) {
+	if v.meta == nil {
+		utils.InternalServerError(c, "模型元数据不可用，无法导入")
+		return
+	}
+
+	fh, err := c.FormFile(ImportFileField)
+	if err != nil {
+		utils.BadRequest(c, "缺少上传文件 "+ImportFileField)
+		return
+	}
+	rows, err := readImportFile(fh)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		utils.BadRequest(c, "文件为空")
+		return
+	}
+
+	fields := v.importFields(rows[0])
+	if len(fields) == 0 {
+		utils.BadRequest(c, "表头中没有可导入的字段")
+		return
+	}
+
+	maxRows := v.ImportMaxRows
+	if maxRows <= 0 {
+		maxRows = defaultImportMaxRows
+	}
+	total := 0
+	for _, row := range rows[1:] {
+		if !emptyRow(row) {
+			total++
+		}
+	}
+	if total == 0 {
+		utils.BadRequest(c, "文件中没有数据")
+		return
+	}
+	if total > maxRows {
+		utils.BadRequest(c, fmt.Sprintf("单次最多导入 %d 行", maxRows))
+		return
+	}
+
+	// 逐行解码和校验
+	var rowErrors []ImportRowError
+	valid := reflect.MakeSlice(v.sliceType, 0, total)
+	for i, row := range rows[1:] {
+		if emptyRow(row) {
+			continue
+		}
+		line := i + 2
+
+		obj := v.newObject()
+		if errs := v.decodeImportRow(c, fields, row, obj); len(errs) > 0 {
+			rowErrors = append(rowErrors, ImportRowError{Row: line, Errors: utils.GroupErrors(errs)})
+			continue
+		}
+		valid = reflect.Append(valid, reflect.ValueOf(obj))
+	}
+
+	// 客户端已断开时不再写入
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	if valid.Len() > 0 {
+		batch := valid.Interface()
+		err := v.dbFor(c).Transaction(func(tx *gorm.DB) error {
+			return tx.CreateInBatches(batch, defaultBulkBatchSize).Error
+		})
+		if err != nil {
+			v.dbError(c, "导入失败", err)
+			return
+		}
+	}
+
+	for n := 0; n < valid.Len(); n++ {
+		obj := valid.Index(n).Interface()
+		v.afterCreate(c, obj)
+		v.publish(c, events.Created, obj)
+	}
+
+	if rowErrors == nil {
+		rowErrors = []ImportRowError{}
+	}
+	v.Respond(c, gin.H{
+		"total":   total,
+		"created": valid.Len(),
+		"failed":  len(rowErrors),
+		"errors":  rowErrors,
+	})
+}
+
+// readImportFile 按扩展名读取 CSV 或 xlsx 文件的全部行
+func readImportFile(fh *multipart.FileHeader) ([][]string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("无法读取上传文件: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(fh.Filename)) {
+	case ".csv":
+		r := csv.NewReader(f)
+		r.FieldsPerRecord = -1
+		rows, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("CSV 格式错误: %w", err)
+		}
+		// Excel 保存的 UTF-8 CSV 带有 BOM
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+		}
+		return rows, nil
+	case ".xlsx":
+		return xlsx.ReadAll(f, fh.Size)
+	default:
+		return nil, fmt.Errorf("只支持 .csv 和 .xlsx 文件")
+	}
+}
+
+// importFields 将表头映射为字段，无法识别或不可写入的列为 nil
+func (v *GenericViewSet) importFields(headers []string) []*meta.Field {
+	byHeader := make(map[string]*meta.Field, len(v.ExportColumns))
+	for _, col := range v.ExportColumns {
+		if f, ok := v.meta.Lookup(col.Field); ok && col.Header != "" {
+			byHeader[col.Header] = f
+		}
+	}
+
+	fields := make([]*meta.Field, len(headers))
+	found := false
+	for i, header := range headers {
+		header = strings.TrimSpace(header)
+		f, ok := byHeader[header]
+		if !ok {
+			f, ok = v.meta.Lookup(header)
+		}
+		if !ok || f.ReadOnly || f.JSONName == "-" {
+			continue
+		}
+		fields[i] = f
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return fields
+}
+
+// decodeImportRow 将一行数据转换为模型对象并校验，返回该行的全部字段错误
+// 空单元格视为未提供，由 binding 规则决定是否必填
+func (v *GenericViewSet) decodeImportRow(c *gin.Context, fields []*meta.Field, row []string, obj interface{}) []utils.FieldError {
+	var errs []utils.FieldError
+	values := make(map[string]interface{}, len(fields))
+	for i, f := range fields {
+		if f == nil || i >= len(row) {
+			continue
+		}
+		raw := strings.TrimSpace(row[i])
+		if raw == "" {
+			continue
+		}
+		value, err := importValue(f, raw)
+		if err != nil {
+			errs = append(errs, utils.FieldError{Field: f.JSONName, Code: utils.CodeInvalidType, Message: fmt.Sprintf("%s 的值无效: %s", f.JSONName, raw)})
+			continue
+		}
+		values[f.JSONName] = value
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	body, err := json.Marshal(values)
+	if err != nil {
+		return utils.BindingErrors(err)
+	}
+	if err := v.decodeItem(c, ActionImport, body, obj); err != nil {
+		return utils.BindingErrors(err)
+	}
+	if err := v.setParentFields(c, obj); err != nil {
+		return utils.BindingErrors(err)
+	}
+	if err := v.setOwnerField(c, obj, true); err != nil {
+		return utils.BindingErrors(err)
+	}
+	if err := v.setTenantField(c, obj, true); err != nil {
+		return utils.BindingErrors(err)
+	}
+	return v.performBulkCreate(c, obj)
+}
+
+// importValue 按字段类型转换单元格的文本
+// 时间支持 "2006-01-02 15:04:05"、"2006-01-02"、RFC 3339 以及 Excel 日期序列号，按本地时区解析
+func importValue(f *meta.Field, raw string) (interface{}, error) {
+	switch f.TypeName {
+	case "time":
+		for _, layout := range []string{xlsx.TimeLayout, "2006-01-02", time.RFC3339} {
+			if t, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		if t, ok := xlsx.ParseSerialTime(raw, time.Local); ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("无法解析时间 %q", raw)
+	}
+
+	switch f.Type.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(raw, 10, f.Type.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(raw, 10, f.Type.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, f.Type.Bits())
+	}
+	return raw, nil
+}
+
+// emptyRow 是否为空行
+func emptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
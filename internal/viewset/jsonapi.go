@@ -0,0 +1,509 @@
+package viewset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON:API 格式（jsonapi.org）
+const (
+	// FormatJSONAPI JSON:API 的格式名，?format=jsonapi
+	FormatJSONAPI = "jsonapi"
+	// MediaTypeJSONAPI Accept 和 Content-Type 中的媒体类型
+	MediaTypeJSONAPI = "application/vnd.api+json"
+)
+
+// jsonapiVersion 响应中声明的 JSON:API 版本
+const jsonapiVersion = "1.1"
+
+// EnableJSONAPI 开启 JSON:API 格式的内容协商：Accept 为 application/vnd.api+json（或 ?format=jsonapi）的请求按 JSON:API 文档响应。
+// 资源的 type 为表名，id 为主键，其余字段为 attributes；belongs-to 关联的外键和 ?expand= 展开的关联放在 relationships 中，
+// 展开的对象放在 included 中；分页信息在 meta.pagination 中，翻页地址在 links 中。
+// Content-Type 为 application/vnd.api+json 的创建和修改请求总是按 JSON:API 文档解析；只使用 JSON:API 的 ViewSet 见 GenericViewSet.JSONAPI
+func EnableJSONAPI() {
+	utils.RegisterRenderer(FormatJSONAPI, []string{MediaTypeJSONAPI}, jsonapiRenderer{})
+}
+
+// jsonapiRenderer JSON:API 文档
+type jsonapiRenderer struct{}
+
+// ContentType 实现 utils.Renderer，JSON:API 不允许 charset 参数
+func (jsonapiRenderer) ContentType() string { return MediaTypeJSONAPI }
+
+// Marshal 实现 utils.Renderer，没有请求信息时不生成资源对象和链接
+func (jsonapiRenderer) Marshal(v interface{}) ([]byte, error) {
+	b := &jsonapiBuilder{}
+	doc, err := b.document(http.StatusOK, v)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MarshalJSON(doc)
+}
+
+// RenderContext 实现 utils.ContextRenderer
+func (jsonapiRenderer) RenderContext(c *gin.Context, status int, v interface{}) ([]byte, error) {
+	b := &jsonapiBuilder{c: c, base: collectionPath(c)}
+	if value, ok := c.Get(contextViewSet); ok {
+		b.v = value.(*GenericViewSet)
+	}
+	doc, err := b.document(status, v)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MarshalJSON(doc)
+}
+
+// jsonapiDocument 顶层文档，data 和 errors 只出现一个
+type jsonapiDocument struct {
+	Data     interface{}            `json:"data,omitempty"`
+	Errors   []jsonapiError         `json:"errors,omitempty"`
+	Included []*jsonapiResource     `json:"included,omitempty"`
+	Links    map[string]string      `json:"links,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	JSONAPI  map[string]string      `json:"jsonapi"`
+}
+
+// jsonapiResource 资源对象
+type jsonapiResource struct {
+	Type          string                            `json:"type"`
+	ID            string                            `json:"id"`
+	Attributes    map[string]interface{}            `json:"attributes"`
+	Relationships map[string]map[string]interface{} `json:"relationships,omitempty"`
+	Links         map[string]string                 `json:"links,omitempty"`
+}
+
+// jsonapiIdentifier 资源标识，relationships 中的 data
+type jsonapiIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// jsonapiError 错误对象
+type jsonapiError struct {
+	Status string            `json:"status"`
+	Code   string            `json:"code,omitempty"`
+	Title  string            `json:"title"`
+	Detail string            `json:"detail,omitempty"`
+	Source map[string]string `json:"source,omitempty"`
+}
+
+// jsonapiBuilder 生成一个响应的 JSON:API 文档
+type jsonapiBuilder struct {
+	c    *gin.Context
+	v    *GenericViewSet // 不是 ViewSet 的请求时为 nil
+	base string          // 资源集合的路径，以 / 结尾
+
+	relations []modelRelation
+	included  []*jsonapiResource
+	seen      map[jsonapiIdentifier]bool
+}
+
+// document 按响应的各部分（见 utils.PartsOf）生成文档
+// 成功响应中 ViewSet 的对象转换为资源对象，其他数据（例如统计结果）放在 meta.result 中
+func (b *jsonapiBuilder) document(status int, v interface{}) (*jsonapiDocument, error) {
+	doc := &jsonapiDocument{JSONAPI: map[string]string{"version": jsonapiVersion}}
+	parts, ok := utils.PartsOf(v)
+	if !ok {
+		parts = utils.ResponseParts{Success: true, Data: v}
+	}
+	data, err := jsonapiDecode(parts.Data)
+	if err != nil {
+		return nil, err
+	}
+	if b.v != nil {
+		b.relations = b.v.modelRelations()
+	}
+
+	if !parts.Success || status >= http.StatusBadRequest {
+		doc.Errors = b.errors(status, parts)
+		if data != nil {
+			doc.Meta = map[string]interface{}{"result": data}
+		}
+		return doc, nil
+	}
+
+	// 空列表（nil 切片）的 data 为 []
+	if data == nil && parts.Pagination != nil {
+		data = []interface{}{}
+	}
+	doc.Meta = make(map[string]interface{})
+	if resources, ok := b.resources(data); ok {
+		doc.Data = resources
+	} else {
+		doc.Data = json.RawMessage("null")
+		if data != nil {
+			doc.Meta["result"] = data
+		}
+	}
+	doc.Included = b.included
+
+	if b.c != nil {
+		doc.Links = map[string]string{"self": b.c.Request.URL.RequestURI()}
+	}
+	if parts.Pagination != nil {
+		doc.Meta["pagination"] = parts.Pagination
+		count := 0
+		if list, ok := data.([]interface{}); ok {
+			count = len(list)
+		}
+		if b.c != nil {
+			for rel, href := range pageLinks(b.c, parts.Pagination, count) {
+				doc.Links[rel] = href
+			}
+		}
+	}
+	if len(doc.Meta) == 0 {
+		doc.Meta = nil
+	}
+	return doc, nil
+}
+
+// resources 将 ViewSet 的对象（包含主键字段的对象或对象列表）转换为资源对象
+func (b *jsonapiBuilder) resources(data interface{}) (interface{}, bool) {
+	if b.v == nil || b.v.meta == nil {
+		return nil, false
+	}
+	pk := jsonapiPrimaryKey(b.v.meta)
+	switch d := data.(type) {
+	case map[string]interface{}:
+		if _, ok := d[pk]; ok {
+			return b.resource(b.v.table, b.v.meta, d, true), true
+		}
+	case []interface{}:
+		out := make([]*jsonapiResource, 0, len(d))
+		for _, item := range d {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if _, ok := obj[pk]; !ok {
+				return nil, false
+			}
+			out = append(out, b.resource(b.v.table, b.v.meta, obj, true))
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// resource 生成资源对象，top 为 true 时是本 ViewSet 的对象：处理关联并生成 links.self，否则是 included 中的关联对象
+func (b *jsonapiBuilder) resource(typ string, model *meta.Model, obj map[string]interface{}, top bool) *jsonapiResource {
+	pk := jsonapiPrimaryKey(model)
+	res := &jsonapiResource{Type: typ, ID: jsonapiID(obj[pk]), Attributes: obj}
+	delete(obj, pk)
+	if !top {
+		return res
+	}
+
+	for _, rel := range b.relations {
+		if rel.foreignKey != "" {
+			if value, ok := obj[rel.foreignKey]; ok {
+				delete(obj, rel.foreignKey)
+				var linkage interface{}
+				if value != nil {
+					linkage = jsonapiIdentifier{Type: rel.typ, ID: jsonapiID(value)}
+				}
+				res.relate(rel.name, linkage)
+			}
+		}
+		if value, ok := obj[rel.name]; ok {
+			delete(obj, rel.name)
+			if value != nil {
+				res.relate(rel.name, b.include(rel, value))
+			}
+		}
+	}
+	if b.base != "" && res.ID != "" {
+		res.Links = map[string]string{"self": b.base + url.PathEscape(res.ID)}
+	}
+	return res
+}
+
+// relate 设置关联的资源标识
+func (res *jsonapiResource) relate(name string, linkage interface{}) {
+	if res.Relationships == nil {
+		res.Relationships = make(map[string]map[string]interface{})
+	}
+	res.Relationships[name] = map[string]interface{}{"data": linkage}
+}
+
+// include 将展开的关联对象（对象或对象列表）加入 included，返回资源标识
+func (b *jsonapiBuilder) include(rel modelRelation, value interface{}) interface{} {
+	add := func(item interface{}) interface{} {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		model := rel.model
+		if model == nil {
+			model = &meta.Model{}
+		}
+		res := b.resource(rel.typ, model, obj, false)
+		id := jsonapiIdentifier{Type: res.Type, ID: res.ID}
+		if b.seen == nil {
+			b.seen = make(map[jsonapiIdentifier]bool)
+		}
+		if !b.seen[id] {
+			b.seen[id] = true
+			b.included = append(b.included, res)
+		}
+		return id
+	}
+
+	if list, ok := value.([]interface{}); ok {
+		out := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			if id := add(item); id != nil {
+				out = append(out, id)
+			}
+		}
+		return out
+	}
+	return add(value)
+}
+
+// errors 错误对象：每个字段错误一个，指向 attributes 或 relationships 中的字段；没有字段错误时为 msg
+// 以 200 状态码返回的错误（见 utils.Error）使用错误码（400~599）或 400 作为 status
+func (b *jsonapiBuilder) errors(status int, parts utils.ResponseParts) []jsonapiError {
+	if status < http.StatusBadRequest {
+		status = http.StatusBadRequest
+		if parts.Code >= http.StatusBadRequest && parts.Code < 600 {
+			status = parts.Code
+		}
+	}
+	base := jsonapiError{Status: strconv.Itoa(status), Title: http.StatusText(status)}
+	if parts.Code != status && parts.Code != 0 {
+		base.Code = strconv.Itoa(parts.Code)
+	}
+	if len(parts.Errors) == 0 {
+		base.Detail = parts.Msg
+		return []jsonapiError{base}
+	}
+
+	fields := make([]string, 0, len(parts.Errors))
+	for field := range parts.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var out []jsonapiError
+	for _, field := range fields {
+		pointer := ""
+		if field != utils.NonFieldErrorsKey {
+			pointer = "/data/attributes/" + field
+			for _, rel := range b.relations {
+				if rel.foreignKey == field || rel.name == field {
+					pointer = "/data/relationships/" + rel.name
+				}
+			}
+		}
+		for _, msg := range parts.Errors[field] {
+			e := base
+			e.Detail = msg
+			if pointer != "" {
+				e.Source = map[string]string{"pointer": pointer}
+			}
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// jsonapiPrimaryKey 主键的 JSON 字段名，没有主键字段时为 id
+func jsonapiPrimaryKey(model *meta.Model) string {
+	for _, f := range model.Fields {
+		if f.PrimaryKey {
+			return f.JSONName
+		}
+	}
+	return "id"
+}
+
+// jsonapiID 主键值转换为字符串
+func jsonapiID(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.Number:
+		return string(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonapiDecode 将响应数据转换为 map / []interface{}，数字保留为 json.Number
+func jsonapiDecode(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	body, err := utils.MarshalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// jsonapiActions 请求体按 JSON:API 文档解析的 action
+var jsonapiActions = map[string]bool{
+	ActionCreate:        true,
+	ActionUpdate:        true,
+	ActionPartialUpdate: true,
+	ActionBulkCreate:    true,
+}
+
+// jsonapiInput 请求中的资源对象
+type jsonapiInput struct {
+	Type          string                     `json:"type"`
+	ID            string                     `json:"id"`
+	Attributes    map[string]json.RawMessage `json:"attributes"`
+	Relationships map[string]struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"relationships"`
+}
+
+// parseJSONAPI 创建和修改请求的请求体为 JSON:API 文档时（Content-Type 为 application/vnd.api+json，或开启了 JSONAPI），
+// 转换为普通的 JSON 请求体：attributes 中的字段加上 belongs-to 关联的外键，之后的绑定和校验不变；
+// 批量创建的 data 为资源对象数组。请求体无效时写出错误响应并返回 false
+func (v *GenericViewSet) parseJSONAPI(c *gin.Context, action string) bool {
+	if !jsonapiActions[action] || c.Request.Body == nil {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != MediaTypeJSONAPI && !(v.JSONAPI && (mediaType == "" || mediaType == "application/json")) {
+		return true
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		utils.BadRequest(c, "读取请求体失败")
+		return false
+	}
+	var doc struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil || len(doc.Data) == 0 {
+		utils.BadRequest(c, `请求体应为 JSON:API 文档：{"data": {"type": "`+v.table+`", "attributes": {...}}}`)
+		return false
+	}
+
+	var out interface{}
+	if action == ActionBulkCreate {
+		var items []jsonapiInput
+		if err := json.Unmarshal(doc.Data, &items); err != nil {
+			utils.BadRequest(c, "批量创建的 data 应为资源对象数组")
+			return false
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			obj, ok := v.jsonapiAttributes(c, action, item)
+			if !ok {
+				return false
+			}
+			list[i] = obj
+		}
+		out = list
+	} else {
+		var item jsonapiInput
+		if err := json.Unmarshal(doc.Data, &item); err != nil {
+			utils.BadRequest(c, "data 应为资源对象")
+			return false
+		}
+		obj, ok := v.jsonapiAttributes(c, action, item)
+		if !ok {
+			return false
+		}
+		out = obj
+	}
+
+	converted, err := json.Marshal(out)
+	if err != nil {
+		utils.BadRequest(c, "data 应为资源对象")
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(converted))
+	c.Request.ContentLength = int64(len(converted))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return true
+}
+
+// jsonapiAttributes 资源对象转换为请求体中的对象
+// type 与本资源不同、data.id 与路径中的 ID 不同时返回 409，创建时不支持客户端生成的 ID，返回 403
+func (v *GenericViewSet) jsonapiAttributes(c *gin.Context, action string, in jsonapiInput) (map[string]json.RawMessage, bool) {
+	if in.Type != v.table {
+		utils.Conflict(c, fmt.Sprintf("资源类型应为 %s", v.table))
+		return nil, false
+	}
+	switch {
+	case action == ActionUpdate || action == ActionPartialUpdate:
+		if in.ID != "" && in.ID != c.Param("id") {
+			utils.Conflict(c, "data.id 与路径中的 ID 不一致")
+			return nil, false
+		}
+	case in.ID != "":
+		utils.Forbidden(c, "不支持客户端生成的 ID")
+		return nil, false
+	}
+
+	obj := make(map[string]json.RawMessage, len(in.Attributes)+len(in.Relationships))
+	for key, value := range in.Attributes {
+		obj[key] = value
+	}
+	if len(in.Relationships) == 0 {
+		return obj, true
+	}
+
+	relations := make(map[string]modelRelation)
+	for _, rel := range v.modelRelations() {
+		relations[rel.name] = rel
+	}
+	var errs []utils.FieldError
+	for name, value := range in.Relationships {
+		rel, ok := relations[name]
+		if !ok || rel.foreignKey == "" {
+			errs = append(errs, utils.FieldError{Field: name, Code: utils.CodeInvalid, Message: name + " 不是可以修改的关联"})
+			continue
+		}
+		var id *jsonapiIdentifier
+		if err := json.Unmarshal(value.Data, &id); err != nil || len(value.Data) == 0 || id != nil && id.Type != rel.typ {
+			errs = append(errs, utils.FieldError{Field: name, Code: utils.CodeInvalid,
+				Message: fmt.Sprintf(`%s 应为 {"data": {"type": "%s", "id": ...}} 或 {"data": null}`, name, rel.typ)})
+			continue
+		}
+		switch {
+		case id == nil:
+			obj[rel.foreignKey] = json.RawMessage("null")
+		case isUnsigned(id.ID):
+			obj[rel.foreignKey] = json.RawMessage(id.ID)
+		default:
+			obj[rel.foreignKey], _ = json.Marshal(id.ID)
+		}
+	}
+	if len(errs) > 0 {
+		utils.ValidationError(c, errs)
+		return nil, false
+	}
+	return obj, true
+}
+
+// isUnsigned 是否为十进制非负整数
+func isUnsigned(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
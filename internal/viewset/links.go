@@ -0,0 +1,216 @@
+package viewset
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-viewset/internal/utils"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LinksKey 对象和分页信息中链接的字段名（HAL）
+const LinksKey = "_links"
+
+// resourcePaths 表名 -> 已注册的集合路径（不以 / 结尾），用于生成关联对象的链接
+var resourcePaths sync.Map
+
+// RegisterResourcePath 记录模型（表名）的集合路径，由路由注册表写入，同一个表以先注册的为准
+func RegisterResourcePath(table, prefix string) {
+	resourcePaths.LoadOrStore(table, strings.TrimSuffix(prefix, "/"))
+}
+
+// EnableLinks 列表和详情响应带 HAL 风格的 _links，客户端按链接访问接口，不需要拼接地址：
+//   - 对象：self、belongs-to 关联对象的地址（关联模型已注册时）；详情响应另有 collection 和对象 action
+//   - 列表：分页信息中的 self、first、prev、next、last 和集合 action，翻页地址同时通过 Link 响应头返回
+//
+// 链接为站内路径，按当前请求的路径生成（版本、嵌套路由下的地址不变）；JSON:API 格式的响应有自己的 links，不受影响
+func (v *GenericViewSet) EnableLinks() {
+	v.links = true
+}
+
+// addLinks 为列表和详情响应的数据增加链接，其他 action 不变
+func (v *GenericViewSet) addLinks(c *gin.Context, action string, data interface{}, pagination *utils.Pagination) interface{} {
+	if !v.links || v.meta == nil || data == nil || utils.NegotiateFormat(c) == FormatJSONAPI {
+		return data
+	}
+	base := collectionPath(c)
+
+	switch action {
+	case ActionList:
+		body, err := utils.MarshalJSON(data)
+		if err != nil {
+			return data
+		}
+		var items []json.RawMessage
+		if json.Unmarshal(body, &items) != nil {
+			return data
+		}
+		relations := v.modelRelations()
+		for i, item := range items {
+			items[i] = v.linkObject(item, base, relations, false)
+		}
+		if pagination != nil {
+			v.listLinks(c, base, pagination, len(items))
+		}
+		return items
+
+	case ActionRetrieve, ActionCreate, ActionUpdate, ActionPartialUpdate:
+		body, err := utils.MarshalJSON(data)
+		if err != nil {
+			return data
+		}
+		return v.linkObject(body, base, v.modelRelations(), true)
+	}
+	return data
+}
+
+// linkObject 在对象的最后增加 _links，没有主键字段（例如 ?fields= 没有选择主键）时不变
+func (v *GenericViewSet) linkObject(raw json.RawMessage, base string, relations []modelRelation, detail bool) json.RawMessage {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) != nil {
+		return raw
+	}
+	id := rawID(obj[jsonapiPrimaryKey(v.meta)])
+	if id == "" {
+		return raw
+	}
+
+	self := base + url.PathEscape(id)
+	links := map[string]utils.Link{"self": {Href: self}}
+	for _, rel := range relations {
+		if rel.foreignKey == "" {
+			continue
+		}
+		prefix, ok := resourcePaths.Load(rel.typ)
+		if fk := rawID(obj[rel.foreignKey]); ok && fk != "" {
+			links[rel.name] = utils.Link{Href: prefix.(string) + "/" + url.PathEscape(fk)}
+		}
+	}
+	if detail {
+		links["collection"] = utils.Link{Href: base}
+		for _, a := range v.actions(true) {
+			links[a.Name] = actionLink(a, self+"/"+a.Name)
+		}
+	}
+
+	encoded, err := json.Marshal(links)
+	if err != nil {
+		return raw
+	}
+	raw = bytes.TrimSpace(raw)
+	var buf bytes.Buffer
+	buf.Write(raw[:len(raw)-1])
+	if len(obj) > 0 {
+		buf.WriteByte(',')
+	}
+	buf.WriteString(`"` + LinksKey + `":`)
+	buf.Write(encoded)
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// listLinks 列表的链接写入分页信息，翻页地址同时写入 Link 响应头（关闭外层结构时分页信息只在响应头中）
+func (v *GenericViewSet) listLinks(c *gin.Context, base string, p *utils.Pagination, count int) {
+	p.Links = map[string]utils.Link{"self": {Href: c.Request.URL.RequestURI()}}
+	pages := pageLinks(c, p, count)
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if href, ok := pages[rel]; ok {
+			p.Links[rel] = utils.Link{Href: href}
+			c.Writer.Header().Add("Link", "<"+href+`>; rel="`+rel+`"`)
+		}
+	}
+	for _, a := range v.actions(false) {
+		p.Links[a.Name] = actionLink(a, base+a.Name)
+	}
+}
+
+// actions ViewSet 的对象 action（detail 为 true）或集合 action
+func (v *GenericViewSet) actions(detail bool) []Action {
+	if v.impl == nil {
+		return nil
+	}
+	var out []Action
+	for _, a := range DiscoverActions(v.impl) {
+		if a.Detail == detail {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// actionLink action 的链接，不是 GET 的 action 带上请求方法
+func actionLink(a Action, href string) utils.Link {
+	link := utils.Link{Href: href}
+	if a.Method != "GET" {
+		link.Method = a.Method
+	}
+	return link
+}
+
+// pageLinks 翻页地址：页码分页为 first、prev、next、last（总数未知时没有 last，按本页条数判断是否有 next），
+// 游标分页只有 next；关闭分页时没有翻页地址
+func pageLinks(c *gin.Context, p *utils.Pagination, count int) map[string]string {
+	links := make(map[string]string)
+	if p.PageSize <= 0 {
+		return links
+	}
+	if p.Page == 0 {
+		if p.NextCursor != "" {
+			links["next"] = pageURL(c, "cursor", p.NextCursor)
+		}
+		return links
+	}
+
+	links["first"] = pageURL(c, "page", "1")
+	if p.Page > 1 {
+		links["prev"] = pageURL(c, "page", strconv.Itoa(p.Page-1))
+	}
+	if p.Total != nil {
+		last := int((*p.Total + int64(p.PageSize) - 1) / int64(p.PageSize))
+		if last < 1 {
+			last = 1
+		}
+		links["last"] = pageURL(c, "page", strconv.Itoa(last))
+		if p.Page < last {
+			links["next"] = pageURL(c, "page", strconv.Itoa(p.Page+1))
+		}
+	} else if count >= p.PageSize {
+		links["next"] = pageURL(c, "page", strconv.Itoa(p.Page+1))
+	}
+	return links
+}
+
+// pageURL 替换当前请求中的一个查询参数
+func pageURL(c *gin.Context, key, value string) string {
+	query := c.Request.URL.Query()
+	query.Set(key, value)
+	return c.Request.URL.Path + "?" + query.Encode()
+}
+
+// collectionPath 资源集合的路径（以 / 结尾）：详情和对象 action 为 :id 之前的部分，其他为最后一个 / 之前的部分
+func collectionPath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	if id := c.Param("id"); id != "" {
+		if i := strings.LastIndex(path, "/"+id); i >= 0 {
+			return path[:i+1]
+		}
+	}
+	return path[:strings.LastIndex(path, "/")+1]
+}
+
+// rawID JSON 中的主键或外键值转换为字符串，null 或不存在时为空
+func rawID(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,129 @@
+package viewset
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Mixin 一组标准路由
+// 多个 Mixin 可以自由组合，只注册需要的路由，例如只读 ViewSet、只允许创建的接收接口。
+// vs 为最外层的 ViewSet，处理函数优先使用它覆盖后的方法（例如 UserViewSet.List）
+type Mixin func(group *gin.RouterGroup, v *GenericViewSet, vs interface{})
+
+// 标准 action 的处理方法，用于从最外层 ViewSet 上取得覆盖后的实现
+type (
+	lister    interface{ List(c *gin.Context) }
+	retriever interface{ Retrieve(c *gin.Context) }
+	creator   interface{ Create(c *gin.Context) }
+	updater   interface{ Update(c *gin.Context) }
+	patcher   interface{ PartialUpdate(c *gin.Context) }
+	destroyer interface{ Delete(c *gin.Context) }
+	exporter  interface{ ExportXLSX(c *gin.Context) }
+)
+
+// ListMixin GET / 和 HEAD /，开启 EnableExport 时同时注册 GET /export.xlsx，开启 EnableAggregate 时注册 GET /aggregate，
+// 开启 EnableStream 时注册 GET /stream
+func ListMixin(group *gin.RouterGroup, v *GenericViewSet, vs interface{}) {
+	handler := v.List
+	if l, ok := vs.(lister); ok {
+		handler = l.List
+	}
+	group.GET("/", v.HandlerFor(ActionList, handler))
+	group.HEAD("/", v.HandlerFor(ActionList, v.ListHead))
+
+	if v.EnableExport {
+		export := v.ExportXLSX
+		if e, ok := vs.(exporter); ok {
+			export = e.ExportXLSX
+		}
+		group.GET("/export.xlsx", v.HandlerFor(ActionExport, export))
+	}
+	if v.EnableAggregate {
+		group.GET("/aggregate", v.HandlerFor(ActionAggregate, v.Aggregate))
+	}
+	if v.EnableStream {
+		group.GET("/stream", v.HandlerFor(ActionStream, v.Stream))
+	}
+}
+
+// RetrieveMixin GET /:id，开启历史记录时同时注册 GET /:id/history
+func RetrieveMixin(group *gin.RouterGroup, v *GenericViewSet, vs interface{}) {
+	handler := v.Retrieve
+	if r, ok := vs.(retriever); ok {
+		handler = r.Retrieve
+	}
+	group.GET("/:id", v.HandlerFor(ActionRetrieve, handler))
+
+	if v.historyTable != "" {
+		group.GET("/:id/history", v.HandlerFor(ActionHistory, v.History))
+	}
+}
+
+// CreateMixin POST /，开启 EnableBulkOperations 时同时注册 POST /bulk，开启 EnableImport 时注册 POST /import
+func CreateMixin(group *gin.RouterGroup, v *GenericViewSet, vs interface{}) {
+	handler := v.Create
+	if cr, ok := vs.(creator); ok {
+		handler = cr.Create
+	}
+	group.POST("/", v.HandlerFor(ActionCreate, handler))
+
+	if v.EnableBulkOperations {
+		group.POST("/bulk", v.HandlerFor(ActionBulkCreate, v.BulkCreate))
+	}
+	if v.EnableImport {
+		group.POST("/import", v.HandlerFor(ActionImport, v.Import))
+	}
+}
+
+// UpdateMixin PUT /:id，开启历史记录时同时注册 POST /:id/revert/:version
+func UpdateMixin(group *gin.RouterGroup, v *GenericViewSet, vs interface{}) {
+	handler := v.Update
+	if u, ok := vs.(updater); ok {
+		handler = u.Update
+	}
+	group.PUT("/:id", v.HandlerFor(ActionUpdate, handler))
+
+	if v.historyTable != "" {
+		group.POST("/:id/revert/:version", v.HandlerFor(ActionRevert, v.Revert))
+	}
+}
+
+// PartialUpdateMixin PATCH /:id
+func PartialUpdateMixin(group *gin.RouterGroup, v *GenericViewSet, vs interface{}) {
+	handler := v.PartialUpdate
+	if p, ok := vs.(patcher); ok {
+		handler = p.PartialUpdate
+	}
+	group.PATCH("/:id", v.HandlerFor(ActionPartialUpdate, handler))
+}
+
+// DestroyMixin DELETE /:id
+func DestroyMixin(group *gin.RouterGroup, v *GenericViewSet, vs interface{}) {
+	handler := v.Delete
+	if d, ok := vs.(destroyer); ok {
+		handler = d.Delete
+	}
+	group.DELETE("/:id", v.HandlerFor(ActionDestroy, handler))
+}
+
+// 常用的 Mixin 组合
+var (
+	// ReadOnlyMixins 只读：列表和详情
+	ReadOnlyMixins = []Mixin{ListMixin, RetrieveMixin}
+
+	// ModelMixins 完整的 CRUD
+	ModelMixins = []Mixin{ListMixin, RetrieveMixin, CreateMixin, UpdateMixin, PartialUpdateMixin, DestroyMixin}
+)
+
+// RegisterMixins 注册指定 Mixin 的路由、OPTIONS 元数据以及 vs 上的自定义 action
+// 例如只读加自定义 action 的 ViewSet：
+//
+//	func (v *ReportViewSet) RegisterRoutes(group *gin.RouterGroup) {
+//		v.RegisterMixins(group, v, ReadOnlyMixins...)
+//	}
+func (v *GenericViewSet) RegisterMixins(group *gin.RouterGroup, vs interface{}, mixins ...Mixin) {
+	group.OPTIONS("/", v.Metadata)
+	for _, mixin := range mixins {
+		mixin(group, v, vs)
+	}
+	v.RegisterActions(group, vs)
+}
@@ -0,0 +1,112 @@
+package viewset
+
+import (
+	"go-viewset/internal/utils"
+	"log"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ParentLookup 嵌套路由中父资源的路径参数与外键字段的对应关系
+// 例如 /users/:user_id/orders 中 {Param: "user_id", Field: "user_id"}
+type ParentLookup struct {
+	Param string // 路径参数名
+	Field string // 子模型上的外键字段（JSON 字段名或列名）
+}
+
+// AddParentLookup 将 ViewSet 声明为某个父资源的子资源
+// 之后的查询都限定在路径参数指定的父资源下，创建和更新时外键取路径参数的值；
+// 通常由 router.Router.RegisterNested 调用
+func (v *GenericViewSet) AddParentLookup(lookup ParentLookup) {
+	if v.meta != nil {
+		if _, ok := v.meta.Lookup(lookup.Field); !ok {
+			log.Printf("[警告] %s 上不存在嵌套路由的外键字段 %s", v.table, lookup.Field)
+		}
+	}
+	v.ParentLookups = append(v.ParentLookups, lookup)
+}
+
+// queryset 返回本次请求的基础查询，嵌套路由下只包含父资源的子记录，
+// ScopeToOwner 时只包含当前用户的记录，设置 TenantField 时只包含当前租户的记录，最后交给 QuerysetHook 定制
+func (v *GenericViewSet) queryset(c *gin.Context) *gorm.DB {
+	db := v.dbFor(c)
+	for _, lookup := range v.ParentLookups {
+		if field, ok := v.lookupField(lookup.Field); ok {
+			db = db.Where(v.table+"."+field+" = ?", c.Param(lookup.Param))
+		}
+	}
+	db = v.scopeToTenant(c, v.scopeToOwner(c, db))
+	if h, ok := v.hooks().(QuerysetHook); ok {
+		db = h.GetQueryset(c, db)
+	}
+	return db
+}
+
+// lookupField 将外键字段解析为列名
+func (v *GenericViewSet) lookupField(name string) (string, bool) {
+	if v.meta == nil {
+		return "", false
+	}
+	field, ok := v.meta.Lookup(name)
+	if !ok {
+		return "", false
+	}
+	return field.Column, true
+}
+
+// setParentFields 将外键字段设置为路径参数中父资源的 ID，客户端传入的值被忽略
+// obj 为模型指针
+func (v *GenericViewSet) setParentFields(c *gin.Context, obj interface{}) error {
+	if len(v.ParentLookups) == 0 || v.schema == nil {
+		return nil
+	}
+	elem := reflect.ValueOf(obj).Elem()
+	for _, lookup := range v.ParentLookups {
+		column, ok := v.lookupField(lookup.Field)
+		if !ok {
+			continue
+		}
+		if sf := v.schema.LookUpField(column); sf != nil {
+			if err := sf.Set(c.Request.Context(), elem, c.Param(lookup.Param)); err != nil {
+				return utils.ValidationErrors{{Field: lookup.Param, Code: utils.CodeInvalid, Message: "无效的 " + lookup.Param}}
+			}
+		}
+	}
+	return nil
+}
+
+// setParentColumns 部分更新时将外键列设置为路径参数中父资源的 ID
+func (v *GenericViewSet) setParentColumns(c *gin.Context, updates map[string]interface{}) {
+	for _, lookup := range v.ParentLookups {
+		if column, ok := v.lookupField(lookup.Field); ok {
+			if _, present := updates[column]; present {
+				updates[column] = c.Param(lookup.Param)
+			}
+		}
+	}
+}
+
+// parentScopeKey 嵌套路由下父资源（以及 ScopeToOwner 时当前用户、TenantField 时当前租户、
+// QuerysetCacheKeyHook 返回的标识）的标识，用于区分不同范围下的缓存和总数
+func (v *GenericViewSet) parentScopeKey(c *gin.Context) string {
+	var key string
+	for _, lookup := range v.ParentLookups {
+		key += lookup.Param + "=" + c.Param(lookup.Param) + ":"
+	}
+	if h, ok := v.hooks().(QuerysetCacheKeyHook); ok {
+		key += "queryset=" + h.QuerysetCacheKey(c) + ":"
+	}
+	return key + v.ownerScopeKey(c) + v.tenantScopeKey(c)
+}
+
+// querysetCacheable QuerysetHook 定制的查询结果能否缓存：没有 QuerysetHook，或者实现了 QuerysetCacheKeyHook
+func (v *GenericViewSet) querysetCacheable() bool {
+	h := v.hooks()
+	if _, ok := h.(QuerysetHook); !ok {
+		return true
+	}
+	_, ok := h.(QuerysetCacheKeyHook)
+	return ok
+}
@@ -0,0 +1,221 @@
+package viewset
+
+import (
+	"crypto/subtle"
+	"errors"
+	"go-viewset/internal/auth"
+	"go-viewset/internal/models"
+	"go-viewset/internal/oidc"
+	"go-viewset/internal/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// oidcStateCookie 保存登录过程中的 state、nonce 和 PKCE code_verifier 的 Cookie，只发送到对应身份提供方的回调地址
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateMaxAge = 10 * time.Minute
+)
+
+var (
+	// errEmailNotVerified 第三方账号没有已验证的邮箱，无法创建或关联用户
+	errEmailNotVerified = errors.New("第三方账号没有已验证的邮箱")
+
+	// errAccountNotLinked 邮箱属于未验证邮箱的已有用户，需要该用户登录后再发起第三方登录进行关联
+	errAccountNotLinked = errors.New("邮箱已被未验证邮箱的用户使用")
+)
+
+// EnableOIDC 开启第三方登录，注册 GET /oidc/:provider/login 和 GET /oidc/:provider/callback
+// 需要同时开启会话（EnableSessions），登录成功后创建会话并跳转到 redirectURL，失败时附带 error 参数
+func (v *AuthViewSet) EnableOIDC(providers map[string]oidc.Provider, redirectURL string) {
+	v.oidc = providers
+	v.oidcRedirect = redirectURL
+	if v.oidcRedirect == "" {
+		v.oidcRedirect = "/"
+	}
+}
+
+// registerOIDC 注册第三方登录的路由，路径带有 :provider 参数，不通过 Actions 声明
+func (v *AuthViewSet) registerOIDC(group *gin.RouterGroup) {
+	if v.sessions == nil || len(v.oidc) == 0 {
+		return
+	}
+	group.GET("/oidc/:provider/login", v.HandlerFor("oidc_login", v.OIDCLogin))
+	group.GET("/oidc/:provider/callback", v.HandlerFor("oidc_callback", v.OIDCCallback))
+}
+
+// OIDCLogin 跳转到身份提供方的授权页面
+// GET /api/auth/oidc/:provider/login
+func (v *AuthViewSet) OIDCLogin(c *gin.Context) {
+	provider, ok := v.oidc[c.Param("provider")]
+	if !ok {
+		utils.NotFound(c, "不支持的登录方式")
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		token, _, err := auth.NewToken()
+		if err != nil {
+			utils.InternalServerError(c, "生成登录参数失败")
+			return
+		}
+		values[i] = token
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	target, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("获取 %s 的授权地址失败: %v", c.Param("provider"), err)
+		utils.ErrorWithStatus(c, http.StatusBadGateway, http.StatusBadGateway, "无法连接第三方登录服务")
+		return
+	}
+
+	// 回调是从身份提供方跳转回来的顶级导航，SameSite=Lax 的 Cookie 会被带上
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, strings.Join(values[:], "."), int(oidcStateMaxAge.Seconds()),
+		v.oidcCookiePath(c), "", v.sessions.Secure(), true)
+	c.Redirect(http.StatusFound, target)
+}
+
+// OIDCCallback 校验 state，用授权码换取第三方账号信息，创建或关联用户后登录
+// 已关联的第三方账号直接登录；已登录时关联到当前用户；否则按已验证的邮箱关联邮箱同样已验证的用户，
+// 没有该邮箱的用户时创建新用户
+// GET /api/auth/oidc/:provider/callback
+func (v *AuthViewSet) OIDCCallback(c *gin.Context) {
+	name := c.Param("provider")
+	provider, ok := v.oidc[name]
+	if !ok {
+		utils.NotFound(c, "不支持的登录方式")
+		return
+	}
+
+	saved, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, v.oidcCookiePath(c), "", v.sessions.Secure(), true)
+
+	fail := func(code string, err error) {
+		if err != nil {
+			log.Printf("%s 登录失败: %v", name, err)
+		}
+		c.Redirect(http.StatusFound, v.oidcRedirectWith(code))
+	}
+
+	if reason := c.Query("error"); reason != "" {
+		fail(reason, nil)
+		return
+	}
+	parts := strings.Split(saved, ".")
+	state := c.Query("state")
+	if len(parts) != 3 || state == "" || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(state)) != 1 {
+		fail("invalid_state", nil)
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), parts[1], parts[2])
+	if err != nil {
+		fail("login_failed", err)
+		return
+	}
+	user, err := v.linkIdentity(c, name, identity)
+	if errors.Is(err, errEmailNotVerified) {
+		fail("email_not_verified", nil)
+		return
+	}
+	if errors.Is(err, errAccountNotLinked) {
+		fail("account_not_linked", nil)
+		return
+	}
+	if err != nil {
+		fail("login_failed", err)
+		return
+	}
+	if _, err := v.sessions.Login(c, user.ID); err != nil {
+		fail("login_failed", err)
+		return
+	}
+	c.Redirect(http.StatusFound, v.oidcRedirect)
+}
+
+// linkIdentity 返回第三方账号对应的用户，没有关联时关联到当前登录的用户，或按邮箱关联或创建用户
+func (v *AuthViewSet) linkIdentity(c *gin.Context, provider string, identity *oidc.Identity) (*models.User, error) {
+	var user models.User
+	err := v.dbFor(c).Transaction(func(tx *gorm.DB) error {
+		var linked models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&linked).Error
+		if err == nil {
+			return tx.First(&user, linked.UserID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if userID, ok := c.Get(ContextUserID); ok {
+			// 已登录的用户发起第三方登录，关联到当前用户
+			err = tx.First(&user, userID).Error
+		} else {
+			err = v.userForIdentity(tx, identity, &user)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userForIdentity 按邮箱查找要关联的用户，没有时创建新用户
+// 只按身份提供方验证过的邮箱关联，避免他人用未验证的邮箱接管已有账号；
+// 已有用户的邮箱也需要已验证，否则可能是他人抢先用该邮箱注册的账号（注册时不验证邮箱），
+// 此时返回 errAccountNotLinked，由用户登录后再关联
+func (v *AuthViewSet) userForIdentity(tx *gorm.DB, identity *oidc.Identity, user *models.User) error {
+	if identity.Email == "" || !identity.EmailVerified {
+		return errEmailNotVerified
+	}
+	err := tx.Where("email = ?", identity.Email).First(user).Error
+	if err == nil && user.EmailVerifiedAt == nil {
+		return errAccountNotLinked
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	*user = models.User{Name: identity.Name, Email: identity.Email, Status: "active", EmailVerifiedAt: &now}
+	if user.Name == "" {
+		user.Name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	return tx.Create(user).Error
+}
+
+// oidcCookiePath state Cookie 的路径：当前身份提供方的路由前缀，例如 /api/auth/oidc/google/
+func (v *AuthViewSet) oidcCookiePath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	return path[:strings.LastIndex(path, "/")+1]
+}
+
+// oidcRedirectWith 登录失败时跳转的地址：RedirectURL 附带 error 参数
+func (v *AuthViewSet) oidcRedirectWith(code string) string {
+	u, err := url.Parse(v.oidcRedirect)
+	if err != nil {
+		return v.oidcRedirect
+	}
+	query := u.Query()
+	query.Set("error", code)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
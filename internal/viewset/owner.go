@@ -0,0 +1,111 @@
+package viewset
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ownerColumn OwnerField 对应的列名，未设置时返回 false
+func (v *GenericViewSet) ownerColumn() (string, bool) {
+	if v.OwnerField == "" {
+		return "", false
+	}
+	return v.lookupField(v.OwnerField)
+}
+
+// scopeToOwner 列表和详情只包含当前用户的记录，管理员不受限制，未登录时不返回任何记录
+func (v *GenericViewSet) scopeToOwner(c *gin.Context, db *gorm.DB) *gorm.DB {
+	column, ok := v.ownerColumn()
+	if !ok || !v.ScopeToOwner || isAdmin(c) {
+		return db
+	}
+	userID, ok := c.Get(ContextUserID)
+	if !ok {
+		return db.Where("1 = 0")
+	}
+	return db.Where(v.table+"."+column+" = ?", userID)
+}
+
+// visibleToOwner 按所有者限定范围时 obj 是否属于当前用户（与 scopeToOwner 的条件相同），用于无法再查询的对象
+func (v *GenericViewSet) visibleToOwner(c *gin.Context, obj interface{}) bool {
+	column, ok := v.ownerColumn()
+	if !ok || !v.ScopeToOwner || isAdmin(c) {
+		return true
+	}
+	return v.ownedByCurrentUser(c, column, obj)
+}
+
+// ownerScopeKey 按所有者限定范围时当前用户的标识，用于区分不同用户的缓存和总数
+func (v *GenericViewSet) ownerScopeKey(c *gin.Context) string {
+	if _, ok := v.ownerColumn(); !ok || !v.ScopeToOwner || isAdmin(c) {
+		return ""
+	}
+	userID, _ := c.Get(ContextUserID)
+	return fmt.Sprintf("owner=%v:", userID)
+}
+
+// isOwner 当前请求是否可以修改 obj：读请求、管理员以及 OwnerField 等于当前用户时返回 true
+func (v *GenericViewSet) isOwner(c *gin.Context, obj interface{}) bool {
+	column, ok := v.ownerColumn()
+	if !ok || isSafeMethod(c.Request.Method) || isAdmin(c) {
+		return true
+	}
+	return v.ownedByCurrentUser(c, column, obj)
+}
+
+// ownerWriteAllowed 设置了 OwnerField 时写请求需要登录（见 checkPermission）
+func (v *GenericViewSet) ownerWriteAllowed(c *gin.Context) bool {
+	_, ok := v.ownerColumn()
+	return !ok || isSafeMethod(c.Request.Method) || isAuthenticated(c)
+}
+
+// setOwnerField 创建时将 OwnerField 设置为当前用户，客户端传入的值被忽略
+// obj 为模型指针；creating 为 false（PUT）时清空该字段，Updates 跳过零值，因此所有者保持不变
+func (v *GenericViewSet) setOwnerField(c *gin.Context, obj interface{}, creating bool) error {
+	column, ok := v.ownerColumn()
+	if !ok {
+		return nil
+	}
+	sf := v.schema.LookUpField(column)
+	if sf == nil {
+		return nil
+	}
+	elem := reflect.ValueOf(obj).Elem()
+	if !creating {
+		fv := sf.ReflectValueOf(c.Request.Context(), elem)
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+	userID, _ := c.Get(ContextUserID)
+	return sf.Set(c.Request.Context(), elem, userID)
+}
+
+// dropOwnerColumn 部分更新时不允许修改所有者
+func (v *GenericViewSet) dropOwnerColumn(updates map[string]interface{}) {
+	if column, ok := v.ownerColumn(); ok {
+		delete(updates, column)
+	}
+}
+
+// isSafeMethod 是否为只读的 HTTP 方法
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// ownedByCurrentUser obj 的所有者字段（column）是否等于当前用户，未登录时返回 false
+func (v *GenericViewSet) ownedByCurrentUser(c *gin.Context, column string, obj interface{}) bool {
+	userID, ok := c.Get(ContextUserID)
+	if !ok {
+		return false
+	}
+	sf := v.schema.LookUpField(column)
+	if sf == nil {
+		return false
+	}
+	owner, zero := sf.ValueOf(c.Request.Context(), reflect.ValueOf(obj).Elem())
+	return !zero && fmt.Sprint(owner) == fmt.Sprint(userID)
+}
@@ -0,0 +1,90 @@
+package viewset
+
+import (
+	"errors"
+	"go-viewset/internal/auth"
+	"go-viewset/internal/mailer"
+	"go-viewset/internal/models"
+	"go-viewset/internal/utils"
+	"log"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultResetTokenTTL 重置密码令牌的默认有效期
+const defaultResetTokenTTL = 30 * time.Minute
+
+// ResetPasswordPath 重置密码邮件中的链接（前端页面），令牌作为 token 参数附加在后面
+const ResetPasswordPath = "/reset-password?token="
+
+// issueResetToken 删除用户之前的令牌并生成新的重置密码令牌，返回令牌和过期时间
+func issueResetToken(db *gorm.DB, userID uint, ttl time.Duration) (string, time.Time, error) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	record := &models.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(record).Error
+	})
+	return token, record.ExpiresAt, err
+}
+
+// sendResetEmail 发送重置密码邮件（模板 reset_password），失败时只记录日志
+func sendResetEmail(m *mailer.Mailer, user *models.User, token string, ttl time.Duration) {
+	err := m.Send([]string{user.Email}, "reset_password", map[string]interface{}{
+		"Name":    user.Name,
+		"Link":    m.URL(ResetPasswordPath + url.QueryEscape(token)),
+		"Minutes": int(ttl.Minutes()),
+	})
+	if err != nil {
+		log.Printf("发送用户 %d 的重置密码邮件失败: %v", user.ID, err)
+	}
+}
+
+// setPassword 保存新密码的哈希，并删除用户的全部重置密码令牌
+func setPassword(db *gorm.DB, user *models.User, password string) error {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password_hash", hash).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.PasswordResetToken{}).Error
+	})
+}
+
+// hasParseError 请求体是否无法解析（此时其他字段的错误没有意义）
+func hasParseError(errs []utils.FieldError) bool {
+	for _, e := range errs {
+		if e.Code == utils.CodeParseError {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordErrors 检查密码长度，返回 field 字段的错误
+func passwordErrors(field, password string) []utils.FieldError {
+	if password == "" {
+		return []utils.FieldError{{Field: field, Code: utils.CodeRequired, Message: field + " 不能为空"}}
+	}
+	switch err := auth.ValidatePassword(password); {
+	case errors.Is(err, auth.ErrPasswordTooShort):
+		return []utils.FieldError{{Field: field, Code: utils.CodeTooShort, Message: err.Error()}}
+	case errors.Is(err, auth.ErrPasswordTooLong):
+		return []utils.FieldError{{Field: field, Code: utils.CodeTooLong, Message: err.Error()}}
+	}
+	return nil
+}
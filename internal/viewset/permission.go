@@ -0,0 +1,169 @@
+package viewset
+
+import (
+	"go-viewset/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 认证中间件写入 gin.Context 的 key
+const (
+	ContextUserID      = "user_id"     // 当前用户 ID
+	ContextIsAdmin     = "is_admin"    // 是否管理员，bool
+	ContextPermissions = "permissions" // 拥有的权限，[]string，例如 "users.manage"
+	ContextRoles       = "roles"       // 拥有的角色，[]string，例如 "admin"（见 middleware.LoadRoles）
+)
+
+// ContextAction 当前执行的 action 名称，由 HandlerFor 写入
+const ContextAction = "viewset_action"
+
+// Permission 权限类
+// HasPermission 在执行 action 之前检查，HasObjectPermission 在取得对象之后检查
+// （Retrieve、Update、PartialUpdate、Delete 以及 GetObjectOr404）。
+// 检查不通过时，未登录的请求返回 401，已登录的返回 403
+type Permission interface {
+	HasPermission(c *gin.Context, action string) bool
+	HasObjectPermission(c *gin.Context, action string, obj interface{}) bool
+}
+
+// PermissionFunc 只检查请求的权限，对象级检查总是通过
+type PermissionFunc func(c *gin.Context) bool
+
+// HasPermission 实现 Permission
+func (f PermissionFunc) HasPermission(c *gin.Context, action string) bool {
+	return f(c)
+}
+
+// HasObjectPermission 实现 Permission
+func (f PermissionFunc) HasObjectPermission(c *gin.Context, action string, obj interface{}) bool {
+	return true
+}
+
+// ObjectPermissionFunc 只检查对象的权限，请求级检查总是通过
+// obj 为模型对象的指针，例如 *models.User
+type ObjectPermissionFunc func(c *gin.Context, obj interface{}) bool
+
+// HasPermission 实现 Permission
+func (f ObjectPermissionFunc) HasPermission(c *gin.Context, action string) bool {
+	return true
+}
+
+// HasObjectPermission 实现 Permission
+func (f ObjectPermissionFunc) HasObjectPermission(c *gin.Context, action string, obj interface{}) bool {
+	return f(c, obj)
+}
+
+var (
+	// AllowAny 允许任何请求
+	AllowAny Permission = PermissionFunc(func(c *gin.Context) bool { return true })
+
+	// IsAuthenticated 只允许已登录的用户
+	IsAuthenticated Permission = PermissionFunc(isAuthenticated)
+
+	// IsAdmin 只允许管理员
+	IsAdmin Permission = PermissionFunc(isAdmin)
+)
+
+func isAuthenticated(c *gin.Context) bool {
+	_, ok := c.Get(ContextUserID)
+	return ok
+}
+
+func isAdmin(c *gin.Context) bool {
+	return isAuthenticated(c) && c.GetBool(ContextIsAdmin)
+}
+
+// HasPerm 只允许拥有指定权限的用户，管理员拥有全部权限
+func HasPerm(perm string) Permission {
+	return PermissionFunc(func(c *gin.Context) bool {
+		if isAdmin(c) {
+			return true
+		}
+		for _, p := range c.GetStringSlice(ContextPermissions) {
+			if p == perm {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RequireRole 只允许拥有任一指定角色的用户，管理员（is_admin）不受限制
+//
+//	v.Permissions = map[string]viewset.Permission{"destroy": viewset.RequireRole("admin")}
+func RequireRole(roles ...string) Permission {
+	return PermissionFunc(func(c *gin.Context) bool {
+		if isAdmin(c) {
+			return true
+		}
+		for _, have := range c.GetStringSlice(ContextRoles) {
+			for _, role := range roles {
+				if have == role {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// permissionsFor 返回 action 适用的权限类：全局的 PermissionClasses，
+// 加上 Permissions 中为该 action 配置的（未单独配置时使用 "*"）
+func (v *GenericViewSet) permissionsFor(action string) []Permission {
+	perms := v.PermissionClasses
+	p, ok := v.Permissions[action]
+	if !ok {
+		p = v.Permissions["*"]
+	}
+	if p != nil {
+		perms = append(perms[:len(perms):len(perms)], p)
+	}
+	return perms
+}
+
+// hasPermissions 是否配置了任何权限类
+func (v *GenericViewSet) hasPermissions() bool {
+	return len(v.PermissionClasses) > 0 || len(v.Permissions) > 0
+}
+
+// checkPermission 检查当前请求是否有权执行 action，无权时写出 401/403 并返回 false
+func (v *GenericViewSet) checkPermission(c *gin.Context, action string) bool {
+	for _, p := range v.permissionsFor(action) {
+		if !p.HasPermission(c, action) {
+			denied(c, "没有权限执行该操作")
+			return false
+		}
+	}
+	if !v.ownerWriteAllowed(c) {
+		denied(c, "没有权限执行该操作")
+		return false
+	}
+	return true
+}
+
+// checkObjectPermission 检查当前请求是否有权对 obj 执行当前 action，无权时写出 401/403 并返回 false
+// action 取自 HandlerFor 写入的 ContextAction
+func (v *GenericViewSet) checkObjectPermission(c *gin.Context, obj interface{}) bool {
+	action := c.GetString(ContextAction)
+	for _, p := range v.permissionsFor(action) {
+		if !p.HasObjectPermission(c, action, obj) {
+			denied(c, "没有权限访问该对象")
+			return false
+		}
+	}
+	if !v.isOwner(c, obj) {
+		denied(c, "只有所有者可以修改该对象")
+		return false
+	}
+	return true
+}
+
+// denied 写出权限检查失败的响应
+func denied(c *gin.Context, msg string) {
+	if !isAuthenticated(c) {
+		utils.Unauthorized(c, "请先登录")
+	} else {
+		utils.Forbidden(c, msg)
+	}
+	c.Abort()
+}
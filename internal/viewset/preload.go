@@ -0,0 +1,97 @@
+package viewset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// defaultMaxExpandDepth 默认的关联展开深度
+const defaultMaxExpandDepth = 2
+
+// preloadPlan 关联加载计划
+// 一对一关联（belongs_to / has_one）通过 JOIN 与主查询一起取回，
+// 其他关联通过 Preload 按关联批量加载（每个关联一条查询，而不是每行一条）
+type preloadPlan struct {
+	joins    []string
+	preloads []string
+}
+
+// apply 将加载计划应用到查询
+func (p *preloadPlan) apply(db *gorm.DB) *gorm.DB {
+	for _, name := range p.joins {
+		db = db.Joins(name)
+	}
+	for _, path := range p.preloads {
+		db = db.Preload(path)
+	}
+	return db
+}
+
+// planPreloads 根据关联路径生成加载计划
+// paths 形如 "Profile"、"Orders.Items"，每一段都必须是模型上定义的关联。
+// allowJoins 为 false 时全部使用 Preload，用于带过滤和排序条件的列表查询，
+// 避免 JOIN 后未加表名前缀的字段产生歧义。
+func (v *GenericViewSet) planPreloads(paths []string, allowJoins bool) (*preloadPlan, error) {
+	plan := &preloadPlan{}
+	if len(paths) == 0 || v.schema == nil {
+		return plan, nil
+	}
+
+	maxDepth := v.MaxExpandDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxExpandDepth
+	}
+
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		segments := strings.Split(path, ".")
+		if len(segments) > maxDepth {
+			return nil, fmt.Errorf("关联 %s 超过最大展开深度 %d", path, maxDepth)
+		}
+
+		// 逐段校验关联是否存在
+		current := v.schema
+		var rel *schema.Relationship
+		for _, segment := range segments {
+			r, ok := current.Relationships.Relations[segment]
+			if !ok {
+				return nil, fmt.Errorf("未知的关联: %s", path)
+			}
+			rel = r
+			current = r.FieldSchema
+		}
+
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		if allowJoins && len(segments) == 1 && (rel.Type == schema.BelongsTo || rel.Type == schema.HasOne) {
+			plan.joins = append(plan.joins, path)
+		} else {
+			plan.preloads = append(plan.preloads, path)
+		}
+	}
+
+	plan.preloads = compactPreloads(plan.preloads)
+	return plan, nil
+}
+
+// compactPreloads 去掉被更深路径覆盖的 Preload
+// 例如同时存在 "Orders" 和 "Orders.Items" 时只保留后者，GORM 会一并加载 Orders
+func compactPreloads(paths []string) []string {
+	sort.Strings(paths)
+
+	result := paths[:0]
+	for i, path := range paths {
+		if i+1 < len(paths) && strings.HasPrefix(paths[i+1], path+".") {
+			continue
+		}
+		result = append(result, path)
+	}
+	return result
+}
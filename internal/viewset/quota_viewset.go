@@ -0,0 +1,182 @@
+package viewset
+
+import (
+	"go-viewset/internal/auth"
+	"go-viewset/internal/events"
+	"go-viewset/internal/models"
+	"go-viewset/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// QuotaViewSet API 配额管理 ViewSet
+// 提供配额的查看、调整和重置，供管理员使用
+type QuotaViewSet struct {
+	*GenericViewSet
+}
+
+// NewQuotaViewSet 创建配额管理 ViewSet
+func NewQuotaViewSet(db *gorm.DB) *QuotaViewSet {
+	v := &QuotaViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.APIQuota{}),
+	}
+
+	// 签发 API Key 和调整配额只允许管理员
+	v.PermissionClasses = []Permission{RequireRole("admin")}
+
+	return v
+}
+
+// QuotaIssueRequest 签发 API Key 的请求参数
+type QuotaIssueRequest struct {
+	Limit  int64  `json:"limit" binding:"required,min=1"`
+	Period string `json:"period"` // daily（默认）或 monthly
+}
+
+// QuotaAdjustRequest 调整配额的请求参数
+// 只更新提供了的字段
+type QuotaAdjustRequest struct {
+	Limit  *int64  `json:"limit"`
+	Used   *int64  `json:"used"`
+	Period *string `json:"period"`
+}
+
+// RegisterRoutes 注册路由
+func (v *QuotaViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	// 配额由中间件创建，管理接口只提供查看、删除和自定义 action
+	v.RegisterMixins(group, v, ListMixin, RetrieveMixin, DestroyMixin)
+}
+
+// Actions 声明自定义 action
+func (v *QuotaViewSet) Actions() []Action {
+	return []Action{
+		// POST /admin/quotas/issue - 签发 API Key
+		ListAction("POST", "issue", v.Issue),
+
+		// POST /admin/quotas/:id/adjust - 调整配额
+		DetailAction(v.GenericViewSet, "POST", "adjust", v.Adjust),
+
+		// POST /admin/quotas/:id/reset - 清零当前周期的已用次数
+		DetailAction(v.GenericViewSet, "POST", "reset", v.Reset),
+	}
+}
+
+// Issue 签发新的 API Key 并创建它的配额记录
+// 数据库中只保存 API Key 的哈希，API Key 只在本次响应中返回
+// POST /admin/quotas/issue
+func (v *QuotaViewSet) Issue(c *gin.Context) {
+	var req QuotaIssueRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if req.Period == "" {
+		req.Period = models.QuotaPeriodDaily
+	}
+	if req.Period != models.QuotaPeriodDaily && req.Period != models.QuotaPeriodMonthly {
+		utils.ValidationError(c, []utils.FieldError{
+			{Field: "period", Code: utils.CodeInvalid, Message: "period 只能是 daily 或 monthly"},
+		})
+		return
+	}
+
+	apiKey, _, err := auth.NewToken()
+	if err != nil {
+		utils.InternalServerError(c, "生成 API Key 失败")
+		return
+	}
+	quota := &models.APIQuota{
+		Key:    models.APIKeyQuotaKey(apiKey),
+		Period: req.Period,
+		Limit:  req.Limit,
+	}
+	quota.ResetAt = quota.NextResetAt(time.Now())
+	if err := v.dbFor(c).Create(quota).Error; err != nil {
+		v.dbError(c, "签发 API Key 失败", err)
+		return
+	}
+
+	v.publish(c, events.Created, quota)
+
+	v.Respond(c, gin.H{
+		"api_key": apiKey,
+		"quota":   quota,
+	})
+}
+
+// Adjust 调整配额上限、已用次数或周期
+// POST /admin/quotas/:id/adjust
+func (v *QuotaViewSet) Adjust(c *gin.Context, quota *models.APIQuota) {
+	var req QuotaAdjustRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Limit != nil {
+		if *req.Limit < 0 {
+			utils.ValidationError(c, []utils.FieldError{
+				{Field: "limit", Code: utils.CodeTooShort, Message: "limit 不能为负数"},
+			})
+			return
+		}
+		updates["quota_limit"] = *req.Limit
+	}
+	if req.Used != nil {
+		if *req.Used < 0 {
+			utils.ValidationError(c, []utils.FieldError{
+				{Field: "used", Code: utils.CodeTooShort, Message: "used 不能为负数"},
+			})
+			return
+		}
+		updates["used"] = *req.Used
+	}
+	if req.Period != nil {
+		if *req.Period != models.QuotaPeriodDaily && *req.Period != models.QuotaPeriodMonthly {
+			utils.ValidationError(c, []utils.FieldError{
+				{Field: "period", Code: utils.CodeInvalid, Message: "period 只能是 daily 或 monthly"},
+			})
+			return
+		}
+		// 周期变化后按新周期重新计算重置时间
+		quota.Period = *req.Period
+		updates["period"] = quota.Period
+		updates["reset_at"] = quota.NextResetAt(time.Now())
+	}
+	if len(updates) == 0 {
+		utils.BadRequest(c, "没有需要更新的字段")
+		return
+	}
+
+	if err := v.dbFor(c).Model(quota).Updates(updates).Error; err != nil {
+		v.dbError(c, "调整配额失败", err)
+		return
+	}
+
+	v.dbFor(c).First(quota, quota.ID)
+
+	v.publish(c, events.Updated, quota)
+
+	v.Respond(c, quota)
+}
+
+// Reset 清零已用次数，并从当前时间重新开始计算周期
+// POST /admin/quotas/:id/reset
+func (v *QuotaViewSet) Reset(c *gin.Context, quota *models.APIQuota) {
+	quota.Used = 0
+	quota.ResetAt = quota.NextResetAt(time.Now())
+	if err := v.dbFor(c).Save(quota).Error; err != nil {
+		v.dbError(c, "重置配额失败", err)
+		return
+	}
+
+	v.publish(c, events.Updated, quota)
+
+	v.Respond(c, gin.H{
+		"message": "配额已重置",
+		"quota":   quota,
+	})
+}
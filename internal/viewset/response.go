@@ -0,0 +1,52 @@
+package viewset
+
+import (
+	"go-viewset/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActionResponse 即将写出的成功响应，ResponseHook 可以修改其中的 Msg 和 Data
+type ActionResponse struct {
+	Action     string
+	Msg        string
+	Data       interface{}
+	Pagination *utils.Pagination
+}
+
+// successMessage 返回 action 的成功提示，未配置时为 "success"
+func (v *GenericViewSet) successMessage(action string) string {
+	if msg, ok := v.Messages[action]; ok {
+		return msg
+	}
+	return "success"
+}
+
+// Respond 写出当前 action 的成功响应
+// msg 取自 Messages，写出前交给 ResponseHook 处理；自定义 action 也应通过它返回结果
+func (v *GenericViewSet) Respond(c *gin.Context, data interface{}) {
+	v.RespondWithPagination(c, data, nil)
+}
+
+// RespondWithPagination 写出当前 action 带分页的成功响应
+func (v *GenericViewSet) RespondWithPagination(c *gin.Context, data interface{}, pagination *utils.Pagination) {
+	action := c.GetString(ContextAction)
+	resp := &ActionResponse{
+		Action:     action,
+		Msg:        v.successMessage(action),
+		Data:       data,
+		Pagination: pagination,
+	}
+
+	if v.ResponseHook != nil {
+		v.ResponseHook(c, resp)
+	}
+
+	resp.Data = v.addLinks(c, action, resp.Data, resp.Pagination)
+
+	if v.EnableETag && v.notModified(c, resp.Data, resp.Pagination) {
+		return
+	}
+
+	utils.SuccessWithMessage(c, resp.Msg, resp.Data, resp.Pagination)
+}
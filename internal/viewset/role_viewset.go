@@ -0,0 +1,211 @@
+package viewset
+
+import (
+	"fmt"
+	"go-viewset/internal/events"
+	"go-viewset/internal/models"
+	"go-viewset/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RoleViewSet 角色管理 ViewSet
+// 角色的增删改查，以及设置角色的权限、为用户分配角色
+type RoleViewSet struct {
+	*GenericViewSet
+}
+
+// NewRoleViewSet 创建角色管理 ViewSet
+func NewRoleViewSet(db *gorm.DB) *RoleViewSet {
+	v := &RoleViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.Role{}),
+	}
+
+	// 角色名不能重复
+	v.Validators = append(v.Validators, UniqueValidator(db, &models.Role{}, "name"))
+
+	// 响应中带上角色的权限
+	v.Relations = []string{"Permissions"}
+
+	// 角色管理（包括分配角色和设置权限）只允许管理员
+	v.PermissionClasses = []Permission{RequireRole("admin")}
+
+	return v
+}
+
+// RolePermissionsRequest 设置角色权限的请求参数
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"` // 权限名，不存在的自动创建
+}
+
+// RoleUsersRequest 分配/撤销角色的请求参数
+type RoleUsersRequest struct {
+	UserIDs []uint `json:"user_ids" binding:"required,min=1"`
+}
+
+// RegisterRoutes 注册路由
+func (v *RoleViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	v.RegisterMixins(group, v, ModelMixins...)
+}
+
+// Actions 声明自定义 action
+func (v *RoleViewSet) Actions() []Action {
+	return []Action{
+		// PUT /admin/roles/:id/permissions - 设置角色的全部权限
+		DetailAction(v.GenericViewSet, "PUT", "permissions", v.SetPermissions),
+
+		// POST /admin/roles/:id/assign - 为用户分配角色
+		DetailAction(v.GenericViewSet, "POST", "assign", v.Assign),
+
+		// POST /admin/roles/:id/unassign - 撤销用户的角色
+		DetailAction(v.GenericViewSet, "POST", "unassign", v.Unassign),
+	}
+}
+
+// SetPermissions 用请求中的权限替换角色现有的权限
+// PUT /admin/roles/:id/permissions
+func (v *RoleViewSet) SetPermissions(c *gin.Context, role *models.Role) {
+	var req RolePermissionsRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+
+	db := v.dbFor(c)
+	perms := make([]models.Permission, 0, len(req.Permissions))
+	for _, codename := range req.Permissions {
+		if codename == "" {
+			utils.ValidationError(c, []utils.FieldError{
+				{Field: "permissions", Code: utils.CodeRequired, Message: "权限名不能为空"},
+			})
+			return
+		}
+		perms = append(perms, models.Permission{Codename: codename})
+	}
+	if len(perms) > 0 {
+		// 已存在的权限保持不变，只补充缺少的
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&perms).Error; err != nil {
+			v.dbError(c, "创建权限失败", err)
+			return
+		}
+		if err := db.Where("codename IN ?", req.Permissions).Find(&perms).Error; err != nil {
+			v.dbError(c, "查询权限失败", err)
+			return
+		}
+	}
+
+	if err := db.Model(role).Association("Permissions").Replace(perms); err != nil {
+		v.dbError(c, "设置权限失败", err)
+		return
+	}
+	role.Permissions = perms
+
+	v.publish(c, events.Updated, role)
+
+	v.Respond(c, role)
+}
+
+// Assign 为用户分配角色，已拥有该角色的用户不受影响
+// POST /admin/roles/:id/assign
+func (v *RoleViewSet) Assign(c *gin.Context, role *models.Role) {
+	users, ok := v.bindRoleUsers(c)
+	if !ok {
+		return
+	}
+
+	if err := v.dbFor(c).Model(role).Association("Users").Append(users); err != nil {
+		v.dbError(c, "分配角色失败", err)
+		return
+	}
+
+	v.publish(c, events.Updated, role)
+
+	v.Respond(c, gin.H{
+		"message":  "角色已分配",
+		"role_id":  role.ID,
+		"user_ids": userIDs(users),
+	})
+}
+
+// Unassign 撤销用户的角色
+// POST /admin/roles/:id/unassign
+func (v *RoleViewSet) Unassign(c *gin.Context, role *models.Role) {
+	users, ok := v.bindRoleUsers(c)
+	if !ok {
+		return
+	}
+
+	if err := v.dbFor(c).Model(role).Association("Users").Delete(users); err != nil {
+		v.dbError(c, "撤销角色失败", err)
+		return
+	}
+
+	v.publish(c, events.Updated, role)
+
+	v.Respond(c, gin.H{
+		"message":  "角色已撤销",
+		"role_id":  role.ID,
+		"user_ids": userIDs(users),
+	})
+}
+
+// bindRoleUsers 解析请求中的用户 ID，有不存在的用户时写出 400 并返回 false
+func (v *RoleViewSet) bindRoleUsers(c *gin.Context) ([]models.User, bool) {
+	var req RoleUsersRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return nil, false
+	}
+
+	var users []models.User
+	if err := v.dbFor(c).Select("id").Where("id IN ?", req.UserIDs).Find(&users).Error; err != nil {
+		v.dbError(c, "查询用户失败", err)
+		return nil, false
+	}
+
+	found := make(map[uint]bool, len(users))
+	for _, u := range users {
+		found[u.ID] = true
+	}
+	for _, id := range req.UserIDs {
+		if !found[id] {
+			utils.ValidationError(c, []utils.FieldError{
+				{Field: "user_ids", Code: utils.CodeInvalid, Message: fmt.Sprintf("用户 %d 不存在", id)},
+			})
+			return nil, false
+		}
+	}
+	return users, true
+}
+
+// userIDs 返回用户的 ID 列表
+func userIDs(users []models.User) []uint {
+	ids := make([]uint, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+// PermissionViewSet 权限管理 ViewSet
+// 权限只有名称和描述，不提供修改，需要改名时删除后重新创建
+type PermissionViewSet struct {
+	*GenericViewSet
+}
+
+// NewPermissionViewSet 创建权限管理 ViewSet
+func NewPermissionViewSet(db *gorm.DB) *PermissionViewSet {
+	v := &PermissionViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.Permission{}),
+	}
+	v.Validators = append(v.Validators, UniqueValidator(db, &models.Permission{}, "codename"))
+	v.PermissionClasses = []Permission{RequireRole("admin")}
+	return v
+}
+
+// RegisterRoutes 注册路由
+func (v *PermissionViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	v.RegisterMixins(group, v, ListMixin, RetrieveMixin, CreateMixin, DestroyMixin)
+}
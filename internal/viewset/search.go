@@ -0,0 +1,50 @@
+package viewset
+
+import (
+	"fmt"
+	"go-viewset/internal/utils"
+	"reflect"
+	"strings"
+)
+
+// EnableFullTextSearch 将 ?search= 切换为全文搜索，并为 SearchFields 创建全文索引（已存在时跳过）
+// MySQL 创建 FULLTEXT 索引，PostgreSQL 创建 GIN 表达式索引，其他数据库不创建索引、搜索退回 LIKE 匹配。
+// SearchFields 只能是字符串字段，需要在设置 SearchFields 之后调用
+func (v *GenericViewSet) EnableFullTextSearch() error {
+	if v.meta == nil {
+		return fmt.Errorf("%s 的模型解析失败，无法开启全文搜索", v.table)
+	}
+	if len(v.SearchFields) == 0 {
+		return fmt.Errorf("%s 没有配置 SearchFields", v.table)
+	}
+
+	columns := make([]string, 0, len(v.SearchFields))
+	for _, name := range v.SearchFields {
+		field, ok := v.meta.Lookup(name)
+		if !ok || !field.Filterable {
+			return fmt.Errorf("%s 的 SearchFields 中的字段 %s 不存在或不允许过滤", v.table, name)
+		}
+		if field.Type.Kind() != reflect.String {
+			return fmt.Errorf("%s 的字段 %s 不是字符串，不能用于全文搜索", v.table, name)
+		}
+		columns = append(columns, field.Column)
+	}
+
+	// 索引名在 PostgreSQL 中全库唯一，因此带上表名
+	index := "idx_" + v.table + "_search"
+	var ddl string
+	switch v.DB.Dialector.Name() {
+	case "mysql":
+		ddl = "CREATE FULLTEXT INDEX " + index + " ON " + v.table + " (" + strings.Join(columns, ", ") + ")"
+	case "postgres":
+		ddl = "CREATE INDEX " + index + " ON " + v.table + " USING GIN (" + utils.TSVector(columns) + ")"
+	}
+	if ddl != "" && !v.DB.Migrator().HasIndex(v.Model, index) {
+		if err := v.DB.Exec(ddl).Error; err != nil {
+			return fmt.Errorf("创建 %s 的全文索引失败: %w", v.table, err)
+		}
+	}
+
+	v.SearchMode = utils.SearchFullText
+	return nil
+}
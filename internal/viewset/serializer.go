@@ -0,0 +1,235 @@
+package viewset
+
+import (
+	"context"
+	"encoding/json"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/models"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/schema"
+)
+
+// 标准 action 的名称，用于按 action 区分行为的钩子（如 GetSerializer）
+const (
+	ActionList     = "list"
+	ActionRetrieve = "retrieve"
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDestroy  = "destroy"
+
+	ActionPartialUpdate = "partial_update"
+)
+
+// Serializer 在请求/响应数据与模型之间转换
+type Serializer interface {
+	// Decode 将请求体解码到模型对象 obj（模型指针）
+	Decode(c *gin.Context, obj interface{}) error
+	// Encode 将模型对象（指针）转换为响应数据
+	Encode(obj interface{}) interface{}
+}
+
+// ModelSerializer 基于反射的默认 Serializer
+// 按字段的 access 标签处理：access:"readonly" 的字段（如 ID、CreatedAt）忽略客户端传入的值，
+// access:"writeonly" 的字段（如 Password）不出现在响应中。
+// 关联字段也可以标记 access:"readonly"，避免客户端通过请求体创建或修改关联记录
+type ModelSerializer struct {
+	readOnly  []*schema.Field
+	writeOnly []string // JSON 字段名
+}
+
+// NewModelSerializer 根据模型元数据创建默认 Serializer
+func NewModelSerializer(m *meta.Model) *ModelSerializer {
+	s := &ModelSerializer{}
+	if m == nil {
+		return s
+	}
+	for _, f := range m.Fields {
+		if f.ReadOnly {
+			if sf := m.Schema.LookUpField(f.Column); sf != nil {
+				s.readOnly = append(s.readOnly, sf)
+			}
+		}
+		if f.WriteOnly && f.JSONName != "-" {
+			s.writeOnly = append(s.writeOnly, f.JSONName)
+		}
+	}
+	for _, rel := range m.Schema.Relationships.Relations {
+		if rel.Field.Tag.Get("access") == "readonly" {
+			s.readOnly = append(s.readOnly, rel.Field)
+		}
+	}
+	return s
+}
+
+// Decode 实现 Serializer，绑定后清空只读字段
+func (s *ModelSerializer) Decode(c *gin.Context, obj interface{}) error {
+	if err := bindJSON(c, obj); err != nil {
+		return err
+	}
+	s.clearReadOnly(c.Request.Context(), obj)
+	return nil
+}
+
+// clearReadOnly 清空只读字段
+func (s *ModelSerializer) clearReadOnly(ctx context.Context, obj interface{}) {
+	elem := reflect.ValueOf(obj).Elem()
+	for _, sf := range s.readOnly {
+		fv := sf.ReflectValueOf(ctx, elem)
+		fv.Set(reflect.Zero(fv.Type()))
+	}
+}
+
+// Encode 实现 Serializer，去掉只写字段
+// 模型没有只写字段时原样返回
+func (s *ModelSerializer) Encode(obj interface{}) interface{} {
+	if len(s.writeOnly) == 0 {
+		return obj
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return obj
+	}
+	for _, name := range s.writeOnly {
+		delete(fields, name)
+	}
+	return fields
+}
+
+// passthrough Encode 是否原样返回对象
+func (s *ModelSerializer) passthrough() bool {
+	return len(s.writeOnly) == 0
+}
+
+// DTOSerializer 使用独立的输入/输出 DTO 的 Serializer
+type DTOSerializer struct {
+	// Input 请求体绑定的类型，例如 CreateUserInput{}，为 nil 时直接绑定到模型
+	// 绑定并校验后按 JSON 字段复制到模型，Input 中没有的字段客户端无法写入
+	Input interface{}
+
+	// Output 将模型对象（指针）转换为响应数据，为 nil 时直接返回模型
+	// 例如列表返回精简的行，详情返回完整对象
+	Output func(obj interface{}) interface{}
+}
+
+// Decode 实现 Serializer
+func (s *DTOSerializer) Decode(c *gin.Context, obj interface{}) error {
+	if s.Input == nil {
+		return bindJSON(c, obj)
+	}
+
+	inputType := reflect.TypeOf(s.Input)
+	if inputType.Kind() == reflect.Ptr {
+		inputType = inputType.Elem()
+	}
+	input := reflect.New(inputType).Interface()
+	if err := bindJSON(c, input); err != nil {
+		return err
+	}
+	if m, ok := input.(Validatable); ok {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, obj)
+}
+
+// Encode 实现 Serializer
+func (s *DTOSerializer) Encode(obj interface{}) interface{} {
+	if s.Output == nil {
+		return obj
+	}
+	return s.Output(obj)
+}
+
+// Validatable 可以自行校验的模型或输入 DTO
+// 返回 utils.ValidationErrors 时按字段输出，其他错误作为整体错误输出
+type Validatable interface {
+	Validate() error
+}
+
+// serializer 返回 action 使用的 Serializer
+// GetSerializer 未配置或返回 nil 时使用默认的 ModelSerializer
+func (v *GenericViewSet) serializer(action string) Serializer {
+	if v.GetSerializer != nil {
+		if s := v.GetSerializer(action); s != nil {
+			return s
+		}
+	}
+	return v.modelSerializer
+}
+
+// bindInput 按 action 的 Serializer 解码请求体到模型对象 obj，并收集全部校验错误（见 validate）
+// 返回的错误可以直接交给 utils.BindingErrors 转换
+func (v *GenericViewSet) bindInput(c *gin.Context, action string, obj interface{}) error {
+	s := v.serializer(action)
+	err := s.Decode(c, obj)
+	if err != nil {
+		// 自定义 Serializer 校验失败时 obj 可能没有被填充，不再继续校验
+		if _, ok := s.(*ModelSerializer); !ok {
+			return err
+		}
+	}
+	return v.validate(c, action, obj, err)
+}
+
+// serialize 按 action 的 Serializer 转换单个对象
+// 开启附件字段时增加附件信息（见 EnableFileFields）；c 为 nil 时不增加（例如计算 ETag）
+func (v *GenericViewSet) serialize(c *gin.Context, action string, obj interface{}) interface{} {
+	data := v.serializer(action).Encode(obj)
+	if v.files == nil || c == nil {
+		return data
+	}
+	return v.serializeFiles(c, data, obj)
+}
+
+// serializeList 按 list action 的 Serializer 转换查询结果
+// results 为模型切片或其指针，例如 *[]*User、[]User；开启附件字段时一次查询所有记录引用的附件
+func (v *GenericViewSet) serializeList(c *gin.Context, results interface{}) interface{} {
+	s := v.serializer(ActionList)
+	if ms, ok := s.(*ModelSerializer); ok && ms.passthrough() && v.files == nil {
+		return results
+	}
+
+	slice := reflect.Indirect(reflect.ValueOf(results))
+	objs := make([]interface{}, slice.Len())
+	var ids []uint
+	for i := range objs {
+		elem := slice.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		objs[i] = elem.Interface()
+		if v.files != nil {
+			ids = append(ids, v.fileIDs(c, objs[i])...)
+		}
+	}
+
+	var files map[uint]*models.Attachment
+	if v.files != nil {
+		var err error
+		if files, err = v.loadFiles(c, ids); err != nil {
+			c.Error(err)
+		}
+	}
+
+	out := make([]interface{}, len(objs))
+	for i, obj := range objs {
+		out[i] = s.Encode(obj)
+		if v.files != nil {
+			out[i] = v.withFiles(c, out[i], obj, files)
+		}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package viewset
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatsRefresher 预计算的统计结果
+// 统计接口通常被看板高频轮询，每次请求都做全表统计代价很高。
+// StatsRefresher 在后台按 Interval 定期刷新结果；数据发生写操作时通过 MarkDirty 标记，
+// 下一次读取时如果距上次计算已超过 MinInterval 则立即重新计算。
+// 读取结果时同时返回计算时间，调用方据此告诉客户端数据的新鲜程度。
+type StatsRefresher struct {
+	Interval    time.Duration
+	MinInterval time.Duration
+
+	compute func(ctx context.Context) (interface{}, error)
+	start   sync.Once
+	dirty   atomic.Bool
+
+	mu         sync.RWMutex
+	refreshMu  sync.Mutex
+	value      interface{}
+	computedAt time.Time
+}
+
+// NewStatsRefresher 创建统计结果刷新器
+func NewStatsRefresher(compute func(ctx context.Context) (interface{}, error), interval time.Duration) *StatsRefresher {
+	return &StatsRefresher{
+		Interval:    interval,
+		MinInterval: time.Second,
+		compute:     compute,
+	}
+}
+
+// MarkDirty 标记数据已变化
+func (r *StatsRefresher) MarkDirty() {
+	r.dirty.Store(true)
+}
+
+// Snapshot 读取统计结果及其计算时间
+// 第一次读取时启动后台定期刷新
+func (r *StatsRefresher) Snapshot(ctx context.Context) (interface{}, time.Time, error) {
+	r.start.Do(func() {
+		go r.loop()
+	})
+
+	r.mu.RLock()
+	value, computedAt := r.value, r.computedAt
+	r.mu.RUnlock()
+
+	if computedAt.IsZero() || (r.dirty.Load() && time.Since(computedAt) >= r.MinInterval) {
+		return r.refresh(ctx, false)
+	}
+	return value, computedAt, nil
+}
+
+// refresh 重新计算统计结果，同一时间只有一个计算在执行
+// force 为 false 时，如果等锁期间其他请求已经刷新过，直接返回已有结果
+func (r *StatsRefresher) refresh(ctx context.Context, force bool) (interface{}, time.Time, error) {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+
+	r.mu.RLock()
+	value, computedAt := r.value, r.computedAt
+	r.mu.RUnlock()
+	if !force && !computedAt.IsZero() && !r.dirty.Load() {
+		return value, computedAt, nil
+	}
+
+	r.dirty.Store(false)
+	value, err := r.compute(ctx)
+	if err != nil {
+		r.dirty.Store(true)
+		return nil, time.Time{}, err
+	}
+
+	computedAt = time.Now()
+	r.mu.Lock()
+	r.value, r.computedAt = value, computedAt
+	r.mu.Unlock()
+
+	return value, computedAt, nil
+}
+
+// loop 后台定期刷新
+func (r *StatsRefresher) loop() {
+	if r.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.refresh(context.Background(), true)
+	}
+}
@@ -0,0 +1,177 @@
+package viewset
+
+import (
+	"fmt"
+	"go-viewset/internal/events"
+	"go-viewset/internal/utils"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ActionStream 变更推送的 action 名称
+const ActionStream = "stream"
+
+const (
+	// streamBufferSize 每个连接缓冲的事件数，客户端读取太慢导致缓冲区满时断开连接（客户端重连后重新加载列表）
+	streamBufferSize = 64
+	// streamKeepAlive 没有事件时发送注释行的间隔，避免代理因空闲断开连接
+	streamKeepAlive = 15 * time.Second
+)
+
+// 推送的事件类型（SSE 的 event 字段）
+const (
+	StreamCreated = "created" // 新建的记录符合过滤条件，data 为对象（与详情接口相同，支持 ?fields=）
+	StreamUpdated = "updated" // 更新后的记录符合过滤条件，data 为对象
+	StreamRemoved = "removed" // 更新后的记录不再符合过滤条件，data 为 {"id": ...}
+	StreamDeleted = "deleted" // 记录被删除，data 为 {"id": ...}
+)
+
+// Stream 以 Server-Sent Events 推送该资源的创建、更新和删除，需要开启 EnableStream
+// GET /items/stream?status=active，过滤参数与 List 相同（分页和排序参数被忽略）。
+// 创建和更新的记录按过滤条件（以及所有者、租户范围）重新查询，符合时推送最新的对象；
+// 只推送连接建立之后发生的变更，客户端应先通过 List 加载当前数据
+func (v *GenericViewSet) Stream(c *gin.Context) {
+	if !v.parseFields(c) {
+		return
+	}
+	filterParams, ok := v.filterParams(c)
+	if !ok {
+		return
+	}
+	defer utils.ReleaseFilterParams(filterParams)
+	newQuery := v.listQuery(c, filterParams)
+
+	// 只接收当前租户的事件（未开启多租户时均为空）
+	var tenantID string
+	if id, ok := c.Get(ContextTenantID); ok {
+		tenantID = fmt.Sprint(id)
+	}
+
+	queue := make(chan events.Event, streamBufferSize)
+	overflow := make(chan struct{})
+	var once sync.Once
+	unsubscribe := v.Events.Subscribe(func(e events.Event) {
+		if e.Resource != v.table || e.TenantID != tenantID {
+			return
+		}
+		select {
+		case queue <- e:
+		default:
+			once.Do(func() { close(overflow) })
+		}
+	})
+	defer unsubscribe()
+
+	// 长连接不受服务器 WriteTimeout 的限制
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if !v.writeComment(c, "connected") {
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-overflow:
+			log.Printf("%s 变更推送的客户端读取太慢，断开连接", v.table)
+			return
+		case <-keepAlive.C:
+			if !v.writeComment(c, "keep-alive") {
+				return
+			}
+		case e := <-queue:
+			name, data, ok := v.streamEvent(c, newQuery, e)
+			if !ok {
+				continue
+			}
+			if !v.writeEvent(c, name, data) {
+				return
+			}
+		}
+	}
+}
+
+// streamEvent 将模型变更事件转换为推送给当前连接的事件，不需要推送时返回 false
+func (v *GenericViewSet) streamEvent(c *gin.Context, newQuery func() *gorm.DB, e events.Event) (string, interface{}, bool) {
+	removed := map[string]string{"id": e.ObjectID}
+
+	if e.Action == events.Deleted {
+		// 已删除的记录无法再查询，只按所有者范围判断
+		if e.Object != nil && !v.visibleToOwner(c, e.Object) {
+			return "", nil, false
+		}
+		return StreamDeleted, removed, true
+	}
+
+	obj := v.newObject()
+	err := v.wherePK(v.selectFields(c, newQuery()), e.ObjectID).First(obj).Error
+	if err == nil {
+		if !v.streamPermitted(c, obj) {
+			return "", nil, false
+		}
+		name := StreamUpdated
+		if e.Action == events.Created {
+			name = StreamCreated
+		}
+		return name, v.pickFields(c, v.serialize(c, ActionRetrieve, obj)), true
+	}
+	if err != gorm.ErrRecordNotFound {
+		c.Error(err)
+		return "", nil, false
+	}
+
+	// 更新后不再符合过滤条件：客户端能看到该记录时通知其移除
+	if e.Action == events.Updated {
+		if err := v.queryset(c).First(v.newObject(), e.ObjectID).Error; err == nil {
+			return StreamRemoved, removed, true
+		}
+	}
+	return "", nil, false
+}
+
+// streamPermitted obj 是否通过对象级权限检查，不通过时不推送（不写出错误响应）
+func (v *GenericViewSet) streamPermitted(c *gin.Context, obj interface{}) bool {
+	for _, p := range v.permissionsFor(ActionStream) {
+		if !p.HasObjectPermission(c, ActionStream, obj) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeEvent 写出一个事件并刷新，连接已断开时返回 false
+func (v *GenericViewSet) writeEvent(c *gin.Context, name string, data interface{}) bool {
+	body, err := utils.MarshalJSON(data)
+	if err != nil {
+		c.Error(err)
+		return true
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", name, body); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
+
+// writeComment 写出注释行并刷新，连接已断开时返回 false
+func (v *GenericViewSet) writeComment(c *gin.Context, comment string) bool {
+	if _, err := fmt.Fprintf(c.Writer, ": %s\n\n", comment); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
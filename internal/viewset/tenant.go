@@ -0,0 +1,89 @@
+package viewset
+
+import (
+	"fmt"
+	"go-viewset/internal/utils"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 租户中间件写入 gin.Context 的 key
+const (
+	ContextTenantID = "tenant_id" // 当前租户 ID（见 middleware.Tenant）
+	ContextTenantDB = "tenant_db" // 每个租户使用独立数据库时当前租户的 *gorm.DB（见 middleware.TenantDatabase）
+)
+
+// DefaultTenantField EnableTenantScope 使用的租户字段
+const DefaultTenantField = "tenant_id"
+
+// EnableTenantScope 模型有 tenant_id 字段时按租户隔离数据（设置 TenantField），返回是否开启
+func (v *GenericViewSet) EnableTenantScope() bool {
+	if _, ok := v.lookupField(DefaultTenantField); !ok {
+		return false
+	}
+	v.TenantField = DefaultTenantField
+	return true
+}
+
+// tenantColumn TenantField 对应的列名，未设置时返回 false
+func (v *GenericViewSet) tenantColumn() (string, bool) {
+	if v.TenantField == "" {
+		return "", false
+	}
+	return v.lookupField(v.TenantField)
+}
+
+// scopeToTenant 所有查询只包含当前租户的记录，请求中没有租户时不返回任何记录
+func (v *GenericViewSet) scopeToTenant(c *gin.Context, query *gorm.DB) *gorm.DB {
+	column, ok := v.tenantColumn()
+	if !ok {
+		return query
+	}
+	tenantID, ok := c.Get(ContextTenantID)
+	if !ok {
+		return query.Where("1 = 0")
+	}
+	return query.Where(v.table+"."+column+" = ?", tenantID)
+}
+
+// tenantScopeKey 当前租户的标识，用于区分不同租户的缓存和总数
+func (v *GenericViewSet) tenantScopeKey(c *gin.Context) string {
+	if _, ok := v.tenantColumn(); !ok {
+		return ""
+	}
+	tenantID, _ := c.Get(ContextTenantID)
+	return fmt.Sprintf("tenant=%v:", tenantID)
+}
+
+// setTenantField 创建时将 TenantField 设置为当前租户，客户端传入的值被忽略
+// obj 为模型指针；creating 为 false（PUT）时清空该字段，Updates 跳过零值，因此租户保持不变
+func (v *GenericViewSet) setTenantField(c *gin.Context, obj interface{}, creating bool) error {
+	column, ok := v.tenantColumn()
+	if !ok {
+		return nil
+	}
+	sf := v.schema.LookUpField(column)
+	if sf == nil {
+		return nil
+	}
+	elem := reflect.ValueOf(obj).Elem()
+	if !creating {
+		fv := sf.ReflectValueOf(c.Request.Context(), elem)
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+	tenantID, ok := c.Get(ContextTenantID)
+	if !ok {
+		return utils.ValidationErrors{{Field: v.TenantField, Code: utils.CodeRequired, Message: "缺少租户"}}
+	}
+	return sf.Set(c.Request.Context(), elem, tenantID)
+}
+
+// dropTenantColumn 部分更新时不允许修改租户
+func (v *GenericViewSet) dropTenantColumn(updates map[string]interface{}) {
+	if column, ok := v.tenantColumn(); ok {
+		delete(updates, column)
+	}
+}
@@ -0,0 +1,70 @@
+package viewset
+
+import (
+	"go-viewset/internal/database"
+	"go-viewset/internal/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TenantViewSet 租户管理 ViewSet，供管理员使用
+type TenantViewSet struct {
+	*GenericViewSet
+
+	// DBs 每个租户使用独立数据库时的连接管理，租户更新或删除后关闭其数据库，下次请求按新的配置重新打开
+	DBs *database.TenantDBs
+}
+
+// NewTenantViewSet 创建租户管理 ViewSet
+func NewTenantViewSet(db *gorm.DB) *TenantViewSet {
+	v := &TenantViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.Tenant{}),
+	}
+	v.Validators = append(v.Validators, UniqueValidator(db, &models.Tenant{}, "slug"))
+	v.SearchFields = []string{"name", "slug"}
+	return v
+}
+
+// RegisterRoutes 注册路由
+func (v *TenantViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	v.RegisterMixins(group, v, ModelMixins...)
+}
+
+// PerformCreate 子域名统一为小写
+func (v *TenantViewSet) PerformCreate(c *gin.Context, obj interface{}) error {
+	tenant := obj.(*models.Tenant)
+	tenant.Slug = strings.ToLower(tenant.Slug)
+	return nil
+}
+
+// PerformUpdate 子域名统一为小写，PATCH 时 obj 为 列名 -> 值
+func (v *TenantViewSet) PerformUpdate(c *gin.Context, obj interface{}) error {
+	switch x := obj.(type) {
+	case *models.Tenant:
+		x.Slug = strings.ToLower(x.Slug)
+	case map[string]interface{}:
+		if slug, ok := x["slug"].(string); ok {
+			x["slug"] = strings.ToLower(slug)
+		}
+	}
+	return nil
+}
+
+// AfterUpdate 数据库配置可能已经变化，关闭租户已打开的数据库
+func (v *TenantViewSet) AfterUpdate(c *gin.Context, obj interface{}) {
+	v.evict(obj)
+}
+
+// AfterDestroy 关闭已删除租户的数据库
+func (v *TenantViewSet) AfterDestroy(c *gin.Context, obj interface{}) {
+	v.evict(obj)
+}
+
+// evict 关闭租户已打开的数据库
+func (v *TenantViewSet) evict(obj interface{}) {
+	if v.DBs != nil {
+		v.DBs.Evict(obj.(*models.Tenant).Slug)
+	}
+}
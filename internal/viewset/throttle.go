@@ -0,0 +1,146 @@
+package viewset
+
+import (
+	"fmt"
+	"go-viewset/internal/throttle"
+	"go-viewset/internal/utils"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Throttle 限流类（见 ThrottleClasses）
+// ThrottleKey 返回本次请求计数使用的 key，返回 "" 表示该限流类不限制本次请求
+type Throttle interface {
+	ThrottleKey(c *gin.Context, resource, action string) string
+	ThrottleRate() throttle.Rate
+}
+
+// rateThrottle 按固定速率限流的 Throttle
+type rateThrottle struct {
+	rate throttle.Rate
+	key  func(c *gin.Context, resource, action string) string
+}
+
+// ThrottleKey 实现 Throttle
+func (t *rateThrottle) ThrottleKey(c *gin.Context, resource, action string) string {
+	return t.key(c, resource, action)
+}
+
+// ThrottleRate 实现 Throttle
+func (t *rateThrottle) ThrottleRate() throttle.Rate {
+	return t.rate
+}
+
+// mustParseRate 解析限流速率，格式错误属于代码问题，直接 panic
+func mustParseRate(spec string) throttle.Rate {
+	rate, err := throttle.ParseRate(spec)
+	if err != nil {
+		panic(err)
+	}
+	return rate
+}
+
+// AnonRateThrottle 只限制未登录的请求，按客户端 IP 计数
+func AnonRateThrottle(rate string) Throttle {
+	return &rateThrottle{rate: mustParseRate(rate), key: func(c *gin.Context, resource, action string) string {
+		if isAuthenticated(c) {
+			return ""
+		}
+		return "anon:" + resource + ":" + action + ":ip:" + c.ClientIP()
+	}}
+}
+
+// UserRateThrottle 已登录用户按用户 ID 计数，未登录的请求按客户端 IP 计数
+func UserRateThrottle(rate string) Throttle {
+	return &rateThrottle{rate: mustParseRate(rate), key: func(c *gin.Context, resource, action string) string {
+		return "user:" + resource + ":" + action + ":" + throttleIdent(c)
+	}}
+}
+
+// ScopedRateThrottle 按 scope 计数，使用同一个 scope 的 action（可以在不同的 ViewSet 中）共享限额，
+// 例如 ScopedRateThrottle("password_reset", "5/hour")
+func ScopedRateThrottle(scope, rate string) Throttle {
+	return &rateThrottle{rate: mustParseRate(rate), key: func(c *gin.Context, resource, action string) string {
+		return "scope:" + scope + ":" + throttleIdent(c)
+	}}
+}
+
+// throttleScopes 返回 action 适用的限流范围及速率
+// "*" 对应整个 ViewSet 共享的限流，action 单独配置的限流与它互相独立、同时生效
+func (v *GenericViewSet) throttleScopes(action string) map[string]throttle.Rate {
+	scopes := make(map[string]throttle.Rate)
+	for _, scope := range []string{"*", action} {
+		spec, ok := v.Throttles[scope]
+		if !ok {
+			continue
+		}
+		rate, err := throttle.ParseRate(spec)
+		if err != nil {
+			panic(fmt.Sprintf("%s 的限流配置 %s 错误: %v", v.table, scope, err))
+		}
+		scopes[scope] = rate
+	}
+	return scopes
+}
+
+// throttleClasses 返回 action 适用的限流类："*" 中的加上为该 action 配置的
+func (v *GenericViewSet) throttleClasses(action string) []Throttle {
+	classes := append([]Throttle(nil), v.ThrottleClasses["*"]...)
+	if action != "*" {
+		classes = append(classes, v.ThrottleClasses[action]...)
+	}
+	return classes
+}
+
+// throttleIdent 限流的主体：已登录用户按用户 ID，否则按客户端 IP
+func throttleIdent(c *gin.Context) string {
+	if userID, ok := c.Get(ContextUserID); ok {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// throttleStore 限流计数的存储，未设置 ThrottleStore 时使用 throttle.Default
+func (v *GenericViewSet) throttleStore() throttle.Store {
+	if v.ThrottleStore != nil {
+		return v.ThrottleStore
+	}
+	return throttle.Default
+}
+
+// checkThrottle 检查限流（Throttles 和 ThrottleClasses），超出时写出 429 并返回 false
+func (v *GenericViewSet) checkThrottle(c *gin.Context, action string, scopes map[string]throttle.Rate, classes []Throttle) bool {
+	if len(scopes) == 0 && len(classes) == 0 {
+		return true
+	}
+
+	store := v.throttleStore()
+	ident := throttleIdent(c)
+	for scope, rate := range scopes {
+		if ok, wait := store.Allow(v.table+":"+scope+":"+ident, rate); !ok {
+			throttled(c, wait)
+			return false
+		}
+	}
+	for _, t := range classes {
+		key := t.ThrottleKey(c, v.table, action)
+		if key == "" {
+			continue
+		}
+		if ok, wait := store.Allow(key, t.ThrottleRate()); !ok {
+			throttled(c, wait)
+			return false
+		}
+	}
+	return true
+}
+
+// throttled 写出限流的 429 响应
+func throttled(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	utils.TooManyRequests(c, "请求过于频繁，请稍后重试")
+	c.Abort()
+}
@@ -0,0 +1,172 @@
+package viewset
+
+import (
+	"bytes"
+	"errors"
+	"go-viewset/internal/events"
+	"go-viewset/internal/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 事务相关的数据在 gin.Context 中的 key
+const (
+	// ContextTx 当前请求的事务（*gorm.DB）
+	ContextTx = "viewset_tx"
+
+	// contextPendingEvents 事务提交后才发布的事件
+	contextPendingEvents = "viewset_pending_events"
+
+	// contextAfterCommit 事务提交后执行的函数
+	contextAfterCommit = "viewset_after_commit"
+)
+
+// errRollback 处理函数返回了错误响应，回滚事务
+var errRollback = errors.New("请求失败，回滚事务")
+
+// TxFrom 返回当前请求的事务，不在事务中时返回 false
+// 钩子和自定义 action 中通过它（或 dbFor）参与同一个事务
+func TxFrom(c *gin.Context) (*gorm.DB, bool) {
+	if value, ok := c.Get(ContextTx); ok {
+		return value.(*gorm.DB), true
+	}
+	return nil, false
+}
+
+// isWriteMethod 判断是否为写请求
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// atomic 在事务中执行 handler
+// handler 写出的响应先缓存，事务提交后再发送给客户端；
+// 响应状态码 >= 400 或请求被中止时回滚，提交失败时返回 500。
+// 事务中发布的事件在提交后统一发布，回滚时丢弃
+func (v *GenericViewSet) atomic(c *gin.Context, handler gin.HandlerFunc) {
+	original := c.Writer
+	buffered := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+	c.Writer = buffered
+	defer func() {
+		c.Writer = original
+	}()
+
+	err := v.requestDB(c).WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		c.Set(ContextTx, tx)
+		defer func() {
+			delete(c.Keys, ContextTx)
+		}()
+
+		handler(c)
+		if c.IsAborted() || buffered.status >= http.StatusBadRequest {
+			return errRollback
+		}
+		return nil
+	})
+	c.Writer = original
+
+	if err != nil && !errors.Is(err, errRollback) {
+		log.Printf("提交事务失败: %v", err)
+		utils.InternalServerError(c, "提交事务失败")
+		return
+	}
+
+	if err == nil {
+		v.flushEvents(c)
+		runAfterCommit(c)
+	}
+	delete(c.Keys, contextAfterCommit)
+	buffered.flush()
+}
+
+// afterCommit 在当前请求的事务提交后执行 fn（例如发送邮件），回滚时不执行；不在事务中时立即执行
+func afterCommit(c *gin.Context, fn func()) {
+	if _, ok := TxFrom(c); !ok {
+		fn()
+		return
+	}
+	pending, _ := c.Get(contextAfterCommit)
+	list, _ := pending.([]func())
+	c.Set(contextAfterCommit, append(list, fn))
+}
+
+// runAfterCommit 执行事务中通过 afterCommit 登记的函数
+func runAfterCommit(c *gin.Context) {
+	value, ok := c.Get(contextAfterCommit)
+	if !ok {
+		return
+	}
+	delete(c.Keys, contextAfterCommit)
+	for _, fn := range value.([]func()) {
+		fn()
+	}
+}
+
+// flushEvents 发布事务中暂存的事件
+func (v *GenericViewSet) flushEvents(c *gin.Context) {
+	value, ok := c.Get(contextPendingEvents)
+	if !ok {
+		return
+	}
+	delete(c.Keys, contextPendingEvents)
+	for _, e := range value.([]events.Event) {
+		v.Events.Publish(e)
+	}
+}
+
+// bufferedWriter 缓存响应，事务结束后再写出
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader 只记录状态码
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow 响应在 flush 时才真正写出
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+// Write 写入缓存
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// WriteString 写入缓存
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Status 返回记录的状态码
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+// Size 返回已缓存的字节数
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+// Written 事务中的响应都在缓存中，总是可以继续写
+func (w *bufferedWriter) Written() bool {
+	return false
+}
+
+// flush 将缓存的响应写到原始的 ResponseWriter
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
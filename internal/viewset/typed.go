@@ -0,0 +1,192 @@
+package viewset
+
+import (
+	"go-viewset/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ViewSet 类型参数化的 ViewSet，T 为模型类型（例如 models.Product）
+// 路由、过滤、分页、权限等与 GenericViewSet 完全相同，区别在于：
+//   - 创建模型实例使用 new(T)，不经过反射
+//   - 钩子直接收到 *T（见 TypedCreateHook 等），不需要类型断言
+//   - Object、Objects 在自定义 action 中返回 *T 和 []T
+//
+// 例如：
+//
+//	type ProductViewSet struct {
+//		*viewset.ViewSet[models.Product]
+//	}
+//
+//	func (v *ProductViewSet) PerformCreate(c *gin.Context, p *models.Product) error {
+//		p.SKU = strings.ToUpper(p.SKU)
+//		return nil
+//	}
+//
+//	func (v *ProductViewSet) RegisterRoutes(group *gin.RouterGroup) {
+//		v.RegisterMixins(group, v, viewset.ModelMixins...)
+//	}
+//
+// 与 GenericViewSet 一样，外层 ViewSet 需要自己实现 RegisterRoutes，钩子和自定义 action 才会在外层上查找
+type ViewSet[T any] struct {
+	*GenericViewSet
+}
+
+// NewViewSet 创建类型参数化的 ViewSet
+func NewViewSet[T any](db *gorm.DB) *ViewSet[T] {
+	v := &ViewSet[T]{
+		GenericViewSet: NewGenericViewSet(db, new(T)),
+	}
+	v.newFunc = func() interface{} { return new(T) }
+	v.typed = typedHooksFor[T]{v.GenericViewSet}
+	return v
+}
+
+// RegisterRoutes 注册路由
+func (v *ViewSet[T]) RegisterRoutes(group *gin.RouterGroup) {
+	v.RegisterMixins(group, v, ModelMixins...)
+}
+
+// Object 按 URL 中的 :id 取得对象并检查对象级权限，失败时已写出错误响应并返回 false
+func (v *ViewSet[T]) Object(c *gin.Context) (*T, bool) {
+	obj, ok := v.GetObjectOr404(c, c.Param("id"))
+	if !ok {
+		return nil, false
+	}
+	return obj.(*T), true
+}
+
+// DetailAction 声明作用于单个对象的 action，fn 收到已检查过权限的 *T，例如：
+//
+//	func (v *ProductViewSet) Actions() []viewset.Action {
+//		return []viewset.Action{v.DetailAction("POST", "publish", v.Publish)}
+//	}
+func (v *ViewSet[T]) DetailAction(method, name string, fn func(c *gin.Context, obj *T)) Action {
+	return DetailAction(v.GenericViewSet, method, name, fn)
+}
+
+// Objects 按请求的过滤、搜索、排序和分页参数查询当前页的对象（与 List 相同的规则），
+// 用于在自定义 action 中取得 []T；参数错误或查询失败时已写出错误响应并返回 false
+func (v *ViewSet[T]) Objects(c *gin.Context) ([]T, *utils.Pagination, bool) {
+	paginationParams := utils.GetPaginationParams(c, v.PaginationConfig)
+	filterParams, ok := v.filterParams(c)
+	if !ok {
+		return nil, nil, false
+	}
+	defer utils.ReleaseFilterParams(filterParams)
+
+	newQuery := v.listQuery(c, filterParams)
+	waitPagination := v.startPagination(c, newQuery, paginationParams, v.parentScopeKey(c)+filterParams.Signature())
+
+	var objects []T
+	if err := utils.ApplyPagination(newQuery(), paginationParams).Find(&objects).Error; err != nil {
+		v.dbError(c, "查询失败", err)
+		return nil, nil, false
+	}
+	pagination, err := waitPagination()
+	if err != nil {
+		v.dbError(c, "查询失败", err)
+		return nil, nil, false
+	}
+	return objects, pagination, true
+}
+
+// TypedCreateHook ViewSet[T] 创建前的钩子，返回错误时不再创建
+type TypedCreateHook[T any] interface {
+	PerformCreate(c *gin.Context, obj *T) error
+}
+
+// TypedUpdateHook ViewSet[T] 更新（PUT）前的钩子，obj 为本次要写入的数据（零值字段不会更新）
+// PATCH 要写入的是 列名 -> 值，见 PartialUpdateHook
+type TypedUpdateHook[T any] interface {
+	PerformUpdate(c *gin.Context, obj *T) error
+}
+
+// PartialUpdateHook ViewSet[T] 部分更新（PATCH）前的钩子，可以修改或补充要写入的列
+type PartialUpdateHook interface {
+	PerformPartialUpdate(c *gin.Context, updates map[string]interface{}) error
+}
+
+// TypedDestroyHook ViewSet[T] 删除前的钩子，返回错误时不再删除
+type TypedDestroyHook[T any] interface {
+	PerformDestroy(c *gin.Context, obj *T) error
+}
+
+// TypedAfterCreateHook ViewSet[T] 创建成功后的钩子
+type TypedAfterCreateHook[T any] interface {
+	AfterCreate(c *gin.Context, obj *T)
+}
+
+// TypedAfterUpdateHook ViewSet[T] 更新成功后的钩子，obj 为重新查询的最新对象
+type TypedAfterUpdateHook[T any] interface {
+	AfterUpdate(c *gin.Context, obj *T)
+}
+
+// TypedAfterDestroyHook ViewSet[T] 删除成功后的钩子
+type TypedAfterDestroyHook[T any] interface {
+	AfterDestroy(c *gin.Context, obj *T)
+}
+
+// typedHooksFor 在最外层的 ViewSet 上查找类型化的钩子
+type typedHooksFor[T any] struct {
+	v *GenericViewSet
+}
+
+// perform 实现 typedHooks
+func (h typedHooksFor[T]) perform(c *gin.Context, hook string, obj interface{}) (bool, error) {
+	impl := h.v.hooks()
+	if updates, ok := obj.(map[string]interface{}); ok {
+		if p, ok := impl.(PartialUpdateHook); ok && hook == hookUpdate {
+			return true, p.PerformPartialUpdate(c, updates)
+		}
+		return false, nil
+	}
+
+	typed, ok := obj.(*T)
+	if !ok {
+		return false, nil
+	}
+	switch hook {
+	case hookCreate:
+		if p, ok := impl.(TypedCreateHook[T]); ok {
+			return true, p.PerformCreate(c, typed)
+		}
+	case hookUpdate:
+		if p, ok := impl.(TypedUpdateHook[T]); ok {
+			return true, p.PerformUpdate(c, typed)
+		}
+	case hookDestroy:
+		if p, ok := impl.(TypedDestroyHook[T]); ok {
+			return true, p.PerformDestroy(c, typed)
+		}
+	}
+	return false, nil
+}
+
+// after 实现 typedHooks
+func (h typedHooksFor[T]) after(c *gin.Context, hook string, obj interface{}) bool {
+	typed, ok := obj.(*T)
+	if !ok {
+		return false
+	}
+	impl := h.v.hooks()
+	switch hook {
+	case hookCreate:
+		if a, ok := impl.(TypedAfterCreateHook[T]); ok {
+			a.AfterCreate(c, typed)
+			return true
+		}
+	case hookUpdate:
+		if a, ok := impl.(TypedAfterUpdateHook[T]); ok {
+			a.AfterUpdate(c, typed)
+			return true
+		}
+	case hookDestroy:
+		if a, ok := impl.(TypedAfterDestroyHook[T]); ok {
+			a.AfterDestroy(c, typed)
+			return true
+		}
+	}
+	return false
+}
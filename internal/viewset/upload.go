@@ -0,0 +1,328 @@
+package viewset
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-viewset/internal/config"
+	"go-viewset/internal/imaging"
+	"go-viewset/internal/models"
+	"go-viewset/internal/storage"
+	"go-viewset/internal/utils"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AttachmentFileField 上传文件的表单字段名
+const AttachmentFileField = "file"
+
+// 附件的默认限制
+const (
+	defaultAttachmentMaxSizeMB = 10
+	defaultAttachmentURLExpiry = time.Hour
+
+	// maxImagePixels 生成缩略图时图片的最大像素数，避免解码超大图片占用过多内存
+	maxImagePixels = 40_000_000
+)
+
+// defaultThumbnailSizes 默认的缩略图尺寸
+var defaultThumbnailSizes = []int{64, 256}
+
+// multipartOverhead 请求体中文件以外的部分（边界、表单头）允许的大小
+const multipartOverhead = 64 << 10
+
+// Uploader 保存上传的文件并创建附件记录，附件接口和头像等 action 共用
+type Uploader struct {
+	store          storage.Storage
+	maxSize        int64
+	allowedTypes   []string
+	expiry         time.Duration
+	thumbnailSizes []int
+}
+
+// NewUploader 按附件配置创建 Uploader，文件保存在 store 中
+func NewUploader(store storage.Storage, cfg config.AttachmentConfig) *Uploader {
+	u := &Uploader{
+		store:          store,
+		maxSize:        int64(cfg.MaxSizeMB) << 20,
+		allowedTypes:   cfg.AllowedTypes,
+		expiry:         time.Duration(cfg.URLExpirySeconds) * time.Second,
+		thumbnailSizes: cfg.ThumbnailSizes,
+	}
+	if u.maxSize <= 0 {
+		u.maxSize = defaultAttachmentMaxSizeMB << 20
+	}
+	if u.expiry <= 0 {
+		u.expiry = defaultAttachmentURLExpiry
+	}
+	if len(u.thumbnailSizes) == 0 {
+		u.thumbnailSizes = defaultThumbnailSizes
+	}
+	return u
+}
+
+// Output 附件在响应中的格式，包含签名的下载地址
+func (u *Uploader) Output(a *models.Attachment) AttachmentOutput {
+	return NewAttachmentOutput(u.store, a, u.expiry)
+}
+
+// Save 保存请求中的文件（multipart/form-data，字段为 file），通过 v 的数据库连接（参与请求的事务）创建附件记录
+// 文件类型根据内容识别（无法识别时按扩展名），大小和类型受配置限制；
+// thumbnails 为 true 时只接受 JPEG、PNG、GIF 图片，并按配置的尺寸生成缩略图。
+// 保存记录失败时删除已写入的文件；失败时已写出错误响应，返回 false
+func (u *Uploader) Save(c *gin.Context, v *GenericViewSet, thumbnails bool) (*models.Attachment, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, u.maxSize+multipartOverhead)
+	fh, err := c.FormFile(AttachmentFileField)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			u.tooLarge(c)
+			return nil, false
+		}
+		utils.ValidationError(c, []utils.FieldError{
+			{Field: AttachmentFileField, Code: utils.CodeRequired, Message: "缺少上传文件 " + AttachmentFileField},
+		})
+		return nil, false
+	}
+	if fh.Size > u.maxSize {
+		u.tooLarge(c)
+		return nil, false
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		utils.BadRequest(c, "读取上传文件失败")
+		return nil, false
+	}
+	defer file.Close()
+
+	contentType, err := detectContentType(file, fh)
+	if err != nil {
+		utils.BadRequest(c, "读取上传文件失败")
+		return nil, false
+	}
+	if !u.typeAllowed(contentType) || (thumbnails && !thumbnailSupported(contentType)) {
+		utils.ValidationError(c, []utils.FieldError{
+			{Field: AttachmentFileField, Code: utils.CodeInvalid, Message: "不允许上传 " + contentType + " 类型的文件"},
+		})
+		return nil, false
+	}
+
+	obj := &models.Attachment{
+		Name:        path.Base(filepath.ToSlash(fh.Filename)),
+		ContentType: contentType,
+		Size:        fh.Size,
+		Storage:     u.store.Name(),
+	}
+	// 附件属于当前租户（与 loadFiles 的过滤条件一致），与 v 是否按租户隔离无关
+	if tenantID, ok := c.Value(ContextTenantID).(uint); ok {
+		obj.TenantID = tenantID
+	}
+	if userID, ok := c.Get(ContextUserID); ok {
+		obj.UploadedBy = fmt.Sprint(userID)
+	}
+	if obj.Key, err = attachmentKey(obj, fh.Filename); err != nil {
+		utils.InternalServerError(c, "生成文件路径失败")
+		return nil, false
+	}
+
+	// 客户端已断开时不再写入
+	if utils.AbortIfCanceled(c) {
+		return nil, false
+	}
+
+	hash := sha256.New()
+	if err := u.store.Put(c.Request.Context(), obj.Key, io.TeeReader(file, hash), fh.Size, contentType); err != nil {
+		log.Printf("保存附件 %s 失败: %v", obj.Key, err)
+		utils.InternalServerError(c, "保存文件失败")
+		return nil, false
+	}
+	obj.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if thumbnails {
+		if err := u.saveThumbnails(c.Request.Context(), obj, file); err != nil {
+			u.Discard(obj)
+			if errors.Is(err, imaging.ErrTooLarge) {
+				utils.ValidationError(c, []utils.FieldError{
+					{Field: AttachmentFileField, Code: utils.CodeInvalid, Message: "图片尺寸过大"},
+				})
+				return nil, false
+			}
+			log.Printf("生成附件 %s 的缩略图失败: %v", obj.Key, err)
+			utils.ValidationError(c, []utils.FieldError{
+				{Field: AttachmentFileField, Code: utils.CodeInvalid, Message: "无法识别的图片"},
+			})
+			return nil, false
+		}
+	}
+
+	if err := v.dbFor(c).Create(obj).Error; err != nil {
+		u.Discard(obj)
+		v.dbError(c, "上传失败", err)
+		return nil, false
+	}
+	return obj, true
+}
+
+// saveThumbnails 按配置的尺寸生成缩略图并保存，记录到附件的 Thumbnails
+// PNG 和 GIF 的缩略图为 PNG（保留透明度），JPEG 的为 JPEG
+func (u *Uploader) saveThumbnails(ctx context.Context, obj *models.Attachment, file multipart.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	img, _, err := imaging.Decode(file, maxImagePixels)
+	if err != nil {
+		return err
+	}
+
+	format, contentType := thumbnailFormat(obj)
+	var sizes []string
+	for _, size := range u.thumbnailSizes {
+		if size <= 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.Thumbnail(img, size), format); err != nil {
+			return err
+		}
+		key := thumbnailKey(obj, size)
+		if err := u.store.Put(ctx, key, &buf, int64(buf.Len()), contentType); err != nil {
+			return err
+		}
+		sizes = append(sizes, strconv.Itoa(size))
+		obj.Thumbnails = strings.Join(sizes, ",")
+	}
+	return nil
+}
+
+// Discard 删除附件在存储后端中的文件（包括缩略图），失败时只记录日志
+func (u *Uploader) Discard(obj *models.Attachment) {
+	ctx := context.Background()
+	for _, size := range thumbnailSizes(obj) {
+		if err := u.store.Delete(ctx, thumbnailKey(obj, size)); err != nil {
+			log.Printf("删除附件缩略图 %s 失败: %v", thumbnailKey(obj, size), err)
+		}
+	}
+	if err := u.store.Delete(ctx, obj.Key); err != nil {
+		log.Printf("删除附件文件 %s 失败: %v", obj.Key, err)
+	}
+}
+
+// tooLarge 文件超过大小限制，返回 413
+func (u *Uploader) tooLarge(c *gin.Context) {
+	utils.ErrorWithStatus(c, http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("文件不能超过 %d MB", u.maxSize>>20))
+}
+
+// typeAllowed 文件类型是否在 allowedTypes 中，支持 image/* 形式的通配，未配置时不限制
+func (u *Uploader) typeAllowed(contentType string) bool {
+	if len(u.allowedTypes) == 0 {
+		return true
+	}
+	for _, allowed := range u.allowedTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(contentType, prefix+"/") {
+				return true
+			}
+		} else if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectContentType 根据文件开头的内容识别类型（不含参数），识别结果为通用类型时按扩展名识别
+// 读取后将文件重新定位到开头
+func detectContentType(file multipart.File, fh *multipart.FileHeader) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if contentType == "application/octet-stream" || contentType == "text/plain" {
+		if byExt, _, err := mime.ParseMediaType(mime.TypeByExtension(filepath.Ext(fh.Filename))); err == nil && byExt != "" {
+			// 按扩展名识别为文本类型时要求内容也是文本，避免二进制文件伪装成 .txt/.csv 等
+			if contentType == "text/plain" || !strings.HasPrefix(byExt, "text/") {
+				contentType = byExt
+			}
+		}
+	}
+	return contentType, nil
+}
+
+// attachmentExt 保留在存储路径中的扩展名
+var attachmentExt = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+// attachmentKey 生成附件在存储后端中的路径：[tenants/<租户>/]年/月/日/<随机值><扩展名>
+func attachmentKey(obj *models.Attachment, filename string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	key := time.Now().UTC().Format("2006/01/02") + "/" + hex.EncodeToString(random)
+	if ext := strings.ToLower(filepath.Ext(filename)); attachmentExt.MatchString(ext) {
+		key += ext
+	}
+	if obj.TenantID != 0 {
+		key = fmt.Sprintf("tenants/%d/%s", obj.TenantID, key)
+	}
+	return key, nil
+}
+
+// thumbnailSupported 是否可以为该类型的文件生成缩略图
+func thumbnailSupported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// thumbnailFormat 缩略图的编码格式和类型
+func thumbnailFormat(obj *models.Attachment) (string, string) {
+	if obj.ContentType == "image/jpeg" {
+		return "jpeg", "image/jpeg"
+	}
+	return "png", "image/png"
+}
+
+// thumbnailKey 缩略图在存储后端中的路径：原文件路径（去掉扩展名）_<尺寸>.jpg/.png
+func thumbnailKey(obj *models.Attachment, size int) string {
+	format, _ := thumbnailFormat(obj)
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	return strings.TrimSuffix(obj.Key, path.Ext(obj.Key)) + "_" + strconv.Itoa(size) + ext
+}
+
+// thumbnailSizes 附件已生成的缩略图尺寸
+func thumbnailSizes(obj *models.Attachment) []int {
+	var sizes []int
+	for _, s := range strings.Split(obj.Thumbnails, ",") {
+		if size, err := strconv.Atoi(s); err == nil && size > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	return sizes
+}
@@ -1,69 +1,210 @@
 package viewset
 
 import (
+	"context"
 	"fmt"
+	"go-viewset/internal/auth"
+	"go-viewset/internal/events"
+	"go-viewset/internal/mailer"
 	"go-viewset/internal/models"
 	"go-viewset/internal/utils"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// selfOrAdmin 需要登录，只允许用户操作自己的账号，管理员不受限制
+var selfOrAdmin Permission = selfOrAdminPermission{}
+
+// selfOrAdminPermission 见 selfOrAdmin
+type selfOrAdminPermission struct{}
+
+// HasPermission 实现 Permission
+func (selfOrAdminPermission) HasPermission(c *gin.Context, action string) bool {
+	return isAuthenticated(c)
+}
+
+// HasObjectPermission 实现 Permission，obj 为 *models.User
+func (selfOrAdminPermission) HasObjectPermission(c *gin.Context, action string, obj interface{}) bool {
+	if isAdmin(c) {
+		return true
+	}
+	user, ok := obj.(*models.User)
+	if !ok {
+		return false
+	}
+	userID, ok := c.Get(ContextUserID)
+	return ok && fmt.Sprint(userID) == fmt.Sprint(user.ID)
+}
+
 // UserViewSet 用户 ViewSet
 // 通过嵌入 GenericViewSet 快速实现 CRUD
 type UserViewSet struct {
 	*GenericViewSet
+
+	// stats 预计算的用户统计信息
+	stats *StatsRefresher
+
+	// avatars 头像的上传，EnableAvatar 开启后注册 POST /users/:id/avatar
+	avatars *Uploader
+
+	// ResetTokenTTL 重置密码令牌的有效期，默认 30 分钟
+	ResetTokenTTL time.Duration
+
+	// Mailer 发送重置密码邮件，为 nil 时重置密码接口返回 503
+	Mailer *mailer.Mailer
+}
+
+// ChangePasswordRequest 修改密码的请求体
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 // NewUserViewSet 创建用户 ViewSet
 func NewUserViewSet(db *gorm.DB) *UserViewSet {
-	return &UserViewSet{
+	v := &UserViewSet{
 		GenericViewSet: NewGenericViewSet(db, &models.User{}),
+		ResetTokenTTL:  defaultResetTokenTTL,
+	}
+
+	// 用户列表访问频繁，缓存总数避免每次都执行 COUNT(*)
+	v.EnableCountCache(30 * time.Second)
+
+	// 缓存未命中时 COUNT 与数据查询并行执行
+	v.ConcurrentCount = true
+
+	// 邮箱不能重复（创建和更新时检查）
+	v.Validators = append(v.Validators, UniqueValidator(db, &models.User{}, "email"))
+
+	// 只允许按这些字段过滤
+	v.FilterFields = []string{"id", "name", "status", "age", "email", "phone", "created_at", "deleted_at"}
+
+	// 只允许按这些字段排序，其他字段返回 400
+	v.OrderingFields = []string{"id", "name", "status", "age", "email", "created_at"}
+
+	// GET /users/aggregate 按状态和年龄分组统计
+	v.EnableAggregate = true
+	v.AggregateFields = []string{"status", "age"}
+
+	// GET /users/stream 推送用户的变更（Server-Sent Events）
+	v.EnableStream = true
+
+	// ?search= 对 name、email、phone 进行模糊搜索
+	v.SearchFields = []string{"name", "email", "phone"}
+
+	// 统计查询较重，单独限流；修改密码限流防止猜测旧密码
+	v.Throttles = map[string]string{
+		"stats":           "10/minute",
+		"change_password": "5/minute",
 	}
+
+	// 重置密码会发送邮件，与其他发送密码重置邮件的接口共享 password_reset 限额
+	v.ThrottleClasses = map[string][]Throttle{
+		"reset_password": {ScopedRateThrottle("password_reset", "3/hour")},
+	}
+
+	// 重置密码只允许管理员调用，用户自己找回密码使用 POST /auth/forgot_password；
+	// 修改密码和上传头像需要登录，只能修改自己的账号（管理员不受限制）
+	v.Permissions = map[string]Permission{
+		"reset_password":  IsAdmin,
+		"change_password": selfOrAdmin,
+		"avatar":          selfOrAdmin,
+	}
+
+	// 统计信息每分钟刷新一次，用户数据变化后在下次请求时刷新
+	v.stats = NewStatsRefresher(v.computeStats, time.Minute)
+	v.Events.Subscribe(func(e events.Event) {
+		if e.Resource == v.table {
+			v.stats.MarkDirty()
+		}
+	})
+
+	return v
 }
 
 // RegisterRoutes 注册路由
 // 除了标准的 CRUD 路由外，还注册自定义 action
 func (v *UserViewSet) RegisterRoutes(group *gin.RouterGroup) {
 	// 注册标准 RESTful 路由（使用子类的方法）
-	group.GET("/", v.List) // 使用覆盖后的 List 方法
-	group.POST("/", v.Create) // 使用覆盖后的 Create 方法
+	ListMixin(group, v.GenericViewSet, v)                 // GET /、HEAD /、GET /aggregate 和 GET /stream
+	RetrieveMixin(group, v.GenericViewSet, v)             // GET /:id
+	group.POST("/", v.HandlerFor(ActionCreate, v.Create)) // 使用覆盖后的 Create 方法
+	group.OPTIONS("/", v.Metadata)
 
-	// 注册自定义 action
-	// POST /users/:id/activate - 激活用户
-	v.RegisterAction(group, "POST", "/:id/activate", v.Activate)
+	// 注册自定义 action（见 Actions）
+	v.RegisterActions(group, v)
+}
+
+// Actions 声明自定义 action
+func (v *UserViewSet) Actions() []Action {
+	actions := []Action{
+		// POST /users/:id/activate - 激活用户
+		DetailAction(v.GenericViewSet, "POST", "activate", v.Activate),
+
+		// POST /users/:id/deactivate - 停用用户
+		DetailAction(v.GenericViewSet, "POST", "deactivate", v.Deactivate),
 
-	// POST /users/:id/deactivate - 停用用户
-	v.RegisterAction(group, "POST", "/:id/deactivate", v.Deactivate)
+		// POST /users/:id/change_password - 修改密码（校验旧密码）
+		DetailAction(v.GenericViewSet, "POST", "change_password", v.ChangePassword),
 
-	// POST /users/:id/reset_password - 重置密码
-	v.RegisterAction(group, "POST", "/:id/reset_password", v.ResetPassword)
+		// POST /users/:id/reset_password - 重置密码
+		DetailAction(v.GenericViewSet, "POST", "reset_password", v.ResetPassword),
 
-	// GET /users/stats - 获取统计信息（不需要 ID 的 action）
-	v.RegisterAction(group, "GET", "/stats", v.GetStats)
+		// GET /users/stats - 获取统计信息（不需要 ID 的 action）
+		ListAction("GET", "stats", v.GetStats),
+	}
+
+	// POST /users/:id/avatar - 上传头像
+	if v.avatars != nil {
+		actions = append(actions, DetailAction(v.GenericViewSet, "POST", "avatar", v.Avatar))
+	}
+	return actions
 }
 
-// Activate 激活用户
-// POST /users/:id/activate
-func (v *UserViewSet) Activate(c *gin.Context) {
-	id := c.Param("id")
+// EnableAvatar 开启头像上传，需要在注册路由之前调用
+// 头像作为附件保存（见 Uploader），用户的 avatar_id 指向它
+func (v *UserViewSet) EnableAvatar(uploader *Uploader) {
+	v.avatars = uploader
+}
 
-	// 使用辅助方法获取对象
-	obj, ok := v.GetObjectOr404(c, id)
+// Avatar 上传头像：保存图片并生成缩略图，设置为用户的头像
+// 返回用户，其中 avatar 包含原图和各尺寸缩略图的下载地址；之前的头像仍保留在附件中。
+// 需要登录，只能设置自己的头像（管理员不受限制）
+// POST /users/:id/avatar（multipart/form-data，文件字段为 file）
+func (v *UserViewSet) Avatar(c *gin.Context, user *models.User) {
+	attachment, ok := v.avatars.Save(c, v.GenericViewSet, true)
 	if !ok {
 		return
 	}
 
-	user := obj.(*models.User)
+	user.AvatarID = &attachment.ID
+	if err := v.dbFor(c).Model(user).Update("avatar_id", attachment.ID).Error; err != nil {
+		v.avatars.Discard(attachment)
+		v.dbError(c, "设置头像失败", err)
+		return
+	}
+
+	v.publish(c, events.Updated, user)
 
+	v.Respond(c, v.serialize(c, ActionRetrieve, user))
+}
+
+// Activate 激活用户
+// POST /users/:id/activate
+func (v *UserViewSet) Activate(c *gin.Context, user *models.User) {
 	// 更新状态
 	user.Status = "active"
-	if err := v.DB.Save(user).Error; err != nil {
-		utils.InternalServerError(c, fmt.Sprintf("激活失败: %v", err))
+	if err := v.dbFor(c).Save(user).Error; err != nil {
+		v.dbError(c, "激活失败", err)
 		return
 	}
 
-	utils.Success(c, gin.H{
+	v.publish(c, events.Updated, user)
+
+	v.Respond(c, gin.H{
 		"message": "用户已激活",
 		"user":    user,
 	})
@@ -71,155 +212,172 @@ func (v *UserViewSet) Activate(c *gin.Context) {
 
 // Deactivate 停用用户
 // POST /users/:id/deactivate
-func (v *UserViewSet) Deactivate(c *gin.Context) {
-	id := c.Param("id")
-
-	obj, ok := v.GetObjectOr404(c, id)
-	if !ok {
-		return
-	}
-
-	user := obj.(*models.User)
-
+func (v *UserViewSet) Deactivate(c *gin.Context, user *models.User) {
 	// 更新状态
 	user.Status = "inactive"
-	if err := v.DB.Save(user).Error; err != nil {
-		utils.InternalServerError(c, fmt.Sprintf("停用失败: %v", err))
+	if err := v.dbFor(c).Save(user).Error; err != nil {
+		v.dbError(c, "停用失败", err)
 		return
 	}
 
-	utils.Success(c, gin.H{
+	v.publish(c, events.Updated, user)
+
+	v.Respond(c, gin.H{
 		"message": "用户已停用",
 		"user":    user,
 	})
 }
 
-// ResetPassword 重置密码
-// POST /users/:id/reset_password
-func (v *UserViewSet) ResetPassword(c *gin.Context) {
-	id := c.Param("id")
-
-	obj, ok := v.GetObjectOr404(c, id)
-	if !ok {
+// ChangePassword 修改密码，需要提供旧密码；修改后该用户未使用的重置密码令牌失效
+// 需要登录，只能修改自己的密码（管理员可以修改任何用户的密码）
+// POST /users/:id/change_password
+func (v *UserViewSet) ChangePassword(c *gin.Context, user *models.User) {
+	var req ChangePasswordRequest
+	if err := bindJSON(c, &req); err != nil {
+		utils.ValidationError(c, utils.BindingErrors(err))
+		return
+	}
+	if !auth.CheckPassword(user.PasswordHash, req.OldPassword) {
+		utils.ValidationError(c, []utils.FieldError{
+			{Field: "old_password", Code: utils.CodeInvalid, Message: "旧密码不正确"},
+		})
+		return
+	}
+	if errs := passwordErrors("new_password", req.NewPassword); len(errs) > 0 {
+		utils.ValidationError(c, errs)
 		return
 	}
 
-	user := obj.(*models.User)
+	if err := setPassword(v.dbFor(c), user, req.NewPassword); err != nil {
+		v.dbError(c, "修改密码失败", err)
+		return
+	}
 
-	// 这里只是示例，实际项目中应该有密码重置逻辑
-	// 例如发送邮件、生成临时密码等
+	v.publish(c, events.Updated, user)
 
-	utils.Success(c, gin.H{
-		"message": "密码重置邮件已发送",
+	v.Respond(c, gin.H{
+		"message": "密码已修改",
 		"user_id": user.ID,
-		"email":   user.Email,
+	})
+}
+
+// ResetPassword 重置密码：生成有效期为 ResetTokenTTL 的一次性令牌，之前未使用的令牌失效
+// 数据库中只保存令牌的哈希，令牌只通过邮件（模板 reset_password）发送给用户，不在响应中返回；
+// 未配置 Mailer 时返回 503。只有管理员可以为其他用户重置密码
+// POST /users/:id/reset_password
+func (v *UserViewSet) ResetPassword(c *gin.Context, user *models.User) {
+	if v.Mailer == nil {
+		utils.ErrorWithStatus(c, http.StatusServiceUnavailable, http.StatusServiceUnavailable, "未配置邮件发送，无法重置密码")
+		return
+	}
+
+	// 同一用户的重置操作在多个实例之间串行执行，避免重复发送邮件
+	v.WithLock(c, v.LockKey(c.Param("id")), func() {
+		token, expiresAt, err := issueResetToken(v.dbFor(c), user.ID, v.ResetTokenTTL)
+		if err != nil {
+			v.dbError(c, "生成重置密码令牌失败", err)
+			return
+		}
+
+		// 令牌保存成功（事务提交）后再发送邮件
+		afterCommit(c, func() { sendResetEmail(v.Mailer, user, token, v.ResetTokenTTL) })
+		v.Respond(c, gin.H{
+			"message":    "密码重置邮件已发送",
+			"user_id":    user.ID,
+			"email":      user.Email,
+			"expires_at": expiresAt,
+		})
 	})
 }
 
 // GetStats 获取用户统计信息
+// 返回预计算的结果，computed_at 为结果的计算时间
 // GET /users/stats
 func (v *UserViewSet) GetStats(c *gin.Context) {
-	var total int64
-	var activeCount int64
-	var inactiveCount int64
-
-	// 统计总数
-	v.DB.Model(&models.User{}).Count(&total)
-
-	// 统计活跃用户
-	v.DB.Model(&models.User{}).Where("status = ?", "active").Count(&activeCount)
-
-	// 统计非活跃用户
-	v.DB.Model(&models.User{}).Where("status = ?", "inactive").Count(&inactiveCount)
+	value, computedAt, err := v.stats.Snapshot(c.Request.Context())
+	if err != nil {
+		v.dbError(c, "统计失败", err)
+		return
+	}
 
-	utils.Success(c, gin.H{
-		"total":    total,
-		"active":   activeCount,
-		"inactive": inactiveCount,
+	stats := value.(map[string]int64)
+	v.Respond(c, gin.H{
+		"total":         stats["total"],
+		"active":        stats["active"],
+		"inactive":      stats["inactive"],
+		"computed_at":   computedAt,
+		"stale_seconds": int64(time.Since(computedAt).Seconds()),
 	})
 }
 
-// 可以覆盖父类的方法来自定义行为
-// 例如：在创建用户前进行额外的验证
-
-// List 覆盖列表方法，添加 keyword 搜索功能
-// 支持通过 ?keyword=xxx 对 name、email、phone 进行模糊搜索
-func (v *UserViewSet) List(c *gin.Context) {
-	// 创建结果切片
-	var users []models.User
-
-	// 获取分页参数
-	paginationParams := utils.GetPaginationParams(c)
-
-	// 获取过滤参数
-	filterParams := utils.GetFilterParams(c, "keyword") // 排除 keyword，因为我们要单独处理
-
-	// 构建查询
-	query := v.DB.Model(&models.User{})
-
-	// 处理 keyword 搜索（多字段模糊匹配）
-	keyword := c.Query("keyword")
-	if keyword != "" {
-		// 使用 OR 条件对多个字段进行模糊搜索
-		query = query.Where(
-			"name LIKE ? OR email LIKE ? OR phone LIKE ?",
-			"%"+keyword+"%",
-			"%"+keyword+"%",
-			"%"+keyword+"%",
-		)
+// computeStats 统计用户数量
+// 一次 GROUP BY 查询得到各状态的数量，总数由各状态相加
+func (v *UserViewSet) computeStats(ctx context.Context) (interface{}, error) {
+	var rows []struct {
+		Status string
+		Count  int64
 	}
-
-	// 应用其他过滤条件（如 status、age 等）
-	query = utils.ApplyFilters(query, filterParams)
-
-	// 获取总数（在应用分页之前）
-	var total int64
-	query.Count(&total)
-
-	// 应用分页
-	query = utils.ApplyPagination(query, paginationParams)
-
-	// 执行查询
-	if err := query.Find(&users).Error; err != nil {
-		utils.InternalServerError(c, fmt.Sprintf("查询失败: %v", err))
-		return
+	if err := v.DB.WithContext(ctx).Model(&models.User{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
 	}
 
-	// 构建分页信息
-	pagination := utils.BuildPagination(paginationParams, total)
-
-	// 返回结果
-	utils.SuccessWithPagination(c, users, pagination)
+	stats := map[string]int64{"total": 0, "active": 0, "inactive": 0}
+	for _, row := range rows {
+		stats[row.Status] += row.Count
+		stats["total"] += row.Count
+	}
+	return stats, nil
 }
 
+// 可以覆盖父类的方法来自定义行为
+// 例如：在创建用户前进行额外的验证
+
 // Create 覆盖创建方法，添加自定义逻辑
 func (v *UserViewSet) Create(c *gin.Context) {
 	var user models.User
 
-	// 绑定请求数据
-	if err := c.ShouldBindJSON(&user); err != nil {
-		utils.BadRequest(c, fmt.Sprintf("请求数据格式错误: %v", err))
+	// 绑定请求数据，密码必填
+	var errs []utils.FieldError
+	if err := v.bindInput(c, ActionCreate, &user); err != nil {
+		errs = utils.BindingErrors(err)
+	}
+	if !hasParseError(errs) {
+		errs = append(errs, passwordErrors("password", user.Password)...)
+	}
+	if len(errs) > 0 {
+		utils.ValidationError(c, errs)
 		return
 	}
 
-	// 自定义验证：检查邮箱是否已存在
-	var count int64
-	v.DB.Model(&models.User{}).Where("email = ?", user.Email).Count(&count)
-	if count > 0 {
-		utils.BadRequest(c, "该邮箱已被注册")
+	// 只保存密码的哈希
+	hash, err := auth.HashPassword(user.Password)
+	if err != nil {
+		utils.InternalServerError(c, "密码处理失败")
 		return
 	}
+	user.PasswordHash = hash
+	user.Password = ""
 
 	// 设置默认状态
 	if user.Status == "" {
 		user.Status = "inactive"
 	}
 
+	if !v.performCreate(c, &user) {
+		return
+	}
+
 	// 创建用户
-	if err := v.DB.Create(&user).Error; err != nil {
-		utils.InternalServerError(c, fmt.Sprintf("创建失败: %v", err))
+	if err := v.dbFor(c).Create(&user).Error; err != nil {
+		v.dbError(c, "创建失败", err)
 		return
 	}
 
-	utils.Success(c, user)
+	v.afterCreate(c, &user)
+	v.publish(c, events.Created, &user)
+
+	v.Respond(c, v.serialize(c, ActionCreate, &user))
 }
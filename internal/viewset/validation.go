@@ -0,0 +1,145 @@
+package viewset
+
+import (
+	"errors"
+	"fmt"
+	"go-viewset/internal/meta"
+	"go-viewset/internal/utils"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ValidatorFunc ViewSet 上的自定义校验函数，在 binding 规则和模型的 Validate 之后执行
+// action 为 ActionCreate、ActionUpdate、ActionPartialUpdate 或 ActionBulkCreate，
+// obj 为解码后的模型指针（部分更新时只有请求中出现的字段有值）。
+// 返回 utils.ValidationErrors 时按字段输出，其他错误作为 non_field_errors 输出
+type ValidatorFunc func(c *gin.Context, action string, obj interface{}) error
+
+// validate 收集 obj 的全部校验错误
+// bindErr 为绑定时 binding 规则的校验结果；无法解码（如 JSON 格式错误）的错误直接返回，
+// 否则继续执行模型的 Validate 和 Validators，所有字段错误合并为 utils.ValidationErrors 返回
+func (v *GenericViewSet) validate(c *gin.Context, action string, obj interface{}, bindErr error) error {
+	var errs utils.ValidationErrors
+	if bindErr != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(bindErr, &fieldErrs) {
+			return bindErr
+		}
+		errs = append(errs, utils.BindingErrors(bindErr)...)
+	}
+
+	if m, ok := obj.(Validatable); ok {
+		if err := m.Validate(); err != nil {
+			errs = append(errs, utils.BindingErrors(err)...)
+		}
+	}
+	for _, fn := range v.Validators {
+		if err := fn(c, action, obj); err != nil {
+			errs = append(errs, utils.BindingErrors(err)...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validatePartial 部分更新的校验
+// 模型的 Validate 通常要求完整的对象，部分更新时只执行 binding 规则和 Validators
+func (v *GenericViewSet) validatePartial(c *gin.Context, obj interface{}, bindErr error) error {
+	var errs utils.ValidationErrors
+	if bindErr != nil {
+		errs = append(errs, utils.BindingErrors(bindErr)...)
+	}
+	for _, fn := range v.Validators {
+		if err := fn(c, ActionPartialUpdate, obj); err != nil {
+			errs = append(errs, utils.BindingErrors(err)...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// UniqueValidator 检查 fields 的取值（多个字段时为组合）在 model 对应的表中唯一
+// fields 为 JSON 字段名或列名。更新时排除 URL 中 :id 对应的当前记录，
+// 请求中没有提供（零值）的字段取当前记录的值；字段为 nil 指针时不检查。
+// 单个字段重复时错误归属该字段，多个字段时作为 non_field_errors 输出，例如：
+//
+//	v.Validators = append(v.Validators, UniqueValidator(db, &models.User{}, "email"))
+func UniqueValidator(db *gorm.DB, model interface{}, fields ...string) ValidatorFunc {
+	m, err := meta.Of(db, model)
+	if err != nil {
+		panic(fmt.Sprintf("UniqueValidator: 无法解析模型元数据: %v", err))
+	}
+
+	columns := make([]*schema.Field, len(fields))
+	names := make([]string, len(fields))
+	for i, name := range fields {
+		f, ok := m.Lookup(name)
+		if !ok {
+			panic(fmt.Sprintf("UniqueValidator: %s 上不存在字段 %s", m.Name, name))
+		}
+		columns[i] = m.Schema.LookUpField(f.Column)
+		names[i] = f.JSONName
+	}
+	pk := m.Schema.PrioritizedPrimaryField
+
+	return func(c *gin.Context, action string, obj interface{}) error {
+		ctx := c.Request.Context()
+		query, ok := TxFrom(c)
+		if !ok {
+			query = db
+		}
+		query = query.WithContext(ctx).Model(model)
+
+		elem := reflect.ValueOf(obj).Elem()
+		updating := action == ActionUpdate || action == ActionPartialUpdate
+		id := c.Param("id")
+
+		// 更新时未提供的字段取当前记录的值
+		var current reflect.Value
+		if updating && id != "" && pk != nil {
+			existing := reflect.New(m.Type).Interface()
+			err := query.Session(&gorm.Session{}).Where(m.Table+"."+pk.DBName+" = ?", id).First(existing).Error
+			if err == nil {
+				current = reflect.ValueOf(existing).Elem()
+			}
+		}
+
+		for _, sf := range columns {
+			value, zero := sf.ValueOf(ctx, elem)
+			if zero && current.IsValid() {
+				value, zero = sf.ValueOf(ctx, current)
+			}
+			if rv := reflect.ValueOf(value); value == nil || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+				return nil
+			}
+			query = query.Where(m.Table+"."+sf.DBName+" = ?", value)
+		}
+		if updating && id != "" && pk != nil {
+			query = query.Where(m.Table+"."+pk.DBName+" <> ?", id)
+		}
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+
+		if len(names) == 1 {
+			return utils.ValidationErrors{{Field: names[0], Code: utils.CodeUnique, Message: names[0] + " 已存在"}}
+		}
+		return utils.ValidationErrors{{Code: utils.CodeUnique, Message: strings.Join(names, ", ") + " 的组合已存在"}}
+	}
+}
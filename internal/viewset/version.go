@@ -0,0 +1,14 @@
+package viewset
+
+import "github.com/gin-gonic/gin"
+
+// ContextAPIVersion 当前请求的 API 版本（例如 "v2"），由版本化路由组的中间件写入
+const ContextAPIVersion = "api_version"
+
+// APIVersion 返回当前请求的 API 版本，不在版本化路由组中时返回空字符串
+// 同一个 ViewSet 注册在多个版本下时可以按版本调整行为，例如：
+//
+//	if viewset.APIVersion(c) == "v1" { ... }
+func APIVersion(c *gin.Context) string {
+	return c.GetString(ContextAPIVersion)
+}
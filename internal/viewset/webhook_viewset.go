@@ -0,0 +1,125 @@
+package viewset
+
+import (
+	"errors"
+	"go-viewset/internal/models"
+	"go-viewset/internal/utils"
+	"go-viewset/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxBulkReplay 一次批量重新投递的最大数量，避免占满投递队列
+const maxBulkReplay = 100
+
+// WebhookDeliveryViewSet Webhook 投递记录 ViewSet，只读，失败的投递可以重新投递或清除
+type WebhookDeliveryViewSet struct {
+	*GenericViewSet
+	dispatcher *webhook.Dispatcher
+}
+
+// NewWebhookDeliveryViewSet 创建 Webhook 投递记录 ViewSet
+func NewWebhookDeliveryViewSet(db *gorm.DB, dispatcher *webhook.Dispatcher) *WebhookDeliveryViewSet {
+	v := &WebhookDeliveryViewSet{
+		GenericViewSet: NewGenericViewSet(db, &models.WebhookDelivery{}),
+		dispatcher:     dispatcher,
+	}
+
+	// 按事件、目标、状态和时间过滤，例如 ?status=failed&event=users.created
+	v.FilterFields = []string{"event", "target", "object_id", "status", "created_at"}
+
+	// 投递记录包含事件内容，只允许管理员查看和操作
+	v.PermissionClasses = []Permission{RequireRole("admin")}
+
+	return v
+}
+
+// RegisterRoutes 注册路由
+func (v *WebhookDeliveryViewSet) RegisterRoutes(group *gin.RouterGroup) {
+	v.RegisterMixins(group, v, ReadOnlyMixins...)
+}
+
+// Actions 声明自定义 action
+func (v *WebhookDeliveryViewSet) Actions() []Action {
+	return []Action{
+		// POST /admin/webhook-deliveries/:id/replay - 重新投递
+		DetailAction(v.GenericViewSet, "POST", "replay", v.Replay),
+
+		// POST /admin/webhook-deliveries/replay_failed - 批量重新投递失败的投递
+		ListAction("POST", "replay_failed", v.ReplayFailed),
+
+		// DELETE /admin/webhook-deliveries/purge_failed - 清除失败的投递（死信）
+		ListAction("DELETE", "purge_failed", v.PurgeFailed),
+	}
+}
+
+// Replay 重新投递，尝试次数重新计算
+// POST /admin/webhook-deliveries/:id/replay
+func (v *WebhookDeliveryViewSet) Replay(c *gin.Context, delivery *models.WebhookDelivery) {
+	replayed, err := v.dispatcher.Replay(c.Request.Context(), delivery.ID)
+	switch {
+	case errors.Is(err, webhook.ErrDeliveryPending):
+		utils.Conflict(c, err.Error())
+	case errors.Is(err, webhook.ErrUnknownTarget):
+		utils.BadRequest(c, err.Error())
+	case err != nil:
+		v.dbError(c, "重新投递失败", err)
+	default:
+		v.Respond(c, replayed)
+	}
+}
+
+// ReplayFailed 批量重新投递失败的投递，查询参数与列表的过滤条件相同，例如 ?event=users.created&target=...
+// 每次最多 maxBulkReplay 条（按 ID 顺序），has_more 为 true 时还有剩余，再次调用即可
+// POST /admin/webhook-deliveries/replay_failed
+func (v *WebhookDeliveryViewSet) ReplayFailed(c *gin.Context) {
+	filterParams, ok := v.filterParams(c)
+	if !ok {
+		return
+	}
+	defer utils.ReleaseFilterParams(filterParams)
+
+	var ids []uint
+	if err := v.listQuery(c, filterParams)().
+		Where("status = ?", models.DeliveryFailed).
+		Order("id").Limit(maxBulkReplay+1).
+		Pluck("id", &ids).Error; err != nil {
+		v.dbError(c, "查询失败的投递失败", err)
+		return
+	}
+	hasMore := len(ids) > maxBulkReplay
+	if hasMore {
+		ids = ids[:maxBulkReplay]
+	}
+
+	replayed, err := v.dispatcher.ReplayFailed(c.Request.Context(), ids)
+	if err != nil {
+		v.dbError(c, "重新投递失败", err)
+		return
+	}
+	v.Respond(c, gin.H{
+		"replayed": replayed,
+		"skipped":  len(ids) - replayed,
+		"has_more": hasMore,
+	})
+}
+
+// PurgeFailed 删除失败的投递，查询参数与列表的过滤条件相同，例如 ?created_at__lt=2024-01-01
+// DELETE /admin/webhook-deliveries/purge_failed
+func (v *WebhookDeliveryViewSet) PurgeFailed(c *gin.Context) {
+	filterParams, ok := v.filterParams(c)
+	if !ok {
+		return
+	}
+	defer utils.ReleaseFilterParams(filterParams)
+
+	result := v.listQuery(c, filterParams)().
+		Where("status = ?", models.DeliveryFailed).
+		Delete(&models.WebhookDelivery{})
+	if result.Error != nil {
+		v.dbError(c, "清除失败的投递失败", result.Error)
+		return
+	}
+	v.Respond(c, gin.H{"deleted": result.RowsAffected})
+}
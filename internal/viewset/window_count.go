@@ -0,0 +1,95 @@
+package viewset
+
+import (
+	"go-viewset/internal/utils"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// windowTotalColumn 窗口函数统计出的总数所在的列
+const windowTotalColumn = "window_total"
+
+// windowCountDialects 支持 COUNT(*) OVER() 的数据库
+var windowCountDialects = map[string]bool{
+	"mysql":    true, // MySQL 8.0+
+	"postgres": true,
+	"sqlite":   true, // SQLite 3.25+
+}
+
+// newWindowRowType 创建用于接收窗口查询结果的行类型
+// 相当于 struct { Row T `gorm:"embedded"`; WindowTotal int64 }
+func newWindowRowType(modelType reflect.Type) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{
+		{Name: "Row", Type: modelType, Tag: `gorm:"embedded"`},
+		{Name: "WindowTotal", Type: reflect.TypeOf(int64(0)), Tag: `gorm:"column:` + windowTotalColumn + `"`},
+	})
+}
+
+// useWindowCount 判断本次列表查询是否使用窗口函数统计总数
+func (v *GenericViewSet) useWindowCount(c *gin.Context, signature string) bool {
+	if !v.WindowCount || len(v.relationsFor(c)) > 0 || !windowCountDialects[v.requestDB(c).Dialector.Name()] {
+		return false
+	}
+	if v.countMode(c) != utils.CountExact {
+		return false
+	}
+	_, cached := v.cachedCount(signature)
+	return !cached
+}
+
+// listWithWindowCount 通过 COUNT(*) OVER() 在一次查询中同时取回当前页数据和总数
+// 当前页没有数据时（例如页码超出范围）无法得到总数，退回单独的 COUNT 查询
+func (v *GenericViewSet) listWithWindowCount(c *gin.Context, newQuery func() *gorm.DB, params *utils.PaginationParams, signature, cacheKey string) {
+	rows := reflect.New(reflect.SliceOf(v.windowRowType))
+	columns := v.table + ".*"
+	if selected := v.selectColumns(c); selected != nil {
+		columns = strings.Join(selected, ", ")
+	}
+	query := utils.ApplyPagination(newQuery(), params).
+		Select(columns + ", COUNT(*) OVER() AS " + windowTotalColumn)
+	if err := query.Find(rows.Interface()).Error; err != nil {
+		v.dbError(c, "查询失败", err)
+		return
+	}
+
+	if utils.AbortIfCanceled(c) {
+		return
+	}
+
+	// 取出模型对象，输出格式与普通列表一致
+	results := v.acquireSlice()
+	defer v.releaseSlice(results)
+
+	slice := reflect.ValueOf(results).Elem()
+	rowSlice := rows.Elem()
+	for i := 0; i < rowSlice.Len(); i++ {
+		slice.Set(reflect.Append(slice, rowSlice.Index(i).Field(0).Addr()))
+	}
+
+	var total int64
+	if rowSlice.Len() > 0 {
+		total = rowSlice.Index(0).Field(1).Int()
+		if v.CountCache != nil && v.querysetCacheable() {
+			v.CountCache.Set(v.table, signature, total)
+		}
+	} else {
+		var err error
+		if total, err = v.countTotal(newQuery(), signature); err != nil {
+			v.dbError(c, "查询失败", err)
+			return
+		}
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	pagination := utils.BuildPagination(params, total)
+	defer utils.ReleasePagination(pagination)
+
+	data := v.pickFields(c, v.serializeList(c, results))
+	v.saveToCache(c, cacheKey, data, pagination)
+
+	v.RespondWithPagination(c, data, pagination)
+}
@@ -0,0 +1,388 @@
+// Package webhook 将模型变更事件以 HTTP POST 发送到配置的目标
+//
+// 每个事件发送到每个订阅了它的目标时先在 webhook_deliveries 表中记录一条投递，再由后台 worker 发送；
+// 失败时按指数退避重试，重试次数用尽后记为 failed，可以通过 Replay 重新投递。
+// 服务重启后，状态仍为 pending 的投递会重新进入队列
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-viewset/internal/config"
+	"go-viewset/internal/events"
+	"go-viewset/internal/models"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 默认配置
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	defaultMaxBackoff  = 5 * time.Minute
+	defaultTimeout     = 10 * time.Second
+	defaultWorkers     = 4
+	defaultQueueSize   = 1000
+)
+
+// 请求头
+const (
+	HeaderEvent     = "X-Webhook-Event"     // 事件名称，例如 users.created
+	HeaderDelivery  = "X-Webhook-Delivery"  // 投递记录 ID，重试和重新投递时不变
+	HeaderTimestamp = "X-Webhook-Timestamp" // 发送时间（Unix 秒）
+	HeaderSignature = "X-Webhook-Signature" // sha256=HMAC-SHA256(secret, 时间戳 + "." + 请求体) 的十六进制
+)
+
+// ErrDeliveryPending 投递仍在进行中，不能重新投递
+var ErrDeliveryPending = errors.New("投递仍在进行中")
+
+// ErrUnknownTarget 投递的目标已不在配置中
+var ErrUnknownTarget = errors.New("目标已不在配置中")
+
+// Dispatcher Webhook 投递
+type Dispatcher struct {
+	db      *gorm.DB
+	targets []config.WebhookTarget
+	client  *http.Client
+
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	workers     int
+
+	queue chan uint
+	stop  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// New 创建 Webhook 投递，投递记录保存在 db 中；调用 Start 后开始发送
+func New(db *gorm.DB, cfg config.WebhookConfig) *Dispatcher {
+	d := &Dispatcher{
+		db:          db,
+		targets:     cfg.Targets,
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     time.Duration(cfg.BackoffSeconds) * time.Second,
+		maxBackoff:  time.Duration(cfg.MaxBackoffSecs) * time.Second,
+		workers:     cfg.Workers,
+		stop:        make(chan struct{}),
+	}
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = defaultMaxAttempts
+	}
+	if d.backoff <= 0 {
+		d.backoff = defaultBackoff
+	}
+	if d.maxBackoff <= 0 {
+		d.maxBackoff = defaultMaxBackoff
+	}
+	if d.workers <= 0 {
+		d.workers = defaultWorkers
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	d.client = &http.Client{Timeout: timeout}
+
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	d.queue = make(chan uint, queueSize)
+	return d
+}
+
+// Start 启动投递 worker，并将上次未完成（pending）的投递重新放入队列
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+
+	var ids []uint
+	if err := d.db.Model(&models.WebhookDelivery{}).
+		Where("status = ?", models.DeliveryPending).
+		Order("id").Pluck("id", &ids).Error; err != nil {
+		log.Printf("读取未完成的 Webhook 投递失败: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if !d.enqueue(id) {
+			log.Printf("Webhook 投递队列已满，%d 条未完成的投递留待下次启动", len(ids))
+			return
+		}
+	}
+}
+
+// Close 停止投递，等待正在发送的请求完成；等待重试的投递保持 pending，下次启动时继续
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.once.Do(func() { close(d.stop) })
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe 订阅事件总线上的模型变更事件
+func (d *Dispatcher) Subscribe(bus *events.Bus) {
+	bus.Subscribe(d.Handle)
+}
+
+// Handle 为订阅了该事件的每个目标记录一条投递并放入队列
+func (d *Dispatcher) Handle(e events.Event) {
+	name := e.Name()
+	var body []byte
+	for _, target := range d.targets {
+		if !subscribed(target, name) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = events.Encode(e); err != nil {
+				log.Printf("序列化 Webhook 事件 %s 失败: %v", name, err)
+				return
+			}
+		}
+
+		delivery := &models.WebhookDelivery{
+			Target:   target.URL,
+			Event:    name,
+			ObjectID: e.ObjectID,
+			Payload:  body,
+			Status:   models.DeliveryPending,
+		}
+		if err := d.db.Create(delivery).Error; err != nil {
+			log.Printf("记录 Webhook 投递失败: %s -> %s: %v", name, target.URL, err)
+			continue
+		}
+		if !d.enqueue(delivery.ID) {
+			d.finish(delivery, models.DeliveryFailed, 0, "投递队列已满")
+		}
+	}
+}
+
+// Replay 重新投递，尝试次数从 0 开始计算
+func (d *Dispatcher) Replay(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	if err := d.db.WithContext(ctx).First(delivery, id).Error; err != nil {
+		return nil, err
+	}
+	if delivery.Status == models.DeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+	if _, ok := d.target(delivery.Target); !ok {
+		return nil, ErrUnknownTarget
+	}
+
+	// 条件更新，避免并发的重新投递
+	result := d.db.WithContext(ctx).Model(delivery).
+		Where("status <> ?", models.DeliveryPending).
+		Updates(map[string]interface{}{
+			"status":     models.DeliveryPending,
+			"attempts":   0,
+			"last_error": "",
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDeliveryPending
+	}
+	delivery.Status, delivery.Attempts, delivery.LastError = models.DeliveryPending, 0, ""
+	if !d.enqueue(delivery.ID) {
+		d.finish(delivery, models.DeliveryFailed, 0, "投递队列已满")
+	}
+	return delivery, nil
+}
+
+// ReplayFailed 批量重新投递 ids 中失败的投递，返回重新投递的数量；
+// 已不存在、仍在进行中或目标已不在配置中的投递跳过
+func (d *Dispatcher) ReplayFailed(ctx context.Context, ids []uint) (int, error) {
+	replayed := 0
+	for _, id := range ids {
+		_, err := d.Replay(ctx, id)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, ErrDeliveryPending), errors.Is(err, ErrUnknownTarget):
+		case err != nil:
+			return replayed, err
+		default:
+			replayed++
+		}
+	}
+	return replayed, nil
+}
+
+// enqueue 放入队列，队列已满或已停止时返回 false
+func (d *Dispatcher) enqueue(id uint) bool {
+	select {
+	case <-d.stop:
+		return false
+	default:
+	}
+	select {
+	case d.queue <- id:
+		return true
+	default:
+		return false
+	}
+}
+
+// work 从队列中取出投递并发送
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case id := <-d.queue:
+			d.deliver(id)
+		}
+	}
+}
+
+// deliver 发送一次，失败且还有重试次数时按退避时间重新放入队列
+func (d *Dispatcher) deliver(id uint) {
+	delivery := &models.WebhookDelivery{}
+	if err := d.db.First(delivery, id).Error; err != nil {
+		log.Printf("读取 Webhook 投递 %d 失败: %v", id, err)
+		return
+	}
+	if delivery.Status != models.DeliveryPending {
+		return
+	}
+
+	target, ok := d.target(delivery.Target)
+	if !ok {
+		d.finish(delivery, models.DeliveryFailed, 0, ErrUnknownTarget.Error())
+		return
+	}
+
+	delivery.Attempts++
+	code, err := d.send(target, delivery)
+	if err == nil {
+		d.finish(delivery, models.DeliverySuccess, code, "")
+		return
+	}
+	if delivery.Attempts >= d.maxAttempts {
+		d.finish(delivery, models.DeliveryFailed, code, err.Error())
+		log.Printf("Webhook 投递 %d（%s -> %s）失败，已尝试 %d 次: %v", id, delivery.Event, delivery.Target, delivery.Attempts, err)
+		return
+	}
+
+	d.finish(delivery, models.DeliveryPending, code, err.Error())
+	time.AfterFunc(d.retryDelay(delivery.Attempts), func() {
+		if !d.enqueue(id) {
+			log.Printf("Webhook 投递 %d 未能放入队列，留待下次启动", id)
+		}
+	})
+}
+
+// send 发送请求，2xx 之外的响应视为失败
+func (d *Dispatcher) send(target config.WebhookTarget, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-viewset-webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if target.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(target.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+	return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+}
+
+// finish 保存投递结果
+func (d *Dispatcher) finish(delivery *models.WebhookDelivery, status string, code int, lastError string) {
+	updates := map[string]interface{}{
+		"status":        status,
+		"attempts":      delivery.Attempts,
+		"response_code": code,
+		"last_error":    lastError,
+	}
+	if status == models.DeliverySuccess {
+		updates["delivered_at"] = time.Now()
+	}
+	if err := d.db.Model(delivery).Updates(updates).Error; err != nil {
+		log.Printf("保存 Webhook 投递 %d 的结果失败: %v", delivery.ID, err)
+	}
+}
+
+// retryDelay 第 attempts 次失败后等待的时间：backoff × 2^(attempts-1)，不超过 maxBackoff
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+	return delay
+}
+
+// target 按 URL 查找配置的目标
+func (d *Dispatcher) target(url string) (config.WebhookTarget, bool) {
+	for _, t := range d.targets {
+		if t.URL == url {
+			return t, true
+		}
+	}
+	return config.WebhookTarget{}, false
+}
+
+// subscribed 目标是否订阅了事件，events 支持 * 通配符，例如 users.*、*.deleted
+func subscribed(target config.WebhookTarget, name string) bool {
+	if len(target.Events) == 0 {
+		return true
+	}
+	for _, pattern := range target.Events {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body) 的十六进制，接收方用同样的方法校验
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,304 @@
+// Package ws WebSocket 订阅接口
+//
+// conn.go 实现了 RFC 6455 服务端需要的部分：握手、读取（支持分片和客户端掩码）、写出文本消息、
+// 自动回复 ping 和关闭握手，不支持扩展（permessage-deflate）和子协议
+package ws
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 帧类型
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// 关闭状态码
+const (
+	CloseNormal        = 1000
+	CloseProtocolError = 1002
+	CloseTooLarge      = 1009
+)
+
+// acceptGUID 计算 Sec-WebSocket-Accept 使用的固定 GUID
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// writeTimeout 写出一帧的超时
+const writeTimeout = 10 * time.Second
+
+// ErrClosed 连接已关闭（收到关闭帧或已调用 Close）
+var ErrClosed = errors.New("websocket: 连接已关闭")
+
+// errTooLarge 消息超过 MaxMessageBytes
+var errTooLarge = errors.New("websocket: 消息过大")
+
+// errProtocol 客户端违反协议
+var errProtocol = errors.New("websocket: 协议错误")
+
+// Conn 服务端的 WebSocket 连接
+// ReadMessage 只能在一个 goroutine 中调用；WriteMessage、Ping、Close 可以并发调用
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	// MaxMessageBytes 单条消息（合并分片后）的最大字节数，0 表示不限制
+	MaxMessageBytes int
+	// ReadTimeout 等待下一帧（包括 pong）的最长时间，0 表示不限制；配合定时 Ping 检测断开的连接
+	ReadTimeout time.Duration
+
+	wmu    sync.Mutex
+	closed bool
+}
+
+// Upgrade 完成 WebSocket 握手并接管底层连接
+// 握手请求无效时写出 400 并返回错误；checkOrigin 不为 nil 且返回 false 时写出 403
+func Upgrade(w http.ResponseWriter, r *http.Request, checkOrigin func(r *http.Request) bool) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "需要 WebSocket 握手请求", http.StatusBadRequest)
+		return nil, fmt.Errorf("%w: 不是握手请求", errProtocol)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "不支持的 WebSocket 版本", http.StatusBadRequest)
+		return nil, fmt.Errorf("%w: 不支持的版本", errProtocol)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "缺少 Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, fmt.Errorf("%w: 缺少 Sec-WebSocket-Key", errProtocol)
+	}
+	if checkOrigin != nil && !checkOrigin(r) {
+		http.Error(w, "不允许的来源", http.StatusForbidden)
+		return nil, fmt.Errorf("websocket: 不允许的来源 %q", r.Header.Get("Origin"))
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "不支持 WebSocket", http.StatusInternalServerError)
+		return nil, errors.New("websocket: ResponseWriter 不支持 Hijack")
+	}
+	netConn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	// 清除 http.Server 设置的读写超时，之后由调用方管理
+	netConn.SetDeadline(time.Time{})
+	netConn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: netConn, br: brw.Reader}, nil
+}
+
+// acceptKey 计算 Sec-WebSocket-Accept
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains 请求头（逗号分隔的列表）是否包含 token，不区分大小写
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage 读取一条文本或二进制消息
+// ping 自动回复 pong；收到关闭帧时回复关闭帧并返回 ErrClosed
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			if errors.Is(err, errTooLarge) {
+				c.closeWith(CloseTooLarge, "消息过大")
+			} else if errors.Is(err, errProtocol) {
+				c.closeWith(CloseProtocolError, "协议错误")
+			}
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.closeWith(CloseNormal, "")
+			return nil, ErrClosed
+		case opText, opBinary:
+			if started {
+				c.closeWith(CloseProtocolError, "协议错误")
+				return nil, fmt.Errorf("%w: 分片消息未结束", errProtocol)
+			}
+			started = true
+			message = payload
+		case opContinuation:
+			if !started {
+				c.closeWith(CloseProtocolError, "协议错误")
+				return nil, fmt.Errorf("%w: 没有起始帧的分片", errProtocol)
+			}
+			if c.MaxMessageBytes > 0 && len(message)+len(payload) > c.MaxMessageBytes {
+				c.closeWith(CloseTooLarge, "消息过大")
+				return nil, errTooLarge
+			}
+			message = append(message, payload...)
+		default:
+			c.closeWith(CloseProtocolError, "协议错误")
+			return nil, fmt.Errorf("%w: 未知的帧类型 %d", errProtocol, opcode)
+		}
+
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame 读取一帧并去掉掩码
+func (c *Conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	if c.ReadTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+	}
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	if head[0]&0x70 != 0 {
+		err = fmt.Errorf("%w: 不支持扩展", errProtocol)
+		return
+	}
+	if head[1]&0x80 == 0 {
+		err = fmt.Errorf("%w: 客户端的帧必须带掩码", errProtocol)
+		return
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if opcode >= opClose && (!fin || length > 125) {
+		err = fmt.Errorf("%w: 控制帧不能分片且不能超过 125 字节", errProtocol)
+		return
+	}
+	if c.MaxMessageBytes > 0 && length > uint64(c.MaxMessageBytes) {
+		err = errTooLarge
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// WriteMessage 写出一条文本消息
+func (c *Conn) WriteMessage(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// Ping 发送 ping，客户端应回复 pong
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// writeFrame 写出一帧（服务端的帧不带掩码）
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	buffers := net.Buffers{header, payload}
+	_, err := buffers.WriteTo(c.conn)
+	return err
+}
+
+// Close 发送关闭帧并关闭连接
+func (c *Conn) Close() error {
+	return c.closeWith(CloseNormal, "")
+}
+
+// closeWith 发送带状态码的关闭帧并关闭连接，可以重复调用
+func (c *Conn) closeWith(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, reason...)
+	c.writeFrame(opClose, payload)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.conn.Close()
+}
@@ -0,0 +1,461 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"go-viewset/internal/config"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 默认配置
+const (
+	defaultMaxSubscriptions = 20
+	defaultMaxMessageBytes  = 64 << 10
+	defaultPing             = 30 * time.Second
+
+	// maxConcurrentRequests 每个连接同时执行的 list / retrieve 请求数，超过时暂停读取客户端消息
+	maxConcurrentRequests = 8
+)
+
+// 服务端消息的 type
+const (
+	TypeSubscribed   = "subscribed"   // 订阅成功
+	TypeUnsubscribed = "unsubscribed" // 取消订阅，或服务端断开了订阅（客户端读取太慢等），需要时重新订阅
+	TypeEvent        = "event"        // 资源变更，event 与 SSE 接口相同：created / updated / removed / deleted
+	TypeResponse     = "response"     // list / retrieve 的响应，data 为 HTTP 接口的响应体
+	TypeError        = "error"        // 请求失败，status 为 HTTP 状态码，data 为 HTTP 接口的响应体（如果有）
+)
+
+// clientMessage 客户端消息，subscribe、unsubscribe、list、retrieve 中只能设置一个，值为频道（资源）名称
+//
+//	{"subscribe": "users", "params": {"status": "active"}}
+//	{"unsubscribe": "users"}
+//	{"id": "1", "list": "users", "params": {"page": "2"}}
+//	{"id": "2", "retrieve": "users", "object_id": "42"}
+type clientMessage struct {
+	ID          string            `json:"id,omitempty"` // 客户端生成的请求 ID，原样带回
+	Subscribe   string            `json:"subscribe,omitempty"`
+	Unsubscribe string            `json:"unsubscribe,omitempty"`
+	List        string            `json:"list,omitempty"`
+	Retrieve    string            `json:"retrieve,omitempty"`
+	ObjectID    string            `json:"object_id,omitempty"`
+	Params      map[string]string `json:"params,omitempty"` // 查询参数：订阅时为过滤条件，list / retrieve 时与 HTTP 接口相同
+}
+
+// serverMessage 服务端消息
+type serverMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Channel string          `json:"channel,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Status  int             `json:"status,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Server WebSocket 订阅接口
+// 连接上的每个订阅和 list / retrieve 都作为一个内部 HTTP 请求交给 handler（通常是 gin.Engine）处理，
+// 请求头（认证信息、租户等）取自握手请求，因此权限、过滤、租户隔离与 HTTP 接口完全相同；
+// 订阅对应资源的 GET /stream（见 viewset.Stream），资源需要开启 EnableStream
+type Server struct {
+	handler  http.Handler
+	channels map[string]string
+
+	origins          map[string]bool
+	maxSubscriptions int
+	maxMessageBytes  int
+	ping             time.Duration
+}
+
+// NewServer 创建 WebSocket 订阅接口，handler 处理内部请求
+func NewServer(handler http.Handler, cfg config.WebSocketConfig) *Server {
+	s := &Server{
+		handler:          handler,
+		channels:         make(map[string]string),
+		maxSubscriptions: cfg.MaxSubscriptions,
+		maxMessageBytes:  cfg.MaxMessageBytes,
+		ping:             time.Duration(cfg.PingSeconds) * time.Second,
+	}
+	if s.maxSubscriptions <= 0 {
+		s.maxSubscriptions = defaultMaxSubscriptions
+	}
+	if s.maxMessageBytes <= 0 {
+		s.maxMessageBytes = defaultMaxMessageBytes
+	}
+	if s.ping <= 0 {
+		s.ping = defaultPing
+	}
+	if len(cfg.AllowedOrigins) > 0 {
+		s.origins = make(map[string]bool, len(cfg.AllowedOrigins))
+		for _, origin := range cfg.AllowedOrigins {
+			s.origins[origin] = true
+		}
+	}
+	return s
+}
+
+// Channel 注册频道，prefix 为资源的路由前缀，例如 Channel("users", "/api/users")
+func (s *Server) Channel(name, prefix string) {
+	s.channels[name] = strings.TrimSuffix(prefix, "/")
+}
+
+// Handle 处理 WebSocket 握手请求，连接关闭后返回
+func (s *Server) Handle(c *gin.Context) {
+	conn, err := Upgrade(c.Writer, c.Request, s.checkOrigin)
+	if err != nil {
+		c.Abort()
+		return
+	}
+	conn.MaxMessageBytes = s.maxMessageBytes
+	conn.ReadTimeout = 2 * s.ping
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	sess := &session{
+		server:  s,
+		conn:    conn,
+		upgrade: c.Request,
+		ctx:     ctx,
+		subs:    make(map[string]*subscription),
+		sem:     make(chan struct{}, maxConcurrentRequests),
+	}
+	sess.run()
+	cancel()
+	sess.wg.Wait()
+	conn.Close()
+}
+
+// checkOrigin 允许没有 Origin 的请求（非浏览器客户端）、与请求的 Host 相同的来源，以及 AllowedOrigins 中的来源
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || s.origins[origin] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// session 一个 WebSocket 连接
+type session struct {
+	server  *Server
+	conn    *Conn
+	upgrade *http.Request
+	ctx     context.Context
+
+	mu   sync.Mutex
+	subs map[string]*subscription
+	sem  chan struct{}
+	wg   sync.WaitGroup
+}
+
+// subscription 一个频道的订阅，cancel 结束对应的内部 /stream 请求
+type subscription struct {
+	cancel context.CancelFunc
+}
+
+// run 读取客户端消息直到连接关闭，并定时发送 ping
+func (sess *session) run() {
+	ping := time.NewTicker(sess.server.ping)
+	defer ping.Stop()
+	sess.wg.Add(1)
+	go func() {
+		defer sess.wg.Done()
+		for {
+			select {
+			case <-sess.ctx.Done():
+				return
+			case <-ping.C:
+				if err := sess.conn.Ping(); err != nil {
+					sess.conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		data, err := sess.conn.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, ErrClosed) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("WebSocket 连接断开: %v", err)
+			}
+			return
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			sess.send(serverMessage{Type: TypeError, Status: http.StatusBadRequest, Message: "消息不是有效的 JSON"})
+			continue
+		}
+		sess.dispatch(msg)
+	}
+}
+
+// dispatch 处理一条客户端消息
+func (sess *session) dispatch(msg clientMessage) {
+	switch {
+	case msg.Subscribe != "":
+		sess.subscribe(msg)
+	case msg.Unsubscribe != "":
+		sess.unsubscribe(msg)
+	case msg.List != "":
+		sess.request(msg, msg.List, "/", msg.Params)
+	case msg.Retrieve != "":
+		if msg.ObjectID == "" {
+			sess.fail(msg, msg.Retrieve, http.StatusBadRequest, "缺少 object_id")
+			return
+		}
+		sess.request(msg, msg.Retrieve, "/"+url.PathEscape(msg.ObjectID), msg.Params)
+	default:
+		sess.fail(msg, "", http.StatusBadRequest, "需要 subscribe、unsubscribe、list 或 retrieve")
+	}
+}
+
+// subscribe 订阅频道，已订阅时按新的过滤条件重新订阅
+func (sess *session) subscribe(msg clientMessage) {
+	channel := msg.Subscribe
+	prefix, ok := sess.server.channels[channel]
+	if !ok {
+		sess.fail(msg, channel, http.StatusNotFound, "未知的频道: "+channel)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(sess.ctx)
+	sub := &subscription{cancel: cancel}
+	sess.mu.Lock()
+	old, exists := sess.subs[channel]
+	if !exists && len(sess.subs) >= sess.server.maxSubscriptions {
+		sess.mu.Unlock()
+		cancel()
+		sess.fail(msg, channel, http.StatusTooManyRequests, "订阅数已达上限")
+		return
+	}
+	sess.subs[channel] = sub
+	sess.mu.Unlock()
+	if exists {
+		old.cancel()
+	}
+
+	w := &streamWriter{
+		header: make(http.Header),
+		onStart: func() {
+			sess.send(serverMessage{Type: TypeSubscribed, ID: msg.ID, Channel: channel})
+		},
+		onEvent: func(event string, data []byte) error {
+			return sess.send(serverMessage{Type: TypeEvent, Channel: channel, Event: event, Data: data})
+		},
+	}
+
+	sess.wg.Add(1)
+	go func() {
+		defer sess.wg.Done()
+		defer cancel()
+		sess.server.handler.ServeHTTP(w, sess.newRequest(ctx, prefix+"/stream", msg.Params))
+
+		// 订阅请求失败（例如过滤参数无效、没有权限、资源没有开启 EnableStream）
+		if !w.stream {
+			sess.mu.Lock()
+			if sess.subs[channel] == sub {
+				delete(sess.subs, channel)
+			}
+			sess.mu.Unlock()
+			if w.status == 0 {
+				return
+			}
+			sess.send(responseMessage(TypeError, msg.ID, channel, w.status, w.body.Bytes()))
+			return
+		}
+
+		// 服务端结束了订阅：由 unsubscribe 或重新订阅取消时不再通知
+		sess.mu.Lock()
+		current := sess.subs[channel] == sub
+		if current {
+			delete(sess.subs, channel)
+		}
+		sess.mu.Unlock()
+		if current && sess.ctx.Err() == nil {
+			sess.send(serverMessage{Type: TypeUnsubscribed, Channel: channel, Message: "订阅已被服务端结束"})
+		}
+	}()
+}
+
+// unsubscribe 取消订阅
+func (sess *session) unsubscribe(msg clientMessage) {
+	channel := msg.Unsubscribe
+	sess.mu.Lock()
+	sub, ok := sess.subs[channel]
+	delete(sess.subs, channel)
+	sess.mu.Unlock()
+	if ok {
+		sub.cancel()
+	}
+	sess.send(serverMessage{Type: TypeUnsubscribed, ID: msg.ID, Channel: channel})
+}
+
+// request 以内部 GET 请求调用频道的 path（/ 为列表，/:id 为详情），响应通过 response 消息返回
+func (sess *session) request(msg clientMessage, channel, path string, params map[string]string) {
+	prefix, ok := sess.server.channels[channel]
+	if !ok {
+		sess.fail(msg, channel, http.StatusNotFound, "未知的频道: "+channel)
+		return
+	}
+
+	select {
+	case sess.sem <- struct{}{}:
+	case <-sess.ctx.Done():
+		return
+	}
+	sess.wg.Add(1)
+	go func() {
+		defer sess.wg.Done()
+		defer func() { <-sess.sem }()
+
+		w := &streamWriter{header: make(http.Header)}
+		sess.server.handler.ServeHTTP(w, sess.newRequest(sess.ctx, prefix+path, params))
+		msgType := TypeResponse
+		if w.status >= http.StatusBadRequest {
+			msgType = TypeError
+		}
+		sess.send(responseMessage(msgType, msg.ID, channel, w.status, w.body.Bytes()))
+	}()
+}
+
+// newRequest 创建内部 GET 请求，请求头取自握手请求（去掉 WebSocket 相关的请求头）
+func (sess *session) newRequest(ctx context.Context, path string, params map[string]string) *http.Request {
+	query := make(url.Values, len(params))
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	u := &url.URL{Path: path, RawQuery: query.Encode()}
+
+	req := (&http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		RequestURI: u.RequestURI(),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header, len(sess.upgrade.Header)),
+		Body:       http.NoBody,
+		Host:       sess.upgrade.Host,
+		RemoteAddr: sess.upgrade.RemoteAddr,
+		TLS:        sess.upgrade.TLS,
+	}).WithContext(ctx)
+	for key, values := range sess.upgrade.Header {
+		switch key {
+		case "Connection", "Upgrade", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol":
+			continue
+		}
+		req.Header[key] = values
+	}
+	return req
+}
+
+// fail 发送错误消息
+func (sess *session) fail(msg clientMessage, channel string, status int, message string) {
+	sess.send(serverMessage{Type: TypeError, ID: msg.ID, Channel: channel, Status: status, Message: message})
+}
+
+// send 编码并发送一条消息
+func (sess *session) send(msg serverMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return sess.conn.WriteMessage(data)
+}
+
+// responseMessage 由内部请求的响应构建消息，响应体是 JSON 时原样放入 data
+func responseMessage(msgType, id, channel string, status int, body []byte) serverMessage {
+	msg := serverMessage{Type: msgType, ID: id, Channel: channel, Status: status}
+	if json.Valid(body) {
+		msg.Data = body
+	} else {
+		msg.Message = strings.TrimSpace(string(body))
+	}
+	return msg
+}
+
+// streamWriter 接收内部请求的响应
+// Server-Sent Events 响应（200 且 Content-Type 为 text/event-stream）逐个事件回调 onEvent，其他响应缓存在 body 中
+type streamWriter struct {
+	header http.Header
+	status int
+	stream bool
+	body   bytes.Buffer
+	buf    []byte
+
+	onStart func()
+	onEvent func(event string, data []byte) error
+}
+
+func (w *streamWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *streamWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	w.stream = status == http.StatusOK && w.onEvent != nil &&
+		strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")
+	if w.stream && w.onStart != nil {
+		w.onStart()
+	}
+}
+
+// Write 流式响应按空行切分事件，回调失败（WebSocket 连接已断开）时返回错误，/stream 随之结束
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.stream {
+		return w.body.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.Index(w.buf, []byte("\n\n"))
+		if i < 0 {
+			break
+		}
+		event, data := parseEvent(w.buf[:i])
+		w.buf = w.buf[i+2:]
+		if len(data) == 0 {
+			continue
+		}
+		if err := w.onEvent(event, data); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush 事件在 Write 中已经转发，不需要刷新
+func (w *streamWriter) Flush() {}
+
+// parseEvent 解析一个 Server-Sent Events 事件的 event 和 data 字段，注释行被忽略
+func parseEvent(block []byte) (event string, data []byte) {
+	for _, line := range bytes.Split(block, []byte("\n")) {
+		switch {
+		case bytes.HasPrefix(line, []byte("event:")):
+			event = string(bytes.TrimSpace(line[len("event:"):]))
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(line[len("data:"):], []byte(" "))...)
+		}
+	}
+	return event, data
+}
@@ -0,0 +1,202 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrNoSheet 文件中没有工作表
+var ErrNoSheet = errors.New("xlsx: 文件中没有工作表")
+
+// 读取时用到的 XML 结构，只声明需要的元素
+type (
+	xmlWorkbook struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+
+	xmlRelationships struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+
+	xmlText struct {
+		T    string `xml:"t"`
+		Runs []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	}
+
+	xmlSharedStrings struct {
+		Items []xmlText `xml:"si"`
+	}
+
+	xmlSheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R  string  `xml:"r,attr"`
+				T  string  `xml:"t,attr"`
+				V  string  `xml:"v"`
+				IS xmlText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+)
+
+// text 单元格中的文本，富文本由多个片段拼接
+func (t xmlText) text() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+// ReadAll 读取第一个工作表的全部单元格
+// 返回值的下标为行号和列号减一，空行为 nil；数字按文件中保存的原样返回（日期为 Excel 序列号，见 ParseSerialTime），
+// bool 返回 "true" / "false"
+func ReadAll(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: 无法读取文件: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xmlSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeFile(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, ErrNoSheet
+	}
+	var sheet xmlSheet
+	if err := decodeFile(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for i, row := range sheet.Rows {
+		// 没有行号时按出现顺序
+		rowIndex := row.R - 1
+		if row.R <= 0 {
+			rowIndex = i
+		}
+		for len(rows) <= rowIndex {
+			rows = append(rows, nil)
+		}
+
+		var cells []string
+		for j, cell := range row.Cells {
+			col := j
+			if cell.R != "" {
+				if n, ok := columnIndex(cell.R); ok {
+					col = n
+				}
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+
+			switch cell.T {
+			case "s":
+				idx, err := strconv.Atoi(cell.V)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("xlsx: 单元格 %s 引用了不存在的共享字符串", cell.R)
+				}
+				cells[col] = shared.Items[idx].text()
+			case "inlineStr":
+				cells[col] = cell.IS.text()
+			case "b":
+				cells[col] = strconv.FormatBool(cell.V == "1")
+			default:
+				cells[col] = cell.V
+			}
+		}
+		rows[rowIndex] = cells
+	}
+	return rows, nil
+}
+
+// firstSheetPath 通过 workbook.xml 和其关系文件找到第一个工作表在 zip 中的路径
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	wf, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", ErrNoSheet
+	}
+	var wb xmlWorkbook
+	if err := decodeFile(wf, &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", ErrNoSheet
+	}
+
+	rf, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return fallback, nil
+	}
+	var rels xmlRelationships
+	if err := decodeFile(rf, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != wb.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+// decodeFile 解析 zip 中的 XML 文件
+func decodeFile(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("xlsx: 解析 %s 失败: %w", f.Name, err)
+	}
+	return nil
+}
+
+// columnIndex 单元格引用（例如 "AB12"）中的列序号（从 0 开始）
+func columnIndex(ref string) (int, bool) {
+	n := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		n = n*26 + int(ref[i]-'A'+1)
+	}
+	return n - 1, i > 0
+}
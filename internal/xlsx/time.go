@@ -0,0 +1,23 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// excelEpoch Excel 日期序列号的起点（1900 日期系统，已包含 1900 年闰年问题的修正）
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ParseSerialTime 将 Excel 日期序列号（例如 "45200.5"）转换为时间，时区为 loc
+// 日期类型的单元格在文件中保存为序列号，显示格式由样式决定
+func ParseSerialTime(value string, loc *time.Location) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil || serial < 0 {
+		return time.Time{}, false
+	}
+	days := math.Floor(serial)
+	millis := math.Round((serial - days) * 24 * float64(time.Hour) / float64(time.Millisecond))
+	t := excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration(millis) * time.Millisecond)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc), true
+}
@@ -0,0 +1,202 @@
+// Package xlsx 读写只包含一个工作表的 Excel（.xlsx）文件
+// 只实现导入导出需要的部分，不依赖第三方库：写出时逐行写入 zip，内存占用与行数无关。
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType .xlsx 文件的 MIME 类型
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// TimeLayout 时间类型单元格的格式
+const TimeLayout = "2006-01-02 15:04:05"
+
+// 除工作表外的固定内容
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+
+	relsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+
+	// 样式 0 为默认，样式 1 为表头使用的粗体
+	stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`
+
+	sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	sheetFooter = `</sheetData></worksheet>`
+)
+
+// Writer 逐行写出单个工作表的 .xlsx 文件
+type Writer struct {
+	zw        *zip.Writer
+	sheet     io.Writer
+	sheetName string
+	rows      int
+	err       error
+}
+
+// NewWriter 创建 Writer，sheetName 为工作表名称（最多 31 个字符）
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+	if r := []rune(sheetName); len(r) > 31 {
+		sheetName = string(r[:31])
+	}
+
+	xw := &Writer{zw: zip.NewWriter(w), sheetName: sheetName}
+	sheet, err := xw.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, sheetHeader); err != nil {
+		return nil, err
+	}
+	xw.sheet = sheet
+	return xw, nil
+}
+
+// WriteHeader 写出粗体的表头行
+func (w *Writer) WriteHeader(headers []string) error {
+	cells := make([]interface{}, len(headers))
+	for i, h := range headers {
+		cells[i] = h
+	}
+	return w.writeRow(cells, 1)
+}
+
+// WriteRow 写出一行
+// 数字和 bool 写为对应类型的单元格，time.Time 按 TimeLayout 格式化，nil 为空单元格，其他值转为字符串
+func (w *Writer) WriteRow(cells []interface{}) error {
+	return w.writeRow(cells, 0)
+}
+
+// writeRow 使用样式 style 写出一行
+func (w *Writer) writeRow(cells []interface{}, style int) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.rows++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, w.rows)
+	for i, value := range cells {
+		ref := ColumnName(i) + strconv.Itoa(w.rows)
+		writeCell(&b, ref, value, style)
+	}
+	b.WriteString(`</row>`)
+
+	_, w.err = io.WriteString(w.sheet, b.String())
+	return w.err
+}
+
+// writeCell 写出一个单元格
+func writeCell(b *strings.Builder, ref string, value interface{}, style int) {
+	attrs := `r="` + ref + `"`
+	if style != 0 {
+		attrs += ` s="` + strconv.Itoa(style) + `"`
+	}
+
+	switch x := value.(type) {
+	case nil:
+		return
+	case bool:
+		v := "0"
+		if x {
+			v = "1"
+		}
+		b.WriteString(`<c ` + attrs + ` t="b"><v>` + v + `</v></c>`)
+		return
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		b.WriteString(`<c ` + attrs + `><v>` + fmt.Sprint(x) + `</v></c>`)
+		return
+	case float32:
+		b.WriteString(`<c ` + attrs + `><v>` + strconv.FormatFloat(float64(x), 'g', -1, 32) + `</v></c>`)
+		return
+	case float64:
+		b.WriteString(`<c ` + attrs + `><v>` + strconv.FormatFloat(x, 'g', -1, 64) + `</v></c>`)
+		return
+	case time.Time:
+		if x.IsZero() {
+			return
+		}
+		value = x.Format(TimeLayout)
+	}
+
+	b.WriteString(`<c ` + attrs + ` t="inlineStr"><is><t xml:space="preserve">`)
+	xml.EscapeText(b, []byte(fmt.Sprint(value)))
+	b.WriteString(`</t></is></c>`)
+}
+
+// Close 写出工作表的结尾和其余固定内容，不关闭底层的 io.Writer
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := io.WriteString(w.sheet, sheetFooter); err != nil {
+		return err
+	}
+
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(w.sheetName))
+	workbookXML := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", relsXML},
+		{"xl/workbook.xml", workbookXML},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	}
+	for _, p := range parts {
+		f, err := w.zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return err
+		}
+	}
+	return w.zw.Close()
+}
+
+// ColumnName 列序号（从 0 开始）对应的列名：A、B、……、Z、AA、AB……
+func ColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -1,66 +1,264 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"go-viewset/internal/config"
+	"go-viewset/internal/createadmin"
+	"go-viewset/internal/database"
+	"go-viewset/internal/events"
+	"go-viewset/internal/gen"
+	"go-viewset/internal/mailer"
 	"go-viewset/internal/models"
+	"go-viewset/internal/mq"
 	"go-viewset/internal/router"
+	"go-viewset/internal/storage"
+	"go-viewset/internal/tracing"
+	"go-viewset/internal/utils"
+	"go-viewset/internal/viewset"
+	"go-viewset/internal/webhook"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 func main() {
-	// 加载配置
-	cfg, err := config.Load("config.json")
+	// go-viewset gen：生成模型和 ViewSet 的脚手架（见 internal/gen）
+	if len(os.Args) > 1 && os.Args[1] == "gen" {
+		os.Exit(gen.Main(os.Args[2:]))
+	}
+
+	// go-viewset createadmin：创建第一个管理员（见 internal/createadmin）
+	if len(os.Args) > 1 && os.Args[1] == "createadmin" {
+		os.Exit(createadmin.Main(os.Args[2:]))
+	}
+
+	// 加载配置，CONFIG_FILE 为空时按 config.json、config.yaml、config.toml 的顺序查找
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
 	if err != nil {
 		log.Fatalf("加载配置失败: %v", err)
 	}
 
+	// 响应结构和错误响应格式
+	if err := setupResponse(cfg); err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 链路追踪
+	if cfg.Tracing.Enabled {
+		tracer, err := tracing.New(tracing.Config{
+			ServiceName: cfg.Tracing.ServiceName,
+			Endpoint:    cfg.Tracing.Endpoint,
+			SampleRate:  cfg.Tracing.SampleRate,
+		})
+		if err != nil {
+			log.Fatalf("初始化链路追踪失败: %v", err)
+		}
+		tracing.SetDefault(tracer)
+		defer tracer.Shutdown(context.Background())
+	}
+
 	// 初始化数据库
 	db, err := initDB(cfg)
 	if err != nil {
 		log.Fatalf("数据库初始化失败: %v", err)
 	}
 
+	// 每个租户独立数据库时的连接管理
+	tenantDBs := newTenantDBs(cfg, db)
+
+	// Webhook：写操作成功后将模型变更事件发送到配置的目标
+	var webhooks *webhook.Dispatcher
+	if cfg.Webhook.Enabled {
+		webhooks = webhook.New(db, cfg.Webhook)
+		webhooks.Subscribe(events.Default)
+		webhooks.Start()
+	}
+
+	// 消息队列：模型变更事件发布到 Kafka / NATS / RabbitMQ
+	var publisher *mq.Publisher
+	if cfg.MQ.Enabled {
+		broker, err := mq.Open(cfg.MQ)
+		if err != nil {
+			log.Fatalf("连接消息队列失败: %v", err)
+		}
+		publisher = mq.NewPublisher(broker, cfg.MQ)
+		publisher.Subscribe(events.Default)
+		publisher.Start()
+	}
+
+	// 附件的存储后端
+	var files storage.Storage
+	if cfg.Attachment.Enabled {
+		if files, err = storage.Open(cfg.Attachment); err != nil {
+			log.Fatalf("初始化附件存储失败: %v", err)
+		}
+	}
+
+	// 邮件发送：重置密码等通知邮件通过后台队列发送
+	var mail *mailer.Mailer
+	if cfg.Mail.Enabled {
+		provider, err := mailer.Open(cfg.Mail)
+		if err != nil {
+			log.Fatalf("初始化邮件发送失败: %v", err)
+		}
+		if mail, err = mailer.New(provider, cfg.Mail); err != nil {
+			log.Fatalf("加载邮件模板失败: %v", err)
+		}
+		mail.Start()
+	}
+
 	// 设置路由
-	r := router.SetupRouter(db)
+	r := router.SetupRouter(db, cfg, tenantDBs, webhooks, files, mail)
 
 	// 启动服务
-	fmt.Printf("🚀 服务启动成功，监听端口: %s\n", cfg.Server.Port)
-	fmt.Println("📚 API 文档:")
-	fmt.Println("  - GET    /api/users/          获取用户列表")
-	fmt.Println("  - GET    /api/users/:id       获取单个用户")
-	fmt.Println("  - POST   /api/users/          创建用户")
-	fmt.Println("  - PUT    /api/users/:id       更新用户")
-	fmt.Println("  - DELETE /api/users/:id       删除用户")
-	fmt.Println("  - POST   /api/users/:id/activate      激活用户")
-	fmt.Println("  - POST   /api/users/:id/deactivate    停用用户")
-	fmt.Println("  - POST   /api/users/:id/reset_password 重置密码")
-	fmt.Println("  - GET    /api/users/stats     获取统计信息")
+	srv := newServer(cfg.Server, r)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	fmt.Printf("🚀 服务启动成功，监听端口: %s\n", srv.Addr)
+	fmt.Printf("📚 API 文档: http://localhost%s/api/docs （OpenAPI: /api/openapi.json）\n", srv.Addr)
 	fmt.Println("")
 
-	if err := r.Run(cfg.Server.Port); err != nil {
+	// 等待退出信号
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errCh:
 		log.Fatalf("服务启动失败: %v", err)
+	case sig := <-quit:
+		log.Printf("收到 %s 信号，开始关闭服务", sig)
+	}
+	signal.Stop(quit)
+
+	// 不再接受新连接，等待处理中的请求完成
+	timeout := time.Duration(cfg.Server.ShutdownTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("等待请求完成超时，强制关闭: %v", err)
+		srv.Close()
+	}
+
+	// 等待正在发送的 Webhook 完成，未完成的投递下次启动时继续
+	if webhooks != nil {
+		if err := webhooks.Close(ctx); err != nil {
+			log.Printf("等待 Webhook 投递完成超时: %v", err)
+		}
+	}
+
+	// 发布完队列中剩余的事件
+	if publisher != nil {
+		if err := publisher.Close(ctx); err != nil {
+			log.Printf("关闭消息队列失败: %v", err)
+		}
+	}
+
+	// 发送完队列中剩余的邮件
+	if mail != nil {
+		if err := mail.Close(ctx); err != nil {
+			log.Printf("等待邮件发送完成超时: %v", err)
+		}
+	}
+
+	// 请求全部结束后再关闭连接池
+	if tenantDBs != nil {
+		tenantDBs.Close()
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("关闭数据库连接失败: %v", err)
+		}
+	}
+	log.Println("服务已关闭")
+}
+
+// newServer 按配置创建 HTTP 服务
+func newServer(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	addr := cfg.Port
+	if addr == "" {
+		addr = ":8080"
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       time.Duration(cfg.ReadTimeoutSeconds) * time.Second,
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeoutSeconds) * time.Second,
+		WriteTimeout:      time.Duration(cfg.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(cfg.IdleTimeoutSeconds) * time.Second,
+	}
+}
+
+// setupResponse 按配置设置响应的外层结构、错误格式和可浏览的 API
+func setupResponse(cfg *config.Config) error {
+	if cfg.Server.BrowsableAPI {
+		viewset.EnableBrowsableAPI()
+	}
+	if cfg.Response.JSONAPI {
+		viewset.EnableJSONAPI()
+	}
+	rc := utils.ResponseConfig{
+		DisableEnvelope: cfg.Response.Envelope != nil && !*cfg.Response.Envelope,
+		SuccessCode:     cfg.Response.SuccessCode,
+	}
+	for name, key := range cfg.Response.Keys {
+		switch name {
+		case "code":
+			rc.CodeKey = key
+		case "msg":
+			rc.MsgKey = key
+		case "data":
+			rc.DataKey = key
+		case "pagination":
+			rc.PaginationKey = key
+		case "errors":
+			rc.ErrorsKey = key
+		default:
+			return fmt.Errorf("response.keys 中未知的字段: %s", name)
+		}
 	}
+	if err := utils.SetResponseConfig(rc); err != nil {
+		return err
+	}
+	return utils.SetErrorFormat(utils.ErrorFormat(cfg.Server.ErrorFormat))
 }
 
 // initDB 初始化数据库
 func initDB(cfg *config.Config) (*gorm.DB, error) {
-	// 构建 DSN 连接字符串
-	dsn := cfg.Database.GetDSN()
+	// 按数据库类型选择驱动并构建 DSN 连接字符串
+	dialector, err := database.Open(cfg.Database.Type, cfg.Database.GetDSN())
+	if err != nil {
+		return nil, err
+	}
 
 	// 连接数据库
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("连接数据库失败: %w", err)
 	}
 
+	// 连接占用检测：替换为记录连接取出和归还的连接池，需要在设置连接池参数之前
+	var detector *database.LeakDetector
+	if cfg.Database.LeakThresholdMs > 0 {
+		detector = database.NewLeakDetector(time.Duration(cfg.Database.LeakThresholdMs) * time.Millisecond)
+		if err := detector.Register(db, cfg.Database.GetDSN()); err != nil {
+			return nil, fmt.Errorf("注册连接占用检测失败: %w", err)
+		}
+	}
+
 	// 设置连接池
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -70,17 +268,130 @@ func initDB(cfg *config.Config) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
+	if detector != nil {
+		go detector.Monitor(sqlDB, 10*time.Second, nil)
+	}
+
+	// 性能分析、监控指标和链路追踪的回调
+	if err := registerCallbacks(db, cfg); err != nil {
+		return nil, err
+	}
+
 	// 自动迁移表结构
-	if err := db.AutoMigrate(&models.User{}); err != nil {
-		return nil, fmt.Errorf("数据库迁移失败: %w", err)
+	if err := migrate(db); err != nil {
+		return nil, err
 	}
 
 	// 创建一些示例数据
 	createSampleData(db)
 
+	// 预热连接池
+	warmup := cfg.Database.WarmupConns
+	if warmup > cfg.Database.MaxIdleConns {
+		warmup = cfg.Database.MaxIdleConns
+	}
+	if err := database.WarmUp(sqlDB, warmup, 10*time.Second); err != nil {
+		return nil, fmt.Errorf("连接池预热失败: %w", err)
+	}
+
 	return db, nil
 }
 
+// registerCallbacks 按配置注册数据库回调，主数据库和租户数据库共用
+func registerCallbacks(db *gorm.DB, cfg *config.Config) error {
+	// 请求级性能分析：统计数据库耗时
+	if cfg.Server.ProfileToken != "" {
+		if err := database.RegisterProfiler(db); err != nil {
+			return fmt.Errorf("注册性能分析失败: %w", err)
+		}
+	}
+
+	// Prometheus 指标：按表统计数据库耗时
+	if cfg.Server.Metrics {
+		if err := database.RegisterMetrics(db); err != nil {
+			return fmt.Errorf("注册数据库指标失败: %w", err)
+		}
+	}
+
+	// 链路追踪：每条 SQL 一个 span
+	if cfg.Tracing.Enabled {
+		if err := database.RegisterTracing(db); err != nil {
+			return fmt.Errorf("注册链路追踪失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrate 自动迁移表结构，主数据库和租户数据库共用
+func migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.APIQuota{},
+		&models.Role{},
+		&models.Permission{},
+		&models.AuditLog{},
+		&models.Tenant{},
+		&models.WebhookDelivery{},
+		&models.Attachment{},
+		&models.PasswordResetToken{},
+		&models.Session{},
+		&models.UserIdentity{},
+		// go-viewset gen: models
+	); err != nil {
+		return fmt.Errorf("数据库迁移失败: %w", err)
+	}
+	return nil
+}
+
+// newTenantDBs tenant.isolation 为 database 时创建租户数据库的连接管理，否则返回 nil
+// 租户的数据库优先取 tenant.databases 中按 slug 配置的连接，其次取 tenants 表中的 database_type 和 dsn；
+// 首次打开时注册与主数据库相同的回调并迁移表结构
+func newTenantDBs(cfg *config.Config, db *gorm.DB) *database.TenantDBs {
+	if !cfg.Tenant.Enabled || cfg.Tenant.Isolation != config.TenantIsolationDatabase {
+		return nil
+	}
+
+	resolve := func(ctx context.Context, slug string) (string, string, error) {
+		if dbCfg, ok := cfg.Tenant.Databases[slug]; ok {
+			return dbCfg.Type, dbCfg.GetDSN(), nil
+		}
+		var tenant models.Tenant
+		if err := db.WithContext(ctx).Where("slug = ?", slug).First(&tenant).Error; err != nil {
+			return "", "", fmt.Errorf("读取租户 %s 失败: %w", slug, err)
+		}
+		if tenant.DSN == "" {
+			return "", "", fmt.Errorf("租户 %s 没有配置数据库", slug)
+		}
+		return tenant.DatabaseType, tenant.DSN, nil
+	}
+
+	setup := func(slug string, tenantDB *gorm.DB) error {
+		sqlDB, err := tenantDB.DB()
+		if err != nil {
+			return err
+		}
+		maxIdle, maxOpen := cfg.Tenant.MaxIdleConns, cfg.Tenant.MaxOpenConns
+		if dbCfg, ok := cfg.Tenant.Databases[slug]; ok {
+			if dbCfg.MaxIdleConns > 0 {
+				maxIdle = dbCfg.MaxIdleConns
+			}
+			if dbCfg.MaxOpenConns > 0 {
+				maxOpen = dbCfg.MaxOpenConns
+			}
+		}
+		sqlDB.SetMaxIdleConns(maxIdle)
+		sqlDB.SetMaxOpenConns(maxOpen)
+		sqlDB.SetConnMaxLifetime(time.Hour)
+
+		if err := registerCallbacks(tenantDB, cfg); err != nil {
+			return err
+		}
+		return migrate(tenantDB)
+	}
+
+	return database.NewTenantDBs(resolve, &gorm.Config{Logger: logger.Default.LogMode(logger.Info)}, setup)
+}
+
 // createSampleData 创建示例数据
 func createSampleData(db *gorm.DB) {
 	// 检查是否已有数据